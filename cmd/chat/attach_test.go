@@ -0,0 +1,100 @@
+package chat_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func TestChatWithAttachments(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"diagram.png": "png",
+		"notes.txt":   "notes",
+		"archive.zip": "PK\x03\x04",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %s: %s\n", name, err)
+		}
+	}
+	coreInstance, _ := test_helpers.CreateCore()
+
+	type testCase struct {
+		name        string
+		args        []string
+		contains    []string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should send attachments with new chat message",
+			args: []string{
+				"--content", "what is it?",
+				"--attach", filepath.Join(dir, "diagram.png"),
+				"-a", filepath.Join(dir, "notes.txt"),
+				"--stream=false",
+			},
+			contains: []string{"> mocked: what is it?"},
+		},
+		{
+			name: "should show attachments of message",
+			args: []string{"show", "1"},
+			contains: []string{
+				"[user] 1\nwhat is it?\n[attachment] diagram.png (image/png, 3 bytes)\n[attachment] notes.txt (text/plain, 5 bytes)\n",
+			},
+		},
+		{
+			name: "should reject unsupported file",
+			args: []string{
+				"--latest",
+				"--content", "and this?",
+				"--attach", filepath.Join(dir, "archive.zip"),
+			},
+			shouldError: true,
+		},
+		{
+			name: "should error on missing file",
+			args: []string{
+				"--latest",
+				"--content", "and this?",
+				"--attach", filepath.Join(dir, "missing.png"),
+			},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf(
+					"%q - bad output\nexpected to contain: %q\nactual: %q\n",
+					test.name,
+					expected,
+					out.String(),
+				)
+			}
+		}
+	}
+}
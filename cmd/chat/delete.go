@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createDeleteCommand(c *core.Core) *cobra.Command {
+	deleteCommand := &cobra.Command{
+		Use:   "delete <chat-id>",
+		Short: "Delete chat",
+		Long: `Marks chat as deleted. Deleted chat is hidden from lists and cannot be continued, but its messages and usage stay in database.
+`,
+		Example: `$ hermes chat delete 1`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := parseChatID(args[0])
+			if err != nil {
+				return err
+			}
+			deleteChat := core.NewDeleteChatCommand(c, chatID)
+			if err := deleteChat.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			fmt.Fprintf(
+				c.GetConfig().Stdoout,
+				"Deleted chat %d %s\n",
+				deleteChat.Result.ID,
+				deleteChat.Result.Name,
+			)
+			return nil
+		},
+	}
+
+	return deleteCommand
+}
@@ -0,0 +1,146 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createEditCommand(c *core.Core) *cobra.Command {
+	editCommand := &cobra.Command{
+		Use:   "edit",
+		Short: "Change completion parameters stored in chat",
+		Long: `Updates model, temperature, max tokens and history strategy that are reused when conversation in chat continues. Only provided flags are changed, the rest stays as stored.
+`,
+		Example: `$ hermes chat edit --chat-id 1 --model anthropic/claude-opus
+$ hermes chat edit --chat-id 1 --temperature 0.2 --max-tokens 500
+$ hermes chat edit --chat-id 1 --history summary --history-limit 8000`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := cmd.Flags().GetInt64("chat-id")
+			if err != nil {
+				return err
+			}
+			parameters := ai_clients.Parameters{}
+			if err := preloadParams(cmd, &parameters); err != nil {
+				return err
+			}
+			ctx := c.GetConfig().ShutdownContext
+			if cmd.Flags().Changed("history") || cmd.Flags().Changed("history-limit") {
+				if err := updateHistory(ctx, c, cmd, chatID); err != nil {
+					return err
+				}
+			}
+			update := core.NewUpdateChatParametersCommand(c, chatID, &parameters)
+			if err := update.Execute(ctx); err != nil {
+				return err
+			}
+			outputChatParameters(c.GetConfig().Stdoout, update.Result)
+			return nil
+		},
+	}
+
+	editCommand.Flags().SortFlags = false
+	editCommand.Flags().Int64("chat-id", 0, "id of chat to be edited")
+	editCommand.Flags().StringP("model", "m", "", "completion model")
+	editCommand.Flags().String(
+		"temperature",
+		"",
+		"degree of randomness of AI answer (higher number - more chaotic)",
+	)
+	editCommand.Flags().String(
+		"max-tokens",
+		"",
+		"maximum number of tokens used in output",
+	)
+	addHistoryFlags(editCommand)
+	err := editCommand.MarkFlagRequired("chat-id")
+	if err != nil {
+		panic(err)
+	}
+
+	return editCommand
+}
+
+// flags that were not provided keep stored values
+func updateHistory(ctx context.Context, c *core.Core, cmd *cobra.Command, chatID int64) error {
+	chatQuery := core.NewGetChatByIDQuery(c, chatID)
+	if err := chatQuery.Execute(ctx); err != nil {
+		return err
+	}
+	history, err := preloadHistory(cmd)
+	if err != nil {
+		return err
+	}
+	if !cmd.Flags().Changed("history") {
+		history.Strategy = chatQuery.Result.Strategy
+	}
+	if !cmd.Flags().Changed("history-limit") {
+		history.Limit = chatQuery.Result.Limit
+	}
+	return core.NewUpdateChatHistoryCommand(c, chatID, history).Execute(ctx)
+}
+
+func addHistoryFlags(cmd *cobra.Command) {
+	cmd.Flags().String(
+		"history",
+		"",
+		"how history is reduced before completion, one of: "+strings.Join(core.HistoryStrategies(), ", "),
+	)
+	cmd.Flags().Int64(
+		"history-limit",
+		0,
+		"messages count for last, tokens budget for tokens and summary (defaults to model context window)",
+	)
+}
+
+func preloadHistory(cmd *cobra.Command) (models.History, error) {
+	history := models.History{}
+	strategy, err := cmd.Flags().GetString("history")
+	if err != nil {
+		return history, err
+	}
+	limit, err := cmd.Flags().GetInt64("history-limit")
+	if err != nil {
+		return history, err
+	}
+	history.Strategy = strategy
+	if limit != 0 {
+		history.Limit = &limit
+	}
+	return history, nil
+}
+
+func outputChatParameters(w io.Writer, chat *models.Chat) {
+	temperature := "default"
+	if chat.Temperature != nil {
+		temperature = strconv.FormatFloat(*chat.Temperature, 'f', -1, 64)
+	}
+	maxTokens := "default"
+	if chat.MaxTokens != nil {
+		maxTokens = strconv.FormatInt(*chat.MaxTokens, 10)
+	}
+	history := chat.Strategy
+	if history == "" {
+		history = core.HistoryAll
+	}
+	if chat.Limit != nil {
+		history = fmt.Sprintf("%s %d", history, *chat.Limit)
+	}
+	fmt.Fprintf(
+		w,
+		"[Chat]        %d %s\n[Model]       %s\n[Temperature] %s\n[Max tokens]  %s\n[History]     %s\n",
+		chat.ID,
+		chat.Name,
+		chat.Model,
+		temperature,
+		maxTokens,
+		history,
+	)
+}
@@ -0,0 +1,178 @@
+package chat_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestEditChatParameters(t *testing.T) {
+	type expected struct {
+		model       string
+		maxTokens   *int64
+		temperature *float64
+		output      string
+	}
+	type testCase struct {
+		name        string
+		args        []string
+		expected    expected
+		shouldError bool
+	}
+
+	maxTokens := int64(500)
+	temperature := 0.5
+
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name: "should set model and temperature",
+			args: []string{"edit", "--chat-id", "1", "--model", "anthropic/claude-opus", "--temperature", "0.5"},
+			expected: expected{
+				model:       "anthropic/claude-opus",
+				temperature: &temperature,
+				output: `[Chat]        1 1
+[Model]       anthropic/claude-opus
+[Temperature] 0.5
+[Max tokens]  default
+[History]     all
+`,
+			},
+		},
+		{
+			name: "should keep stored values that were not provided",
+			args: []string{"edit", "--chat-id", "1", "--max-tokens", "500"},
+			expected: expected{
+				model:       "anthropic/claude-opus",
+				temperature: &temperature,
+				maxTokens:   &maxTokens,
+				output: `[Chat]        1 1
+[Model]       anthropic/claude-opus
+[Temperature] 0.5
+[Max tokens]  500
+[History]     all
+`,
+			},
+		},
+		{
+			name: "should set history strategy",
+			args: []string{"edit", "--chat-id", "1", "--history", "last", "--history-limit", "10"},
+			expected: expected{
+				model:       "anthropic/claude-opus",
+				temperature: &temperature,
+				maxTokens:   &maxTokens,
+				output: `[Chat]        1 1
+[Model]       anthropic/claude-opus
+[Temperature] 0.5
+[Max tokens]  500
+[History]     last 10
+`,
+			},
+		},
+		{
+			name:        "should error on unknown history strategy",
+			args:        []string{"edit", "--chat-id", "1", "--history", "everything"},
+			shouldError: true,
+		},
+		{
+			name:        "should error on non existing chat",
+			args:        []string{"edit", "--chat-id", "999", "--model", "openai/gpt-4o"},
+			shouldError: true,
+		},
+		{
+			name:        "should error without chat id",
+			args:        []string{"edit", "--model", "openai/gpt-4o"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		dbChat, err := db_helpers.GetChatByID(db, context.Background(), 1)
+		if err != nil {
+			t.Fatalf("%q - failed to get chat: %s\n", test.name, err)
+		}
+		actual := expected{
+			model:       dbChat.Model,
+			maxTokens:   dbChat.MaxTokens,
+			temperature: dbChat.Temperature,
+			output:      out.String(),
+		}
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf(
+				"%q - bad chat parameters\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestContinueChatWithStoredParameters(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	edit := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	edit.SetArgs([]string{"edit", "--chat-id", "1", "--model", "anthropic/claude-opus"})
+	if err := edit.Execute(); err != nil {
+		t.Fatalf("failed to edit chat: %s\n", err)
+	}
+
+	usedModels := []string{}
+	completion := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		params *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		usedModels = append(usedModels, params.Model)
+		return test_helpers.MockCompletion(ctx, messages, params, providers, onDelta)
+	}
+
+	for _, args := range [][]string{
+		{"--latest", "--content", "reuse stored model"},
+		{"--latest", "--content", "override", "--model", "openai/gpt-4o"},
+		{"--latest", "--content", "reuse stored model again"},
+	} {
+		cmd := chat.CreateChatCommand(coreInstance, completion)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("failed to execute chat with %v: %s\n", args, err)
+		}
+	}
+
+	expected := []string{"anthropic/claude-opus", "openai/gpt-4o", "anthropic/claude-opus"}
+	if !reflect.DeepEqual(expected, usedModels) {
+		t.Errorf("bad models used\nexpected: %v\nactual:   %v\n", expected, usedModels)
+	}
+}
@@ -0,0 +1,46 @@
+package chat
+
+import (
+	"strings"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createExportCommand(c *core.Core) *cobra.Command {
+	exportCommand := &cobra.Command{
+		Use:   "export <chat-id>",
+		Short: "Print chat in portable format",
+		Long: `Prints chat with its messages, roles, timestamps and model metadata. ` + "`json`" + ` output can be restored with ` + "`hermes chat import`" + `, ` + "`jsonl`" + ` holds one message per line without chat parameters, ` + "`md`" + ` is meant for reading.
+Only selected generations are exported.
+`,
+		Example: `$ hermes chat export 1 > chat.md
+$ hermes chat export 1 --format json > chat.json
+$ hermes chat export 1 --format jsonl | jq -r .content`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := parseChatID(args[0])
+			if err != nil {
+				return err
+			}
+			format, err := cmd.Flags().GetString("format")
+			if err != nil {
+				return err
+			}
+			query := core.NewExportChatQuery(c, chatID)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			return core.WriteChatExport(c.GetConfig().Stdoout, query.Result, format)
+		},
+	}
+
+	exportCommand.Flags().StringP(
+		"format",
+		"f",
+		core.ChatExportMarkdown,
+		"output format: "+strings.Join(core.ChatExportFormats, ", "),
+	)
+
+	return exportCommand
+}
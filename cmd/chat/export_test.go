@@ -0,0 +1,233 @@
+package chat_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestExportChat(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateChats(db, ctx, []*models.Chat{
+		{ID: 1, Name: "crash"},
+	}); err != nil {
+		t.Fatalf("failed to create chat: %s\n", err)
+	}
+	if err := db_helpers.CreateMessages(db, ctx, []*models.Message{
+		{ChatID: 1, Role: "user", Content: "what happened?"},
+		{ChatID: 1, Role: "assistant", Content: "it crashed"},
+	}); err != nil {
+		t.Fatalf("failed to create messages: %s\n", err)
+	}
+	if err := db_helpers.CreateMessageUsage(db, ctx, []*models.Usage{
+		{MessageID: 2, Model: "openai/gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.25},
+	}); err != nil {
+		t.Fatalf("failed to create usage: %s\n", err)
+	}
+	if _, err := db.Exec(
+		"INSERT INTO attachments (message_id, name, media_type, size, data) VALUES (1, 'trace.txt', 'text/plain', 5, 'trace')",
+	); err != nil {
+		t.Fatalf("failed to create attachment: %s\n", err)
+	}
+
+	type testCase struct {
+		name        string
+		args        []string
+		contains    []string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should export markdown by default",
+			args: []string{"export", "1"},
+			contains: []string{
+				"# crash\n",
+				"- created at: ",
+				"## user (",
+				"what happened?\n\n_attachment: trace.txt (text/plain)_\n",
+				"_openai/gpt-4o, 10 input / 5 output tokens, $0.25_\n\nit crashed\n",
+			},
+		},
+		{
+			name: "should export json",
+			args: []string{"export", "1", "--format", "json"},
+			contains: []string{
+				`"name": "crash"`,
+				`"role": "assistant"`,
+				`"input_tokens": 10`,
+				`"attachments": [
+        {
+          "name": "trace.txt",
+          "media_type": "text/plain"
+        }
+      ]`,
+				`"created_at": "`,
+			},
+		},
+		{
+			name: "should export message per line in jsonl",
+			args: []string{"export", "1", "--format", "jsonl"},
+			contains: []string{
+				"{\"role\":\"user\",\"content\":\"what happened?\",\"created_at\":",
+				"\"attachments\":[{\"name\":\"trace.txt\",\"media_type\":\"text/plain\"}]}\n",
+				"\"usage\":{\"model\":\"openai/gpt-4o\",\"input_tokens\":10,\"output_tokens\":5,\"cost\":0.25}}\n",
+			},
+		},
+		{
+			name:        "should error on unknown format",
+			args:        []string{"export", "1", "--format", "html"},
+			shouldError: true,
+		},
+		{
+			name:        "should error on non existing chat",
+			args:        []string{"export", "2"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(out.String(), expected) {
+				t.Errorf(
+					"%q - bad output\nexpected to contain: %q\nactual: %q\n",
+					test.name,
+					expected,
+					out.String(),
+				)
+			}
+		}
+	}
+}
+
+func TestImportChat(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateChats(db, ctx, []*models.Chat{
+		{ID: 1, Name: "crash"},
+	}); err != nil {
+		t.Fatalf("failed to create chat: %s\n", err)
+	}
+	if err := db_helpers.CreateMessages(db, ctx, []*models.Message{
+		{ChatID: 1, Role: "user", Content: "what happened?"},
+		{ChatID: 1, Role: "assistant", Content: "it crashed"},
+	}); err != nil {
+		t.Fatalf("failed to create messages: %s\n", err)
+	}
+	if err := db_helpers.CreateMessageUsage(db, ctx, []*models.Usage{
+		{MessageID: 2, Model: "openai/gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.25},
+	}); err != nil {
+		t.Fatalf("failed to create usage: %s\n", err)
+	}
+	exported := &strings.Builder{}
+	coreInstance.GetConfig().Stdoout = exported
+	cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.SetArgs([]string{"export", "1", "--format", "json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to export chat: %s\n", err)
+	}
+	file := filepath.Join(t.TempDir(), "chat.json")
+	if err := os.WriteFile(file, []byte(exported.String()), 0o644); err != nil {
+		t.Fatalf("failed to write export: %s\n", err)
+	}
+
+	type testCase struct {
+		name        string
+		args        []string
+		stdin       string
+		expected    string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name:     "should import json export from file",
+			args:     []string{"import", file},
+			expected: "[Chat]     2 crash\n[Messages] 2\n",
+		},
+		{
+			name:     "should import OpenAI messages from stdin",
+			args:     []string{"import"},
+			stdin:    `{"model": "gpt-4o", "messages": [{"role": "developer", "content": "be brief"}, {"role": "user", "content": [{"type": "text", "text": "hi"}]}]}`,
+			expected: "[Chat]     3 be brief\n[Messages] 2\n",
+		},
+		{
+			name:        "should error on unsupported role",
+			args:        []string{"import"},
+			stdin:       `[{"role": "tool", "content": "42"}]`,
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		coreInstance.GetConfig().Stdin = strings.NewReader(test.stdin)
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+
+	original := &strings.Builder{}
+	imported := &strings.Builder{}
+	for id, out := range map[string]*strings.Builder{"1": original, "2": imported} {
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs([]string{"export", id, "--format", "jsonl"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("failed to export chat %s: %s\n", id, err)
+		}
+	}
+	if original.String() != imported.String() {
+		t.Errorf(
+			"imported chat differs from original\noriginal: %q\nimported: %q\n",
+			original.String(),
+			imported.String(),
+		)
+	}
+}
@@ -0,0 +1,67 @@
+package chat
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/k10wl/hermes/cmd/utils"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
+	"github.com/spf13/cobra"
+)
+
+func createForkCommand(c *core.Core) *cobra.Command {
+	forkCommand := &cobra.Command{
+		Use:   "fork",
+		Short: "Copy chat up to given message into new chat",
+		Long: `Creates new chat with messages of original chat up to and including given message. Original chat stays untouched, so conversation can continue differently in fork.
+Forked chat keeps completion parameters of original chat and remembers where it came from.
+`,
+		Example: `$ hermes chat fork --chat 1 --message 12
+$ hermes chat fork --chat 1 --message 12 && hermes chat --latest --content "what if we try another approach?"`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := cmd.Flags().GetInt64("chat")
+			if err != nil {
+				return err
+			}
+			messageID, err := cmd.Flags().GetInt64("message")
+			if err != nil {
+				return err
+			}
+			fork := core.NewForkChatCommand(c, chatID, messageID)
+			if err := fork.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			id := uuid.NewString()
+			if data, err := messages.Encode(
+				messages.NewServerChatCreated(
+					id,
+					fork.Result.Chat,
+					fork.Result.Message,
+				),
+			); err == nil {
+				utils.NotifyActiveSessions(c, id, data)
+			}
+			fmt.Fprintf(
+				c.GetConfig().Stdoout,
+				"[Chat]    %d %s\n[Forked]  chat %d, message %d\n",
+				fork.Result.Chat.ID,
+				fork.Result.Chat.Name,
+				chatID,
+				messageID,
+			)
+			return nil
+		},
+	}
+
+	forkCommand.Flags().SortFlags = false
+	forkCommand.Flags().Int64("chat", 0, "id of chat to be forked")
+	forkCommand.Flags().Int64("message", 0, "id of last message to be copied into fork")
+	for _, flag := range []string{"chat", "message"} {
+		if err := forkCommand.MarkFlagRequired(flag); err != nil {
+			panic(err)
+		}
+	}
+
+	return forkCommand
+}
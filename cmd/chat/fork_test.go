@@ -0,0 +1,84 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestForkChat(t *testing.T) {
+	type testCase struct {
+		name        string
+		args        []string
+		expected    string
+		shouldError bool
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(3, 1); err != nil {
+		t.Fatalf("failed to seed messages: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name:     "should fork chat",
+			args:     []string{"fork", "--chat", "1", "--message", "2"},
+			expected: "[Chat]    2 1\n[Forked]  chat 1, message 2\n",
+		},
+		{
+			name:        "should error when message is not in chat",
+			args:        []string{"fork", "--chat", "2", "--message", "3"},
+			shouldError: true,
+		},
+		{
+			name:        "should error without message",
+			args:        []string{"fork", "--chat", "1"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+
+	forked, err := db_helpers.GetChatByID(db, context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to get forked chat: %s\n", err)
+	}
+	if forked.ParentChatID == nil || *forked.ParentChatID != 1 ||
+		forked.ParentMessageID == nil || *forked.ParentMessageID != 2 {
+		t.Errorf("bad parent of forked chat: %+v\n", forked)
+	}
+}
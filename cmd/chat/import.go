@@ -0,0 +1,66 @@
+package chat
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/k10wl/hermes/cmd/utils"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
+	"github.com/spf13/cobra"
+)
+
+func createImportCommand(c *core.Core) *cobra.Command {
+	importCommand := &cobra.Command{
+		Use:   "import [file]",
+		Short: "Recreate chat from exported file",
+		Long: `Creates new chat from ` + "`hermes chat export --format json`" + ` or ` + "`jsonl`" + ` output, or from OpenAI-style ` + "`{\"messages\": [...]}`" + ` object and bare messages array. Reads stdin when file is omitted.
+Timestamps and usage are kept when present, missing model falls back to default one.
+`,
+		Example: `$ hermes chat import chat.json
+$ cat openai-request.json | hermes chat import`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var content []byte
+			var err error
+			if len(args) == 1 {
+				content, err = os.ReadFile(args[0])
+			} else {
+				content, err = io.ReadAll(c.GetConfig().Stdin)
+			}
+			if err != nil {
+				return err
+			}
+			export, err := core.ParseChatExport(content)
+			if err != nil {
+				return err
+			}
+			command := core.NewImportChatCommand(c, export)
+			if err := command.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			id := uuid.NewString()
+			if data, err := messages.Encode(
+				messages.NewServerChatCreated(
+					id,
+					command.Result.Chat,
+					command.Result.Message,
+				),
+			); err == nil {
+				utils.NotifyActiveSessions(c, id, data)
+			}
+			fmt.Fprintf(
+				c.GetConfig().Stdoout,
+				"[Chat]     %d %s\n[Messages] %d\n",
+				command.Result.Chat.ID,
+				command.Result.Chat.Name,
+				len(export.Messages),
+			)
+			return nil
+		},
+	}
+
+	return importCommand
+}
@@ -0,0 +1,88 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createListCommand(c *core.Core) *cobra.Command {
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "List stored chats, newest first",
+		Long: `Lists chats page by page, same as ` + "`/api/v1/chats`" + ` does. ` + "`--limit`" + ` sets page size (-1 lists everything), ` + "`--start-before-id`" + ` continues listing after the last shown chat.
+Use ` + "`--json`" + ` to get machine readable output.
+`,
+		Example: `$ hermes chat list
+$ hermes chat list --limit 5 --start-before-id 42
+$ hermes chat list --limit -1 --json | jq '.[].name'`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, err := cmd.Flags().GetInt64("limit")
+			if err != nil {
+				return err
+			}
+			startBeforeID, err := cmd.Flags().GetInt64("start-before-id")
+			if err != nil {
+				return err
+			}
+			asJSON, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			query := core.NewGetChatsQuery(c, limit, startBeforeID)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			config := c.GetConfig()
+			// hint goes to stderr and does not break piped output
+			if limit > 0 && int64(len(query.Result)) == limit {
+				defer fmt.Fprintf(
+					config.Stderr,
+					"more chats: --start-before-id %d\n",
+					query.Result[len(query.Result)-1].ID,
+				)
+			}
+			if asJSON {
+				return json.NewEncoder(config.Stdoout).Encode(query.Result)
+			}
+			return writeChats(config.Stdoout, query.Result)
+		},
+	}
+
+	listCommand.Flags().SortFlags = false
+	listCommand.Flags().Int64("limit", 20, "amount of chats per page, -1 for all")
+	listCommand.Flags().Int64(
+		"start-before-id",
+		-1,
+		"list chats with id lower than given one",
+	)
+	listCommand.Flags().Bool("json", false, "output chats as json")
+
+	return listCommand
+}
+
+func writeChats(w io.Writer, chats []*models.Chat) error {
+	if len(chats) == 0 {
+		_, err := fmt.Fprintf(w, "No chats found\n")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\tMODEL\tCREATED\tNAME\n")
+	for _, chat := range chats {
+		created := ""
+		if chat.CreatedAt != nil {
+			created = chat.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		model := chat.Model
+		if model == "" {
+			model = "-"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", chat.ID, model, created, chat.Name)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,94 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestListChats(t *testing.T) {
+	type testCase struct {
+		name        string
+		args        []string
+		expectedIDs []int64
+		hint        string
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(5); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	if _, err := db.Exec(`UPDATE chats SET deleted_at = CURRENT_TIMESTAMP WHERE id = 4`); err != nil {
+		t.Fatalf("failed to delete chat: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name:        "should list all chats except deleted",
+			args:        []string{"list", "--limit", "-1", "--json"},
+			expectedIDs: []int64{5, 3, 2, 1},
+		},
+		{
+			name:        "should limit page and hint next one",
+			args:        []string{"list", "--limit", "2", "--json"},
+			expectedIDs: []int64{5, 3},
+			hint:        "more chats: --start-before-id 3\n",
+		},
+		{
+			name:        "should start before id",
+			args:        []string{"list", "--limit", "2", "--start-before-id", "3", "--json"},
+			expectedIDs: []int64{2, 1},
+			hint:        "more chats: --start-before-id 1\n",
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		errOut := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		coreInstance.GetConfig().Stderr = errOut
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		var chats []*models.Chat
+		if err := json.Unmarshal([]byte(out.String()), &chats); err != nil {
+			t.Errorf("%q - bad json output: %s\n", test.name, out.String())
+			continue
+		}
+		ids := []int64{}
+		for _, chat := range chats {
+			ids = append(ids, chat.ID)
+		}
+		if !reflect.DeepEqual(test.expectedIDs, ids) {
+			t.Errorf("%q - bad chats\nexpected: %v\nactual:   %v\n", test.name, test.expectedIDs, ids)
+		}
+		if errOut.String() != test.hint {
+			t.Errorf("%q - bad hint\nexpected: %q\nactual:   %q\n", test.name, test.hint, errOut.String())
+		}
+	}
+
+	out := &strings.Builder{}
+	coreInstance.GetConfig().Stdoout = out
+	cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.SetArgs([]string{"list", "--limit", "1"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to list chats: %s\n", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 ||
+		!strings.HasPrefix(lines[0], "ID") ||
+		!strings.HasPrefix(lines[1], "5 ") ||
+		!strings.HasSuffix(lines[1], " 5") {
+		t.Errorf("bad human readable output:\n%s\n", out.String())
+	}
+}
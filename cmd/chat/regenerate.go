@@ -0,0 +1,208 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/k10wl/hermes/cmd/utils"
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
+	"github.com/spf13/cobra"
+)
+
+func createRegenerateCommand(c *core.Core, completion ai_clients.CompletionFn) *cobra.Command {
+	regenerateCommand := &cobra.Command{
+		Use:   "regenerate",
+		Short: "Create another generation of assistant answer",
+		Long: `Sends the same history for completion once more and stores answer as new generation of assistant message. New generation becomes selected and is used as history when conversation continues.
+Without ` + "`--message-id`" + ` the last answer in latest chat is regenerated.
+`,
+		Example: `$ hermes chat regenerate
+$ hermes chat regenerate --message-id 42 --model openai/o1 --temperature 1`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := c.GetConfig().ShutdownContext
+			messageID, err := cmd.Flags().GetInt64("message-id")
+			if err != nil {
+				return err
+			}
+			stream, err := cmd.Flags().GetBool("stream")
+			if err != nil {
+				return err
+			}
+			parameters := ai_clients.Parameters{}
+			if err := preloadParams(cmd, &parameters); err != nil {
+				return err
+			}
+			history, err := preloadHistory(cmd)
+			if err != nil {
+				return err
+			}
+			if messageID == 0 {
+				messageID, err = latestAnswerID(ctx, c)
+				if err != nil {
+					return err
+				}
+			}
+			regenerate := core.NewRegenerateMessageCommand(
+				c,
+				messageID,
+				&parameters,
+				completion,
+			)
+			regenerate.WithHistory(&history)
+			if stream {
+				regenerate.Stream(streamOutput(c.GetConfig().Stdoout))
+			}
+			completionCtx, stop := interruptContext(ctx)
+			defer stop()
+			if err := regenerate.Execute(completionCtx); err != nil {
+				return completionError(err)
+			}
+			id := uuid.NewString()
+			if data, err := messages.Encode(
+				messages.NewServerGenerationCreated(
+					id,
+					regenerate.Result.ChatID,
+					regenerate.Result,
+				),
+			); err == nil {
+				utils.NotifyActiveSessions(c, id, data)
+			}
+			finishOutput(c.GetConfig().Stdoout, regenerate.Result, stream)
+			reportInterrupted(c.GetConfig().Stderr, regenerate.Result)
+			return nil
+		},
+	}
+
+	regenerateCommand.Flags().SortFlags = false
+	regenerateCommand.Flags().Int64(
+		"message-id",
+		0,
+		"id of assistant message to be regenerated, defaults to last answer in latest chat",
+	)
+	regenerateCommand.Flags().StringP(
+		"model",
+		"m",
+		"",
+		"completion model, defaults to the one stored in chat, default_model of config profile or "+core.DefaultModel,
+	)
+	regenerateCommand.Flags().String(
+		"temperature",
+		"",
+		"degree of randomness of AI answer (higher number - more chaotic)",
+	)
+	regenerateCommand.Flags().String(
+		"max-tokens",
+		"",
+		"maximum number of tokens used in output",
+	)
+	regenerateCommand.Flags().Bool(
+		"stream",
+		true,
+		"print completion as soon as tokens arrive (--stream=false waits for whole answer)",
+	)
+	addHistoryFlags(regenerateCommand)
+
+	return regenerateCommand
+}
+
+func createGenerationsCommand(c *core.Core) *cobra.Command {
+	generationsCommand := &cobra.Command{
+		Use:   "generations",
+		Short: "List or select generations of message",
+		Long: `Lists all generations of message, selected one is marked with "*". Use ` + "`--select`" + ` to make another generation active in chat history.
+`,
+		Example: `$ hermes chat generations --message-id 42
+$ hermes chat generations --message-id 42 --select 45`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := c.GetConfig().ShutdownContext
+			messageID, err := cmd.Flags().GetInt64("message-id")
+			if err != nil {
+				return err
+			}
+			selectID, err := cmd.Flags().GetInt64("select")
+			if err != nil {
+				return err
+			}
+			if selectID != 0 {
+				selectGeneration := core.NewSelectGenerationCommand(c, selectID)
+				if err := selectGeneration.Execute(ctx); err != nil {
+					return err
+				}
+				id := uuid.NewString()
+				if data, err := messages.Encode(
+					messages.NewServerGenerationSelected(
+						id,
+						selectGeneration.Result.ChatID,
+						selectGeneration.Result,
+					),
+				); err == nil {
+					utils.NotifyActiveSessions(c, id, data)
+				}
+				messageID = selectID
+			}
+			query := core.NewGetMessageGenerationsQuery(c, messageID)
+			if err := query.Execute(ctx); err != nil {
+				return err
+			}
+			if len(query.Result) == 0 {
+				return fmt.Errorf("message with id %d does not exist\n", messageID)
+			}
+			outputGenerations(c.GetConfig().Stdoout, query.Result)
+			return nil
+		},
+	}
+
+	generationsCommand.Flags().SortFlags = false
+	generationsCommand.Flags().Int64("message-id", 0, "id of any generation of message")
+	generationsCommand.Flags().Int64(
+		"select",
+		0,
+		"id of generation to be selected",
+	)
+	err := generationsCommand.MarkFlagRequired("message-id")
+	if err != nil {
+		panic(err)
+	}
+
+	return generationsCommand
+}
+
+// last assistant message in latest chat
+func latestAnswerID(ctx context.Context, c *core.Core) (int64, error) {
+	chatQuery := core.LatestChatQuery{Core: c}
+	if err := chatQuery.Execute(ctx); err != nil {
+		return 0, err
+	}
+	messagesQuery := core.GetChatMessagesQuery{Core: c, ChatID: chatQuery.Result.ID}
+	if err := messagesQuery.Execute(ctx); err != nil {
+		return 0, err
+	}
+	for i := len(messagesQuery.Result) - 1; i >= 0; i-- {
+		if messagesQuery.Result[i].Role == core.AssistantRole {
+			return messagesQuery.Result[i].ID, nil
+		}
+	}
+	return 0, fmt.Errorf("latest chat has no answers to regenerate\n")
+}
+
+func outputGenerations(w io.Writer, generations []*models.Message) {
+	for _, generation := range generations {
+		selected := " "
+		if generation.SelectedGeneration {
+			selected = "*"
+		}
+		fmt.Fprintf(
+			w,
+			"%s [%d] id %d\n%s\n\n",
+			selected,
+			generation.Generation,
+			generation.ID,
+			generation.Content,
+		)
+	}
+}
@@ -0,0 +1,98 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestRegenerateAndSelectGeneration(t *testing.T) {
+	type testCase struct {
+		name        string
+		args        []string
+		expected    string
+		shouldError bool
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	prepare := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	prepare.SetArgs([]string{"--latest", "--content", "hello"})
+	if err := prepare.Execute(); err != nil {
+		t.Fatalf("failed to create completion: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name:     "should regenerate last answer in latest chat",
+			args:     []string{"regenerate", "--stream=false"},
+			expected: "> mocked: hello\n",
+		},
+		{
+			name: "should list generations",
+			args: []string{"generations", "--message-id", "2"},
+			expected: `  [0] id 2
+> mocked: hello
+
+* [1] id 3
+> mocked: hello
+
+`,
+		},
+		{
+			name: "should select generation",
+			args: []string{"generations", "--message-id", "3", "--select", "2"},
+			expected: `* [0] id 2
+> mocked: hello
+
+  [1] id 3
+> mocked: hello
+
+`,
+		},
+		{
+			name:        "should error upon regeneration of user message",
+			args:        []string{"regenerate", "--message-id", "1"},
+			shouldError: true,
+		},
+		{
+			name:        "should error upon non existing message",
+			args:        []string{"generations", "--message-id", "999"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+}
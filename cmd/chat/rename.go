@@ -0,0 +1,33 @@
+package chat
+
+import (
+	"strings"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createRenameCommand(c *core.Core) *cobra.Command {
+	renameCommand := &cobra.Command{
+		Use:   "rename <chat-id> <name>",
+		Short: "Change chat name",
+		Long: `Replaces chat name, all arguments after chat id are joined into new name.
+`,
+		Example: `$ hermes chat rename 1 crash investigation`,
+		Args:    cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := parseChatID(args[0])
+			if err != nil {
+				return err
+			}
+			rename := core.NewRenameChatCommand(c, chatID, strings.Join(args[1:], " "))
+			if err := rename.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			outputChatParameters(c.GetConfig().Stdoout, rename.Result)
+			return nil
+		},
+	}
+
+	return renameCommand
+}
@@ -0,0 +1,128 @@
+package chat_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestRenameAndDeleteChat(t *testing.T) {
+	type testCase struct {
+		name         string
+		args         []string
+		expectedName string
+		shouldError  bool
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name:         "should rename chat",
+			args:         []string{"rename", "1", "crash", "investigation"},
+			expectedName: "crash investigation",
+		},
+		{
+			name:        "should not rename into empty name",
+			args:        []string{"rename", "1", " "},
+			shouldError: true,
+		},
+		{
+			name:        "should not rename non existing chat",
+			args:        []string{"rename", "2", "name"},
+			shouldError: true,
+		},
+		{
+			name:         "should delete chat",
+			args:         []string{"delete", "1"},
+			expectedName: "crash investigation",
+		},
+		{
+			name:        "should not delete chat twice",
+			args:        []string{"delete", "1"},
+			shouldError: true,
+		},
+		{
+			name:        "should not rename deleted chat",
+			args:        []string{"rename", "1", "name"},
+			shouldError: true,
+		},
+		{
+			name:        "should not continue deleted chat",
+			args:        []string{"--chat-id", "1", "--content", "hello"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		dbChat, err := db_helpers.GetChatByID(db, context.Background(), 1)
+		if err != nil {
+			t.Fatalf("%q - failed to get chat: %s\n", test.name, err)
+		}
+		if dbChat.Name != test.expectedName {
+			t.Errorf(
+				"%q - bad name\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expectedName,
+				dbChat.Name,
+			)
+		}
+	}
+}
+
+func TestContinueChatByID(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(2); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.SetArgs([]string{"--chat-id", "1", "--content", "hello", "--stream=false"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to continue chat: %s\n", err)
+	}
+	for id, expected := range map[int64]int{1: 2, 2: 0} {
+		messages, err := db_helpers.GetMessagesByChatID(db, context.Background(), id)
+		if err != nil {
+			t.Fatalf("failed to get messages: %s\n", err)
+		}
+		actual := 0
+		for _, message := range messages {
+			if message.ChatID == id {
+				actual++
+			}
+		}
+		if actual != expected {
+			t.Errorf("bad amount of messages in chat %d, expected %d, got %d\n", id, expected, actual)
+		}
+	}
+
+	cmd = chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.SetArgs([]string{"--chat-id", "1", "--latest", "--content", "hello"})
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	if err := cmd.Execute(); err == nil {
+		t.Errorf("expected to error when both --latest and --chat-id are used\n")
+	}
+}
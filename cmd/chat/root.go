@@ -1,9 +1,12 @@
 package chat
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
 
@@ -12,6 +15,7 @@ import (
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
 	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
 	"github.com/spf13/cobra"
 )
@@ -24,14 +28,25 @@ func CreateChatCommand(c *core.Core, completion ai_clients.CompletionFn) *cobra.
 		Use:   "chat [flags] (will error upon empty content)",
 		Short: "Send chat message for completion",
 		Long: `Sends messages for AI completion. You can provide your message directly with the ` + "`--content`" + ` flag or pipe in text. Options include model selection, randomness adjustment, and template usage.
+Model, temperature and max tokens are stored in chat upon creation and reused when conversation continues, unless overridden by flags.
+Long chats can exceed model context window, history strategy reduces what is sent: last N messages, tokens budget or rolling summary of older messages. Set it per chat with ` + "`hermes chat edit`" + ` or per call with ` + "`--history`" + `.
 `,
 		Example: `$ cat crash.log | hermes chat
 $ hermes chat --content "hello world"
 
 $ cat crash.log | hermes chat --content "what happened here?"
 $ hermes chat --latest --content "how can I fix that crash I send you before?"
+$ hermes chat --chat-id 3 --content "let's get back to this one"
+$ git diff | hermes chat --persona reviewer
+$ hermes chat edit --chat-id 1 --model anthropic/claude-opus --temperature 0.5
+$ hermes chat regenerate --temperature 1
+$ hermes chat --chat-id 3 --history last --history-limit 10 --content "only recent messages matter"
+$ hermes chat fork --chat 1 --message 12
+$ hermes chat export 1 --format json > chat.json && hermes chat import chat.json
 
 $ git diff --cached | hermes chat --template commit --model openai/o1
+$ hermes chat --attach diagram.png --attach spec.pdf --content "does diagram match spec?"
+$ hermes chat --template review --data review.yaml --var language=go --content "$(cat main.go)"
 
 $ hermes chat \
     --model anthropic/claude-3-5-sonnet-latest \
@@ -61,21 +76,36 @@ $ hermes chat \
 			if err != nil {
 				return err
 			}
+			noDefaultTemplate, err := cmd.Flags().GetBool("no-default-template")
+			if err != nil {
+				return err
+			}
+			if template == "" && !noDefaultTemplate {
+				template = c.GetConfig().DefaultTemplate
+			}
 			ok, err := cmd.Flags().GetBool("latest")
 			if err != nil {
 				return err
 			}
-			var complete func(
-				c *core.Core,
-				aiParameters *ai_clients.Parameters,
-				content string,
-				template string,
-				completion ai_clients.CompletionFn,
-			) error
-			if ok {
-				complete = completeInChat
-			} else {
-				complete = createChatAndComplete
+			chatID, err := cmd.Flags().GetInt64("chat-id")
+			if err != nil {
+				return err
+			}
+			stream, err := cmd.Flags().GetBool("stream")
+			if err != nil {
+				return err
+			}
+			history, err := preloadHistory(cmd)
+			if err != nil {
+				return err
+			}
+			persona, err := cmd.Flags().GetString("persona")
+			if err != nil {
+				return err
+			}
+			variables, err := utils.ReadTemplateVariables(cmd)
+			if err != nil {
+				return err
 			}
 			if stdin != "" {
 				content = fmt.Sprintf("%s\n\n%s", stdin, content)
@@ -83,11 +113,41 @@ $ hermes chat \
 			if strings.Trim(content, " \n\t") == "" {
 				return fmt.Errorf("input message was empty")
 			}
-			err = complete(c, &aiParameters, content, template, completion)
+			files, err := cmd.Flags().GetStringArray("attach")
 			if err != nil {
 				return err
 			}
-			return nil
+			attachments, err := readAttachments(files)
+			if err != nil {
+				return err
+			}
+			if ok || chatID != 0 {
+				return completeInChat(
+					c,
+					chatID,
+					&aiParameters,
+					&history,
+					persona,
+					content,
+					template,
+					variables,
+					attachments,
+					stream,
+					completion,
+				)
+			}
+			return createChatAndComplete(
+				c,
+				&aiParameters,
+				&history,
+				persona,
+				content,
+				template,
+				variables,
+				attachments,
+				stream,
+				completion,
+			)
 		},
 	}
 
@@ -102,7 +162,19 @@ $ hermes chat \
 		"template",
 		"t",
 		"",
-		"name of predefined template to be applied (see `hermes template --help)",
+		"name of predefined template to be applied, defaults to default_template of config profile (see `hermes template --help)",
+	)
+	chatCommand.Flags().Bool(
+		"no-default-template",
+		false,
+		"sends message without default_template of config profile",
+	)
+	utils.AddTemplateVariablesFlags(chatCommand)
+	chatCommand.Flags().StringArrayP(
+		"attach",
+		"a",
+		[]string{},
+		"file sent with message, can be repeated. Images (png, jpeg, gif, webp), pdf and text files are accepted, provider support varies",
 	)
 	chatCommand.Flags().BoolP(
 		"latest",
@@ -110,7 +182,24 @@ $ hermes chat \
 		false,
 		"continues conversation in latest chat",
 	)
-	chatCommand.Flags().StringP("model", "m", "openai/gpt-4o-mini", "completion model")
+	chatCommand.Flags().Int64(
+		"chat-id",
+		0,
+		"continues conversation in chat with given id (see `hermes chat list`)",
+	)
+	chatCommand.MarkFlagsMutuallyExclusive("latest", "chat-id")
+	chatCommand.Flags().StringP(
+		"persona",
+		"p",
+		"",
+		"name of persona attached to chat, its prompt is sent as system message (see `hermes persona --help`)",
+	)
+	chatCommand.Flags().StringP(
+		"model",
+		"m",
+		"",
+		"completion model, defaults to the one stored in chat, default_model of config profile or "+core.DefaultModel,
+	)
 	chatCommand.Flags().
 		String(
 			"temperature",
@@ -122,62 +211,130 @@ $ hermes chat \
 		"",
 		"maximum number of tokens used in output",
 	)
+	chatCommand.Flags().Bool(
+		"stream",
+		true,
+		"print completion as soon as tokens arrive (--stream=false waits for whole answer)",
+	)
+	addHistoryFlags(chatCommand)
+
+	chatCommand.AddCommand(createEditCommand(c))
+	chatCommand.AddCommand(createRegenerateCommand(c, completion))
+	chatCommand.AddCommand(createGenerationsCommand(c))
+	chatCommand.AddCommand(createForkCommand(c))
+	chatCommand.AddCommand(createListCommand(c))
+	chatCommand.AddCommand(createShowCommand(c))
+	chatCommand.AddCommand(createExportCommand(c))
+	chatCommand.AddCommand(createImportCommand(c))
+	chatCommand.AddCommand(createRenameCommand(c))
+	chatCommand.AddCommand(createDeleteCommand(c))
 
 	return chatCommand
 }
 
+// chatID 0 continues latest chat
 func completeInChat(
 	c *core.Core,
+	chatID int64,
 	aiParameters *ai_clients.Parameters,
+	history *models.History,
+	persona string,
 	content string,
 	template string,
+	variables map[string]any,
+	attachments []*models.Attachment,
+	stream bool,
 	completion ai_clients.CompletionFn,
 ) error {
 	config := c.GetConfig()
-	chatQuery := core.LatestChatQuery{Core: c}
-	if err := chatQuery.Execute(config.ShutdownContext); err != nil {
+	chat, err := resolveChat(config.ShutdownContext, c, chatID)
+	if err != nil {
 		return err
 	}
 	id := uuid.NewString()
 	if data, err := messages.Encode(
 		messages.NewServerMessageCreated(
 			id,
-			chatQuery.Result.ID,
+			chat.ID,
 			&models.Message{
-				ChatID:  chatQuery.Result.ID,
-				Content: content,
-				Role:    core.UserRole,
+				ChatID:             chat.ID,
+				Content:            content,
+				Role:               core.UserRole,
+				SelectedGeneration: true,
 			}),
 	); err == nil {
 		utils.NotifyActiveSessions(c, id, data)
 	}
 	cmd := core.NewCreateCompletionCommand(
 		c,
-		chatQuery.Result.ID,
+		chat.ID,
 		core.UserRole,
 		content,
 		template,
 		aiParameters,
 		completion,
 	)
-	err := cmd.Execute(config.ShutdownContext)
-	if err != nil {
-		return err
+	cmd.WithHistory(history)
+	cmd.WithPersona(persona)
+	cmd.WithVariables(variables)
+	cmd.WithUploads(attachments)
+	if stream {
+		cmd.Stream(streamOutput(config.Stdoout))
+	}
+	ctx, stop := interruptContext(config.ShutdownContext)
+	defer stop()
+	if err := cmd.Execute(ctx); err != nil {
+		return completionError(err)
 	}
 	if data, err := messages.Encode(
 		messages.NewServerMessageCreated(id, cmd.Result.ChatID, cmd.Result),
 	); err == nil {
 		utils.NotifyActiveSessions(c, id, data)
 	}
-	outputMessage(config.Stdoout, cmd.Result)
+	finishOutput(config.Stdoout, cmd.Result, stream)
+	reportInterrupted(config.Stderr, cmd.Result)
 	return nil
 }
 
+// reads and checks files, they are stored together with message
+func readAttachments(files []string) ([]*models.Attachment, error) {
+	attachments := []*models.Attachment{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		attachment, err := core.NewAttachment(file, data)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// chatID 0 resolves latest chat
+func resolveChat(ctx context.Context, c *core.Core, chatID int64) (*models.Chat, error) {
+	if chatID == 0 {
+		query := core.LatestChatQuery{Core: c}
+		err := query.Execute(ctx)
+		return query.Result, err
+	}
+	query := core.NewGetChatByIDQuery(c, chatID)
+	err := query.Execute(ctx)
+	return query.Result, err
+}
+
 func createChatAndComplete(
 	c *core.Core,
 	aiParameters *ai_clients.Parameters,
+	history *models.History,
+	persona string,
 	content string,
 	template string,
+	variables map[string]any,
+	attachments []*models.Attachment,
+	stream bool,
 	completion ai_clients.CompletionFn,
 ) error {
 	ctx := c.GetConfig().ShutdownContext
@@ -191,6 +348,10 @@ func createChatAndComplete(
 		},
 		template,
 	)
+	cmd.WithParameters(aiParameters)
+	cmd.WithPersona(persona)
+	cmd.WithVariables(variables)
+	cmd.WithUploads(attachments)
 	if err := cmd.Execute(ctx); err != nil {
 		return err
 	}
@@ -214,8 +375,16 @@ func createChatAndComplete(
 		completion,
 	)
 	cmd2.ShouldPersistUserMessage(false)
-	if err := cmd2.Execute(ctx); err != nil {
-		return err
+	cmd2.DiscardChatOnError(true)
+	cmd2.WithHistory(history)
+	cmd2.WithVariables(variables)
+	if stream {
+		cmd2.Stream(streamOutput(c.GetConfig().Stdoout))
+	}
+	completionCtx, stop := interruptContext(ctx)
+	defer stop()
+	if err := cmd2.Execute(completionCtx); err != nil {
+		return completionError(err)
 	}
 
 	if data, err := messages.Encode(
@@ -226,14 +395,105 @@ func createChatAndComplete(
 		)); err == nil {
 		utils.NotifyActiveSessions(c, id, data)
 	}
-	outputMessage(c.GetConfig().Stdoout, cmd2.Result)
+	finishOutput(c.GetConfig().Stdoout, cmd2.Result, stream)
+	reportInterrupted(c.GetConfig().Stderr, cmd2.Result)
+	if c.GetConfig().TitleModel != "" {
+		c.Background(func() { titleChat(c, cmd.Result.Chat.ID, completion) })
+	}
 	return nil
 }
 
+// names chat using title model and notifies active sessions about it,
+// failure is reported but keeps default name
+func titleChat(c *core.Core, chatID int64, completion ai_clients.CompletionFn) {
+	config := c.GetConfig()
+	cmd := core.NewGenerateChatTitleCommand(c, chatID, completion)
+	if err := cmd.Execute(config.ShutdownContext); err != nil {
+		fmt.Fprintf(config.Stderr, "failed to title chat %d: %s\n", chatID, err)
+		return
+	}
+	id := uuid.NewString()
+	if data, err := messages.Encode(
+		messages.NewServerChatUpdated(id, cmd.Result),
+	); err == nil {
+		utils.NotifyActiveSessions(c, id, data)
+	}
+}
+
 func outputMessage(w io.Writer, message *models.Message) {
 	fmt.Fprintf(w, "%s\n", message.Content)
 }
 
+func streamOutput(w io.Writer) ai_clients.OnDelta {
+	return func(delta string) {
+		fmt.Fprint(w, delta)
+	}
+}
+
+// first interrupt cancels completion instead of killing process, so that
+// streamed part of answer can be saved. Next interrupt kills it as usual
+func interruptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+func reportInterrupted(w io.Writer, message *models.Message) {
+	if !message.Interrupted {
+		return
+	}
+	fmt.Fprintf(w, "Completion was interrupted, partial answer is saved as message %d\n", message.ID)
+	if !message.SelectedGeneration {
+		fmt.Fprintf(
+			w,
+			"Previous generation remains selected, use `hermes chat generations --message-id %d --select %d` to pick partial one\n",
+			message.ID,
+			message.ID,
+		)
+	}
+}
+
+// provider failures get hint on what can be done about them
+func completionError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("completion was cancelled, answer is not saved\n")
+	}
+	hint := ""
+	switch {
+	case errors.Is(err, ai_clients.ErrAuth):
+		hint = "check api key of provider, it is set by env or `hermes config set`"
+	case errors.Is(err, ai_clients.ErrRateLimit):
+		hint = "provider kept limiting requests, try again later or raise " + settings.HermesMaxRetriesName
+	case errors.Is(err, ai_clients.ErrContextLength):
+		hint = "chat does not fit into model, reduce it with --history or fork chat"
+	case errors.Is(err, ai_clients.ErrServer):
+		hint = "provider is unavailable, try again later"
+	case errors.Is(err, ai_clients.ErrTimeout):
+		hint = "slow models may need bigger " + settings.HermesRequestTimeoutName
+	case errors.Is(err, ai_clients.ErrUnsupportedAttachment):
+		hint = "remove attachment or pick model of provider that accepts it"
+	default:
+		return err
+	}
+	separator := "\n"
+	if strings.HasSuffix(err.Error(), "\n") {
+		separator = ""
+	}
+	return fmt.Errorf("%w%s%s\n", err, separator, hint)
+}
+
+// streamed content is already written, only trailing newline is missing
+func finishOutput(w io.Writer, message *models.Message, streamed bool) {
+	if streamed {
+		fmt.Fprintln(w)
+		return
+	}
+	outputMessage(w, message)
+}
+
 func preloadParams(cmd *cobra.Command, params *ai_clients.Parameters) error {
 	model, err := cmd.Flags().GetString("model")
 	if err != nil {
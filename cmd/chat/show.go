@@ -0,0 +1,83 @@
+package chat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createShowCommand(c *core.Core) *cobra.Command {
+	showCommand := &cobra.Command{
+		Use:   "show <chat-id>",
+		Short: "Print chat conversation",
+		Long: `Prints chat parameters followed by all messages of conversation. Use ` + "`--json`" + ` to get machine readable output.
+`,
+		Example: `$ hermes chat show 1
+$ hermes chat show 1 --json | jq -r '.messages[-1].content'`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			chatID, err := parseChatID(args[0])
+			if err != nil {
+				return err
+			}
+			asJSON, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			ctx := c.GetConfig().ShutdownContext
+			chatQuery := core.NewGetChatByIDQuery(c, chatID)
+			if err := chatQuery.Execute(ctx); err != nil {
+				return err
+			}
+			messagesQuery := core.GetChatMessagesQuery{Core: c, ChatID: chatID}
+			if err := messagesQuery.Execute(ctx); err != nil {
+				return err
+			}
+			w := c.GetConfig().Stdoout
+			if asJSON {
+				return json.NewEncoder(w).Encode(struct {
+					Chat     *models.Chat      `json:"chat"`
+					Messages []*models.Message `json:"messages"`
+				}{
+					Chat:     chatQuery.Result,
+					Messages: messagesQuery.Result,
+				})
+			}
+			outputChatParameters(w, chatQuery.Result)
+			writeMessages(w, messagesQuery.Result)
+			return nil
+		},
+	}
+
+	showCommand.Flags().Bool("json", false, "output chat and messages as json")
+
+	return showCommand
+}
+
+func writeMessages(w io.Writer, messages []*models.Message) {
+	for _, message := range messages {
+		fmt.Fprintf(w, "\n[%s] %d\n%s\n", message.Role, message.ID, message.Content)
+		for _, attachment := range message.Attachments {
+			fmt.Fprintf(
+				w,
+				"[attachment] %s (%s, %d bytes)\n",
+				attachment.Name,
+				attachment.MediaType,
+				attachment.Size,
+			)
+		}
+	}
+}
+
+func parseChatID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("chat id must be positive number, got %q\n", arg)
+	}
+	return id, nil
+}
@@ -0,0 +1,106 @@
+package chat_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestShowChat(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(2, 1); err != nil {
+		t.Fatalf("failed to seed messages: %s\n", err)
+	}
+
+	type testCase struct {
+		name        string
+		args        []string
+		expected    string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should print chat and messages",
+			args: []string{"show", "1"},
+			expected: `[Chat]        1 1
+[Model]       
+[Temperature] default
+[Max tokens]  default
+[History]     all
+
+[assistant] 1
+generated
+
+[assistant] 2
+generated
+`,
+		},
+		{
+			name:        "should error on non existing chat",
+			args:        []string{"show", "2"},
+			shouldError: true,
+		},
+		{
+			name:        "should error on bad id",
+			args:        []string{"show", "one"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+
+	out := &strings.Builder{}
+	coreInstance.GetConfig().Stdoout = out
+	cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.SetArgs([]string{"show", "1", "--json"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to show chat: %s\n", err)
+	}
+	var res struct {
+		Chat     *models.Chat      `json:"chat"`
+		Messages []*models.Message `json:"messages"`
+	}
+	if err := json.Unmarshal([]byte(out.String()), &res); err != nil {
+		t.Fatalf("bad json output: %s\n", out.String())
+	}
+	if res.Chat.ID != 1 || len(res.Messages) != 2 {
+		t.Errorf("bad json content: %s\n", out.String())
+	}
+}
@@ -0,0 +1,79 @@
+package chat_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestTitleNewChat(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	config := coreInstance.GetConfig()
+	config.DatabaseDSN = "deez"
+	config.TitleModel = "local/title"
+	completion := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		if parameters.Model == config.TitleModel {
+			return &ai_clients.AIResponse{
+				Message: ai_clients.Message{Role: "assistant", Content: "Testing stuff"},
+			}, nil
+		}
+		return test_helpers.MockCompletion(ctx, messages, parameters, providers, onDelta)
+	}
+
+	mu := sync.Mutex{}
+	relayed := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		relayed = append(relayed, string(body))
+		mu.Unlock()
+	}))
+	defer server.Close()
+	db_helpers.CreateActiveSession(
+		db,
+		context.Background(),
+		&models.ActiveSession{DatabaseDNS: config.DatabaseDSN, Address: server.URL},
+	)
+
+	cmd := chat.CreateChatCommand(coreInstance, completion)
+	cmd.Flags().Set("content", "testing stuff")
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to execute cmd: %s", err)
+	}
+	out := config.Stdoout.(*strings.Builder).String()
+	if out != "> mocked: testing stuff\n" {
+		t.Errorf("expected title not to be written to output, got %q\n", out)
+	}
+	coreInstance.WaitBackground()
+
+	dbChat, err := db_helpers.GetChatByID(db, context.Background(), 1)
+	if err != nil {
+		t.Fatalf("failed to retrieve db chat: %s", err)
+	}
+	if dbChat.Name != "Testing stuff" {
+		t.Errorf("expected chat to be titled, got %q\n", dbChat.Name)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(relayed) == 0 || !strings.Contains(relayed[len(relayed)-1], `"type":"chat-updated"`) ||
+		!strings.Contains(relayed[len(relayed)-1], `"name":"Testing stuff"`) {
+		t.Errorf("expected chat-updated to be relayed last, got %q\n", relayed)
+	}
+}
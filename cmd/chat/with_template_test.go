@@ -2,6 +2,8 @@ package chat_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/k10wl/hermes/cmd/chat"
@@ -37,3 +39,88 @@ func TestShouldUseTemplate(t *testing.T) {
 	}
 
 }
+
+func TestShouldUseTemplateVariables(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateTemplate(db, ctx, &models.Template{
+		Name:    "template",
+		Content: `--{{define "template"}}--{{var "name"}} (--{{(var "user").role}}): --{{.}}--{{end}}`,
+	}); err != nil {
+		t.Fatalf("failed to create template, error: %s\n", err)
+	}
+	data := filepath.Join(t.TempDir(), "vars.yaml")
+	if err := os.WriteFile(data, []byte("name: file\nuser:\n  role: admin\n"), 0o644); err != nil {
+		t.Fatalf("failed to write data file, error: %s\n", err)
+	}
+	cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+	cmd.Flags().Set("content", "content")
+	cmd.Flags().Set("template", "template")
+	cmd.Flags().Set("data", data)
+	cmd.Flags().Set("var", "name=flag")
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("failed to execute cmd: %s", err)
+	}
+	dbMessages, err := db_helpers.GetMessagesByChatID(db, ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to retrieve messages, error: %s\n", err)
+	}
+	if dbMessages[0].Content != "flag (admin): content" {
+		t.Fatalf(
+			"failed to apply template variables, actual stored data: %s\n",
+			dbMessages[0].Content,
+		)
+	}
+}
+
+func TestShouldUseDefaultTemplate(t *testing.T) {
+	type testCase struct {
+		name     string
+		flags    map[string]string
+		expected string
+	}
+	table := []testCase{
+		{
+			name:     "should apply default template when none is given",
+			flags:    map[string]string{},
+			expected: "[content]",
+		},
+		{
+			name:     "should skip default template on demand",
+			flags:    map[string]string{"no-default-template": "true"},
+			expected: "content",
+		},
+	}
+	for _, test := range table {
+		coreInstance, db := test_helpers.CreateCore()
+		ctx := context.Background()
+		if err := db_helpers.CreateTemplate(db, ctx, &models.Template{
+			Name:    "default",
+			Content: `--{{define "default"}}[--{{.}}]--{{end}}`,
+		}); err != nil {
+			t.Fatalf("failed to create template, error: %s\n", err)
+		}
+		coreInstance.GetConfig().DefaultTemplate = "default"
+		cmd := chat.CreateChatCommand(coreInstance, test_helpers.MockCompletion)
+		cmd.Flags().Set("content", "content")
+		for name, value := range test.flags {
+			cmd.Flags().Set(name, value)
+		}
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("%q - failed to execute cmd: %s\n", test.name, err)
+			continue
+		}
+		dbMessages, err := db_helpers.GetMessagesByChatID(db, ctx, 1)
+		if err != nil {
+			t.Fatalf("%q - failed to retrieve messages, error: %s\n", test.name, err)
+		}
+		if dbMessages[0].Content != test.expected {
+			t.Errorf(
+				"%q - bad stored message\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				dbMessages[0].Content,
+			)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/spf13/cobra"
+)
+
+func CreateConfigCommand(c *core.Core) *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:   "config",
+		Short: "Manage config file profiles",
+		Long: `Manage ` + "`" + settings.ConfigFileName + "`" + ` in hermes config directory. File holds named profiles, each with provider keys, default model and template, database location and server address. Profile is selected with ` + "`--profile`" + `, ` + settings.HermesProfileName + ` or ` + "`profile`" + ` key of config file, in that order, and falls back to ` + "`" + settings.DefaultProfile + "`" + `.
+Environment variables take precedence over profile values. Files with comments are not changed by ` + "`hermes config set`" + `, edit them manually.
+Profile keys: ` + strings.Join(settings.ProfileKeys, ", ") + `.`,
+		Example: `  $ hermes config set default_model anthropic/claude-3-7-sonnet-latest
+  $ hermes --profile work config set openai_api_key sk-...
+  $ hermes config set profile work
+  $ hermes config get default_model
+  $ hermes config list`,
+	}
+
+	configCommand.AddCommand(createGetCommand(c))
+	configCommand.AddCommand(createSetCommand(c))
+	configCommand.AddCommand(createListCommand(c))
+
+	return configCommand
+}
+
+func createGetCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print value of selected profile, " + settings.ProfileKey + " prints active profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := c.GetConfig()
+			path, err := configFilePath(config)
+			if err != nil {
+				return err
+			}
+			file, err := settings.ReadConfigFile(path)
+			if err != nil {
+				return err
+			}
+			value, err := file.Get(config.Profile, args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(config.Stdoout, "%s\n", value)
+			return nil
+		},
+	}
+}
+
+func createSetCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Store value in selected profile, empty value removes key",
+		Long: `Stores value in selected profile, profile is created when missing. ` + "`" + settings.ProfileKey + "`" + ` key sets profile that is used by default.
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := c.GetConfig()
+			path, err := configFilePath(config)
+			if err != nil {
+				return err
+			}
+			file, err := settings.ReadConfigFile(path)
+			if err != nil {
+				return err
+			}
+			if err := file.Set(config.Profile, args[0], args[1]); err != nil {
+				return err
+			}
+			if err := file.Save(path); err != nil {
+				return err
+			}
+			if args[0] == settings.ProfileKey {
+				fmt.Fprintf(config.Stdoout, "%s = %s\n", settings.ProfileKey, strconv.Quote(args[1]))
+				return nil
+			}
+			fmt.Fprintf(
+				config.Stdoout,
+				"[%s] %s = %s\n",
+				config.Profile,
+				args[0],
+				strconv.Quote(maskSecret(args[0], args[1], false)),
+			)
+			return nil
+		},
+	}
+}
+
+func createListCommand(c *core.Core) *cobra.Command {
+	listCommand := &cobra.Command{
+		Use:   "list",
+		Short: "Print config file path and every profile",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			showSecrets, err := cmd.Flags().GetBool("show-secrets")
+			if err != nil {
+				return err
+			}
+			config := c.GetConfig()
+			path, err := configFilePath(config)
+			if err != nil {
+				return err
+			}
+			file, err := settings.ReadConfigFile(path)
+			if err != nil {
+				return err
+			}
+			writeConfigFile(config.Stdoout, path, config.Profile, file, showSecrets)
+			return nil
+		},
+	}
+
+	listCommand.Flags().Bool("show-secrets", false, "print api keys instead of masking them")
+
+	return listCommand
+}
+
+// there is no file to manage when system has no config directory
+func configFilePath(config *settings.Config) (string, error) {
+	if config.ConfigDir == "" {
+		return "", fmt.Errorf("config directory is unavailable, set HOME or XDG_CONFIG_HOME\n")
+	}
+	return settings.ConfigFilePath(config.ConfigDir), nil
+}
+
+func writeConfigFile(
+	w io.Writer,
+	path string,
+	selected string,
+	file *settings.ConfigFile,
+	showSecrets bool,
+) {
+	fmt.Fprintf(w, "File     %s\n", path)
+	fmt.Fprintf(w, "Profile  %s\n", selected)
+	for _, name := range file.ProfileNames() {
+		fmt.Fprintf(w, "\n[%s]", name)
+		if name == selected {
+			fmt.Fprint(w, " (selected)")
+		}
+		fmt.Fprintln(w)
+		for _, key := range settings.ProfileKeys {
+			if value, ok := file.Profiles[name][key]; ok {
+				fmt.Fprintf(w, "%s = %s\n", key, strconv.Quote(maskSecret(key, value, showSecrets)))
+			}
+		}
+	}
+}
+
+// keeps last characters of api keys, so they can be told apart
+func maskSecret(key string, value string, show bool) string {
+	if show || !slices.Contains(settings.SecretKeys, key) {
+		return value
+	}
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return strings.Repeat("*", 4) + value[len(value)-4:]
+}
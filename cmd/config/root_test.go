@@ -0,0 +1,119 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func runConfigCommand(c *core.Core, args ...string) (string, error) {
+	out := &strings.Builder{}
+	c.GetConfig().Stdoout = out
+	cmd := CreateConfigCommand(c)
+	cmd.SetArgs(args)
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestConfigCommand(t *testing.T) {
+	c, _ := test_helpers.CreateCore()
+	c.GetConfig().ConfigDir = t.TempDir()
+
+	type testCase struct {
+		name        string
+		profile     string
+		args        []string
+		expected    string
+		contains    []string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name:     "should print empty value of missing key",
+			profile:  "default",
+			args:     []string{"get", "default_model"},
+			expected: "\n",
+		},
+		{
+			name:     "should set value in selected profile",
+			profile:  "work",
+			args:     []string{"set", "default_model", "openai/gpt-4o"},
+			expected: "[work] default_model = \"openai/gpt-4o\"\n",
+		},
+		{
+			name:     "should mask api key when it is set",
+			profile:  "work",
+			args:     []string{"set", "openai_api_key", "sk-1234567890"},
+			expected: "[work] openai_api_key = \"****7890\"\n",
+		},
+		{
+			name:     "should set default profile",
+			profile:  "default",
+			args:     []string{"set", "profile", "work"},
+			expected: "profile = \"work\"\n",
+		},
+		{
+			name:     "should get value of selected profile",
+			profile:  "work",
+			args:     []string{"get", "default_model"},
+			expected: "openai/gpt-4o\n",
+		},
+		{
+			name:     "should not read value of other profile",
+			profile:  "default",
+			args:     []string{"get", "default_model"},
+			expected: "\n",
+		},
+		{
+			name:    "should list profiles with masked secrets",
+			profile: "work",
+			args:    []string{"list"},
+			contains: []string{
+				"File     " + settings.ConfigFilePath(c.GetConfig().ConfigDir) + "\n",
+				"Profile  work\n",
+				"[work] (selected)\nopenai_api_key = \"****7890\"\ndefault_model = \"openai/gpt-4o\"\n",
+			},
+		},
+		{
+			name:     "should show secrets on demand",
+			profile:  "work",
+			args:     []string{"list", "--show-secrets"},
+			contains: []string{"openai_api_key = \"sk-1234567890\"\n"},
+		},
+		{
+			name:        "should reject unknown key",
+			profile:     "work",
+			args:        []string{"set", "model", "openai/gpt-4o"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		c.GetConfig().Profile = test.profile
+		out, err := runConfigCommand(c, test.args...)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if test.expected != "" && out != test.expected {
+			t.Errorf("%q - bad output\nexpected: %q\nactual:   %q\n", test.name, test.expected, out)
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(out, expected) {
+				t.Errorf("%q - bad output\nexpected to contain: %q\nactual: %q\n", test.name, expected, out)
+			}
+		}
+	}
+}
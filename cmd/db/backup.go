@@ -0,0 +1,78 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createBackupCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <path>",
+		Short: "Copy database into new file",
+		Long: `Writes consistent snapshot of database into new file, it is safe to run while ` + "`hermes serve`" + ` is running. Existing file is never overwritten.
+`,
+		Example: `  $ hermes db backup ~/backups/hermes-$(date +%F).db`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := core.NewBackupDatabaseCommand(c, args[0])
+			if err := command.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.GetConfig().Stdoout, "Backup   %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func createRestoreCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <path>",
+		Short: "Replace database with backup",
+		Long: `Replaces database content with backup. Backup is checked first: it must be intact, not dirty and its schema version must be known to this hermes. Older backups are migrated on next start.
+Current database is backed up next to its file before it is replaced.
+`,
+		Example: `  $ hermes db restore ~/backups/hermes-2025-01-01.db`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			command := core.NewRestoreDatabaseCommand(c, args[0])
+			err := command.Execute(c.GetConfig().ShutdownContext)
+			writeBackup(c.GetConfig().Stdoout, command.Result)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(c.GetConfig().Stdoout, "Restored %s\n", args[0])
+			return writeVersion(c)
+		},
+	}
+}
+
+func createVacuumCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "vacuum",
+		Short: "Rebuild database file to reclaim free space",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := c.GetConfig().ShutdownContext
+			before := core.NewGetDatabaseStatsQuery(c)
+			if err := before.Execute(ctx); err != nil {
+				return err
+			}
+			if err := core.NewVacuumDatabaseCommand(c).Execute(ctx); err != nil {
+				return err
+			}
+			after := core.NewGetDatabaseStatsQuery(c)
+			if err := after.Execute(ctx); err != nil {
+				return err
+			}
+			fmt.Fprintf(
+				c.GetConfig().Stdoout,
+				"Size     %s -> %s\n",
+				formatSize(before.Result.Size),
+				formatSize(after.Result.Size),
+			)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/sqlite3"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	dsn := filepath.Join(dir, "main.db")
+	c := createFileCore(t, dsn, true)
+	db := c.GetDB().(*sqlite3.SQLite3).DB
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+
+	backup := filepath.Join(dir, "backup.db")
+	if _, err := runDBCommand(c, "backup", backup); err != nil {
+		t.Fatalf("failed to back up: %s\n", err)
+	}
+	if _, err := runDBCommand(c, "backup", backup); err == nil {
+		t.Errorf("expected backup to refuse overwriting existing file\n")
+	}
+	if err := seeder.SeedChatsN(2); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+
+	// uri characters in path must not be read as parameters
+	escaped := filepath.Join(dir, "back?up#1.db")
+	if _, err := runDBCommand(c, "backup", escaped); err != nil {
+		t.Fatalf("failed to back up: %s\n", err)
+	}
+
+	newer := filepath.Join(dir, "newer.db")
+	dirty := filepath.Join(dir, "dirty.db")
+	for path, query := range map[string]string{
+		newer: "UPDATE schema_migrations SET version = 9999",
+		dirty: "UPDATE schema_migrations SET dirty = true",
+	} {
+		if _, err := runDBCommand(c, "backup", path); err != nil {
+			t.Fatalf("failed to back up: %s\n", err)
+		}
+		broken, err := sql.Open("sqlite3", path)
+		if err != nil {
+			t.Fatalf("failed to open backup: %s\n", err)
+		}
+		if _, err := broken.Exec(query); err != nil {
+			t.Fatalf("failed to break backup: %s\n", err)
+		}
+		broken.Close()
+	}
+
+	type testCase struct {
+		name        string
+		path        string
+		chats       int64
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name:        "should refuse backup with unknown schema version",
+			path:        newer,
+			chats:       3,
+			shouldError: true,
+		},
+		{
+			name:        "should refuse dirty backup",
+			path:        dirty,
+			chats:       3,
+			shouldError: true,
+		},
+		{
+			name:        "should refuse missing file",
+			path:        filepath.Join(dir, "missing.db"),
+			chats:       3,
+			shouldError: true,
+		},
+		{
+			name:  "should restore backup with uri characters in path",
+			path:  escaped,
+			chats: 3,
+		},
+		{
+			name:  "should restore backup",
+			path:  backup,
+			chats: 1,
+		},
+	}
+
+	for _, test := range table {
+		out, err := runDBCommand(c, "restore", test.path)
+		if test.shouldError && err == nil {
+			t.Errorf("%q - expected to error but did not\n", test.name)
+		}
+		if !test.shouldError {
+			if err != nil {
+				t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			} else if !strings.Contains(out, "Backup   "+dsn+".restore-") {
+				t.Errorf("%q - expected backup of replaced database, got %q\n", test.name, out)
+			}
+		}
+		var chats int64
+		if err := db.QueryRow("SELECT COUNT(*) FROM chats").Scan(&chats); err != nil {
+			t.Fatalf("%q - failed to count chats: %s\n", test.name, err)
+		}
+		if chats != test.chats {
+			t.Errorf("%q - expected %d chats, got %d\n", test.name, test.chats, chats)
+		}
+	}
+}
+
+func TestStatsCommand(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "main.db")
+	c := createFileCore(t, dsn, true)
+	db := c.GetDB().(*sqlite3.SQLite3).DB
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(2); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(3, 1); err != nil {
+		t.Fatalf("failed to seed messages: %s\n", err)
+	}
+
+	out, err := runDBCommand(c, "stats")
+	if err != nil {
+		t.Fatalf("failed to get stats: %s\n", err)
+	}
+	for _, expected := range []string{
+		"Path     " + dsn + "\n",
+		"Size     ",
+		"chats                  2\n",
+		"messages               3\n",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("bad stats output\nexpected to contain: %q\nactual: %q\n", expected, out)
+		}
+	}
+	if strings.Contains(out, "schema_migrations") || strings.Contains(out, "messages_fts_") {
+		t.Errorf("stats should list only hermes tables, got %q\n", out)
+	}
+	if _, err := runDBCommand(c, "vacuum"); err != nil {
+		t.Errorf("failed to vacuum: %s\n", err)
+	}
+}
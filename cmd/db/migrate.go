@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createMigrateCommand(c *core.Core) *cobra.Command {
+	migrateCommand := &cobra.Command{
+		Use:   "migrate",
+		Short: "Inspect and change database schema version",
+		Long: `Hermes migrates database on start and backs it up next to database file before applying pending migrations. When migration is interrupted database is marked dirty and hermes refuses to use it, nothing is reset or deleted.
+To recover, inspect schema, restore backup or fix it by hand, then mark version that schema matches with ` + "`force`" + `. Every change is preceded by backup.
+`,
+		Example: `  $ hermes db migrate status
+  $ hermes db migrate up
+  $ hermes db migrate down 2
+  $ hermes db migrate force 13`,
+	}
+
+	migrateCommand.AddCommand(createMigrateStatusCommand(c))
+	migrateCommand.AddCommand(createMigrateStepsCommand(c, "up"))
+	migrateCommand.AddCommand(createMigrateStepsCommand(c, "down"))
+	migrateCommand.AddCommand(createMigrateForceCommand(c))
+
+	return migrateCommand
+}
+
+func createMigrateStatusCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Print schema version and known migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := core.NewGetMigrationStatusQuery(c)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			return writeMigrationStatus(c.GetConfig().Stdoout, query.Result)
+		},
+	}
+}
+
+// up applies every pending migration by default, down rolls back one
+func createMigrateStepsCommand(c *core.Core, direction string) *cobra.Command {
+	short := "Apply pending migrations, all of them unless number is given"
+	if direction == "down" {
+		short = "Roll back migrations, one unless number is given"
+	}
+	return &cobra.Command{
+		Use:   direction + " [n]",
+		Short: short,
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			steps := 0
+			if direction == "down" {
+				steps = 1
+			}
+			if len(args) == 1 {
+				n, err := strconv.Atoi(args[0])
+				if err != nil || n < 1 {
+					return fmt.Errorf("number of migrations must be positive, got %q\n", args[0])
+				}
+				steps = n
+			}
+			if direction == "down" {
+				steps = -steps
+			}
+			command := core.NewMigrateCommand(c, steps)
+			err := command.Execute(c.GetConfig().ShutdownContext)
+			writeBackup(c.GetConfig().Stdoout, command.Result)
+			if err != nil {
+				return err
+			}
+			return writeVersion(c)
+		},
+	}
+}
+
+func createMigrateForceCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "force <version>",
+		Short: "Mark version as applied and clean without running migrations",
+		Long: `Sets schema version and clears dirty flag, migrations are not run. Use it once schema matches given version, 0 marks database as empty.
+`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("version must be number, got %q\n", args[0])
+			}
+			command := core.NewForceMigrationVersionCommand(c, version)
+			err = command.Execute(c.GetConfig().ShutdownContext)
+			writeBackup(c.GetConfig().Stdoout, command.Result)
+			if err != nil {
+				return err
+			}
+			return writeVersion(c)
+		},
+	}
+}
+
+func writeBackup(w io.Writer, backup string) {
+	if backup != "" {
+		fmt.Fprintf(w, "Backup   %s\n", backup)
+	}
+}
+
+func writeVersion(c *core.Core) error {
+	query := core.NewGetMigrationStatusQuery(c)
+	if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.GetConfig().Stdoout, "Version  %s\n", formatVersion(query.Result))
+	return nil
+}
+
+func formatVersion(status *models.MigrationStatus) string {
+	version := strconv.FormatInt(status.Version, 10)
+	if status.Dirty {
+		version += " (dirty)"
+	}
+	return version
+}
+
+func writeMigrationStatus(w io.Writer, status *models.MigrationStatus) error {
+	fmt.Fprintf(w, "Version  %s\n", formatVersion(status))
+	fmt.Fprintf(w, "Latest   %d\n\n", status.Latest)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, migration := range status.Migrations {
+		state := "pending"
+		if migration.Applied {
+			state = "applied"
+		}
+		if status.Dirty && migration.Version == status.Version {
+			state = "dirty"
+		}
+		fmt.Fprintf(tw, "%04d\t%s\t%s\n", migration.Version, migration.Name, state)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,186 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/sqlite3"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func createFileCore(t *testing.T, dsn string, migrate bool) *core.Core {
+	open := sqlite3.OpenSQLite3
+	if migrate {
+		open = sqlite3.NewSQLite3
+	}
+	db, err := open(dsn)
+	if err != nil {
+		t.Fatalf("failed to open database: %s\n", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	c := core.NewCore(db, &settings.Config{})
+	c.GetConfig().Stdoout = &strings.Builder{}
+	c.GetConfig().ShutdownContext = context.Background()
+	return c
+}
+
+func latestMigration(t *testing.T, c *core.Core) int64 {
+	query := core.NewGetMigrationStatusQuery(c)
+	if err := query.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to get migration status: %s\n", err)
+	}
+	return query.Result.Latest
+}
+
+func runDBCommand(c *core.Core, args ...string) (string, error) {
+	out := &strings.Builder{}
+	c.GetConfig().Stdoout = out
+	cmd := CreateDBCommand(c)
+	cmd.SetArgs(args)
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestMigrateCommand(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "main.db")
+	c := createFileCore(t, dsn, true)
+	sqlite := c.GetDB().(*sqlite3.SQLite3)
+	if err := db_helpers.NewSeeder(sqlite.DB, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	latest := latestMigration(t, c)
+
+	type testCase struct {
+		name        string
+		args        []string
+		contains    []string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should print status",
+			args: []string{"migrate", "status"},
+			contains: []string{
+				fmt.Sprintf("Version  %d\nLatest   %d\n", latest, latest),
+				"0001  init",
+				"applied\n",
+			},
+		},
+		{
+			name: "should back up before rolling back",
+			args: []string{"migrate", "down"},
+			contains: []string{
+				"Backup   " + dsn + ".migration-",
+				fmt.Sprintf("Version  %d\n", latest-1),
+			},
+		},
+		{
+			name:     "should mark rolled back migration as pending",
+			args:     []string{"migrate", "status"},
+			contains: []string{fmt.Sprintf("Version  %d\n", latest-1), "pending\n"},
+		},
+		{
+			name:     "should apply pending migrations",
+			args:     []string{"migrate", "up"},
+			contains: []string{"Backup   ", fmt.Sprintf("Version  %d\n", latest)},
+		},
+		{
+			name:        "should reject non positive steps",
+			args:        []string{"migrate", "down", "0"},
+			shouldError: true,
+		},
+		{
+			name:        "should reject unknown version",
+			args:        []string{"migrate", "force", "99"},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out, err := runDBCommand(c, test.args...)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(out, expected) {
+				t.Errorf(
+					"%q - bad output\nexpected to contain: %q\nactual: %q\n",
+					test.name,
+					expected,
+					out,
+				)
+			}
+		}
+	}
+
+	backups, err := filepath.Glob(dsn + ".migration-*.bak")
+	if err != nil || len(backups) != 2 {
+		t.Errorf("expected 2 backups, got %v (%v)\n", backups, err)
+	}
+}
+
+func TestDirtyDatabaseIsNotReset(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "main.db")
+	c := createFileCore(t, dsn, true)
+	sqlite := c.GetDB().(*sqlite3.SQLite3)
+	if err := db_helpers.NewSeeder(sqlite.DB, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats: %s\n", err)
+	}
+	if _, err := sqlite.DB.Exec("UPDATE schema_migrations SET dirty = true"); err != nil {
+		t.Fatalf("failed to mark database dirty: %s\n", err)
+	}
+
+	if _, err := sqlite3.NewSQLite3(dsn); !errors.Is(err, sqlite3.ErrDatabaseDirty) {
+		t.Fatalf("expected dirty database error, got %v\n", err)
+	}
+	if _, err := db_helpers.GetChatByID(sqlite.DB, context.Background(), 1); err != nil {
+		t.Fatalf("chat was lost after refusing dirty database: %s\n", err)
+	}
+
+	recovery := createFileCore(t, dsn, false)
+	latest := strconv.FormatInt(latestMigration(t, recovery), 10)
+	if _, err := runDBCommand(recovery, "migrate", "up"); err == nil {
+		t.Errorf("expected migrate up to refuse dirty database\n")
+	}
+	out, err := runDBCommand(recovery, "migrate", "force", latest)
+	if err != nil {
+		t.Fatalf("failed to force version: %s\n", err)
+	}
+	if !strings.Contains(out, "Version  "+latest+"\n") {
+		t.Errorf("bad force output: %q\n", out)
+	}
+	backups, err := filepath.Glob(dsn + ".force-*.bak")
+	if err != nil || len(backups) != 1 {
+		t.Errorf("expected backup before force, got %v (%v)\n", backups, err)
+	}
+	if _, err := os.Stat(dsn); err != nil {
+		t.Fatalf("database file is gone: %s\n", err)
+	}
+
+	migrated := createFileCore(t, dsn, true)
+	if _, err := db_helpers.GetChatByID(
+		migrated.GetDB().(*sqlite3.SQLite3).DB,
+		context.Background(),
+		1,
+	); err != nil {
+		t.Errorf("chat was lost after recovery: %s\n", err)
+	}
+}
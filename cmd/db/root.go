@@ -0,0 +1,28 @@
+package db
+
+import (
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func CreateDBCommand(c *core.Core) *cobra.Command {
+	dbCommand := &cobra.Command{
+		Use:   "db",
+		Short: "Manage local database",
+		Long:  `Manage local database. Database is not migrated automatically while running ` + "`hermes db`" + ` commands, so they can be used to recover database that other commands refuse to open.`,
+		Example: `  $ hermes db stats
+  $ hermes db backup ~/backups/hermes.db
+  $ hermes db restore ~/backups/hermes.db
+  $ hermes db vacuum
+  $ hermes db migrate status
+  $ hermes db migrate force 14`,
+	}
+
+	dbCommand.AddCommand(createMigrateCommand(c))
+	dbCommand.AddCommand(createBackupCommand(c))
+	dbCommand.AddCommand(createRestoreCommand(c))
+	dbCommand.AddCommand(createStatsCommand(c))
+	dbCommand.AddCommand(createVacuumCommand(c))
+
+	return dbCommand
+}
@@ -0,0 +1,73 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createStatsCommand(c *core.Core) *cobra.Command {
+	statsCommand := &cobra.Command{
+		Use:   "stats",
+		Short: "Print database size and row counts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			asJSON, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			query := core.NewGetDatabaseStatsQuery(c)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			if asJSON {
+				return json.NewEncoder(c.GetConfig().Stdoout).Encode(query.Result)
+			}
+			return writeStats(c.GetConfig().Stdoout, query.Result)
+		},
+	}
+
+	statsCommand.Flags().Bool("json", false, "output stats as json")
+
+	return statsCommand
+}
+
+func writeStats(w io.Writer, stats *models.DatabaseStats) error {
+	path := stats.Path
+	if path == "" {
+		path = "in-memory"
+	}
+	fmt.Fprintf(w, "Path     %s\n", path)
+	fmt.Fprintf(w, "Size     %s (%s free)\n", formatSize(stats.Size), formatSize(stats.FreeSize))
+	fmt.Fprintf(w, "Version  %d", stats.Version)
+	if stats.Dirty {
+		fmt.Fprint(w, " (dirty)")
+	}
+	fmt.Fprint(w, "\n\n")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "TABLE\tROWS\n")
+	for _, table := range stats.Tables {
+		fmt.Fprintf(tw, "%s\t%d\n", table.Name, table.Rows)
+	}
+	return tw.Flush()
+}
+
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	size := float64(bytes)
+	for _, suffix := range []string{"KiB", "MiB", "GiB"} {
+		size /= unit
+		if size < unit || suffix == "GiB" {
+			return fmt.Sprintf("%.1f %s", size, suffix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package persona
+
+import (
+	"fmt"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createDeleteCommand(c *core.Core) *cobra.Command {
+	deleteCommand := &cobra.Command{
+		Use:     "delete",
+		Short:   "Remove a persona by the specified name.",
+		Long:    `Marks persona as deleted. Chats that used it continue without its prompt, stored model and parameters are kept.`,
+		Example: `$ hermes persona delete -n reviewer`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				return err
+			}
+			command := core.NewDeletePersonaCommand(c, name)
+			if err := command.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.GetConfig().Stdoout, "Persona %q successfully deleted.\n", name)
+			return nil
+		},
+	}
+
+	deleteCommand.Flags().StringP("name", "n", "", "exact name of persona to be deleted")
+	err := deleteCommand.MarkFlagRequired("name")
+	if err != nil {
+		panic(err)
+	}
+
+	return deleteCommand
+}
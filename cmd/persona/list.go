@@ -0,0 +1,105 @@
+package persona
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createListCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   "List stored personas",
+		Example: `$ hermes persona list`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := core.NewGetPersonasQuery(c)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			return outputPersonas(c.GetConfig().Stdoout, query.Result)
+		},
+	}
+}
+
+func createViewCommand(c *core.Core) *cobra.Command {
+	viewCommand := &cobra.Command{
+		Use:     "view",
+		Short:   "Display prompt and defaults of persona",
+		Example: `$ hermes persona view --name reviewer`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil {
+				return err
+			}
+			query := core.NewGetPersonaByNameQuery(c, name)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			outputPersona(c.GetConfig().Stdoout, query.Result)
+			return nil
+		},
+	}
+
+	viewCommand.Flags().StringP("name", "n", "", "exact name of persona")
+	err := viewCommand.MarkFlagRequired("name")
+	if err != nil {
+		panic(err)
+	}
+
+	return viewCommand
+}
+
+func outputPersonas(w io.Writer, personas []*models.Persona) error {
+	if len(personas) == 0 {
+		_, err := fmt.Fprintf(w, "No personas found.\nUse `hermes persona upsert` to add one.\n")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "NAME\tMODEL\tPROMPT\n")
+	for _, persona := range personas {
+		model := persona.Model
+		if model == "" {
+			model = "default"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", persona.Name, model, firstLine(persona.Prompt))
+	}
+	return tw.Flush()
+}
+
+func outputPersona(w io.Writer, persona *models.Persona) {
+	model := persona.Model
+	if model == "" {
+		model = "default"
+	}
+	temperature := "default"
+	if persona.Temperature != nil {
+		temperature = strconv.FormatFloat(*persona.Temperature, 'f', -1, 64)
+	}
+	maxTokens := "default"
+	if persona.MaxTokens != nil {
+		maxTokens = strconv.FormatInt(*persona.MaxTokens, 10)
+	}
+	fmt.Fprintf(
+		w,
+		"[Persona]     %s\n[Model]       %s\n[Temperature] %s\n[Max tokens]  %s\n\n%s\n",
+		persona.Name,
+		model,
+		temperature,
+		maxTokens,
+		persona.Prompt,
+	)
+}
+
+func firstLine(s string) string {
+	line, _, cut := strings.Cut(s, "\n")
+	if cut {
+		return line + "..."
+	}
+	return line
+}
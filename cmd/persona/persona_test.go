@@ -0,0 +1,100 @@
+package persona_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/persona"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func TestPersonaCommand(t *testing.T) {
+	type testCase struct {
+		name        string
+		args        []string
+		expected    string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name:     "should tell that there are no personas",
+			args:     []string{"list"},
+			expected: "No personas found.\nUse `hermes persona upsert` to add one.\n",
+		},
+		{
+			name:     "should create persona",
+			args:     []string{"upsert", "--name", "reviewer", "--prompt", "be strict\nand short", "--temperature", "0.2"},
+			expected: "Persona \"reviewer\" upserted successfully\n",
+		},
+		{
+			name:     "should create persona with model",
+			args:     []string{"upsert", "-n", "poet", "-p", "rhyme", "-m", "openai/gpt-4o"},
+			expected: "Persona \"poet\" upserted successfully\n",
+		},
+		{
+			name: "should list personas",
+			args: []string{"list"},
+			expected: `NAME      MODEL          PROMPT
+poet      openai/gpt-4o  rhyme
+reviewer  default        be strict...
+`,
+		},
+		{
+			name: "should view persona",
+			args: []string{"view", "--name", "reviewer"},
+			expected: `[Persona]     reviewer
+[Model]       default
+[Temperature] 0.2
+[Max tokens]  default
+
+be strict
+and short
+`,
+		},
+		{
+			name:     "should delete persona",
+			args:     []string{"delete", "--name", "poet"},
+			expected: "Persona \"poet\" successfully deleted.\n",
+		},
+		{
+			name:        "should error upon view of deleted persona",
+			args:        []string{"view", "--name", "poet"},
+			shouldError: true,
+		},
+		{
+			name:        "should error without name",
+			args:        []string{"upsert", "--prompt", "nameless"},
+			shouldError: true,
+		},
+	}
+
+	coreInstance, _ := test_helpers.CreateCore()
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := persona.CreatePersonaCommand(coreInstance)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package persona
+
+import (
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func CreatePersonaCommand(c *core.Core) *cobra.Command {
+	personaCommand := &cobra.Command{
+		Use:   "persona",
+		Short: "Manage reusable personas",
+		Long:  `Manage personas - named system prompts with optional default model and parameters. Persona is attached to chat with ` + "`hermes chat --persona <name>`" + `, its prompt is sent as system message with every completion in that chat.`,
+		Example: `  $ hermes persona upsert --name reviewer --prompt "You are strict code reviewer" --model anthropic/claude-3-7-sonnet
+  $ hermes persona list
+  $ hermes persona view   --name reviewer
+  $ hermes persona delete --name reviewer`,
+	}
+
+	personaCommand.AddCommand(createUpsertCommand(c))
+	personaCommand.AddCommand(createListCommand(c))
+	personaCommand.AddCommand(createViewCommand(c))
+	personaCommand.AddCommand(createDeleteCommand(c))
+
+	return personaCommand
+}
@@ -0,0 +1,88 @@
+package persona
+
+import (
+	"fmt"
+
+	"github.com/k10wl/hermes/cmd/utils"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createUpsertCommand(c *core.Core) *cobra.Command {
+	upsertCommand := &cobra.Command{
+		Use:   "upsert",
+		Short: "Update an existing persona or create a new one if it does not exist.",
+		Long: `Saves persona under given name, persona with the same name is replaced and chats that use it receive new prompt. If the ` + "`--prompt`" + ` (` + "`-p`" + `) flag is not provided, the default text editor will be opened. Model, temperature and max tokens are optional, chats fallback to defaults when they are not set.
+`,
+		Example: `$ hermes persona upsert --name reviewer
+$ hermes persona upsert -n reviewer -p "You are strict code reviewer" -m openai/o1 --temperature 0.2`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config := c.GetConfig()
+			persona := models.Persona{}
+			var err error
+			if persona.Name, err = cmd.Flags().GetString("name"); err != nil {
+				return err
+			}
+			if persona.Prompt, err = cmd.Flags().GetString("prompt"); err != nil {
+				return err
+			}
+			if persona.Model, err = cmd.Flags().GetString("model"); err != nil {
+				return err
+			}
+			if cmd.Flags().Changed("temperature") {
+				temperature, err := cmd.Flags().GetFloat64("temperature")
+				if err != nil {
+					return err
+				}
+				persona.Temperature = &temperature
+			}
+			if cmd.Flags().Changed("max-tokens") {
+				maxTokens, err := cmd.Flags().GetInt64("max-tokens")
+				if err != nil {
+					return err
+				}
+				persona.MaxTokens = &maxTokens
+			}
+			if persona.Prompt == "" {
+				persona.Prompt, err = utils.OpenInEditor(
+					"",
+					config.Stdin,
+					config.Stdoout,
+					config.Stderr,
+				)
+				if err != nil {
+					return err
+				}
+			}
+			upsert := core.NewUpsertPersonaCommand(c, &persona)
+			if err := upsert.Execute(config.ShutdownContext); err != nil {
+				return err
+			}
+			fmt.Fprintf(config.Stdoout, "Persona %q upserted successfully\n", upsert.Result.Name)
+			return nil
+		},
+	}
+
+	upsertCommand.Flags().SortFlags = false
+	upsertCommand.Flags().StringP("name", "n", "", "name of persona")
+	upsertCommand.Flags().StringP(
+		"prompt",
+		"p",
+		"",
+		"system prompt; if not provided, the text editor will be opened",
+	)
+	upsertCommand.Flags().StringP("model", "m", "", "default model of chats with persona")
+	upsertCommand.Flags().Float64(
+		"temperature",
+		0,
+		"default degree of randomness of AI answer",
+	)
+	upsertCommand.Flags().Int64("max-tokens", 0, "default maximum number of tokens used in output")
+	err := upsertCommand.MarkFlagRequired("name")
+	if err != nil {
+		panic(err)
+	}
+
+	return upsertCommand
+}
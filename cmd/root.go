@@ -2,11 +2,17 @@ package cmd
 
 import (
 	"github.com/k10wl/hermes/cmd/chat"
+	"github.com/k10wl/hermes/cmd/config"
+	"github.com/k10wl/hermes/cmd/db"
+	"github.com/k10wl/hermes/cmd/persona"
+	"github.com/k10wl/hermes/cmd/search"
 	"github.com/k10wl/hermes/cmd/serve"
 	"github.com/k10wl/hermes/cmd/template"
+	"github.com/k10wl/hermes/cmd/usage"
 	"github.com/k10wl/hermes/cmd/version"
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/settings"
 	"github.com/spf13/cobra"
 )
 
@@ -21,10 +27,21 @@ It provides templating, chat persistence, and a local database.`,
 Hello! How can I assist you today?`,
 	}
 
+	rootCmd.PersistentFlags().String(
+		"profile",
+		"",
+		"config profile to use, overrides "+settings.HermesProfileName+" and profile set in config file",
+	)
+
 	rootCmd.AddCommand(version.CreateVersionCommand(core))
 	rootCmd.AddCommand(serve.CreateServeCommand(core))
 	rootCmd.AddCommand(template.CreateTemplateCommand(core))
 	rootCmd.AddCommand(chat.CreateChatCommand(core, completion))
+	rootCmd.AddCommand(usage.CreateUsageCommand(core))
+	rootCmd.AddCommand(search.CreateSearchCommand(core))
+	rootCmd.AddCommand(persona.CreatePersonaCommand(core))
+	rootCmd.AddCommand(db.CreateDBCommand(core))
+	rootCmd.AddCommand(config.CreateConfigCommand(core))
 
 	return rootCmd.Execute()
 }
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ansiHighlight = "\x1b[1;33m"
+	ansiReset     = "\x1b[0m"
+)
+
+func CreateSearchCommand(c *core.Core) *cobra.Command {
+	searchCommand := &cobra.Command{
+		Use:   "search <terms>",
+		Short: "Find messages by their content or chat name",
+		Long: `Full text search across all messages and chat names, best matches first. Message must contain every term, terms are matched as whole words ignoring case and diacritics.
+Matches are highlighted in terminal and wrapped into "**" when output is piped. Use ` + "`--json`" + ` to get machine readable output.
+`,
+		Example: `$ hermes search segmentation fault
+$ hermes search docker compose --limit 5
+$ hermes search migration --json | jq '.[0].chat_id'`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, err := cmd.Flags().GetInt64("limit")
+			if err != nil {
+				return err
+			}
+			asJSON, err := cmd.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			config := c.GetConfig()
+			query := core.NewSearchMessagesQuery(c, strings.Join(args, " "), limit)
+			if !asJSON && isTerminal(config.Stdoout) {
+				query.Highlight(ansiHighlight, ansiReset)
+			}
+			if err := query.Execute(config.ShutdownContext); err != nil {
+				return err
+			}
+			if asJSON {
+				return json.NewEncoder(config.Stdoout).Encode(query.Result)
+			}
+			return writeResults(config.Stdoout, query.Result)
+		},
+	}
+
+	searchCommand.Flags().SortFlags = false
+	searchCommand.Flags().Int64P("limit", "l", 20, "maximum amount of results, -1 for all")
+	searchCommand.Flags().Bool("json", false, "output results as json")
+
+	return searchCommand
+}
+
+func writeResults(w io.Writer, results []*models.SearchResult) error {
+	if len(results) == 0 {
+		_, err := fmt.Fprintf(w, "Nothing found\n")
+		return err
+	}
+	for _, result := range results {
+		if _, err := fmt.Fprintf(
+			w,
+			"[Chat %d] %s\n[%s %d] %s\n\n",
+			result.ChatID,
+			result.ChatName,
+			result.Role,
+			result.MessageID,
+			strings.Join(strings.Fields(result.Snippet), " "),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (stat.Mode() & os.ModeCharDevice) != 0
+}
@@ -0,0 +1,81 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestSearchCommand(t *testing.T) {
+	type testCase struct {
+		name        string
+		args        []string
+		expected    string
+		shouldError bool
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateChats(db, ctx, []*models.Chat{{Name: "debugging"}}); err != nil {
+		t.Fatalf("failed to create chats: %s\n", err)
+	}
+	if err := db_helpers.CreateMessages(db, ctx, []*models.Message{
+		{ChatID: 1, Role: "user", Content: "why does it crash?"},
+		{ChatID: 1, Role: "assistant", Content: "It crashes because of\nnil pointer"},
+	}); err != nil {
+		t.Fatalf("failed to create messages: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name: "should print highlighted snippets with chat ids",
+			args: []string{"nil", "pointer"},
+			expected: `[Chat 1] debugging
+[assistant 2] It crashes because of **nil** **pointer**
+
+`,
+		},
+		{
+			name:     "should notify when nothing found",
+			args:     []string{"segfault"},
+			expected: "Nothing found\n",
+		},
+		{
+			name:        "should require terms",
+			args:        []string{},
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		cmd := CreateSearchCommand(coreInstance)
+		cmd.SetArgs(test.args)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		err := cmd.Execute()
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package serve
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/k10wl/hermes/internal/core"
+)
+
+const autoBackupSuffix = ".auto-"
+
+// backs database up next to its file on every tick until shutdown, only
+// newest backups are kept
+func scheduleBackups(c *core.Core, interval time.Duration, keep int) error {
+	config := c.GetConfig()
+	stats := core.NewGetDatabaseStatsQuery(c)
+	if err := stats.Execute(config.ShutdownContext); err != nil {
+		return err
+	}
+	if stats.Result.Path == "" {
+		return fmt.Errorf("periodic backup requires database file, in-memory database can not be backed up\n")
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-config.ShutdownContext.Done():
+				return
+			case now := <-ticker.C:
+				path := fmt.Sprintf(
+					"%s%s%s.bak",
+					stats.Result.Path,
+					autoBackupSuffix,
+					now.Format("20060102-150405"),
+				)
+				if err := core.NewBackupDatabaseCommand(c, path).
+					Execute(config.ShutdownContext); err != nil {
+					fmt.Fprintf(config.Stderr, "periodic backup failed - %s\n", err)
+					continue
+				}
+				if err := pruneBackups(stats.Result.Path, keep); err != nil {
+					fmt.Fprintf(config.Stderr, "failed to remove old backups - %s\n", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// timestamp in name sorts backups from oldest to newest, names are matched
+// literally, so database path may hold glob characters
+func pruneBackups(database string, keep int) error {
+	dir := filepath.Dir(database)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	prefix := filepath.Base(database) + autoBackupSuffix
+	backups := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".bak") {
+			continue
+		}
+		backups = append(backups, filepath.Join(dir, name))
+	}
+	slices.Sort(backups)
+	for len(backups) > keep {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
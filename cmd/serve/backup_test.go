@@ -0,0 +1,68 @@
+package serve
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPruneBackups(t *testing.T) {
+	type testCase struct {
+		name     string
+		database string
+		// files that are not backups of database
+		other []string
+	}
+	table := []testCase{
+		{
+			name:     "should keep newest backups",
+			database: "main.db",
+			other:    []string{"main.db.migration-20250101-000000.000.bak"},
+		},
+		{
+			name:     "should match database name literally",
+			database: "[main]*?.db",
+			other:    []string{"ab.db.auto-20250101-000000.bak"},
+		},
+	}
+
+	for _, test := range table {
+		dir := t.TempDir()
+		database := filepath.Join(dir, test.database)
+		backups := []string{
+			test.database + ".auto-20250101-000000.bak",
+			test.database + ".auto-20250103-000000.bak",
+			test.database + ".auto-20250102-000000.bak",
+		}
+		for _, file := range append(backups, test.other...) {
+			if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+				t.Fatalf("%q - failed to write file - %s\n", test.name, err)
+			}
+		}
+		if err := pruneBackups(database, 2); err != nil {
+			t.Errorf("%q - failed to prune backups - %s\n", test.name, err)
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		left := map[string]bool{}
+		for _, entry := range entries {
+			left[entry.Name()] = true
+		}
+		expected := map[string]bool{backups[1]: true, backups[2]: true}
+		for _, file := range test.other {
+			expected[file] = true
+		}
+		if !reflect.DeepEqual(left, expected) {
+			t.Errorf(
+				"%q - bad files left\nexpected: %v\nactual:   %v\n",
+				test.name,
+				expected,
+				left,
+			)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/models"
@@ -19,6 +20,9 @@ func CreateServeCommand(c *core.Core) *cobra.Command {
 		addr     string
 		open     bool
 		latest   bool
+
+		backupInterval time.Duration
+		backupKeep     int
 	)
 
 	config := c.GetConfig()
@@ -31,7 +35,8 @@ func CreateServeCommand(c *core.Core) *cobra.Command {
 		Long:  "Serve as a HTTP web server.",
 		Example: `$ hermes serve
 $ hermes serve --hostname 192.168.1.1 --port 8080
-$ hermes serve --open --latest`,
+$ hermes serve --open --latest
+$ hermes serve --backup-interval 24h --backup-keep 7`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			h, err := cmd.Flags().GetString("hostname")
 			if err != nil {
@@ -49,6 +54,14 @@ $ hermes serve --open --latest`,
 			if err != nil {
 				return err
 			}
+			backupInterval, err = cmd.Flags().GetDuration("backup-interval")
+			if err != nil {
+				return err
+			}
+			backupKeep, err = cmd.Flags().GetInt("backup-keep")
+			if err != nil {
+				return err
+			}
 			port = p
 			hostname = h
 			open = o
@@ -62,6 +75,23 @@ $ hermes serve --open --latest`,
 			if latest && !open {
 				return fmt.Errorf("cannot use --latest without --open\n")
 			}
+			// signal cancels requests, completions and backups, server
+			// returns once they are over
+			ctx, stop := signal.NotifyContext(
+				config.ShutdownContext,
+				os.Interrupt,
+				syscall.SIGTERM,
+			)
+			defer stop()
+			config.ShutdownContext = ctx
+			if backupInterval > 0 {
+				if backupKeep < 1 {
+					return fmt.Errorf("--backup-keep must be positive\n")
+				}
+				if err := scheduleBackups(c, backupInterval, backupKeep); err != nil {
+					return err
+				}
+			}
 			if open {
 				web.OpenBrowser(
 					web.GetUrl(
@@ -76,39 +106,32 @@ $ hermes serve --open --latest`,
 			if err != nil {
 				fmt.Fprintf(config.Stderr, "failed to store active session record - %s\n", err)
 			}
-			quit := make(chan os.Signal, 1)
-			errChan := make(chan error, 1)
-			signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-			go func() { errChan <- web.Serve(c, config, hostname, port) }()
-			for {
-				select {
-				case err := <-errChan:
-					close(quit)
-					db.RemoveActiveSession(&activeSession)
-					os.Exit(1)
-					return err
-				case <-quit:
-					close(errChan)
-					db.RemoveActiveSession(&activeSession)
-					os.Exit(0)
-					return nil
-				}
-			}
+			err = web.Serve(c, config, hostname, port)
+			db.RemoveActiveSession(&activeSession)
+			return err
 		},
 	}
 
 	serveCommand.Flags().SortFlags = false
+	defaultHostname := "127.0.0.1"
+	if config.Server.Host != "" {
+		defaultHostname = config.Server.Host
+	}
+	defaultPort := "8123"
+	if config.Server.Port != "" {
+		defaultPort = config.Server.Port
+	}
 	serveCommand.Flags().StringP(
 		"hostname",
 		"H",
-		"127.0.0.1",
-		"specify the hostname",
+		defaultHostname,
+		"specify the hostname, defaults to host of config profile",
 	)
 	serveCommand.Flags().StringP(
 		"port",
 		"p",
-		"8123",
-		"set the port",
+		defaultPort,
+		"set the port, defaults to port of config profile",
 	)
 	serveCommand.Flags().BoolP("open", "o", false, "opens server in browser")
 	serveCommand.Flags().BoolP(
@@ -117,6 +140,16 @@ $ hermes serve --open --latest`,
 		false,
 		"will open latest chat if --open was provided",
 	)
+	serveCommand.Flags().Duration(
+		"backup-interval",
+		0,
+		"back up database next to its file on given interval, e.g. 24h (disabled by default)",
+	)
+	serveCommand.Flags().Int(
+		"backup-keep",
+		7,
+		"number of newest periodic backups to keep",
+	)
 
 	return serveCommand
 }
@@ -22,17 +22,23 @@ func relayTemplateDeleted(c *core.Core, name string) {
 
 func createDeleteCommand(c *core.Core) *cobra.Command {
 	deleteCommand := &cobra.Command{
-		Use:     "delete",
-		Short:   "Remove a template by the specified name.",
-		Long:    `Mark the template with the given name as deleted. Ensure that the template you wish to delete is not currently in use. It expects the ` + "`--name`" + ` or ` + "`-n`" + ` flag to indicate which template must be deleted.`,
-		Example: `$ hermes template delete -n tldr`,
+		Use:   "delete",
+		Short: "Remove a template by the specified name.",
+		Long:  `Mark the template with the given name as deleted. Templates used by other templates are not deleted unless ` + "`--force`" + ` is set, see ` + "`hermes template deps`" + `. It expects the ` + "`--name`" + ` or ` + "`-n`" + ` flag to indicate which template must be deleted.`,
+		Example: `$ hermes template delete -n tldr
+$ hermes template delete -n tldr --force`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			config := c.GetConfig()
 			name, err := cmd.Flags().GetString("name")
 			if err != nil {
 				return err
 			}
+			force, err := cmd.Flags().GetBool("force")
+			if err != nil {
+				return err
+			}
 			command := core.NewDeleteTemplateByName(c, name)
+			command.WithForce(force)
 			if err := command.Execute(context.Background()); err != nil {
 				return err
 			}
@@ -52,6 +58,11 @@ func createDeleteCommand(c *core.Core) *cobra.Command {
 	if err != nil {
 		panic(err)
 	}
+	deleteCommand.Flags().Bool(
+		"force",
+		false,
+		"delete even if other templates use it",
+	)
 
 	return deleteCommand
 }
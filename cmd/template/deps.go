@@ -0,0 +1,51 @@
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createDepsCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "deps <name>",
+		Short: "Show templates used by template and templates that use it",
+		Long: `Prints tree of templates the given template uses through ` + "`template \"name\"`" + ` actions, followed by tree of templates that depend on it.
+`,
+		Example: `$ hermes template deps tldr`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := core.NewGetTemplateDependenciesQuery(c, args[0])
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			outputTemplateDependencies(c.GetConfig().Stdoout, query.Result)
+			return nil
+		},
+	}
+}
+
+func outputTemplateDependencies(w io.Writer, deps *models.TemplateDependencies) {
+	fmt.Fprintf(w, "Uses:\n%s\n", deps.Name)
+	outputTemplateTree(w, deps.Dependencies, "")
+	fmt.Fprintf(w, "\nUsed by:\n%s\n", deps.Name)
+	outputTemplateTree(w, deps.Dependents, "")
+}
+
+func outputTemplateTree(w io.Writer, nodes []*models.TemplateTree, indent string) {
+	for i, node := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		name := node.Name
+		if node.Deleted {
+			name += " (deleted)"
+		}
+		fmt.Fprintf(w, "%s%s%s\n", indent, branch, name)
+		outputTemplateTree(w, node.Children, indent+next)
+	}
+}
@@ -0,0 +1,71 @@
+package template_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/template"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func TestTemplateDeps(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	out := coreInstance.GetConfig().Stdoout.(*strings.Builder)
+	run := func(args ...string) error {
+		out.Reset()
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+	for _, content := range []string{
+		`--{{define "c"}}c--{{end}}`,
+		`--{{define "d"}}d--{{end}}`,
+		`--{{define "b"}}--{{template "c"}}--{{template "d"}}--{{end}}`,
+		`--{{define "a"}}--{{template "b"}}--{{end}}`,
+	} {
+		if err := run("upsert", "--content", content); err != nil {
+			t.Fatalf("failed to upsert template: %s\n", err)
+		}
+	}
+
+	if err := run("deps", "b"); err != nil {
+		t.Fatalf("failed to execute deps: %s\n", err)
+	}
+	expected := `Uses:
+b
+├── c
+└── d
+
+Used by:
+b
+└── a
+`
+	if out.String() != expected {
+		t.Errorf("bad deps output\nexpected:\n%s\nactual:\n%s\n", expected, out.String())
+	}
+
+	err := run("delete", "--name", "c")
+	if err == nil || !strings.Contains(err.Error(), `template "c" is used by: b`) {
+		t.Errorf("expected delete of used template to be refused, got %v\n", err)
+	}
+	if err := run("delete", "--name", "c", "--force"); err != nil {
+		t.Fatalf("failed to force delete: %s\n", err)
+	}
+	if err := run("deps", "a"); err != nil {
+		t.Fatalf("failed to execute deps: %s\n", err)
+	}
+	expected = `Uses:
+a
+└── b
+    ├── c (deleted)
+    └── d
+
+Used by:
+a
+`
+	if out.String() != expected {
+		t.Errorf("bad deps output\nexpected:\n%s\nactual:\n%s\n", expected, out.String())
+	}
+}
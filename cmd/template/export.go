@@ -0,0 +1,41 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createExportCommand(c *core.Core) *cobra.Command {
+	exportCommand := &cobra.Command{
+		Use:   "export",
+		Short: "Write every template into its own file",
+		Long: `Writes each stored template into ` + "`<name>.tmpl`" + ` file inside given directory, directory is created when missing. Existing files of the same templates are overwritten. Use ` + "`hermes template import`" + ` to bring files back.
+`,
+		Example: `$ hermes template export --dir ./prompts`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cmd.Flags().GetString("dir")
+			if err != nil {
+				return err
+			}
+			export := core.NewExportTemplatesCommand(c, dir)
+			if err := export.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			for _, path := range export.Result {
+				fmt.Fprintf(c.GetConfig().Stdoout, "%s\n", path)
+			}
+			fmt.Fprintf(c.GetConfig().Stdoout, "\nExported %d template(s)\n", len(export.Result))
+			return nil
+		},
+	}
+
+	exportCommand.Flags().StringP("dir", "d", "", "directory to write template files into")
+	if err := exportCommand.MarkFlagRequired("dir"); err != nil {
+		panic(err)
+	}
+
+	return exportCommand
+}
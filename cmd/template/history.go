@@ -0,0 +1,131 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createHistoryCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "history <name>",
+		Short: "List stored versions of template",
+		Long: `Every upsert, edit and rollback of template stores its content as new version. Lists versions of template, newest first.
+`,
+		Example: `$ hermes template history tldr`,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := core.NewGetTemplateHistoryQuery(c, args[0])
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			return outputTemplateHistory(c.GetConfig().Stdoout, query.Result)
+		},
+	}
+}
+
+func createDiffCommand(c *core.Core) *cobra.Command {
+	diffCommand := &cobra.Command{
+		Use:   "diff <name>",
+		Short: "Show changes between two versions of template",
+		Long: `Prints unified diff between versions of template. Versions can be written as "v3" or "3", ` + "`--to`" + ` defaults to the latest version.
+`,
+		Example: `$ hermes template diff tldr --from v3 --to v5
+$ hermes template diff tldr --from 1`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := c.GetConfig().ShutdownContext
+			rawFrom, err := cmd.Flags().GetString("from")
+			if err != nil {
+				return err
+			}
+			from, err := parseVersion(rawFrom)
+			if err != nil {
+				return err
+			}
+			rawTo, err := cmd.Flags().GetString("to")
+			if err != nil {
+				return err
+			}
+			var to int64
+			if rawTo == "" {
+				history := core.NewGetTemplateHistoryQuery(c, args[0])
+				if err := history.Execute(ctx); err != nil {
+					return err
+				}
+				to = history.Result[0].Version
+			} else if to, err = parseVersion(rawTo); err != nil {
+				return err
+			}
+			query := core.NewDiffTemplateQuery(c, args[0], from, to)
+			if err := query.Execute(ctx); err != nil {
+				return err
+			}
+			if query.Result == "" {
+				fmt.Fprintf(c.GetConfig().Stdoout, "Versions are identical\n")
+				return nil
+			}
+			fmt.Fprint(c.GetConfig().Stdoout, query.Result)
+			return nil
+		},
+	}
+
+	diffCommand.Flags().String("from", "", "version to compare from, e.g. v3")
+	if err := diffCommand.MarkFlagRequired("from"); err != nil {
+		panic(err)
+	}
+	diffCommand.Flags().String("to", "", "version to compare to, defaults to latest")
+
+	return diffCommand
+}
+
+func createRollbackCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rollback <name> <version>",
+		Short: "Restore content of template version",
+		Long: `Replaces template content with content of given version. Rollback is stored as new version, so it can be rolled back as well.
+`,
+		Example: `$ hermes template rollback tldr v3`,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, err := parseVersion(args[1])
+			if err != nil {
+				return err
+			}
+			rollback := core.NewRollbackTemplateCommand(c, args[0], version)
+			if err := rollback.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			relayEdit(c, rollback.Result, "edit")
+			fmt.Fprintf(c.GetConfig().Stdoout, "Template rolled back to v%d\n", version)
+			return nil
+		},
+	}
+}
+
+func parseVersion(raw string) (int64, error) {
+	version, err := strconv.ParseInt(strings.TrimPrefix(strings.ToLower(raw), "v"), 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("bad version %q, expected number like v3\n", raw)
+	}
+	return version, nil
+}
+
+func outputTemplateHistory(w io.Writer, versions []*models.TemplateVersion) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "VERSION\tNAME\tCREATED\n")
+	for _, version := range versions {
+		created := ""
+		if version.CreatedAt != nil {
+			created = version.CreatedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "v%d\t%s\t%s\n", version.Version, version.Name, created)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,85 @@
+package template_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/template"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestTemplateHistoryDiffRollback(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateTemplate(db, ctx, &models.Template{
+		Name:    "custom",
+		Content: "--{{define \"custom\"}}\nfirst\n--{{end}}",
+	}); err != nil {
+		t.Fatalf("failed to create template for test - %s\n", err)
+	}
+	out := coreInstance.GetConfig().Stdoout.(*strings.Builder)
+	run := func(args ...string) string {
+		out.Reset()
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("failed to execute %q: %s\n", args, err)
+		}
+		return out.String()
+	}
+
+	run("edit", "--name", "custom", "--content", "--{{define \"custom\"}}\nsecond\n--{{end}}")
+
+	history := run("history", "custom")
+	lines := strings.Split(strings.TrimSpace(history), "\n")
+	if len(lines) != 3 ||
+		!strings.HasPrefix(lines[1], "v2       custom") ||
+		!strings.HasPrefix(lines[2], "v1       custom") {
+		t.Errorf("bad history output:\n%s\n", history)
+	}
+
+	diff := run("diff", "custom", "--from", "v1", "--to", "v2")
+	expectedDiff := `--- custom v1
++++ custom v2
+@@ -1,3 +1,3 @@
+ --{{define "custom"}}
+-first
++second
+ --{{end}}
+`
+	if diff != expectedDiff {
+		t.Errorf("bad diff\nexpected:\n%s\nactual:\n%s\n", expectedDiff, diff)
+	}
+	if latest := run("diff", "custom", "--from", "2"); latest != "Versions are identical\n" {
+		t.Errorf("expected diff to latest version to be empty, got:\n%s\n", latest)
+	}
+
+	run("rollback", "custom", "v1")
+	tmp, err := db_helpers.GetTemplateByName(db, ctx, "custom")
+	if err != nil {
+		t.Fatalf("failed to retrieve template - %s\n", err)
+	}
+	if !strings.Contains(tmp.Content, "first") {
+		t.Errorf("expected rollback to restore first version, got %q\n", tmp.Content)
+	}
+	if history := run("history", "custom"); !strings.Contains(history, "v3") {
+		t.Errorf("expected rollback to be stored as new version:\n%s\n", history)
+	}
+
+	for _, args := range [][]string{
+		{"history", "missing"},
+		{"diff", "custom", "--from", "first"},
+		{"rollback", "custom", "v9"},
+	} {
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err == nil {
+			t.Errorf("expected %q to error\n", args)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createImportCommand(c *core.Core) *cobra.Command {
+	importCommand := &cobra.Command{
+		Use:   "import",
+		Short: "Sync directory of template files into database",
+		Long: `Reads every ` + "`.tmpl`" + ` file inside given directory and its subdirectories. Each file holds one template, its name derives from the first definition, not from file name, other definitions in the file stay part of it. Templates are created or updated in order of their dependencies and in one transaction, templates with the same content are left untouched. With ` + "`--prune`" + ` stored templates that have no file are deleted.
+Directory is validated before any change is made.
+`,
+		Example: `$ hermes template import --dir ./prompts
+$ hermes template import --dir ./prompts --prune`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := cmd.Flags().GetString("dir")
+			if err != nil {
+				return err
+			}
+			prune, err := cmd.Flags().GetBool("prune")
+			if err != nil {
+				return err
+			}
+			command := core.NewImportTemplatesCommand(c, dir)
+			command.WithPrune(prune)
+			if err := command.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			for _, template := range command.Result.Created {
+				relayUpsert(c, template)
+			}
+			for _, template := range command.Result.Updated {
+				relayEdit(c, template, "edit")
+			}
+			for _, name := range command.Result.Deleted {
+				relayTemplateDeleted(c, name)
+			}
+			outputTemplateImport(c.GetConfig().Stdoout, command.Result)
+			return nil
+		},
+	}
+
+	importCommand.Flags().StringP("dir", "d", "", "directory to read template files from")
+	if err := importCommand.MarkFlagRequired("dir"); err != nil {
+		panic(err)
+	}
+	importCommand.Flags().Bool("prune", false, "delete stored templates that have no file")
+
+	return importCommand
+}
+
+func outputTemplateImport(w io.Writer, result *models.TemplateImport) {
+	for _, template := range result.Created {
+		fmt.Fprintf(w, "created    %s\n", template.Name)
+	}
+	for _, template := range result.Updated {
+		fmt.Fprintf(w, "updated    %s\n", template.Name)
+	}
+	for _, template := range result.Unchanged {
+		fmt.Fprintf(w, "unchanged  %s\n", template.Name)
+	}
+	for _, name := range result.Deleted {
+		fmt.Fprintf(w, "deleted    %s\n", name)
+	}
+	fmt.Fprintf(
+		w,
+		"\n%d created, %d updated, %d unchanged, %d deleted\n",
+		len(result.Created),
+		len(result.Updated),
+		len(result.Unchanged),
+		len(result.Deleted),
+	)
+}
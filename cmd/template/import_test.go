@@ -0,0 +1,127 @@
+package template_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/template"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func TestTemplateExportImport(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	out := coreInstance.GetConfig().Stdoout.(*strings.Builder)
+	run := func(args ...string) error {
+		out.Reset()
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+	write := func(dir string, file string, content string) {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write %q: %s\n", file, err)
+		}
+	}
+	for _, content := range []string{
+		`--{{define "short"}}(be short)--{{end}}`,
+		`--{{define "a/b"}}--{{template "short"}}--{{end}}`,
+	} {
+		if err := run("upsert", "--content", content); err != nil {
+			t.Fatalf("failed to upsert template: %s\n", err)
+		}
+	}
+
+	dir := filepath.Join(t.TempDir(), "prompts")
+	if err := run("export", "--dir", dir); err != nil {
+		t.Fatalf("failed to export templates: %s\n", err)
+	}
+	expected := filepath.Join(dir, "a%2Fb.tmpl") + "\n" +
+		filepath.Join(dir, "short.tmpl") + "\n\nExported 2 template(s)\n"
+	if out.String() != expected {
+		t.Errorf("bad export output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "short.tmpl"))
+	if err != nil || string(content) != `--{{define "short"}}(be short)--{{end}}` {
+		t.Errorf("bad exported content %q, error: %v\n", content, err)
+	}
+
+	if err := run("import", "--dir", dir); err != nil {
+		t.Fatalf("failed to import templates: %s\n", err)
+	}
+	expected = "unchanged  short\nunchanged  a/b\n\n0 created, 0 updated, 2 unchanged, 0 deleted\n"
+	if out.String() != expected {
+		t.Errorf("bad unchanged import output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+
+	// new template is used by updated one, so it has to be created first
+	write(dir, "a%2Fb.tmpl", `--{{define "a/b"}}--{{template "polite"}} --{{template "short"}}--{{end}}`)
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatalf("failed to create nested dir: %s\n", err)
+	}
+	write(filepath.Join(dir, "nested"), "any-file-name.tmpl", `--{{define "polite"}}(be polite)--{{end}}`)
+	write(dir, "notes.md", "not a template")
+	if err := run("import", "--dir", dir); err != nil {
+		t.Fatalf("failed to import templates: %s\n", err)
+	}
+	expected = "created    polite\nupdated    a/b\nunchanged  short\n\n1 created, 1 updated, 1 unchanged, 0 deleted\n"
+	if out.String() != expected {
+		t.Errorf("bad import output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+	if err := run("render", "a/b", "--content", "x"); err != nil {
+		t.Fatalf("failed to render imported template: %s\n", err)
+	}
+	if out.String() != "(be polite) (be short)\n" {
+		t.Errorf("imported template renders badly: %q\n", out.String())
+	}
+
+	// short is still used by a/b, so pruning it must be refused
+	if err := os.Remove(filepath.Join(dir, "short.tmpl")); err != nil {
+		t.Fatalf("failed to remove file: %s\n", err)
+	}
+	err = run("import", "--dir", dir, "--prune")
+	if err == nil || !strings.Contains(err.Error(), `template "a/b" uses "short", which has no file and would be pruned`) {
+		t.Errorf("expected prune of used template to be refused, got %v\n", err)
+	}
+	write(dir, "a%2Fb.tmpl", `--{{define "a/b"}}--{{template "polite"}}--{{end}}`)
+	if err := run("import", "--dir", dir, "--prune"); err != nil {
+		t.Fatalf("failed to import templates: %s\n", err)
+	}
+	expected = "updated    a/b\nunchanged  polite\ndeleted    short\n\n0 created, 1 updated, 1 unchanged, 1 deleted\n"
+	if out.String() != expected {
+		t.Errorf("bad prune output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+
+	// helper defines stay inside template, name comes from the first define
+	write(dir, "with-helper.tmpl", `--{{define "x"}}--{{template "y"}}--{{end}}--{{define "y"}}helper--{{end}}`)
+	if err := run("import", "--dir", dir); err != nil {
+		t.Fatalf("failed to import template with helper: %s\n", err)
+	}
+	expected = "created    x\nunchanged  polite\nunchanged  a/b\n\n1 created, 0 updated, 2 unchanged, 0 deleted\n"
+	if out.String() != expected {
+		t.Errorf("bad helper import output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatalf("failed to remove dir: %s\n", err)
+	}
+	if err := run("export", "--dir", dir); err != nil {
+		t.Fatalf("failed to export templates: %s\n", err)
+	}
+	if err := run("import", "--dir", dir); err != nil {
+		t.Fatalf("failed to import exported template with helper: %s\n", err)
+	}
+	if !strings.HasSuffix(out.String(), "0 created, 0 updated, 3 unchanged, 0 deleted\n") {
+		t.Errorf("expected exported templates to import unchanged, got %q\n", out.String())
+	}
+	write(dir, "broken.tmpl", `--{{define "polite"}}duplicate--{{end}}`)
+	if err := run("import", "--dir", dir); err == nil {
+		t.Errorf("expected duplicate template to be refused\n")
+	}
+	write(dir, "broken.tmpl", `--{{define "broken"}}--{{template "missing"}}--{{end}}`)
+	if err := run("import", "--dir", dir); err == nil {
+		t.Errorf("expected unknown reference to be refused\n")
+	}
+}
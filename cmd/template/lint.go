@@ -0,0 +1,39 @@
+package template
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func createLintCommand(c *core.Core) *cobra.Command {
+	return &cobra.Command{
+		Use:   "lint",
+		Short: "Check stored templates for errors",
+		Long: `Parses every stored template and reports syntax errors, templates that do not define their own name and references to templates that do not exist. Exits with error when any problem is found, so it can be used in CI.
+`,
+		Example: `$ hermes template lint`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			query := core.NewLintTemplatesQuery(c)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			if len(query.Result) == 0 {
+				fmt.Fprintf(c.GetConfig().Stdoout, "All templates are valid\n")
+				return nil
+			}
+			outputTemplateIssues(c.GetConfig().Stdoout, query.Result)
+			return fmt.Errorf("found %d template problem(s)\n", len(query.Result))
+		},
+	}
+}
+
+func outputTemplateIssues(w io.Writer, issues []*models.TemplateIssue) {
+	for _, issue := range issues {
+		fmt.Fprintf(w, "%s: %s\n", issue.Name, issue.Message)
+	}
+}
@@ -0,0 +1,67 @@
+package template
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/k10wl/hermes/cmd/utils"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func createRenderCommand(c *core.Core) *cobra.Command {
+	stdin := ""
+
+	renderCommand := &cobra.Command{
+		Use:   "render <name>",
+		Short: "Print prompt produced by template without calling AI",
+		Long: `Expands template with nested templates and variables exactly as chat does before completion and prints the result. Content can be provided with ` + "`--content`" + ` or piped in, variables with ` + "`--data`" + ` and ` + "`--var`" + `.
+`,
+		Example: `$ hermes template render tldr --content "long read"
+$ git diff --cached | hermes template render commit --var language=go`,
+		Args: cobra.ExactArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			stat, _ := os.Stdin.Stat()
+			if (stat.Mode() & os.ModeCharDevice) != 0 {
+				return nil
+			}
+			p, err := io.ReadAll(c.GetConfig().Stdin)
+			if err != nil {
+				return err
+			}
+			stdin = string(p)
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := cmd.Flags().GetString("content")
+			if err != nil {
+				return err
+			}
+			variables, err := utils.ReadTemplateVariables(cmd)
+			if err != nil {
+				return err
+			}
+			if stdin != "" {
+				content = fmt.Sprintf("%s\n\n%s", stdin, content)
+			}
+			query := core.NewRenderTemplateQuery(c, args[0], content)
+			query.WithVariables(variables)
+			if err := query.Execute(c.GetConfig().ShutdownContext); err != nil {
+				return err
+			}
+			fmt.Fprintf(c.GetConfig().Stdoout, "%s\n", query.Result)
+			return nil
+		},
+	}
+
+	renderCommand.Flags().StringP(
+		"content",
+		"c",
+		"",
+		"content passed to template, can be combined with stdin",
+	)
+	utils.AddTemplateVariablesFlags(renderCommand)
+
+	return renderCommand
+}
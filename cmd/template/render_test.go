@@ -0,0 +1,99 @@
+package template_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/cmd/template"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestTemplateRender(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	out := coreInstance.GetConfig().Stdoout.(*strings.Builder)
+	run := func(args ...string) error {
+		out.Reset()
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+	for _, content := range []string{
+		`--{{define "short"}}(be short)--{{end}}`,
+		`--{{define "review"}}review --{{var "language"}}: --{{.}} --{{template "short"}}--{{end}}`,
+	} {
+		if err := run("upsert", "--content", content); err != nil {
+			t.Fatalf("failed to upsert template: %s\n", err)
+		}
+	}
+
+	if err := run("render", "review", "--content", "main.go", "--var", "language=go"); err != nil {
+		t.Fatalf("failed to render template: %s\n", err)
+	}
+	if expected := "review go: main.go (be short)\n"; out.String() != expected {
+		t.Errorf("bad render output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+
+	if err := run("render", "missing", "--content", "main.go"); err == nil {
+		t.Errorf("expected render of missing template to fail\n")
+	}
+}
+
+func TestTemplateLint(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	out := coreInstance.GetConfig().Stdoout.(*strings.Builder)
+	run := func(args ...string) error {
+		out.Reset()
+		cmd := template.CreateTemplateCommand(coreInstance)
+		cmd.SetOut(&strings.Builder{})
+		cmd.SetErr(&strings.Builder{})
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	if err := db_helpers.CreateTemplate(db, ctx, &models.Template{
+		Name:    "valid",
+		Content: `--{{define "valid"}}valid--{{end}}`,
+	}); err != nil {
+		t.Fatalf("failed to create template: %s\n", err)
+	}
+	if err := run("lint"); err != nil {
+		t.Fatalf("expected valid templates to pass lint: %s\n", err)
+	}
+	if expected := "All templates are valid\n"; out.String() != expected {
+		t.Errorf("bad lint output\nexpected: %q\nactual:   %q\n", expected, out.String())
+	}
+
+	for _, broken := range []*models.Template{
+		{Name: "syntax", Content: `--{{define "syntax"}}--{{if}}--{{end}}`},
+		{Name: "renamed", Content: `--{{define "other"}}--{{template "valid"}}--{{end}}`},
+		{Name: "dangling", Content: `--{{define "dangling"}}--{{template "gone"}}--{{end}}`},
+	} {
+		if err := db_helpers.CreateTemplate(db, ctx, broken); err != nil {
+			t.Fatalf("failed to create template: %s\n", err)
+		}
+	}
+	err := run("lint")
+	if err == nil || err.Error() != "found 3 template problem(s)\n" {
+		t.Errorf("expected lint to fail with 3 problems, got %v\n", err)
+	}
+	expected := []string{
+		`dangling: unresolved reference to "gone"`,
+		`renamed: missing definition of "renamed"`,
+		`syntax: syntax error: `,
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("bad lint output\nexpected: %q\nactual:   %q\n", expected, lines)
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, expected[i]) {
+			t.Errorf("bad lint line %d\nexpected prefix: %q\nactual:          %q\n", i, expected[i], line)
+		}
+	}
+}
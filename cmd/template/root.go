@@ -13,11 +13,27 @@ func CreateTemplateCommand(c *core.Core) *cobra.Command {
 		Example: `  $ hermes template upsert --content '--{{define "tldr"}}tldr--{{end}}'
   $ hermes template view   --name tldr
   $ hermes template edit   --name tldr
-  $ hermes template delete --name tldr`,
+  $ hermes template delete --name tldr
+  $ hermes template history tldr
+  $ hermes template diff tldr --from v1 --to v2
+  $ hermes template rollback tldr v1
+  $ hermes template deps tldr
+  $ hermes template render tldr --content "long read"
+  $ hermes template lint
+  $ hermes template export --dir ./prompts
+  $ hermes template import --dir ./prompts --prune`,
 	}
 
 	templateCommand.AddCommand(createDeleteCommand(c))
+	templateCommand.AddCommand(createDepsCommand(c))
+	templateCommand.AddCommand(createDiffCommand(c))
 	templateCommand.AddCommand(createEditCommand(c))
+	templateCommand.AddCommand(createExportCommand(c))
+	templateCommand.AddCommand(createHistoryCommand(c))
+	templateCommand.AddCommand(createImportCommand(c))
+	templateCommand.AddCommand(createLintCommand(c))
+	templateCommand.AddCommand(createRenderCommand(c))
+	templateCommand.AddCommand(createRollbackCommand(c))
 	templateCommand.AddCommand(createUpsertCommand(c))
 	templateCommand.AddCommand(createViewCommand(c))
 
@@ -0,0 +1,85 @@
+package usage
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/spf13/cobra"
+)
+
+func CreateUsageCommand(c *core.Core) *cobra.Command {
+	usageCommand := &cobra.Command{
+		Use:   "usage",
+		Short: "Show tokens and money spent on completions",
+		Long: `Aggregates tokens and cost of stored assistant messages. Cost is calculated upon completion using known model prices (USD per token), models without known price (e.g. local ones) are free.
+Group results by ` + "`day`" + `, ` + "`model`" + ` or ` + "`chat`" + ` with ` + "`--by`" + ` ` + "(`-b`)" + ` flag.`,
+		Example: `  $ hermes usage
+  $ hermes usage --by model
+  $ hermes usage -b chat`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			by, err := cmd.Flags().GetString("by")
+			if err != nil {
+				return err
+			}
+			query := core.NewGetUsageReportQuery(c, by)
+			if err := query.Execute(cmd.Context()); err != nil {
+				return err
+			}
+			return writeReport(c.GetConfig().Stdoout, by, query.Result)
+		},
+	}
+
+	usageCommand.Flags().StringP(
+		"by",
+		"b",
+		models.UsageByDay,
+		"group usage by day, model or chat",
+	)
+
+	return usageCommand
+}
+
+func writeReport(w io.Writer, by string, reports []*models.UsageReport) error {
+	if len(reports) == 0 {
+		_, err := fmt.Fprintf(w, "No usage recorded\n")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "DAY"
+	switch by {
+	case models.UsageByModel:
+		header = "MODEL"
+	case models.UsageByChat:
+		header = "CHAT"
+	}
+	fmt.Fprintf(tw, "%s\tMESSAGES\tINPUT\tOUTPUT\tCOST\n", header)
+	total := models.UsageReport{Key: "total"}
+	for _, report := range reports {
+		key := report.Key
+		if report.Name != "" {
+			key = fmt.Sprintf("%s %s", report.Key, report.Name)
+		}
+		writeRow(tw, key, report)
+		total.Messages += report.Messages
+		total.InputTokens += report.InputTokens
+		total.OutputTokens += report.OutputTokens
+		total.Cost += report.Cost
+	}
+	writeRow(tw, total.Key, &total)
+	return tw.Flush()
+}
+
+func writeRow(w io.Writer, key string, report *models.UsageReport) {
+	fmt.Fprintf(
+		w,
+		"%s\t%d\t%d\t%d\t$%.4f\n",
+		key,
+		report.Messages,
+		report.InputTokens,
+		report.OutputTokens,
+		report.Cost,
+	)
+}
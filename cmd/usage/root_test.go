@@ -0,0 +1,116 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestUsageCommand(t *testing.T) {
+	type testCase struct {
+		name     string
+		by       string
+		seed     bool
+		expected string
+	}
+
+	table := []testCase{
+		{
+			name:     "should notify that usage is empty",
+			by:       models.UsageByDay,
+			expected: "No usage recorded\n",
+		},
+		{
+			name: "should group usage by day",
+			by:   models.UsageByDay,
+			seed: true,
+			expected: fmt.Sprintf(`DAY         MESSAGES  INPUT  OUTPUT  COST
+%s  3         60     30      $0.7500
+total       3         60     30      $0.7500
+`, time.Now().UTC().Format(time.DateOnly)),
+		},
+		{
+			name: "should group usage by model, most expensive first",
+			by:   models.UsageByModel,
+			seed: true,
+			expected: `MODEL                  MESSAGES  INPUT  OUTPUT  COST
+anthropic/claude-opus  1         30     15      $0.5000
+openai/gpt-4o          1         10     5       $0.2500
+local/llama            1         20     10      $0.0000
+total                  3         60     30      $0.7500
+`,
+		},
+		{
+			name: "should group usage by chat, latest first",
+			by:   models.UsageByChat,
+			seed: true,
+			expected: `CHAT   MESSAGES  INPUT  OUTPUT  COST
+2 2    1         30     15      $0.5000
+1 1    2         30     15      $0.2500
+total  3         60     30      $0.7500
+`,
+		},
+	}
+
+	for _, test := range table {
+		coreInstance, db := test_helpers.CreateCore()
+		out := &strings.Builder{}
+		coreInstance.GetConfig().Stdoout = out
+		if test.seed {
+			seedUsage(t, db)
+		}
+		cmd := CreateUsageCommand(coreInstance)
+		cmd.SetArgs([]string{"--by", test.by})
+		if err := cmd.Execute(); err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf(
+				"%q - bad output\nexpected:\n%s\nactual:\n%s\n",
+				test.name,
+				test.expected,
+				out.String(),
+			)
+		}
+	}
+}
+
+func TestUsageCommandBadGrouping(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	cmd := CreateUsageCommand(coreInstance)
+	cmd.SetArgs([]string{"--by", "week"})
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	if err := cmd.Execute(); err == nil {
+		t.Errorf("expected to error on unknown grouping\n")
+	}
+}
+
+func seedUsage(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	seeder := db_helpers.NewSeeder(db, ctx)
+	if err := seeder.SeedChatsN(2); err != nil {
+		t.Fatalf("failed to seed chats - %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(2, 1); err != nil {
+		t.Fatalf("failed to seed messages - %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(1, 2); err != nil {
+		t.Fatalf("failed to seed messages - %s\n", err)
+	}
+	if err := db_helpers.CreateMessageUsage(db, ctx, []*models.Usage{
+		{MessageID: 1, Model: "openai/gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.25},
+		{MessageID: 2, Model: "local/llama", InputTokens: 20, OutputTokens: 10},
+		{MessageID: 3, Model: "anthropic/claude-opus", InputTokens: 30, OutputTokens: 15, Cost: 0.5},
+	}); err != nil {
+		t.Fatalf("failed to seed usage - %s\n", err)
+	}
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"os"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/spf13/cobra"
+)
+
+func AddTemplateVariablesFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray(
+		"var",
+		[]string{},
+		"template variable as key=value, read in template with var \"key\" (repeatable)",
+	)
+	cmd.Flags().String(
+		"data",
+		"",
+		"path to JSON or YAML file with template variables, --var overrides its keys",
+	)
+}
+
+// variables from data file merged with --var pairs, pairs take precedence
+func ReadTemplateVariables(cmd *cobra.Command) (map[string]any, error) {
+	path, err := cmd.Flags().GetString("data")
+	if err != nil {
+		return nil, err
+	}
+	pairs, err := cmd.Flags().GetStringArray("var")
+	if err != nil {
+		return nil, err
+	}
+	variables := map[string]any{}
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if variables, err = core.ParseTemplateData(content); err != nil {
+			return nil, err
+		}
+	}
+	overrides, err := core.ParseTemplateVariables(pairs)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range overrides {
+		variables[key] = value
+	}
+	return variables, nil
+}
@@ -3,6 +3,7 @@ module github.com/k10wl/hermes
 go 1.24.0
 
 require (
+	github.com/BurntSushi/toml v1.5.0
 	github.com/go-playground/validator/v10 v10.23.0
 	github.com/golang-migrate/migrate/v4 v4.18.1
 	github.com/google/uuid v1.6.0
@@ -11,6 +12,7 @@ require (
 	github.com/ncruces/go-sqlite3 v0.23.3
 	github.com/spf13/cobra v1.8.1
 	go.uber.org/atomic v1.11.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
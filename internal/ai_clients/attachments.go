@@ -0,0 +1,80 @@
+package ai_clients
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// file sent together with message content
+type Attachment struct {
+	Name      string
+	MediaType string
+	Data      []byte
+}
+
+const (
+	attachmentImage    = "image"
+	attachmentDocument = "document"
+	attachmentText     = "text"
+)
+
+var imageMediaTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+var textMediaTypes = []string{
+	"application/json",
+	"application/xml",
+	"application/yaml",
+	"application/x-yaml",
+	"application/javascript",
+	"application/x-sh",
+}
+
+// empty kind means that none of providers accepts media type
+func attachmentKind(mediaType string) string {
+	switch {
+	case slices.Contains(imageMediaTypes, mediaType):
+		return attachmentImage
+	case mediaType == "application/pdf":
+		return attachmentDocument
+	case strings.HasPrefix(mediaType, "text/") || slices.Contains(textMediaTypes, mediaType):
+		return attachmentText
+	}
+	return ""
+}
+
+// attachments of other media types are rejected before they are stored
+func CheckAttachment(mediaType string) error {
+	if attachmentKind(mediaType) == "" {
+		return fmt.Errorf(
+			"%w %q, expected image (png, jpeg, gif, webp), pdf or text file\n",
+			ErrUnsupportedAttachment,
+			mediaType,
+		)
+	}
+	return nil
+}
+
+func unsupportedAttachment(provider string, attachment *Attachment) error {
+	return fmt.Errorf(
+		"%w %q of %q, %s provider does not accept it\n",
+		ErrUnsupportedAttachment,
+		attachment.MediaType,
+		attachment.Name,
+		provider,
+	)
+}
+
+// text files are sent inline for every provider
+func (a *Attachment) text() string {
+	return fmt.Sprintf("<file name=%q>\n%s\n</file>", a.Name, a.Data)
+}
+
+func (a *Attachment) base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+func (a *Attachment) dataURL() string {
+	return "data:" + a.MediaType + ";base64," + a.base64()
+}
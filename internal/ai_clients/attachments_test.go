@@ -0,0 +1,137 @@
+package ai_clients
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/settings"
+)
+
+func TestEncodeAttachments(t *testing.T) {
+	local, err := newClientLocal(settings.LocalProvider{BaseURL: "http://localhost"})
+	if err != nil {
+		t.Fatalf("failed to create local client: %s\n", err)
+	}
+	image := &Attachment{Name: "a.png", MediaType: "image/png", Data: []byte("png")}
+	pdf := &Attachment{Name: "a.pdf", MediaType: "application/pdf", Data: []byte("pdf")}
+	text := &Attachment{Name: "a.txt", MediaType: "text/plain", Data: []byte("notes")}
+
+	type testCase struct {
+		name        string
+		prepare     func(messages []*Message) ([]byte, error)
+		attachments []*Attachment
+		contains    []string
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should send openai image and file as content parts",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return newClientOpenAI("").prepare(messages, &Parameters{}, false)
+			},
+			attachments: []*Attachment{image, pdf, text},
+			contains: []string{
+				`"content":[{"type":"text","text":"question"}`,
+				`{"type":"image_url","image_url":{"url":"data:image/png;base64,cG5n"}}`,
+				`{"type":"file","file":{"filename":"a.pdf","file_data":"data:application/pdf;base64,cGRm"}}`,
+				`{"type":"text","text":"\u003cfile name=\"a.txt\"\u003e\nnotes\n\u003c/file\u003e"}`,
+			},
+		},
+		{
+			name: "should send claude image and document blocks before text",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return newClientClaude("").prepare(messages, &Parameters{}, false)
+			},
+			attachments: []*Attachment{image, pdf},
+			contains: []string{
+				`"content":[{"type":"image","source":{"type":"base64","media_type":"image/png","data":"cG5n"}}`,
+				`{"type":"document","source":{"type":"base64","media_type":"application/pdf","data":"cGRm"}}`,
+				`{"type":"text","text":"question"}]`,
+			},
+		},
+		{
+			name: "should send gemini inline data",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return newClientGemini("").prepare(messages, &Parameters{})
+			},
+			attachments: []*Attachment{image, pdf},
+			contains: []string{
+				`"parts":[{"inlineData":{"mimeType":"image/png","data":"cG5n"}}`,
+				`{"inlineData":{"mimeType":"application/pdf","data":"cGRm"}},{"text":"question"}]`,
+			},
+		},
+		{
+			name: "should send local image",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return local.prepare(messages, &Parameters{}, false)
+			},
+			attachments: []*Attachment{image},
+			contains:    []string{`"image_url":{"url":"data:image/png;base64,cG5n"}`},
+		},
+		{
+			name: "should reject pdf for local provider",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return local.prepare(messages, &Parameters{}, false)
+			},
+			attachments: []*Attachment{pdf},
+			shouldError: true,
+		},
+		{
+			name: "should keep plain content without attachments",
+			prepare: func(messages []*Message) ([]byte, error) {
+				return newClientOpenAI("").prepare(messages, &Parameters{}, false)
+			},
+			contains: []string{`"content":"question"`},
+		},
+	}
+
+	for _, test := range table {
+		data, err := test.prepare([]*Message{{
+			Role:        "user",
+			Content:     "question",
+			Attachments: test.attachments,
+		}})
+		if test.shouldError {
+			if !errors.Is(err, ErrUnsupportedAttachment) {
+				t.Errorf("%q - expected unsupported attachment error, got %v\n", test.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(string(data), expected) {
+				t.Errorf(
+					"%q - bad request\nexpected to contain: %s\nactual: %s\n",
+					test.name,
+					expected,
+					data,
+				)
+			}
+		}
+	}
+}
+
+func TestCheckAttachment(t *testing.T) {
+	table := map[string]bool{
+		"image/png":        true,
+		"application/pdf":  true,
+		"text/markdown":    true,
+		"application/json": true,
+		"application/zip":  false,
+		"video/mp4":        false,
+	}
+	for mediaType, supported := range table {
+		err := CheckAttachment(mediaType)
+		if supported && err != nil {
+			t.Errorf("%q - unexpected error: %s\n", mediaType, err)
+		}
+		if !supported && !errors.Is(err, ErrUnsupportedAttachment) {
+			t.Errorf("%q - expected unsupported attachment error, got %v\n", mediaType, err)
+		}
+	}
+}
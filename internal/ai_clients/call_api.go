@@ -1,34 +1,247 @@
 package ai_clients
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"time"
+
+	"github.com/k10wl/hermes/internal/settings"
 )
 
-func callApi(
+// first retry waits around this long, every next one twice as long
+var baseRetryDelay = time.Second
+
+// longest wait between retries, requests asked to wait longer are not retried
+var maxRetryDelay = time.Minute
+
+// sends requests of single completion, they are cancelled with its context
+type caller struct {
+	ctx     context.Context
+	options settings.Requests
+}
+
+func newCaller(ctx context.Context, options settings.Requests) *caller {
+	return &caller{ctx: ctx, options: options}
+}
+
+func (c *caller) get(
 	url string,
 	body io.Reader,
 	fillHeaders func(*http.Request) error,
 ) ([]byte, error) {
-	request, err := http.NewRequest(http.MethodPost, url, body)
+	attempt, err := c.send(url, body, fillHeaders, "")
 	if err != nil {
 		return nil, err
 	}
-	err = fillHeaders(request)
+	defer attempt.close()
+	data, err := io.ReadAll(attempt.res.Body)
 	if err != nil {
-		return nil, err
+		return nil, attempt.wrap(err)
 	}
-	res, err := http.DefaultClient.Do(request)
+	return data, nil
+}
+
+func (c *caller) stream(
+	url string,
+	body io.Reader,
+	fillHeaders func(*http.Request) error,
+	onEvent func(event string, data []byte) error,
+) error {
+	attempt, err := c.send(url, body, fillHeaders, "text/event-stream")
+	if err != nil {
+		return err
+	}
+	defer attempt.close()
+	err = readEvents(attempt.res.Body, func(event string, data []byte) error {
+		attempt.touch()
+		return onEvent(event, data)
+	})
+	if err != nil {
+		return attempt.wrap(err)
+	}
+	return nil
+}
+
+// rate limited and failed requests are repeated with exponential backoff,
+// Retry-After of provider takes precedence over it
+func (c *caller) send(
+	url string,
+	body io.Reader,
+	fillHeaders func(*http.Request) error,
+	accept string,
+) (*attempt, error) {
+	data, err := io.ReadAll(body)
 	if err != nil {
 		return nil, err
 	}
-	data, err := io.ReadAll(res.Body)
+	for retry := 0; ; retry++ {
+		attempt, err := c.try(url, data, fillHeaders, accept)
+		if err == nil {
+			return attempt, nil
+		}
+		delay, ok := c.retryDelay(retry, err)
+		if !ok {
+			return nil, err
+		}
+		select {
+		case <-c.ctx.Done():
+			return nil, c.ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *caller) try(
+	url string,
+	data []byte,
+	fillHeaders func(*http.Request) error,
+	accept string,
+) (*attempt, error) {
+	attempt := newAttempt(c.ctx, c.options.Timeout)
+	request, err := http.NewRequestWithContext(
+		attempt.ctx,
+		http.MethodPost,
+		url,
+		bytes.NewReader(data),
+	)
 	if err != nil {
+		attempt.close()
+		return nil, err
+	}
+	if err := fillHeaders(request); err != nil {
+		attempt.close()
 		return nil, err
 	}
+	if accept != "" {
+		request.Header.Set("Accept", accept)
+	}
+	res, err := http.DefaultClient.Do(request)
+	if err != nil {
+		attempt.close()
+		return nil, attempt.wrap(err)
+	}
+	attempt.res = res
 	if res.StatusCode < 200 || 399 < res.StatusCode {
-		return nil, fmt.Errorf("API error - %s\n", data)
+		defer attempt.close()
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, attempt.wrap(err)
+		}
+		return nil, newAPIError(res.StatusCode, res.Header, body)
 	}
-	return data, nil
+	attempt.touch()
+	return attempt, nil
+}
+
+func (c *caller) retryDelay(retry int, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if retry >= c.options.MaxRetries ||
+		!errors.As(err, &apiErr) ||
+		!(errors.Is(err, ErrRateLimit) || errors.Is(err, ErrServer)) {
+		return 0, false
+	}
+	if apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, apiErr.RetryAfter <= maxRetryDelay
+	}
+	delay := min(baseRetryDelay<<retry, maxRetryDelay)
+	// jitter spreads retries of concurrent completions
+	return delay/2 + rand.N(delay/2+1), true
+}
+
+// single request, it is cancelled when provider is silent for longer than
+// timeout. Streamed response restarts timeout on every event
+type attempt struct {
+	ctx     context.Context
+	cancel  context.CancelCauseFunc
+	timer   *time.Timer
+	timeout time.Duration
+	res     *http.Response
+}
+
+func newAttempt(parent context.Context, timeout time.Duration) *attempt {
+	ctx, cancel := context.WithCancelCause(parent)
+	a := &attempt{ctx: ctx, cancel: cancel, timeout: timeout}
+	if timeout > 0 {
+		a.timer = time.AfterFunc(timeout, func() {
+			cancel(fmt.Errorf("%w, no response in %s", ErrTimeout, timeout))
+		})
+	}
+	return a
+}
+
+func (a *attempt) touch() {
+	if a.timer != nil {
+		a.timer.Reset(a.timeout)
+	}
+}
+
+// timeout replaces errors of cancelled request
+func (a *attempt) wrap(err error) error {
+	if cause := context.Cause(a.ctx); errors.Is(cause, ErrTimeout) {
+		return cause
+	}
+	return err
+}
+
+func (a *attempt) close() {
+	if a.timer != nil {
+		a.timer.Stop()
+	}
+	if a.res != nil {
+		a.res.Body.Close()
+	}
+	a.cancel(nil)
+}
+
+// maximum size of single server-sent event line
+const maxEventSize = 1024 * 1024
+
+// reads server-sent events, see
+// https://html.spec.whatwg.org/multipage/server-sent-events.html
+func readEvents(r io.Reader, onEvent func(event string, data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+	event := ""
+	data := &bytes.Buffer{}
+	dispatch := func() error {
+		if data.Len() == 0 {
+			event = ""
+			return nil
+		}
+		err := onEvent(event, bytes.TrimSuffix(data.Bytes(), []byte{'\n'}))
+		event = ""
+		data.Reset()
+		return err
+	}
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if line[0] == ':' {
+			continue
+		}
+		field, value, _ := bytes.Cut(line, []byte{':'})
+		value = bytes.TrimPrefix(value, []byte{' '})
+		switch string(field) {
+		case "event":
+			event = string(value)
+		case "data":
+			data.Write(value)
+			data.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return dispatch()
 }
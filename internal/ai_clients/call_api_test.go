@@ -0,0 +1,271 @@
+package ai_clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/k10wl/hermes/internal/settings"
+)
+
+func TestReadEvents(t *testing.T) {
+	type event struct {
+		name string
+		data string
+	}
+	type testCase struct {
+		name     string
+		input    string
+		expected []event
+	}
+
+	table := []testCase{
+		{
+			name:  "should read data only events",
+			input: "data: {\"a\":1}\n\ndata: [DONE]\n\n",
+			expected: []event{
+				{data: `{"a":1}`},
+				{data: `[DONE]`},
+			},
+		},
+		{
+			name:  "should read named events and skip comments",
+			input: ": keep-alive\nevent: ping\ndata: {}\n\nevent: message_stop\ndata: {\"type\":\"message_stop\"}\n\n",
+			expected: []event{
+				{name: "ping", data: `{}`},
+				{name: "message_stop", data: `{"type":"message_stop"}`},
+			},
+		},
+		{
+			name:  "should join multiline data and flush without trailing blank line",
+			input: "data: first\ndata: second",
+			expected: []event{
+				{data: "first\nsecond"},
+			},
+		},
+	}
+
+	for _, test := range table {
+		actual := []event{}
+		err := readEvents(strings.NewReader(test.input), func(name string, data []byte) error {
+			actual = append(actual, event{name: name, data: string(data)})
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, actual) {
+			t.Errorf(
+				"%q - bad events\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestCallerRetries(t *testing.T) {
+	baseRetryDelay = time.Millisecond
+	defer func() { baseRetryDelay = time.Second }()
+
+	type response struct {
+		status int
+		header map[string]string
+		body   string
+	}
+	type testCase struct {
+		name             string
+		responses        []response // last one repeats
+		maxRetries       int
+		expectedAttempts int64
+		expectedErr      error
+	}
+
+	ok := response{status: http.StatusOK, body: "ok"}
+	table := []testCase{
+		{
+			name: "should retry server errors until success",
+			responses: []response{
+				{status: http.StatusServiceUnavailable},
+				{status: 529, body: "overloaded"},
+				ok,
+			},
+			maxRetries:       3,
+			expectedAttempts: 3,
+		},
+		{
+			name: "should honor retry after",
+			responses: []response{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "0.01"}},
+				ok,
+			},
+			maxRetries:       1,
+			expectedAttempts: 2,
+		},
+		{
+			name:             "should give up after max retries",
+			responses:        []response{{status: http.StatusTooManyRequests}},
+			maxRetries:       2,
+			expectedAttempts: 3,
+			expectedErr:      ErrRateLimit,
+		},
+		{
+			name: "should not wait longer than max retry delay",
+			responses: []response{
+				{status: http.StatusTooManyRequests, header: map[string]string{"Retry-After": "3600"}},
+				ok,
+			},
+			maxRetries:       3,
+			expectedAttempts: 1,
+			expectedErr:      ErrRateLimit,
+		},
+		{
+			name:             "should not retry rejected key",
+			responses:        []response{{status: http.StatusUnauthorized}, ok},
+			maxRetries:       3,
+			expectedAttempts: 1,
+			expectedErr:      ErrAuth,
+		},
+		{
+			name: "should recognize context length error",
+			responses: []response{{
+				status: http.StatusBadRequest,
+				body:   `{"error":{"code":"context_length_exceeded"}}`,
+			}},
+			maxRetries:       3,
+			expectedAttempts: 1,
+			expectedErr:      ErrContextLength,
+		},
+	}
+
+	for _, test := range table {
+		var attempts atomic.Int64
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			attempt := attempts.Add(1)
+			if body, _ := io.ReadAll(r.Body); string(body) != "request" {
+				t.Errorf("%q - attempt %d got bad body %q\n", test.name, attempt, body)
+			}
+			res := test.responses[min(int(attempt), len(test.responses))-1]
+			for key, value := range res.header {
+				w.Header().Set(key, value)
+			}
+			w.WriteHeader(res.status)
+			fmt.Fprint(w, res.body)
+		}))
+		caller := newCaller(context.Background(), settings.Requests{MaxRetries: test.maxRetries})
+		data, err := caller.get(
+			server.URL,
+			strings.NewReader("request"),
+			func(*http.Request) error { return nil },
+		)
+		server.Close()
+		if attempts.Load() != test.expectedAttempts {
+			t.Errorf(
+				"%q - bad attempts\nexpected: %d\nactual:   %d\n",
+				test.name,
+				test.expectedAttempts,
+				attempts.Load(),
+			)
+		}
+		if test.expectedErr != nil {
+			if !errors.Is(err, test.expectedErr) {
+				t.Errorf("%q - expected %v, got %v\n", test.name, test.expectedErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if string(data) != "ok" {
+			t.Errorf("%q - bad response %q\n", test.name, data)
+		}
+	}
+}
+
+func TestCallerTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") == "text/event-stream" {
+			fmt.Fprint(w, "data: first\n\n")
+			w.(http.Flusher).Flush()
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+	caller := newCaller(
+		context.Background(),
+		settings.Requests{Timeout: 50 * time.Millisecond, MaxRetries: 3},
+	)
+	noHeaders := func(*http.Request) error { return nil }
+
+	if _, err := caller.get(server.URL, strings.NewReader(""), noHeaders); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected response to time out, got %v\n", err)
+	}
+
+	events := []string{}
+	err := caller.stream(server.URL, strings.NewReader(""), noHeaders, func(_ string, data []byte) error {
+		events = append(events, string(data))
+		return nil
+	})
+	if !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected stream to time out after silence, got %v\n", err)
+	}
+	if !reflect.DeepEqual(events, []string{"first"}) {
+		t.Errorf("expected event before silence, got %q\n", events)
+	}
+}
+
+func TestCallerCancel(t *testing.T) {
+	var attempts atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	caller := newCaller(ctx, settings.Requests{MaxRetries: 3})
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := caller.get(server.URL, strings.NewReader(""), func(*http.Request) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled request, got %v\n", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled request kept waiting for retry for %s\n", elapsed)
+	}
+	if attempts.Load() != 1 {
+		t.Errorf("expected single attempt, got %d\n", attempts.Load())
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	table := map[error]string{
+		newAPIError(http.StatusTooManyRequests, http.Header{}, nil):                     "rate_limit",
+		newAPIError(http.StatusInternalServerError, http.Header{}, nil):                 "server",
+		fmt.Errorf("%w, no response in 1s", ErrTimeout):                                 "timeout",
+		&APIError{Kind: classifyClaudeError("overloaded_error")}:                        "server",
+		newAPIError(http.StatusBadRequest, http.Header{}, []byte("bad")):                "",
+		newAPIError(http.StatusBadRequest, http.Header{}, []byte("prompt is too long")): "context_length",
+	}
+	for err, expected := range table {
+		if actual := ErrorCode(err); actual != expected {
+			t.Errorf("%q - bad code\nexpected: %q\nactual:   %q\n", err, expected, actual)
+		}
+	}
+}
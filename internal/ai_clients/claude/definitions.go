@@ -1,6 +1,8 @@
 // Generated by AI based on https://docs.anthropic.com/en/api/messages
 package claude
 
+import "encoding/json"
+
 type MessagesRequest struct {
 	Model         string            `json:"model"`
 	Messages      []*MessageContent `json:"messages"`
@@ -18,6 +20,26 @@ type MessagesRequest struct {
 type MessageContent struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// replaces content in request when message carries images or documents
+	Blocks []ContentBlockParam `json:"-"`
+}
+
+func (m MessageContent) MarshalJSON() ([]byte, error) {
+	if len(m.Blocks) == 0 {
+		type plain MessageContent
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		Role    string              `json:"role"`
+		Content []ContentBlockParam `json:"content"`
+	}{Role: m.Role, Content: m.Blocks})
+}
+
+// type is "text", "image" or "document", last two carry base64 source
+type ContentBlockParam struct {
+	Type   string  `json:"type"`
+	Text   string  `json:"text,omitempty"`
+	Source *Source `json:"source,omitempty"`
 }
 
 type Source struct {
@@ -73,3 +95,25 @@ type Usage struct {
 	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens,omitempty"`
 	CacheReadInputTokens     int64 `json:"cache_read_input_tokens,omitempty"`
 }
+
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`
+	Index        int64             `json:"index,omitempty"`
+	ContentBlock *ContentBlock     `json:"content_block,omitempty"`
+	Delta        *StreamDelta      `json:"delta,omitempty"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	Error        *StreamError      `json:"error,omitempty"`
+}
+
+type StreamDelta struct {
+	Type         string `json:"type,omitempty"`
+	Text         string `json:"text,omitempty"`
+	StopReason   string `json:"stop_reason,omitempty"`
+	StopSequence string `json:"stop_sequence,omitempty"`
+}
+
+type StreamError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
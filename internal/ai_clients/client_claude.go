@@ -36,7 +36,7 @@ func (client clientClaude) complete(
 	parameters *Parameters,
 	get getter,
 ) (*AIResponse, error) {
-	data, err := client.prepare(messages, parameters)
+	data, err := client.prepare(messages, parameters, false)
 	if err != nil {
 		return nil, err
 	}
@@ -56,20 +56,91 @@ func (client clientClaude) complete(
 	return client.decodeResult(&response)
 }
 
+func (client clientClaude) stream(
+	messages []*Message,
+	parameters *Parameters,
+	stream streamer,
+	onDelta OnDelta,
+) (*AIResponse, error) {
+	data, err := client.prepare(messages, parameters, true)
+	if err != nil {
+		return nil, err
+	}
+	response := &AIResponse{Message: Message{Role: "assistant"}}
+	content := &strings.Builder{}
+	err = stream(
+		client.apiUrl,
+		bytes.NewReader(data),
+		client.fillHeaders,
+		func(_ string, data []byte) error {
+			return client.decodeEvent(data, response, content, onDelta)
+		},
+	)
+	response.Content = content.String()
+	// part streamed before failure is kept, cancelled completion saves it
+	return response, err
+}
+
+func (client clientClaude) decodeEvent(
+	data []byte,
+	response *AIResponse,
+	content *strings.Builder,
+	onDelta OnDelta,
+) error {
+	var event claude.StreamEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return err
+	}
+	switch event.Type {
+	case "message_start":
+		if event.Message == nil {
+			return nil
+		}
+		response.Role = event.Message.Role
+		response.Model = event.Message.Model
+		response.TokensUsage.Input = event.Message.Usage.InputTokens
+		response.TokensUsage.Output = event.Message.Usage.OutputTokens
+	case "content_block_delta":
+		if event.Delta == nil || event.Delta.Text == "" {
+			return nil
+		}
+		content.WriteString(event.Delta.Text)
+		onDelta(event.Delta.Text)
+	case "message_delta":
+		if event.Usage != nil {
+			response.TokensUsage.Output = event.Usage.OutputTokens
+		}
+	case "error":
+		if event.Error == nil {
+			return &APIError{Body: string(data)}
+		}
+		return &APIError{
+			Kind: classifyClaudeError(event.Error.Type),
+			Body: event.Error.Type + ": " + event.Error.Message,
+		}
+	}
+	return nil
+}
+
 func (client clientClaude) prepare(
 	messages []*Message,
 	parameters *Parameters,
+	stream bool,
 ) ([]byte, error) {
 	model := parameters.Model
 	if fullName, ok := claudeModelsShorthands[model]; ok {
 		model = fullName
 	}
-	encodedMessages, systemPrompt := client.encodeMessages(messages)
+	encodedMessages, systemPrompt, err := client.encodeMessages(messages)
+	if err != nil {
+		return nil, err
+	}
 	data := claude.MessagesRequest{
 		Model:     model,
 		Messages:  encodedMessages,
 		System:    systemPrompt,
 		MaxTokens: client.maxTokensFallback,
+		Stream:    stream,
 	}
 	if parameters.MaxTokens != nil {
 		data.MaxTokens = *parameters.MaxTokens
@@ -82,20 +153,54 @@ func (client clientClaude) prepare(
 
 func (client clientClaude) encodeMessages(
 	messages []*Message,
-) ([]*claude.MessageContent, string) {
-	result := make([]*claude.MessageContent, len(messages))
+) ([]*claude.MessageContent, string, error) {
+	result := []*claude.MessageContent{}
 	sb := &strings.Builder{}
-	for i, v := range messages {
+	for _, v := range messages {
 		if v.Role == "system" {
 			sb.WriteString(v.Content + "\n")
 			continue
 		}
-		result[i] = &claude.MessageContent{
+		blocks, err := client.encodeAttachments(v)
+		if err != nil {
+			return nil, "", err
+		}
+		result = append(result, &claude.MessageContent{
 			Role:    v.Role,
 			Content: v.Content,
+			Blocks:  blocks,
+		})
+	}
+	return result, sb.String(), nil
+}
+
+// nil blocks when message has no attachments, content is sent as is
+func (client clientClaude) encodeAttachments(
+	message *Message,
+) ([]claude.ContentBlockParam, error) {
+	if len(message.Attachments) == 0 {
+		return nil, nil
+	}
+	blocks := []claude.ContentBlockParam{}
+	for _, attachment := range message.Attachments {
+		source := &claude.Source{
+			Type:      "base64",
+			MediaType: attachment.MediaType,
+			Data:      attachment.base64(),
+		}
+		switch attachmentKind(attachment.MediaType) {
+		case attachmentText:
+			blocks = append(blocks, claude.ContentBlockParam{Type: "text", Text: attachment.text()})
+		case attachmentImage:
+			blocks = append(blocks, claude.ContentBlockParam{Type: "image", Source: source})
+		case attachmentDocument:
+			blocks = append(blocks, claude.ContentBlockParam{Type: "document", Source: source})
+		default:
+			return nil, unsupportedAttachment("anthropic", attachment)
 		}
 	}
-	return result, sb.String()
+	// anthropic suggests placing files before question about them
+	return append(blocks, claude.ContentBlockParam{Type: "text", Text: message.Content}), nil
 }
 
 func (client clientClaude) decodeResult(response *claude.MessagesResponse) (*AIResponse, error) {
@@ -111,6 +216,7 @@ func (client clientClaude) decodeResult(response *claude.MessagesResponse) (*AIR
 			Input:  response.Usage.InputTokens,
 			Output: response.Usage.OutputTokens,
 		},
+		Model: response.Model,
 	}, nil
 }
 
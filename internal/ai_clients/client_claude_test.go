@@ -86,6 +86,7 @@ func TestClaudeCompletion(t *testing.T) {
 					Input:  200,
 					Output: 20,
 				},
+				Model: "claude-3-5-sonnet-20240620",
 			},
 			apiKey: "SECRET",
 		},
@@ -201,3 +202,117 @@ out:
 		}
 	}
 }
+
+func TestClaudeStream(t *testing.T) {
+	inputMessages := []*Message{{Role: "user", Content: "stuff"}}
+
+	type testCase struct {
+		name             string
+		events           []string
+		apiKey           string
+		expectedDeltas   []string
+		expectedResponse AIResponse
+		expectErr        bool
+	}
+
+	table := []testCase{
+		{
+			name: "should concatenate deltas and collect usage",
+			events: []string{
+				`{"type":"message_start","message":{"id":"1","type":"message","role":"assistant","model":"claude-3-5-sonnet-20240620","content":[],"usage":{"input_tokens":200,"output_tokens":1}}}`,
+				`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+				`{"type":"ping"}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"res"}}`,
+				`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"ponse"}}`,
+				`{"type":"content_block_stop","index":0}`,
+				`{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":20}}`,
+				`{"type":"message_stop"}`,
+			},
+			apiKey:         "SECRET",
+			expectedDeltas: []string{"res", "ponse"},
+			expectedResponse: AIResponse{
+				Message: Message{Role: "assistant", Content: "response"},
+				TokensUsage: TokensUsage{
+					Input:  200,
+					Output: 20,
+				},
+				Model: "claude-3-5-sonnet-20240620",
+			},
+		},
+
+		{
+			name: "should return error event as error",
+			events: []string{
+				`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			},
+			apiKey:    "SECRET",
+			expectErr: true,
+		},
+
+		{
+			name:      "should error if api key was not provided",
+			events:    []string{`{"type":"message_stop"}`},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range table {
+		var request claude.MessagesRequest
+		stream := func(
+			url string,
+			body io.Reader,
+			fillHeaders func(*http.Request) error,
+			onEvent func(string, []byte) error,
+		) error {
+			req, _ := http.NewRequest(http.MethodPost, "", nil)
+			if err := fillHeaders(req); err != nil {
+				return err
+			}
+			if err := json.NewDecoder(body).Decode(&request); err != nil {
+				return err
+			}
+			for _, event := range test.events {
+				if err := onEvent("", []byte(event)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		deltas := []string{}
+		actual, err := newClientClaude(test.apiKey).stream(
+			inputMessages,
+			&Parameters{Model: "claude-sonet"},
+			stream,
+			func(delta string) { deltas = append(deltas, delta) },
+		)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%q - expected to error but didn't\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !request.Stream {
+			t.Errorf("%q - did not request stream: %+v\n", test.name, request)
+		}
+		if !reflect.DeepEqual(test.expectedDeltas, deltas) {
+			t.Errorf(
+				"%q - bad deltas\nexpected: %q\nactual:   %q\n\n",
+				test.name,
+				test.expectedDeltas,
+				deltas,
+			)
+		}
+		if !reflect.DeepEqual(test.expectedResponse, *actual) {
+			t.Errorf(
+				"%q - bad return from client.\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expectedResponse,
+				*actual,
+			)
+		}
+	}
+}
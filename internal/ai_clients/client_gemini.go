@@ -0,0 +1,243 @@
+package ai_clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/ai_clients/gemini"
+	"github.com/k10wl/hermes/internal/settings"
+)
+
+type clientGemini struct {
+	apiKey string
+	apiUrl string // model and method are appended to it
+}
+
+func newClientGemini(apiKey string) *clientGemini {
+	return &clientGemini{
+		apiKey: apiKey,
+		apiUrl: "https://generativelanguage.googleapis.com/v1beta/models/",
+	}
+}
+
+func (client clientGemini) complete(
+	messages []*Message,
+	parameters *Parameters,
+	get getter,
+) (*AIResponse, error) {
+	data, err := client.prepare(messages, parameters)
+	if err != nil {
+		return nil, err
+	}
+	data, err = get(
+		client.url(parameters.Model, "generateContent"),
+		bytes.NewReader(data),
+		client.fillHeaders,
+	)
+	if err != nil {
+		return nil, err
+	}
+	var response gemini.GenerateContentResponse
+	err = json.Unmarshal(data, &response)
+	if err != nil {
+		return nil, err
+	}
+	return client.decodeResult(&response)
+}
+
+func (client clientGemini) stream(
+	messages []*Message,
+	parameters *Parameters,
+	stream streamer,
+	onDelta OnDelta,
+) (*AIResponse, error) {
+	data, err := client.prepare(messages, parameters)
+	if err != nil {
+		return nil, err
+	}
+	response := &AIResponse{Message: Message{Role: "assistant"}}
+	content := &strings.Builder{}
+	err = stream(
+		client.url(parameters.Model, "streamGenerateContent")+"?alt=sse",
+		bytes.NewReader(data),
+		client.fillHeaders,
+		func(_ string, data []byte) error {
+			return client.decodeChunk(data, response, content, onDelta)
+		},
+	)
+	response.Content = content.String()
+	// part streamed before failure is kept, cancelled completion saves it
+	return response, err
+}
+
+func (client clientGemini) url(model string, method string) string {
+	return client.apiUrl + url.PathEscape(model) + ":" + method
+}
+
+// every chunk is complete response with next piece of text, usage holds
+// totals so far
+func (client clientGemini) decodeChunk(
+	data []byte,
+	response *AIResponse,
+	content *strings.Builder,
+	onDelta OnDelta,
+) error {
+	var chunk gemini.GenerateContentResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return err
+	}
+	if err := client.checkBlocked(&chunk); err != nil {
+		return err
+	}
+	if chunk.ModelVersion != "" {
+		response.Model = chunk.ModelVersion
+	}
+	if chunk.UsageMetadata != nil {
+		response.TokensUsage = client.decodeUsage(chunk.UsageMetadata)
+	}
+	if len(chunk.Candidates) == 0 {
+		return nil
+	}
+	text := client.decodeText(chunk.Candidates[0].Content)
+	if text == "" {
+		return nil
+	}
+	content.WriteString(text)
+	onDelta(text)
+	return nil
+}
+
+func (client clientGemini) prepare(
+	messages []*Message,
+	parameters *Parameters,
+) ([]byte, error) {
+	contents, systemInstruction, err := client.encodeMessages(messages)
+	if err != nil {
+		return nil, err
+	}
+	data := gemini.GenerateContentRequest{
+		Contents:          contents,
+		SystemInstruction: systemInstruction,
+	}
+	if parameters.MaxTokens != nil || parameters.Temperature != nil {
+		data.GenerationConfig = &gemini.GenerationConfig{
+			MaxOutputTokens: parameters.MaxTokens,
+			Temperature:     parameters.Temperature,
+		}
+	}
+	return json.Marshal(data)
+}
+
+// system messages are joined into system instruction, assistant speaks as
+// "model"
+func (client clientGemini) encodeMessages(
+	messages []*Message,
+) ([]*gemini.Content, *gemini.Content, error) {
+	contents := []*gemini.Content{}
+	system := []gemini.Part{}
+	for _, v := range messages {
+		part := gemini.Part{Text: v.Content}
+		switch v.Role {
+		case "system":
+			system = append(system, part)
+		case "assistant":
+			contents = append(contents, &gemini.Content{Role: "model", Parts: []gemini.Part{part}})
+		default:
+			parts, err := client.encodeAttachments(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			contents = append(contents, &gemini.Content{Role: "user", Parts: append(parts, part)})
+		}
+	}
+	if len(system) == 0 {
+		return contents, nil, nil
+	}
+	return contents, &gemini.Content{Parts: system}, nil
+}
+
+// parts go before message text
+func (client clientGemini) encodeAttachments(message *Message) ([]gemini.Part, error) {
+	parts := []gemini.Part{}
+	for _, attachment := range message.Attachments {
+		switch attachmentKind(attachment.MediaType) {
+		case attachmentText:
+			parts = append(parts, gemini.Part{Text: attachment.text()})
+		case attachmentImage, attachmentDocument:
+			parts = append(parts, gemini.Part{InlineData: &gemini.Blob{
+				MimeType: attachment.MediaType,
+				Data:     attachment.base64(),
+			}})
+		default:
+			return nil, unsupportedAttachment("google", attachment)
+		}
+	}
+	return parts, nil
+}
+
+func (client clientGemini) decodeResult(
+	response *gemini.GenerateContentResponse,
+) (*AIResponse, error) {
+	if err := client.checkBlocked(response); err != nil {
+		return nil, err
+	}
+	if len(response.Candidates) == 0 {
+		return nil, fmt.Errorf("empty response candidates")
+	}
+	res := &AIResponse{
+		Message: Message{
+			Content: client.decodeText(response.Candidates[0].Content),
+			Role:    "assistant",
+		},
+		Model: response.ModelVersion,
+	}
+	if response.UsageMetadata != nil {
+		res.TokensUsage = client.decodeUsage(response.UsageMetadata)
+	}
+	return res, nil
+}
+
+// thought summaries are not part of answer
+func (client clientGemini) decodeText(content *gemini.Content) string {
+	if content == nil {
+		return ""
+	}
+	sb := &strings.Builder{}
+	for _, part := range content.Parts {
+		if !part.Thought {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// thinking tokens are billed as output
+func (client clientGemini) decodeUsage(usage *gemini.UsageMetadata) TokensUsage {
+	return TokensUsage{
+		Input:  usage.PromptTokenCount,
+		Output: usage.CandidatesTokenCount + usage.ThoughtsTokenCount,
+	}
+}
+
+func (client clientGemini) checkBlocked(response *gemini.GenerateContentResponse) error {
+	if response.PromptFeedback == nil || response.PromptFeedback.BlockReason == "" {
+		return nil
+	}
+	return fmt.Errorf("prompt was blocked - %s\n", response.PromptFeedback.BlockReason)
+}
+
+func (client clientGemini) fillHeaders(r *http.Request) error {
+	if client.apiKey == "" {
+		return fmt.Errorf(
+			"Google API key %q was not provided\n",
+			settings.HermesGoogleApiKeyName,
+		)
+	}
+	r.Header.Set("x-goog-api-key", client.apiKey)
+	r.Header.Set("content-type", "application/json")
+	return nil
+}
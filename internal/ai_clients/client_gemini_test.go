@@ -0,0 +1,336 @@
+package ai_clients
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/ai_clients/gemini"
+)
+
+func TestGeminiCompletion(t *testing.T) {
+	temperature := float64(1)
+	maxTokens := int64(1000)
+
+	var lastRequest *http.Request
+	var lastUrl string
+	var lastBody gemini.GenerateContentRequest
+
+	respond := func(response gemini.GenerateContentResponse) getter {
+		return func(
+			url string,
+			body io.Reader,
+			fillHeaders func(*http.Request) error,
+		) ([]byte, error) {
+			lastUrl = url
+			lastRequest, _ = http.NewRequest(http.MethodPost, "", nil)
+			if err := fillHeaders(lastRequest); err != nil {
+				return nil, err
+			}
+			if err := json.NewDecoder(body).Decode(&lastBody); err != nil {
+				return nil, err
+			}
+			marshaled, err := json.Marshal(response)
+			if err != nil {
+				panic("bad test setup, error in marshaling response")
+			}
+			return marshaled, nil
+		}
+	}
+
+	type input struct {
+		messages   []*Message
+		parameters Parameters
+		getter     getter
+	}
+	type testCase struct {
+		name             string
+		input            input
+		apiKey           string
+		expectedUrl      string
+		expectedRequest  gemini.GenerateContentRequest
+		expectedResponse AIResponse
+		expectedHeaders  map[string]string
+		expectErr        bool
+	}
+
+	table := []testCase{
+		{
+			name: "should map roles and report usage",
+			input: input{
+				messages: []*Message{
+					{Role: "system", Content: "be brief"},
+					{Role: "user", Content: "stuff"},
+					{Role: "assistant", Content: "answer"},
+					{Role: "system", Content: "be polite"},
+					{Role: "user", Content: "more stuff"},
+				},
+				parameters: Parameters{
+					Temperature: &temperature,
+					MaxTokens:   &maxTokens,
+					Model:       "gemini-2.0-flash",
+				},
+				getter: respond(gemini.GenerateContentResponse{
+					Candidates: []gemini.Candidate{{
+						Content: &gemini.Content{
+							Role: "model",
+							Parts: []gemini.Part{
+								{Text: "thinking", Thought: true},
+								{Text: "res"},
+								{Text: "ponse"},
+							},
+						},
+						FinishReason: "STOP",
+					}},
+					UsageMetadata: &gemini.UsageMetadata{
+						PromptTokenCount:     200,
+						CandidatesTokenCount: 20,
+						ThoughtsTokenCount:   5,
+						TotalTokenCount:      225,
+					},
+					ModelVersion: "gemini-2.0-flash-001",
+				}),
+			},
+			apiKey:      "SECRET",
+			expectedUrl: "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:generateContent",
+			expectedRequest: gemini.GenerateContentRequest{
+				Contents: []*gemini.Content{
+					{Role: "user", Parts: []gemini.Part{{Text: "stuff"}}},
+					{Role: "model", Parts: []gemini.Part{{Text: "answer"}}},
+					{Role: "user", Parts: []gemini.Part{{Text: "more stuff"}}},
+				},
+				SystemInstruction: &gemini.Content{
+					Parts: []gemini.Part{{Text: "be brief"}, {Text: "be polite"}},
+				},
+				GenerationConfig: &gemini.GenerationConfig{
+					MaxOutputTokens: &maxTokens,
+					Temperature:     &temperature,
+				},
+			},
+			expectedHeaders: map[string]string{
+				"x-goog-api-key": "SECRET",
+				"content-type":   "application/json",
+			},
+			expectedResponse: AIResponse{
+				Message: Message{Role: "assistant", Content: "response"},
+				TokensUsage: TokensUsage{
+					Input:  200,
+					Output: 25,
+				},
+				Model: "gemini-2.0-flash-001",
+			},
+		},
+
+		{
+			name: "should omit system instruction and generation config when unset",
+			input: input{
+				messages:   []*Message{{Role: "user", Content: "stuff"}},
+				parameters: Parameters{Model: "gemini-2.5-pro"},
+				getter: respond(gemini.GenerateContentResponse{
+					Candidates: []gemini.Candidate{{
+						Content: &gemini.Content{Role: "model", Parts: []gemini.Part{{Text: "response"}}},
+					}},
+				}),
+			},
+			apiKey:      "SECRET",
+			expectedUrl: "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.5-pro:generateContent",
+			expectedRequest: gemini.GenerateContentRequest{
+				Contents: []*gemini.Content{
+					{Role: "user", Parts: []gemini.Part{{Text: "stuff"}}},
+				},
+			},
+			expectedResponse: AIResponse{
+				Message: Message{Role: "assistant", Content: "response"},
+			},
+		},
+
+		{
+			name: "should error if prompt was blocked",
+			input: input{
+				messages:   []*Message{{Role: "user", Content: "stuff"}},
+				parameters: Parameters{Model: "gemini-2.0-flash"},
+				getter: respond(gemini.GenerateContentResponse{
+					PromptFeedback: &gemini.PromptFeedback{BlockReason: "SAFETY"},
+				}),
+			},
+			apiKey:    "SECRET",
+			expectErr: true,
+		},
+
+		{
+			name: "should error if api key was not provided",
+			input: input{
+				messages:   []*Message{{Role: "user", Content: "stuff"}},
+				parameters: Parameters{Model: "gemini-2.0-flash"},
+				getter:     respond(gemini.GenerateContentResponse{}),
+			},
+			expectErr: true,
+		},
+	}
+
+out:
+	for _, test := range table {
+		lastBody = gemini.GenerateContentRequest{}
+		actual, err := newClientGemini(test.apiKey).complete(
+			test.input.messages,
+			&test.input.parameters,
+			test.input.getter,
+		)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%q - expected to error but didn't\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		for key, val := range test.expectedHeaders {
+			if header := lastRequest.Header.Get(key); header != val {
+				t.Errorf(
+					"%q - bad header for %q.\nexpected: %q\nactual:   %q\n\n",
+					test.name,
+					key,
+					val,
+					header,
+				)
+				continue out
+			}
+		}
+		if lastUrl != test.expectedUrl {
+			t.Errorf("%q - bad url\nexpected: %q\nactual:   %q\n", test.name, test.expectedUrl, lastUrl)
+		}
+		if !reflect.DeepEqual(test.expectedRequest, lastBody) {
+			t.Errorf(
+				"%q - bad request\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expectedRequest,
+				lastBody,
+			)
+		}
+		if !reflect.DeepEqual(test.expectedResponse, *actual) {
+			t.Errorf(
+				"%q - bad return from client.\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expectedResponse,
+				*actual,
+			)
+		}
+	}
+}
+
+func TestGeminiStream(t *testing.T) {
+	inputMessages := []*Message{{Role: "user", Content: "stuff"}}
+
+	type testCase struct {
+		name             string
+		events           []string
+		apiKey           string
+		expectedDeltas   []string
+		expectedResponse AIResponse
+		expectErr        bool
+	}
+
+	table := []testCase{
+		{
+			name: "should concatenate deltas and keep last usage",
+			events: []string{
+				`{"candidates":[{"content":{"role":"model","parts":[{"text":"res"}]}}],"usageMetadata":{"promptTokenCount":200,"candidatesTokenCount":1},"modelVersion":"gemini-2.0-flash-001"}`,
+				`{"candidates":[{"content":{"role":"model","parts":[{"text":"ponse"}]}}],"usageMetadata":{"promptTokenCount":200,"candidatesTokenCount":10},"modelVersion":"gemini-2.0-flash-001"}`,
+				`{"candidates":[{"content":{"role":"model","parts":[{"text":""}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":200,"candidatesTokenCount":20,"totalTokenCount":220},"modelVersion":"gemini-2.0-flash-001"}`,
+			},
+			apiKey:         "SECRET",
+			expectedDeltas: []string{"res", "ponse"},
+			expectedResponse: AIResponse{
+				Message: Message{Role: "assistant", Content: "response"},
+				TokensUsage: TokensUsage{
+					Input:  200,
+					Output: 20,
+				},
+				Model: "gemini-2.0-flash-001",
+			},
+		},
+
+		{
+			name:      "should return blocked prompt as error",
+			events:    []string{`{"promptFeedback":{"blockReason":"SAFETY"}}`},
+			apiKey:    "SECRET",
+			expectErr: true,
+		},
+
+		{
+			name:      "should error if api key was not provided",
+			events:    []string{`{"candidates":[]}`},
+			expectErr: true,
+		},
+	}
+
+	for _, test := range table {
+		var url string
+		var request gemini.GenerateContentRequest
+		stream := func(
+			u string,
+			body io.Reader,
+			fillHeaders func(*http.Request) error,
+			onEvent func(string, []byte) error,
+		) error {
+			url = u
+			req, _ := http.NewRequest(http.MethodPost, "", nil)
+			if err := fillHeaders(req); err != nil {
+				return err
+			}
+			if err := json.NewDecoder(body).Decode(&request); err != nil {
+				return err
+			}
+			for _, event := range test.events {
+				if err := onEvent("", []byte(event)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		deltas := []string{}
+		actual, err := newClientGemini(test.apiKey).stream(
+			inputMessages,
+			&Parameters{Model: "gemini-2.0-flash"},
+			stream,
+			func(delta string) { deltas = append(deltas, delta) },
+		)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%q - expected to error but didn't\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		expectedUrl := "https://generativelanguage.googleapis.com/v1beta/models/gemini-2.0-flash:streamGenerateContent?alt=sse"
+		if url != expectedUrl {
+			t.Errorf("%q - bad url\nexpected: %q\nactual:   %q\n", test.name, expectedUrl, url)
+		}
+		if len(request.Contents) != 1 || request.Contents[0].Role != "user" {
+			t.Errorf("%q - bad request: %+v\n", test.name, request)
+		}
+		if !reflect.DeepEqual(test.expectedDeltas, deltas) {
+			t.Errorf(
+				"%q - bad deltas\nexpected: %q\nactual:   %q\n\n",
+				test.name,
+				test.expectedDeltas,
+				deltas,
+			)
+		}
+		if !reflect.DeepEqual(test.expectedResponse, *actual) {
+			t.Errorf(
+				"%q - bad return from client.\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expectedResponse,
+				*actual,
+			)
+		}
+	}
+}
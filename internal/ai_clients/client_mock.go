@@ -2,6 +2,7 @@ package ai_clients
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -22,3 +23,19 @@ func (m mock) complete(
 		Message: *messages[len(messages)-1],
 	}, nil
 }
+
+func (m mock) stream(
+	messages []*Message,
+	parameters *Parameters,
+	stream streamer,
+	onDelta OnDelta,
+) (*AIResponse, error) {
+	content := fmt.Sprintf("> mocked: %s", messages[len(messages)-1].Content)
+	for _, word := range strings.SplitAfter(content, " ") {
+		time.Sleep(50 * time.Millisecond)
+		onDelta(word)
+	}
+	return &AIResponse{
+		Message: Message{Role: "assistant", Content: content},
+	}, nil
+}
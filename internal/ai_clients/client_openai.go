@@ -5,29 +5,63 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 
 	"github.com/k10wl/hermes/internal/ai_clients/openai"
 	"github.com/k10wl/hermes/internal/settings"
 )
 
 type clientOpenAI struct {
-	apiKey string
-	apiUrl string
+	apiKey     string
+	apiUrl     string
+	authHeader string
+	apiKeyName string   // variable that must hold api key, empty if optional
+	models     []string // empty list allows any model
+	provider   string
+	// local servers understand images, but not pdf files
+	acceptsDocuments bool
 }
 
 func newClientOpenAI(apiKey string) *clientOpenAI {
 	return &clientOpenAI{
-		apiKey: apiKey,
-		apiUrl: "https://api.openai.com/v1/chat/completions",
+		apiKey:     apiKey,
+		apiUrl:     "https://api.openai.com/v1/chat/completions",
+		authHeader: "Authorization",
+		apiKeyName: settings.HermesOpenAIApiKeyName,
+		provider:   "openai",
+
+		acceptsDocuments: true,
 	}
 }
 
+// local servers (ollama, llama.cpp, vLLM, ...) reuse OpenAI wire format
+func newClientLocal(local settings.LocalProvider) (*clientOpenAI, error) {
+	if local.BaseURL == "" {
+		return nil, fmt.Errorf(
+			"local provider base url %q was not provided\n",
+			settings.HermesLocalBaseURLName,
+		)
+	}
+	authHeader := local.AuthHeader
+	if authHeader == "" {
+		authHeader = "Authorization"
+	}
+	return &clientOpenAI{
+		apiKey:     local.APIKey,
+		apiUrl:     strings.TrimSuffix(local.BaseURL, "/") + "/chat/completions",
+		authHeader: authHeader,
+		models:     local.Models,
+		provider:   "local",
+	}, nil
+}
+
 func (client clientOpenAI) complete(
 	messages []*Message,
 	parameters *Parameters,
 	get getter,
 ) (*AIResponse, error) {
-	data, err := client.prepare(messages, parameters)
+	data, err := client.prepare(messages, parameters, false)
 	if err != nil {
 		return nil, err
 	}
@@ -47,10 +81,91 @@ func (client clientOpenAI) complete(
 	return client.decodeResponse(&openaiResponse)
 }
 
-func (client clientOpenAI) prepare(messages []*Message, parameters *Parameters) ([]byte, error) {
+func (client clientOpenAI) stream(
+	messages []*Message,
+	parameters *Parameters,
+	stream streamer,
+	onDelta OnDelta,
+) (*AIResponse, error) {
+	data, err := client.prepare(messages, parameters, true)
+	if err != nil {
+		return nil, err
+	}
+	response := &AIResponse{Message: Message{Role: "assistant"}}
+	content := &strings.Builder{}
+	err = stream(
+		client.apiUrl,
+		bytes.NewBuffer(data),
+		client.fillHeaders,
+		func(_ string, data []byte) error {
+			return client.decodeChunk(data, response, content, onDelta)
+		},
+	)
+	response.Content = content.String()
+	// part streamed before failure is kept, cancelled completion saves it
+	return response, err
+}
+
+func (client clientOpenAI) decodeChunk(
+	data []byte,
+	response *AIResponse,
+	content *strings.Builder,
+	onDelta OnDelta,
+) error {
+	if string(data) == "[DONE]" {
+		return nil
+	}
+	var chunk openai.ChatCompletionChunk
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return err
+	}
+	if chunk.Model != "" {
+		response.Model = chunk.Model
+	}
+	if chunk.Usage != nil {
+		response.TokensUsage = TokensUsage{
+			Input:  chunk.Usage.PromptTokens,
+			Output: chunk.Usage.CompletionTokens,
+		}
+	}
+	if len(chunk.Choices) == 0 {
+		return nil
+	}
+	delta := chunk.Choices[0].Delta
+	if delta.Role != "" {
+		response.Role = delta.Role
+	}
+	if delta.Content == "" {
+		return nil
+	}
+	content.WriteString(delta.Content)
+	onDelta(delta.Content)
+	return nil
+}
+
+func (client clientOpenAI) prepare(
+	messages []*Message,
+	parameters *Parameters,
+	stream bool,
+) ([]byte, error) {
+	if len(client.models) > 0 && !slices.Contains(client.models, parameters.Model) {
+		return nil, fmt.Errorf(
+			"model %q is not available, known models: %s\n",
+			parameters.Model,
+			strings.Join(client.models, ", "),
+		)
+	}
+	encodedMessages, err := client.encodeMessages(messages)
+	if err != nil {
+		return nil, err
+	}
 	data := openai.ChatCompletionRequest{
 		Model:    parameters.Model,
-		Messages: client.encodeMessages(messages),
+		Messages: encodedMessages,
+	}
+	if stream {
+		data.Stream = true
+		data.StreamOptions = &openai.StreamOptions{IncludeUsage: true}
 	}
 	if parameters.MaxTokens != nil {
 		data.MaxTokens = *parameters.MaxTokens
@@ -61,15 +176,47 @@ func (client clientOpenAI) prepare(messages []*Message, parameters *Parameters)
 	return json.Marshal(data)
 }
 
-func (client clientOpenAI) encodeMessages(messages []*Message) []*openai.Message {
+func (client clientOpenAI) encodeMessages(messages []*Message) ([]*openai.Message, error) {
 	result := make([]*openai.Message, len(messages))
 	for i, v := range messages {
+		parts, err := client.encodeAttachments(v)
+		if err != nil {
+			return nil, err
+		}
 		result[i] = &openai.Message{
 			Role:    v.Role,
 			Content: v.Content,
+			Parts:   parts,
+		}
+	}
+	return result, nil
+}
+
+// nil parts when message has no attachments, content is sent as is
+func (client clientOpenAI) encodeAttachments(message *Message) ([]openai.ContentPart, error) {
+	if len(message.Attachments) == 0 {
+		return nil, nil
+	}
+	parts := []openai.ContentPart{{Type: "text", Text: message.Content}}
+	for _, attachment := range message.Attachments {
+		switch kind := attachmentKind(attachment.MediaType); {
+		case kind == attachmentText:
+			parts = append(parts, openai.ContentPart{Type: "text", Text: attachment.text()})
+		case kind == attachmentImage:
+			parts = append(parts, openai.ContentPart{
+				Type:     "image_url",
+				ImageURL: &openai.ImageURL{URL: attachment.dataURL()},
+			})
+		case kind == attachmentDocument && client.acceptsDocuments:
+			parts = append(parts, openai.ContentPart{
+				Type: "file",
+				File: &openai.File{Filename: attachment.Name, FileData: attachment.dataURL()},
+			})
+		default:
+			return nil, unsupportedAttachment(client.provider, attachment)
 		}
 	}
-	return result
+	return parts, nil
 }
 
 func (client clientOpenAI) decodeMessage(messages openai.Message) *Message {
@@ -98,17 +245,25 @@ func (client clientOpenAI) decodeResponse(
 			Input:  response.Usage.PromptTokens,
 			Output: response.Usage.CompletionTokens,
 		},
+		Model: response.Model,
 	}, nil
 }
 
 func (client clientOpenAI) fillHeaders(req *http.Request) error {
+	req.Header.Set("Content-Type", "application/json")
 	if client.apiKey == "" {
+		if client.apiKeyName == "" {
+			return nil
+		}
 		return fmt.Errorf(
 			"OpenAI API key %q was not provided\n",
-			settings.HermesOpenAIApiKeyName,
+			client.apiKeyName,
 		)
 	}
-	req.Header.Add("Authorization", "Bearer "+client.apiKey)
-	req.Header.Set("Content-Type", "application/json")
+	value := client.apiKey
+	if strings.EqualFold(client.authHeader, "Authorization") {
+		value = "Bearer " + value
+	}
+	req.Header.Set(client.authHeader, value)
 	return nil
 }
@@ -1,14 +1,18 @@
 package ai_clients
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"testing"
 
 	"github.com/k10wl/hermes/internal/ai_clients/openai"
+	"github.com/k10wl/hermes/internal/settings"
 )
 
 func TestOpenAICompletion(t *testing.T) {
@@ -89,6 +93,7 @@ func TestOpenAICompletion(t *testing.T) {
 					Input:  200,
 					Output: 20,
 				},
+				Model: "gpt-4o-mini",
 			},
 			apiKey: "SECRET",
 		},
@@ -207,3 +212,275 @@ out:
 		}
 	}
 }
+
+func TestOpenAIStream(t *testing.T) {
+	inputMessages := []*Message{{Role: "user", Content: "stuff"}}
+	maxTokens := int64(1000)
+
+	type testCase struct {
+		name             string
+		events           []string
+		apiKey           string
+		expectedDeltas   []string
+		expectedResponse AIResponse
+		expectErr        bool
+	}
+
+	table := []testCase{
+		{
+			name: "should concatenate deltas and read usage from last chunk",
+			events: []string{
+				`{"id":"1","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}`,
+				`{"id":"1","choices":[{"index":0,"delta":{"content":"res"}}]}`,
+				`{"id":"1","choices":[{"index":0,"delta":{"content":"ponse"}}]}`,
+				`{"id":"1","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+				`{"id":"1","choices":[],"usage":{"prompt_tokens":200,"completion_tokens":20,"total_tokens":220}}`,
+				`[DONE]`,
+			},
+			apiKey:         "SECRET",
+			expectedDeltas: []string{"res", "ponse"},
+			expectedResponse: AIResponse{
+				Message: Message{Role: "assistant", Content: "response"},
+				TokensUsage: TokensUsage{
+					Input:  200,
+					Output: 20,
+				},
+			},
+		},
+
+		{
+			name:      "should error if api key was not provided",
+			events:    []string{`[DONE]`},
+			expectErr: true,
+		},
+
+		{
+			name:      "should error on malformed chunk",
+			events:    []string{`{"id":`},
+			apiKey:    "SECRET",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range table {
+		var request openai.ChatCompletionRequest
+		stream := func(
+			url string,
+			body io.Reader,
+			fillHeaders func(*http.Request) error,
+			onEvent func(string, []byte) error,
+		) error {
+			req, _ := http.NewRequest(http.MethodPost, "", nil)
+			if err := fillHeaders(req); err != nil {
+				return err
+			}
+			if err := json.NewDecoder(body).Decode(&request); err != nil {
+				return err
+			}
+			for _, event := range test.events {
+				if err := onEvent("", []byte(event)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		deltas := []string{}
+		actual, err := newClientOpenAI(test.apiKey).stream(
+			inputMessages,
+			&Parameters{Model: "gpt-4o-mini", MaxTokens: &maxTokens},
+			stream,
+			func(delta string) { deltas = append(deltas, delta) },
+		)
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%q - expected to error but didn't\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !request.Stream || request.StreamOptions == nil || !request.StreamOptions.IncludeUsage {
+			t.Errorf("%q - did not request stream with usage: %+v\n", test.name, request)
+		}
+		if !reflect.DeepEqual(test.expectedDeltas, deltas) {
+			t.Errorf(
+				"%q - bad deltas\nexpected: %q\nactual:   %q\n\n",
+				test.name,
+				test.expectedDeltas,
+				deltas,
+			)
+		}
+		if !reflect.DeepEqual(test.expectedResponse, *actual) {
+			t.Errorf(
+				"%q - bad return from client.\nexpected: %+v\nactual:   %+v\n\n",
+				test.name,
+				test.expectedResponse,
+				*actual,
+			)
+		}
+	}
+}
+
+func TestLocalCompletion(t *testing.T) {
+	type testCase struct {
+		name            string
+		local           settings.LocalProvider
+		model           string
+		expectedHeaders map[string]string
+		expectErr       bool
+	}
+
+	var lastRequest *http.Request
+	var lastBody openai.ChatCompletionRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRequest = r
+		if r.URL.Path != "/v1/chat/completions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&lastBody); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Model: lastBody.Model,
+			Usage: openai.Usage{PromptTokens: 10, CompletionTokens: 5},
+			Choices: []openai.CompletionChoice{{
+				Message: openai.Message{Role: "assistant", Content: "local response"},
+			}},
+		})
+	}))
+	defer server.Close()
+
+	table := []testCase{
+		{
+			name:  "should call local server without api key",
+			local: settings.LocalProvider{BaseURL: server.URL + "/v1/"},
+			model: "llama3.1",
+			expectedHeaders: map[string]string{
+				"Authorization": "",
+			},
+		},
+		{
+			name: "should use bearer token for authorization header",
+			local: settings.LocalProvider{
+				BaseURL: server.URL + "/v1",
+				APIKey:  "SECRET",
+			},
+			model: "llama3.1",
+			expectedHeaders: map[string]string{
+				"Authorization": "Bearer SECRET",
+			},
+		},
+		{
+			name: "should pass key as is in custom auth header",
+			local: settings.LocalProvider{
+				BaseURL:    server.URL + "/v1",
+				AuthHeader: "X-Api-Key",
+				APIKey:     "SECRET",
+			},
+			model: "llama3.1",
+			expectedHeaders: map[string]string{
+				"X-Api-Key":     "SECRET",
+				"Authorization": "",
+			},
+		},
+		{
+			name: "should accept model from models list",
+			local: settings.LocalProvider{
+				BaseURL: server.URL + "/v1",
+				Models:  []string{"llama3.1", "mistral"},
+			},
+			model: "mistral",
+		},
+		{
+			name: "should reject model outside of models list",
+			local: settings.LocalProvider{
+				BaseURL: server.URL + "/v1",
+				Models:  []string{"llama3.1"},
+			},
+			model:     "mistral",
+			expectErr: true,
+		},
+		{
+			name:      "should error if base url was not provided",
+			local:     settings.LocalProvider{},
+			model:     "llama3.1",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range table {
+		lastRequest = nil
+		client, err := newClientLocal(test.local)
+		var actual *AIResponse
+		if err == nil {
+			actual, err = client.complete(
+				[]*Message{{Role: "user", Content: "stuff"}},
+				&Parameters{Model: test.model},
+				newCaller(context.Background(), settings.Requests{}).get,
+			)
+		}
+		if test.expectErr {
+			if err == nil {
+				t.Errorf("%q - expected to error but didn't\n", test.name)
+			}
+			if lastRequest != nil {
+				t.Errorf("%q - expected not to call server\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %v\n", test.name, err)
+			continue
+		}
+		for key, val := range test.expectedHeaders {
+			if header := lastRequest.Header.Get(key); header != val {
+				t.Errorf(
+					"%q - bad header for %q.\nexpected: %q\nactual:   %q\n\n",
+					test.name,
+					key,
+					val,
+					header,
+				)
+			}
+		}
+		if lastBody.Model != test.model {
+			t.Errorf("%q - bad model\nexpected: %q\nactual:   %q\n", test.name, test.model, lastBody.Model)
+		}
+		if actual.Content != "local response" || actual.TokensUsage.Input != 10 {
+			t.Errorf("%q - bad response: %+v\n", test.name, *actual)
+		}
+	}
+}
+
+func TestOpenAIStreamCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\n", `{"id":"1","choices":[{"index":0,"delta":{"content":"part"}}]}`)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client, err := newClientLocal(settings.LocalProvider{BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("failed to create client: %s\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	res, err := client.stream(
+		[]*Message{{Role: "user", Content: "stuff"}},
+		&Parameters{Model: "llama3.1"},
+		newCaller(ctx, settings.Requests{}).stream,
+		func(string) { cancel() },
+	)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelled stream, got %v\n", err)
+	}
+	if res == nil || res.Content != "part" {
+		t.Errorf("expected part streamed before cancel to be kept, got %+v\n", res)
+	}
+}
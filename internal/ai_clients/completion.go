@@ -1,16 +1,23 @@
 package ai_clients
 
 import (
+	"context"
 	"io"
 	"net/http"
 
 	"github.com/k10wl/hermes/internal/settings"
 )
 
+// receives pieces of completion as soon as provider emits them,
+// nil value means that completion must not be streamed
+type OnDelta func(delta string)
+
 type CompletionFn func(
+	ctx context.Context,
 	messages []*Message,
 	parameters *Parameters,
 	providers *settings.Providers,
+	onDelta OnDelta,
 ) (*AIResponse, error)
 
 type getter func(
@@ -19,17 +26,31 @@ type getter func(
 	fillHeaders func(*http.Request) error,
 ) ([]byte, error)
 
+type streamer func(
+	url string,
+	body io.Reader,
+	fillHeaders func(*http.Request) error,
+	onEvent func(event string, data []byte) error,
+) error
+
 type client interface {
 	complete(
 		messages []*Message,
 		parameters *Parameters,
 		get getter,
 	) (*AIResponse, error)
+	stream(
+		messages []*Message,
+		parameters *Parameters,
+		stream streamer,
+		onDelta OnDelta,
+	) (*AIResponse, error)
 }
 
 type Message struct {
-	Role    string
-	Content string
+	Role        string
+	Content     string
+	Attachments []*Attachment // only user messages carry them
 }
 
 type TokensUsage struct {
@@ -38,7 +59,7 @@ type TokensUsage struct {
 }
 
 type Parameters struct {
-	Model       string   `json:"model"       validate:"required"`
+	Model       string   `json:"model"`
 	MaxTokens   *int64   `json:"max_tokens"`
 	Temperature *float64 `json:"temperature"`
 }
@@ -46,12 +67,17 @@ type Parameters struct {
 type AIResponse struct {
 	Message
 	TokensUsage TokensUsage
+	Model       string // model that served completion, as "provider/model"
+	// streamed completion was cancelled, content is part received before it
+	Interrupted bool
 }
 
 func Complete(
+	ctx context.Context,
 	messages []*Message,
 	parameters *Parameters,
 	providers *settings.Providers,
+	onDelta OnDelta,
 ) (*AIResponse, error) {
 	provider, model, err := extractProviderAndModel(parameters.Model)
 	if err != nil {
@@ -63,5 +89,22 @@ func Complete(
 	}
 	parametersCopy := *parameters
 	parametersCopy.Model = model
-	return client.complete(messages, &parametersCopy, callApi)
+	caller := newCaller(ctx, providers.Requests)
+	var res *AIResponse
+	if onDelta != nil {
+		res, err = client.stream(messages, &parametersCopy, caller.stream, onDelta)
+	} else {
+		res, err = client.complete(messages, &parametersCopy, caller.get)
+	}
+	if err != nil {
+		if onDelta == nil || ctx.Err() == nil || res == nil || res.Content == "" {
+			return nil, err
+		}
+		res.Interrupted = true
+	}
+	if res.Model == "" {
+		res.Model = model
+	}
+	res.Model = provider + "/" + res.Model
+	return res, nil
 }
@@ -0,0 +1,35 @@
+package ai_clients
+
+// tokens that model accepts in single request, input and output combined
+var contextWindows = map[string]int64{
+	"openai/gpt-4o":                128_000,
+	"openai/gpt-4o-mini":           128_000,
+	"openai/gpt-4.1":               1_047_576,
+	"openai/gpt-4.1-mini":          1_047_576,
+	"openai/gpt-4.1-nano":          1_047_576,
+	"openai/gpt-4-turbo":           128_000,
+	"openai/gpt-3.5-turbo":         16_385,
+	"openai/o1":                    200_000,
+	"openai/o3-mini":               200_000,
+	"anthropic/claude-3-5-sonnet":  200_000,
+	"anthropic/claude-3-7-sonnet":  200_000,
+	"anthropic/claude-3-opus":      200_000,
+	"anthropic/claude-3-5-haiku":   200_000,
+	"anthropic/claude-3-haiku":     200_000,
+	"google/gemini-2.5-pro":        1_048_576,
+	"google/gemini-2.5-flash":      1_048_576,
+	"google/gemini-2.0-flash":      1_048_576,
+	"google/gemini-2.0-flash-lite": 1_048_576,
+	"google/gemini-1.5-pro":        2_097_152,
+	"google/gemini-1.5-flash":      1_048_576,
+}
+
+// unknown for local and not listed models
+func ContextWindow(model string) (int64, bool) {
+	return lookupModel(contextWindows, model)
+}
+
+// rough estimation, around four characters per token for english text
+func EstimateTokens(content string) int64 {
+	return int64(len(content))/4 + 4
+}
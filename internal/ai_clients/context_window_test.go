@@ -0,0 +1,47 @@
+package ai_clients
+
+import "testing"
+
+func TestContextWindow(t *testing.T) {
+	type testCase struct {
+		name          string
+		model         string
+		expected      int64
+		expectedKnown bool
+	}
+
+	table := []testCase{
+		{
+			name:          "should return context window of known model",
+			model:         "openai/gpt-4o",
+			expected:      128_000,
+			expectedKnown: true,
+		},
+		{
+			name:          "should match dated snapshot by longest prefix",
+			model:         "anthropic/claude-3-5-sonnet-20240620",
+			expected:      200_000,
+			expectedKnown: true,
+		},
+		{
+			name:          "should not know local models",
+			model:         "local/llama3.1",
+			expected:      0,
+			expectedKnown: false,
+		},
+	}
+
+	for _, test := range table {
+		actual, known := ContextWindow(test.model)
+		if actual != test.expected || known != test.expectedKnown {
+			t.Errorf(
+				"%q - bad context window\nexpected: %v %v\nactual:   %v %v\n",
+				test.name,
+				test.expected,
+				test.expectedKnown,
+				actual,
+				known,
+			)
+		}
+	}
+}
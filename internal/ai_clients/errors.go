@@ -0,0 +1,132 @@
+package ai_clients
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kinds of provider failures, match them with errors.Is
+var (
+	ErrAuth          = errors.New("authentication failed")
+	ErrRateLimit     = errors.New("rate limit exceeded")
+	ErrContextLength = errors.New("context length exceeded")
+	ErrServer        = errors.New("provider server error")
+	ErrTimeout       = errors.New("request timed out")
+	// provider or hermes itself can not read attached file
+	ErrUnsupportedAttachment = errors.New("unsupported attachment")
+)
+
+// failed provider response, kind is one of errors above or nil when failure
+// is not recognized
+type APIError struct {
+	StatusCode int
+	Kind       error
+	Body       string
+	RetryAfter time.Duration // zero when provider did not ask to wait
+}
+
+func (e *APIError) Error() string {
+	if e.Kind == nil {
+		return fmt.Sprintf("API error - %s\n", e.Body)
+	}
+	return fmt.Sprintf("API error - %s: %s\n", e.Kind, e.Body)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Kind
+}
+
+func newAPIError(status int, header http.Header, body []byte) *APIError {
+	return &APIError{
+		StatusCode: status,
+		Kind:       classifyStatus(status, body),
+		Body:       string(body),
+		RetryAfter: parseRetryAfter(header),
+	}
+}
+
+// providers report context overflow as bad request, only message tells it
+// apart from other bad requests
+var contextLengthMessages = []string{
+	"context_length_exceeded",
+	"maximum context length",
+	"prompt is too long",
+	"exceeds the maximum number of tokens",
+	"context size",
+	"context window",
+}
+
+func classifyStatus(status int, body []byte) error {
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return ErrAuth
+	case status == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case status == http.StatusRequestTimeout || status == http.StatusGatewayTimeout:
+		return ErrTimeout
+	case status >= 500: // includes 529, anthropic is overloaded
+		return ErrServer
+	}
+	lower := strings.ToLower(string(body))
+	for _, message := range contextLengthMessages {
+		if strings.Contains(lower, message) {
+			return ErrContextLength
+		}
+	}
+	return nil
+}
+
+// error types sent inside anthropic stream
+func classifyClaudeError(errorType string) error {
+	switch errorType {
+	case "authentication_error", "permission_error":
+		return ErrAuth
+	case "rate_limit_error":
+		return ErrRateLimit
+	case "api_error", "overloaded_error":
+		return ErrServer
+	}
+	return nil
+}
+
+// seconds or http date, openai also sends milliseconds in retry-after-ms
+func parseRetryAfter(header http.Header) time.Duration {
+	if ms, err := strconv.ParseFloat(header.Get("retry-after-ms"), 64); err == nil && ms > 0 {
+		return time.Duration(ms * float64(time.Millisecond))
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0)
+	}
+	return 0
+}
+
+// short name of failure kind for clients that receive error as text, empty
+// for unrecognized failures
+func ErrorCode(err error) string {
+	for code, kind := range map[string]error{
+		"auth":                   ErrAuth,
+		"rate_limit":             ErrRateLimit,
+		"context_length":         ErrContextLength,
+		"server":                 ErrServer,
+		"timeout":                ErrTimeout,
+		"cancelled":              context.Canceled,
+		"unsupported_attachment": ErrUnsupportedAttachment,
+	} {
+		if errors.Is(err, kind) {
+			return code
+		}
+	}
+	return ""
+}
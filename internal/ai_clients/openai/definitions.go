@@ -1,6 +1,8 @@
 // Generated by AI based on https://platform.openai.com/docs/api-reference/chat
 package openai
 
+import "encoding/json"
+
 type ChatCompletionRequest struct {
 	Messages          []*Message       `json:"messages"`
 	Model             string           `json:"model"`
@@ -28,6 +30,38 @@ type ChatCompletionRequest struct {
 type Message struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
+	// replaces content in request when message carries images or files
+	Parts []ContentPart `json:"-"`
+}
+
+func (m Message) MarshalJSON() ([]byte, error) {
+	if len(m.Parts) == 0 {
+		type plain Message
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		Role    string        `json:"role"`
+		Content []ContentPart `json:"content"`
+	}{Role: m.Role, Content: m.Parts})
+}
+
+// type is "text", "image_url" or "file"
+type ContentPart struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+	File     *File     `json:"file,omitempty"`
+}
+
+// url can be data url with base64 encoded image
+type ImageURL struct {
+	URL string `json:"url"`
+}
+
+// file data is data url with base64 encoded file
+type File struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
 }
 
 type ResponseFormat struct {
@@ -64,3 +98,19 @@ type Usage struct {
 	CompletionTokens int64 `json:"completion_tokens"`
 	TotalTokens      int64 `json:"total_tokens"`
 }
+
+type ChatCompletionChunk struct {
+	ID                string        `json:"id"`
+	Choices           []ChunkChoice `json:"choices"`
+	Created           int64         `json:"created"`
+	Model             string        `json:"model"`
+	SystemFingerprint string        `json:"system_fingerprint"`
+	Object            string        `json:"object"`
+	Usage             *Usage        `json:"usage,omitempty"`
+}
+
+type ChunkChoice struct {
+	Delta        Message `json:"delta"`
+	FinishReason string  `json:"finish_reason,omitempty"`
+	Index        int64   `json:"index"`
+}
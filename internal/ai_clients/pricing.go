@@ -0,0 +1,64 @@
+package ai_clients
+
+import "strings"
+
+// USD per million tokens
+type Price struct {
+	Input  float64
+	Output float64
+}
+
+// keys are "provider/model", dated snapshots match by longest prefix
+var prices = map[string]Price{
+	"openai/gpt-4o":                {Input: 2.5, Output: 10},
+	"openai/gpt-4o-mini":           {Input: 0.15, Output: 0.6},
+	"openai/gpt-4.1":               {Input: 2, Output: 8},
+	"openai/gpt-4.1-mini":          {Input: 0.4, Output: 1.6},
+	"openai/gpt-4.1-nano":          {Input: 0.1, Output: 0.4},
+	"openai/gpt-4-turbo":           {Input: 10, Output: 30},
+	"openai/gpt-3.5-turbo":         {Input: 0.5, Output: 1.5},
+	"openai/o1":                    {Input: 15, Output: 60},
+	"openai/o3-mini":               {Input: 1.1, Output: 4.4},
+	"anthropic/claude-3-5-sonnet":  {Input: 3, Output: 15},
+	"anthropic/claude-3-7-sonnet":  {Input: 3, Output: 15},
+	"anthropic/claude-3-opus":      {Input: 15, Output: 75},
+	"anthropic/claude-3-5-haiku":   {Input: 0.8, Output: 4},
+	"anthropic/claude-3-haiku":     {Input: 0.25, Output: 1.25},
+	"google/gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"google/gemini-2.5-flash":      {Input: 0.3, Output: 2.5},
+	"google/gemini-2.0-flash":      {Input: 0.1, Output: 0.4},
+	"google/gemini-2.0-flash-lite": {Input: 0.075, Output: 0.3},
+	"google/gemini-1.5-pro":        {Input: 1.25, Output: 5},
+	"google/gemini-1.5-flash":      {Input: 0.075, Output: 0.3},
+}
+
+// unknown models (including local ones) are free
+func Cost(model string, usage TokensUsage) float64 {
+	price, ok := lookupPrice(model)
+	if !ok {
+		return 0
+	}
+	return (float64(usage.Input)*price.Input + float64(usage.Output)*price.Output) / 1_000_000
+}
+
+func lookupPrice(model string) (Price, bool) {
+	return lookupModel(prices, model)
+}
+
+// exact key or longest "key-" prefix, so dated snapshots share entry
+func lookupModel[T any](table map[string]T, model string) (T, bool) {
+	if value, ok := table[model]; ok {
+		return value, true
+	}
+	match := ""
+	for key := range table {
+		if strings.HasPrefix(model, key+"-") && len(key) > len(match) {
+			match = key
+		}
+	}
+	if match == "" {
+		var zero T
+		return zero, false
+	}
+	return table[match], true
+}
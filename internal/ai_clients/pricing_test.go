@@ -0,0 +1,62 @@
+package ai_clients
+
+import "testing"
+
+func TestCost(t *testing.T) {
+	type testCase struct {
+		name     string
+		model    string
+		usage    TokensUsage
+		expected float64
+	}
+
+	table := []testCase{
+		{
+			name:     "should calculate cost of known model",
+			model:    "openai/gpt-4o",
+			usage:    TokensUsage{Input: 1_000_000, Output: 1_000_000},
+			expected: 12.5,
+		},
+		{
+			name:     "should match dated snapshot by longest prefix",
+			model:    "openai/gpt-4o-mini-2024-07-18",
+			usage:    TokensUsage{Input: 1_000_000, Output: 1_000_000},
+			expected: 0.75,
+		},
+		{
+			name:     "should match anthropic snapshot",
+			model:    "anthropic/claude-3-5-sonnet-20240620",
+			usage:    TokensUsage{Input: 2_000_000, Output: 0},
+			expected: 6,
+		},
+		{
+			name:     "should match gemini version by longest prefix",
+			model:    "google/gemini-2.0-flash-lite-001",
+			usage:    TokensUsage{Input: 1_000_000, Output: 1_000_000},
+			expected: 0.375,
+		},
+		{
+			name:     "should not match model that only shares prefix",
+			model:    "openai/gpt-4ox",
+			usage:    TokensUsage{Input: 1_000_000, Output: 1_000_000},
+			expected: 0,
+		},
+		{
+			name:     "should treat local models as free",
+			model:    "local/llama3.1",
+			usage:    TokensUsage{Input: 1_000_000, Output: 1_000_000},
+			expected: 0,
+		},
+	}
+
+	for _, test := range table {
+		if actual := Cost(test.model, test.usage); actual != test.expected {
+			t.Errorf(
+				"%q - bad cost\nexpected: %v\nactual:   %v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
@@ -11,6 +11,8 @@ import (
 var (
 	cachedClientOpenAI    *clientOpenAI
 	cachedClientAnthropic *clientClaude
+	cachedClientGoogle    *clientGemini
+	cachedClientLocal     *clientOpenAI
 	clientMutext          sync.Mutex
 )
 
@@ -32,8 +34,22 @@ func selectClient(provider string, providers *settings.Providers) (client, error
 			cachedClientAnthropic = newClientClaude(providers.AnthropicKey)
 		}
 		client = cachedClientAnthropic
+	case "google":
+		if cachedClientGoogle == nil {
+			cachedClientGoogle = newClientGemini(providers.GoogleKey)
+		}
+		client = cachedClientGoogle
+	case "local":
+		if cachedClientLocal == nil {
+			local, err := newClientLocal(providers.Local)
+			if err != nil {
+				return nil, err
+			}
+			cachedClientLocal = local
+		}
+		client = cachedClientLocal
 	default:
-		return nil, fmt.Errorf("unsupported provider %q - use openai/model or anthropic/model", provider)
+		return nil, fmt.Errorf("unsupported provider %q - use openai/model, anthropic/model, google/model or local/model", provider)
 	}
 	return client, nil
 }
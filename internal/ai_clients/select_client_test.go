@@ -36,6 +36,22 @@ func TestSelectProvider(t *testing.T) {
 			},
 			expected: &clientClaude{},
 		},
+		{
+			name: "should return gemini handler",
+			input: []string{
+				"google/gemini-2.0-flash",
+				"google/gemini-2.5-pro-preview-05-06",
+			},
+			expected: &clientGemini{},
+		},
+		{
+			name: "should return openai compatible handler for local provider",
+			input: []string{
+				"local/llama3.1",
+				"local/qwen2.5-coder:7b",
+			},
+			expected: &clientOpenAI{},
+		},
 		{
 			name: "should error on unhandled provider",
 			input: []string{
@@ -52,6 +68,7 @@ func TestSelectProvider(t *testing.T) {
 			provider, _, _ := extractProviderAndModel(input)
 			res, err := selectClient(provider, &settings.Providers{
 				OpenAIKey: "SECRET",
+				Local:     settings.LocalProvider{BaseURL: "http://localhost:11434/v1"},
 			})
 			if test.shouldError {
 				if err == nil {
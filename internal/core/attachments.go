@@ -0,0 +1,191 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/models"
+)
+
+// larger files are rejected, providers do not accept much bigger ones anyway
+const MaxAttachmentSize = 20 << 20
+
+// uploads that were not sent with message for this long are removed
+const pendingAttachmentLifetime = 30 * time.Minute
+
+type CreateAttachmentCommand struct {
+	core   *Core
+	name   string
+	data   []byte
+	Result *models.Attachment
+}
+
+// stored attachment waits until it is sent with message
+func NewCreateAttachmentCommand(c *Core, name string, data []byte) *CreateAttachmentCommand {
+	return &CreateAttachmentCommand{core: c, name: name, data: data}
+}
+
+func (c *CreateAttachmentCommand) Execute(ctx context.Context) error {
+	attachment, err := NewAttachment(c.name, c.data)
+	if err != nil {
+		return err
+	}
+	if err := c.core.db.DeleteStaleAttachments(ctx, pendingAttachmentLifetime); err != nil {
+		return err
+	}
+	c.Result, err = c.core.db.CreateAttachment(ctx, attachment)
+	return err
+}
+
+// checks file and detects its media type, result is not stored
+func NewAttachment(name string, data []byte) (*models.Attachment, error) {
+	name = filepath.Base(name)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("attachment %q is empty\n", name)
+	}
+	if len(data) > MaxAttachmentSize {
+		return nil, fmt.Errorf("attachment %q is larger than %d MB\n", name, MaxAttachmentSize>>20)
+	}
+	mediaType := attachmentMediaType(name, data)
+	if err := ai_clients.CheckAttachment(mediaType); err != nil {
+		return nil, fmt.Errorf("attachment %q: %w", name, err)
+	}
+	return &models.Attachment{Name: name, MediaType: mediaType, Data: data}, nil
+}
+
+// extension wins when it names supported type, otherwise content is sniffed,
+// so source files with odd extensions are still read as text
+func attachmentMediaType(name string, data []byte) string {
+	if mediaType := baseMediaType(mime.TypeByExtension(filepath.Ext(name))); mediaType != "" &&
+		ai_clients.CheckAttachment(mediaType) == nil {
+		return mediaType
+	}
+	return baseMediaType(http.DetectContentType(data))
+}
+
+// drops parameters, e.g. charset
+func baseMediaType(mediaType string) string {
+	if parsed, _, err := mime.ParseMediaType(mediaType); err == nil {
+		return parsed
+	}
+	return mediaType
+}
+
+type GetAttachmentQuery struct {
+	core   *Core
+	id     int64
+	Result *models.Attachment
+}
+
+// result holds attachment data
+func NewGetAttachmentQuery(c *Core, id int64) *GetAttachmentQuery {
+	return &GetAttachmentQuery{core: c, id: id}
+}
+
+func (q *GetAttachmentQuery) Execute(ctx context.Context) error {
+	attachment, err := q.core.db.GetAttachmentByID(ctx, q.id)
+	q.Result = attachment
+	return err
+}
+
+type GetPendingAttachmentsQuery struct {
+	core   *Core
+	ids    []int64
+	Result []*models.Attachment
+}
+
+// errors when any attachment does not exist or was already sent
+func NewGetPendingAttachmentsQuery(c *Core, ids []int64) *GetPendingAttachmentsQuery {
+	return &GetPendingAttachmentsQuery{core: c, ids: ids}
+}
+
+func (q *GetPendingAttachmentsQuery) Execute(ctx context.Context) error {
+	attachments, err := q.core.pendingAttachments(ctx, q.ids, nil)
+	q.Result = attachments
+	return err
+}
+
+// attachments that were uploaded, but not sent yet, with data, repeated ids
+// are attached once, uploads without id are stored together with message
+func (c Core) pendingAttachments(
+	ctx context.Context,
+	ids []int64,
+	uploads []*models.Attachment,
+) ([]*models.Attachment, error) {
+	attachments := []*models.Attachment{}
+	for _, upload := range uploads {
+		attachment, err := NewAttachment(upload.Name, upload.Data)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		attachment, err := c.db.GetAttachmentByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if attachment.MessageID != nil {
+			return nil, fmt.Errorf("attachment with id %d was already sent\n", id)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// fills attachments of chat messages, completion needs their data too
+func (c Core) loadAttachments(
+	ctx context.Context,
+	chatID int64,
+	messages []*models.Message,
+	withData bool,
+) error {
+	attachments, err := c.db.GetChatAttachments(ctx, chatID)
+	if err != nil || len(attachments) == 0 {
+		return err
+	}
+	byMessage := map[int64][]*models.Attachment{}
+	for _, message := range messages {
+		byMessage[message.ID] = nil
+	}
+	for _, attachment := range attachments {
+		if _, ok := byMessage[*attachment.MessageID]; !ok {
+			continue
+		}
+		if withData {
+			if attachment, err = c.db.GetAttachmentByID(ctx, attachment.ID); err != nil {
+				return err
+			}
+		}
+		byMessage[*attachment.MessageID] = append(byMessage[*attachment.MessageID], attachment)
+	}
+	for _, message := range messages {
+		message.Attachments = byMessage[message.ID]
+	}
+	return nil
+}
+
+func attachmentsToAIAttachments(attachments []*models.Attachment) []*ai_clients.Attachment {
+	if len(attachments) == 0 {
+		return nil
+	}
+	res := []*ai_clients.Attachment{}
+	for _, attachment := range attachments {
+		res = append(res, &ai_clients.Attachment{
+			Name:      attachment.Name,
+			MediaType: attachment.MediaType,
+			Data:      attachment.Data,
+		})
+	}
+	return res
+}
@@ -0,0 +1,314 @@
+package core_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestCreateAttachmentCommand(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	ctx := context.Background()
+
+	type testCase struct {
+		name              string
+		fileName          string
+		data              []byte
+		expectedName      string
+		expectedMediaType string
+		shouldError       bool
+	}
+
+	table := []testCase{
+		{
+			name:              "should detect image by extension",
+			fileName:          "/tmp/diagram.png",
+			data:              []byte("\x89PNG\r\n\x1a\n"),
+			expectedName:      "diagram.png",
+			expectedMediaType: "image/png",
+		},
+		{
+			name:              "should detect pdf by extension",
+			fileName:          "spec.pdf",
+			data:              []byte("%PDF-1.7"),
+			expectedName:      "spec.pdf",
+			expectedMediaType: "application/pdf",
+		},
+		{
+			name:              "should sniff text content without extension",
+			fileName:          "Makefile",
+			data:              []byte("build:\n\tgo build ./...\n"),
+			expectedName:      "Makefile",
+			expectedMediaType: "text/plain",
+		},
+		{
+			name:        "should reject unsupported file",
+			fileName:    "archive.zip",
+			data:        []byte("PK\x03\x04"),
+			shouldError: true,
+		},
+		{
+			name:        "should reject empty file",
+			fileName:    "empty.txt",
+			data:        []byte{},
+			shouldError: true,
+		},
+		{
+			name:        "should reject too large file",
+			fileName:    "large.txt",
+			data:        []byte(strings.Repeat("a", core.MaxAttachmentSize+1)),
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		cmd := core.NewCreateAttachmentCommand(coreInstance, test.fileName, test.data)
+		err := cmd.Execute(ctx)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if cmd.Result.Name != test.expectedName ||
+			cmd.Result.MediaType != test.expectedMediaType ||
+			cmd.Result.Size != int64(len(test.data)) ||
+			cmd.Result.MessageID != nil {
+			t.Errorf("%q - bad attachment: %+v\n", test.name, cmd.Result)
+		}
+	}
+}
+
+func TestCompletionWithAttachments(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	upload := core.NewCreateAttachmentCommand(coreInstance, "diagram.png", []byte("png"))
+	if err := upload.Execute(ctx); err != nil {
+		t.Fatalf("failed to create attachment, err: %s\n", err)
+	}
+
+	sent := [][]string{}
+	completion := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		names := []string{}
+		for _, message := range messages {
+			for _, attachment := range message.Attachments {
+				names = append(names, message.Content+":"+attachment.Name+":"+string(attachment.Data))
+			}
+		}
+		sent = append(sent, names)
+		return &ai_clients.AIResponse{
+			Message: ai_clients.Message{Role: core.AssistantRole, Content: "answer"},
+		}, nil
+	}
+	complete := func(content string, attachments []int64) error {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			content,
+			"",
+			&ai_clients.Parameters{Model: "openai/gpt-4o"},
+			completion,
+		)
+		cmd.WithAttachments(attachments)
+		return cmd.Execute(ctx)
+	}
+
+	if err := complete("first", []int64{upload.Result.ID}); err != nil {
+		t.Fatalf("failed to complete with attachment, err: %s\n", err)
+	}
+	if err := complete("second", nil); err != nil {
+		t.Fatalf("failed to complete after attachment, err: %s\n", err)
+	}
+	if err := complete("third", []int64{upload.Result.ID}); err == nil {
+		t.Errorf("expected to error upon sending attachment twice\n")
+	}
+	if err := complete("fourth", []int64{999}); err == nil {
+		t.Errorf("expected to error upon sending non existing attachment\n")
+	}
+	expected := [][]string{{"first:diagram.png:png"}, {"first:diagram.png:png"}}
+	if !reflect.DeepEqual(expected, sent) {
+		t.Errorf("bad sent attachments\nexpected: %q\nactual:   %q\n", expected, sent)
+	}
+
+	messages := core.GetChatMessagesQuery{Core: coreInstance, ChatID: 1}
+	if err := messages.Execute(ctx); err != nil {
+		t.Fatalf("failed to get chat messages, err: %s\n", err)
+	}
+	if len(messages.Result) < 1 || len(messages.Result[0].Attachments) != 1 {
+		t.Fatalf("expected first message to have attachment, got %+v\n", messages.Result)
+	}
+	attachment := messages.Result[0].Attachments[0]
+	if attachment.Name != "diagram.png" || attachment.Data != nil {
+		t.Errorf("expected attachment without data, got %+v\n", attachment)
+	}
+
+	fork := core.NewForkChatCommand(coreInstance, 1, messages.Result[1].ID)
+	if err := fork.Execute(ctx); err != nil {
+		t.Fatalf("failed to fork chat, err: %s\n", err)
+	}
+	forked := core.GetChatMessagesQuery{Core: coreInstance, ChatID: fork.Result.Chat.ID}
+	if err := forked.Execute(ctx); err != nil {
+		t.Fatalf("failed to get forked messages, err: %s\n", err)
+	}
+	if len(forked.Result) != 2 ||
+		len(forked.Result[0].Attachments) != 1 ||
+		len(forked.Result[1].Attachments) != 0 {
+		t.Errorf("expected fork to copy attachment of first message, got %+v\n", forked.Result)
+	}
+}
+
+func TestCreateChatWithAttachments(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	upload := core.NewCreateAttachmentCommand(coreInstance, "notes.txt", []byte("notes"))
+	if err := upload.Execute(ctx); err != nil {
+		t.Fatalf("failed to create attachment, err: %s\n", err)
+	}
+	create := func(ids []int64) (*core.CreateChatWithMessageCommand, error) {
+		cmd := core.NewCreateChatWithMessageCommand(
+			coreInstance,
+			&models.Message{Role: core.UserRole, Content: "content"},
+			"",
+		)
+		cmd.WithAttachments(ids)
+		return cmd, cmd.Execute(ctx)
+	}
+	countChats := func() int {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM chats`).Scan(&count); err != nil {
+			t.Fatalf("failed to count chats, err: %s\n", err)
+		}
+		return count
+	}
+
+	cmd, err := create([]int64{upload.Result.ID, upload.Result.ID})
+	if err != nil {
+		t.Fatalf("failed to create chat with repeated attachment, err: %s\n", err)
+	}
+	if len(cmd.Result.Message.Attachments) != 1 {
+		t.Errorf(
+			"expected attachment to be linked once, got %+v\n",
+			cmd.Result.Message.Attachments,
+		)
+	}
+	if _, err := create([]int64{upload.Result.ID}); err == nil {
+		t.Errorf("expected to error upon sending attachment twice\n")
+	}
+	if count := countChats(); count != 1 {
+		t.Errorf("expected refused message to leave no chat, got %d chats\n", count)
+	}
+}
+
+func TestCreateAttachmentCommandRemovesStaleUploads(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	upload := func(name string) int64 {
+		cmd := core.NewCreateAttachmentCommand(coreInstance, name, []byte("notes"))
+		if err := cmd.Execute(ctx); err != nil {
+			t.Fatalf("failed to create attachment, err: %s\n", err)
+		}
+		return cmd.Result.ID
+	}
+	stale := upload("stale.txt")
+	sent := upload("sent.txt")
+	fresh := upload("fresh.txt")
+	created := core.NewCreateChatWithMessageCommand(
+		coreInstance,
+		&models.Message{Role: core.UserRole, Content: "content"},
+		"",
+	)
+	created.WithAttachments([]int64{sent})
+	if err := created.Execute(ctx); err != nil {
+		t.Fatalf("failed to create chat, err: %s\n", err)
+	}
+	if _, err := db.Exec(
+		`UPDATE attachments SET created_at = datetime('now', '-1 day') WHERE id IN ($1, $2)`,
+		stale,
+		sent,
+	); err != nil {
+		t.Fatalf("failed to age attachments, err: %s\n", err)
+	}
+
+	upload("new.txt")
+	for _, id := range []int64{stale, sent, fresh} {
+		query := core.NewGetAttachmentQuery(coreInstance, id)
+		err := query.Execute(ctx)
+		if id == stale && err == nil {
+			t.Errorf("expected stale upload to be removed\n")
+		}
+		if id != stale && err != nil {
+			t.Errorf("expected attachment %d to remain, err: %s\n", id, err)
+		}
+	}
+}
+
+func TestCompletionWithUploads(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	cmd := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		test_helpers.MockCompletion,
+	)
+	cmd.WithUploads([]*models.Attachment{{Name: "/tmp/notes.txt", Data: []byte("notes")}})
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to complete with upload, err: %s\n", err)
+	}
+	messages := core.GetChatMessagesQuery{Core: coreInstance, ChatID: 1}
+	if err := messages.Execute(ctx); err != nil {
+		t.Fatalf("failed to get chat messages, err: %s\n", err)
+	}
+	if len(messages.Result) != 2 || len(messages.Result[0].Attachments) != 1 {
+		t.Fatalf("expected user message to have attachment, got %+v\n", messages.Result)
+	}
+	attachment := messages.Result[0].Attachments[0]
+	if attachment.Name != "notes.txt" ||
+		attachment.MediaType != "text/plain" ||
+		attachment.Size != 5 ||
+		*attachment.MessageID != messages.Result[0].ID {
+		t.Errorf("bad stored upload: %+v\n", attachment)
+	}
+
+	rejected := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		test_helpers.MockCompletion,
+	)
+	rejected.WithUploads([]*models.Attachment{{Name: "archive.zip", Data: []byte("PK\x03\x04")}})
+	if err := rejected.Execute(ctx); err == nil {
+		t.Errorf("expected to error upon unsupported upload\n")
+	}
+}
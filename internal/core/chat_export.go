@@ -0,0 +1,264 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+const (
+	ChatExportMarkdown = "md"
+	ChatExportJSON     = "json"
+	ChatExportJSONL    = "jsonl"
+)
+
+var ChatExportFormats = []string{ChatExportMarkdown, ChatExportJSON, ChatExportJSONL}
+
+func newChatExport(
+	chat *models.Chat,
+	messages []*models.Message,
+	usage []*models.Usage,
+) *models.ChatExport {
+	byMessage := map[int64]*models.Usage{}
+	for _, u := range usage {
+		byMessage[u.MessageID] = u
+	}
+	export := &models.ChatExport{
+		Name:                 chat.Name,
+		Model:                chat.Model,
+		CompletionParameters: chat.CompletionParameters,
+		History:              chat.History,
+		CreatedAt:            chat.CreatedAt,
+		Messages:             []*models.ExportMessage{},
+	}
+	for _, message := range messages {
+		exported := &models.ExportMessage{
+			Role:      message.Role,
+			Content:   message.Content,
+			CreatedAt: message.CreatedAt,
+		}
+		if u, ok := byMessage[message.ID]; ok {
+			exported.Usage = &models.ExportUsage{
+				Model:        u.Model,
+				InputTokens:  u.InputTokens,
+				OutputTokens: u.OutputTokens,
+				Cost:         u.Cost,
+			}
+		}
+		for _, attachment := range message.Attachments {
+			exported.Attachments = append(exported.Attachments, &models.ExportAttachment{
+				Name:      attachment.Name,
+				MediaType: attachment.MediaType,
+			})
+		}
+		export.Messages = append(export.Messages, exported)
+	}
+	return export
+}
+
+// writes chat in given format, jsonl holds one message per line and drops
+// chat parameters
+func WriteChatExport(w io.Writer, export *models.ChatExport, format string) error {
+	switch format {
+	case ChatExportJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(export)
+	case ChatExportJSONL:
+		encoder := json.NewEncoder(w)
+		for _, message := range export.Messages {
+			if err := encoder.Encode(message); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ChatExportMarkdown:
+		return writeChatMarkdown(w, export)
+	}
+	return fmt.Errorf(
+		"unknown export format %q - use %s\n",
+		format,
+		strings.Join(ChatExportFormats, ", "),
+	)
+}
+
+const chatExportTimeLayout = "2006-01-02 15:04:05"
+
+func writeChatMarkdown(w io.Writer, export *models.ChatExport) error {
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "# %s\n\n", export.Name)
+	fmt.Fprintf(b, "- model: %s\n", export.Model)
+	if export.MaxTokens != nil {
+		fmt.Fprintf(b, "- max tokens: %d\n", *export.MaxTokens)
+	}
+	if export.Temperature != nil {
+		fmt.Fprintf(b, "- temperature: %g\n", *export.Temperature)
+	}
+	if export.CreatedAt != nil {
+		fmt.Fprintf(b, "- created at: %s\n", export.CreatedAt.Format(chatExportTimeLayout))
+	}
+	for _, message := range export.Messages {
+		fmt.Fprintf(b, "\n## %s", message.Role)
+		if message.CreatedAt != nil {
+			fmt.Fprintf(b, " (%s)", message.CreatedAt.Format(chatExportTimeLayout))
+		}
+		b.WriteString("\n\n")
+		if message.Usage != nil {
+			fmt.Fprintf(
+				b,
+				"_%s, %d input / %d output tokens, $%g_\n\n",
+				message.Usage.Model,
+				message.Usage.InputTokens,
+				message.Usage.OutputTokens,
+				message.Usage.Cost,
+			)
+		}
+		fmt.Fprintf(b, "%s\n", strings.TrimRight(message.Content, "\n"))
+		for _, attachment := range message.Attachments {
+			fmt.Fprintf(b, "\n_attachment: %s (%s)_\n", attachment.Name, attachment.MediaType)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// chat as written by json export, or OpenAI-style request
+type importedChat struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	models.CompletionParameters
+	models.History
+	CreatedAt *time.Time         `json:"created_at"`
+	Messages  []*importedMessage `json:"messages"`
+}
+
+type importedMessage struct {
+	Role      string              `json:"role"`
+	Content   json.RawMessage     `json:"content"`
+	CreatedAt *time.Time          `json:"created_at"`
+	Usage     *models.ExportUsage `json:"usage"`
+}
+
+// OpenAI content part, only text parts can be imported
+type importedContentPart struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// decodes json export, jsonl export, OpenAI-style `{"messages": [...]}`
+// object or bare messages array
+func ParseChatExport(content []byte) (*models.ChatExport, error) {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("chat import is empty\n")
+	}
+	chat := importedChat{}
+	switch trimmed[0] {
+	case '[':
+		if err := json.Unmarshal(trimmed, &chat.Messages); err != nil {
+			return nil, fmt.Errorf("failed to decode messages: %s\n", err)
+		}
+	case '{':
+		values := []json.RawMessage{}
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		for decoder.More() {
+			var value json.RawMessage
+			if err := decoder.Decode(&value); err != nil {
+				return nil, fmt.Errorf("failed to decode chat: %s\n", err)
+			}
+			values = append(values, value)
+		}
+		// chat object is told apart from jsonl by its messages field, so
+		// single message jsonl is not mistaken for a chat
+		keys := map[string]json.RawMessage{}
+		if err := json.Unmarshal(values[0], &keys); err != nil {
+			return nil, fmt.Errorf("failed to decode chat: %s\n", err)
+		}
+		if _, ok := keys["messages"]; ok {
+			if len(values) != 1 {
+				return nil, fmt.Errorf("chat import must hold single chat object\n")
+			}
+			if err := json.Unmarshal(values[0], &chat); err != nil {
+				return nil, fmt.Errorf("failed to decode chat: %s\n", err)
+			}
+			break
+		}
+		for i, value := range values {
+			var message importedMessage
+			if err := json.Unmarshal(value, &message); err != nil {
+				return nil, fmt.Errorf("failed to decode message %d: %s\n", i+1, err)
+			}
+			chat.Messages = append(chat.Messages, &message)
+		}
+	default:
+		return nil, fmt.Errorf("chat import must be json object, array or jsonl\n")
+	}
+	return chat.export()
+}
+
+func (c importedChat) export() (*models.ChatExport, error) {
+	if len(c.Messages) == 0 {
+		return nil, fmt.Errorf("chat import has no messages\n")
+	}
+	export := &models.ChatExport{
+		Name:                 c.Name,
+		Model:                c.Model,
+		CompletionParameters: c.CompletionParameters,
+		History:              c.History,
+		CreatedAt:            c.CreatedAt,
+		Messages:             []*models.ExportMessage{},
+	}
+	for i, message := range c.Messages {
+		role, err := importedRole(message.Role)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %s", i+1, err)
+		}
+		content, err := importedContent(message.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %s", i+1, err)
+		}
+		export.Messages = append(export.Messages, &models.ExportMessage{
+			Role:      role,
+			Content:   content,
+			CreatedAt: message.CreatedAt,
+			Usage:     message.Usage,
+		})
+	}
+	return export, nil
+}
+
+// OpenAI developer messages replace system ones in newer models
+func importedRole(role string) (string, error) {
+	switch role {
+	case UserRole, AssistantRole, SystemRole:
+		return role, nil
+	case "developer":
+		return SystemRole, nil
+	}
+	return "", fmt.Errorf("unsupported role %q\n", role)
+}
+
+// content is either string or list of parts, text parts are joined
+func importedContent(raw json.RawMessage) (string, error) {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		return text, nil
+	}
+	parts := []importedContentPart{}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", fmt.Errorf("content must be string or list of parts\n")
+	}
+	texts := []string{}
+	for _, part := range parts {
+		if part.Type != "text" {
+			return "", fmt.Errorf("unsupported content part %q\n", part.Type)
+		}
+		texts = append(texts, part.Text)
+	}
+	return strings.Join(texts, "\n"), nil
+}
@@ -0,0 +1,107 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+func TestParseChatExport(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected []models.ExportMessage
+		errors   bool
+	}
+	table := []testCase{
+		{
+			name:  "should decode json export",
+			input: `{"name": "chat", "messages": [{"role": "user", "content": "hi"}, {"role": "assistant", "content": "hello", "usage": {"model": "openai/gpt-4o", "input_tokens": 1}}]}`,
+			expected: []models.ExportMessage{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: "hello", Usage: &models.ExportUsage{Model: "openai/gpt-4o", InputTokens: 1}},
+			},
+		},
+		{
+			name:  "should decode jsonl export",
+			input: "{\"role\": \"user\", \"content\": \"hi\"}\n{\"role\": \"assistant\", \"content\": \"hello\"}\n",
+			expected: []models.ExportMessage{
+				{Role: "user", Content: "hi"},
+				{Role: "assistant", Content: "hello"},
+			},
+		},
+		{
+			name:  "should decode single message jsonl export",
+			input: "{\"role\": \"user\", \"content\": \"hi\"}\n",
+			expected: []models.ExportMessage{
+				{Role: "user", Content: "hi"},
+			},
+		},
+		{
+			name:   "should error on several chat objects",
+			input:  "{\"messages\": [{\"role\": \"user\", \"content\": \"a\"}]}\n{\"messages\": [{\"role\": \"user\", \"content\": \"b\"}]}\n",
+			errors: true,
+		},
+		{
+			name:  "should decode bare messages array",
+			input: `[{"role": "system", "content": "be brief"}, {"role": "user", "content": "hi"}]`,
+			expected: []models.ExportMessage{
+				{Role: "system", Content: "be brief"},
+				{Role: "user", Content: "hi"},
+			},
+		},
+		{
+			name:  "should join text parts and map developer role",
+			input: `{"messages": [{"role": "developer", "content": [{"type": "text", "text": "a"}, {"type": "text", "text": "b"}]}]}`,
+			expected: []models.ExportMessage{
+				{Role: "system", Content: "a\nb"},
+			},
+		},
+		{
+			name:   "should error on non text parts",
+			input:  `[{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "x"}}]}]`,
+			errors: true,
+		},
+		{
+			name:   "should error on unsupported role",
+			input:  `[{"role": "tool", "content": "42"}]`,
+			errors: true,
+		},
+		{
+			name:   "should error on chat without messages",
+			input:  `{"name": "empty"}`,
+			errors: true,
+		},
+		{
+			name:   "should error on plain text",
+			input:  "hello",
+			errors: true,
+		},
+	}
+	for _, test := range table {
+		actual, err := ParseChatExport([]byte(test.input))
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got %+v\n", test.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %v\n", test.name, err)
+			continue
+		}
+		messages := []models.ExportMessage{}
+		for _, message := range actual.Messages {
+			messages = append(messages, *message)
+		}
+		if !reflect.DeepEqual(messages, test.expected) {
+			t.Errorf(
+				"%q bad result.\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				messages,
+			)
+		}
+	}
+}
@@ -3,6 +3,10 @@ package core
 import (
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/models"
@@ -18,10 +22,15 @@ type CreateChatWithMessageCommandResult struct {
 }
 
 type CreateChatWithMessageCommand struct {
-	core     *Core
-	message  *models.Message
-	template string
-	Result   *CreateChatWithMessageCommandResult
+	core        *Core
+	message     *models.Message
+	template    string
+	parameters  *ai_clients.Parameters
+	persona     string
+	variables   map[string]any
+	attachments []int64
+	uploads     []*models.Attachment
+	Result      *CreateChatWithMessageCommandResult
 }
 
 func NewCreateChatWithMessageCommand(
@@ -36,19 +45,65 @@ func NewCreateChatWithMessageCommand(
 	}
 }
 
+// parameters are stored in chat and reused upon continuation
+func (c *CreateChatWithMessageCommand) WithParameters(parameters *ai_clients.Parameters) {
+	c.parameters = parameters
+}
+
+// persona prompt is used as system message, its model and parameters are
+// defaults for chat
+func (c *CreateChatWithMessageCommand) WithPersona(name string) {
+	c.persona = name
+}
+
+// variables are read in template with `var "key"`, `.` remains message content
+func (c *CreateChatWithMessageCommand) WithVariables(variables map[string]any) {
+	c.variables = variables
+}
+
+// ids of uploaded attachments, they are linked to created message
+func (c *CreateChatWithMessageCommand) WithAttachments(ids []int64) {
+	c.attachments = ids
+}
+
+// files that were not uploaded, they are stored together with message
+func (c *CreateChatWithMessageCommand) WithUploads(uploads []*models.Attachment) {
+	c.uploads = uploads
+}
+
 func (c *CreateChatWithMessageCommand) Execute(ctx context.Context) error {
-	msg, err := c.core.prepareMessage(ctx, c.message.Content, c.template)
+	msg, err := c.core.prepareMessage(ctx, c.message.Content, c.template, c.variables)
 	if err != nil {
 		return err
 	}
+	attachments, err := c.core.pendingAttachments(ctx, c.attachments, c.uploads)
+	if err != nil {
+		return err
+	}
+	var persona *models.Persona
+	var personaID *int64
+	if c.persona != "" {
+		if persona, err = c.core.db.GetPersonaByName(ctx, c.persona); err != nil {
+			return err
+		}
+		personaID = &persona.ID
+	}
+	model, parameters := chatParameters(c.core.resolveParameters(personaChat(persona), c.parameters))
 	chat, message, err := c.core.db.CreateChatAndMessage(
 		ctx,
 		c.message.Role,
 		msg,
+		model,
+		parameters,
+		personaID,
+		attachments,
 	)
 	if err != nil {
 		return err
 	}
+	if len(attachments) > 0 {
+		message.Attachments = attachments
+	}
 	c.Result = &CreateChatWithMessageCommandResult{
 		Chat:    chat,
 		Message: message,
@@ -87,33 +142,36 @@ func NewCreateChatAndCompletionCommand(
 }
 
 func (c *CreateChatAndCompletionCommand) Execute(ctx context.Context) error {
-	input, err := c.core.prepareMessage(ctx, c.message, c.template)
+	input, err := c.core.prepareMessage(ctx, c.message, c.template, nil)
 	if err != nil {
 		return err
 	}
+	parameters := c.core.resolveParameters(&models.Chat{}, c.parameters)
+	model, chatParams := chatParameters(parameters)
 	chat, _, err := c.core.db.CreateChatAndMessage(
 		ctx,
 		c.role,
 		input,
+		model,
+		chatParams,
+		nil,
+		nil,
 	)
 	if err != nil {
 		return err
 	}
 	// TODO insert used value into the db and adjust queries to receive less messages
 	res, err := c.completion(
+		ctx,
 		[]*ai_clients.Message{{Content: input, Role: UserRole}},
-		c.parameters,
+		parameters,
 		&c.core.config.Providers,
+		nil,
 	)
 	if err != nil {
 		return err
 	}
-	message, err := c.core.db.CreateMessage(
-		ctx,
-		chat.ID,
-		res.Role,
-		res.Content,
-	)
+	message, err := c.core.persistCompletion(ctx, chat.ID, nil, parameters.Model, res)
 	c.Result = message
 	return err
 }
@@ -128,6 +186,13 @@ type CreateCompletionCommand struct {
 	completion               ai_clients.CompletionFn
 	Result                   *models.Message
 	shouldPersistUserMessage bool
+	onDelta                  ai_clients.OnDelta
+	history                  *models.History
+	persona                  string
+	variables                map[string]any
+	attachments              []int64
+	uploads                  []*models.Attachment
+	discardChat              bool
 }
 
 func NewCreateCompletionCommand(
@@ -155,46 +220,534 @@ func (c *CreateCompletionCommand) ShouldPersistUserMessage(skipPersistingUserMes
 	c.shouldPersistUserMessage = skipPersistingUserMessage
 }
 
+// streams completion pieces into onDelta, persisted result remains whole
+func (c *CreateCompletionCommand) Stream(onDelta ai_clients.OnDelta) {
+	c.onDelta = onDelta
+}
+
+// overrides history strategy stored in chat for this completion only
+func (c *CreateCompletionCommand) WithHistory(history *models.History) {
+	c.history = history
+}
+
+// attaches persona to chat, it stays attached for following completions
+func (c *CreateCompletionCommand) WithPersona(name string) {
+	c.persona = name
+}
+
+// variables are read in template with `var "key"`, `.` remains message content
+func (c *CreateCompletionCommand) WithVariables(variables map[string]any) {
+	c.variables = variables
+}
+
+// files that were not uploaded, they are stored together with user message
+func (c *CreateCompletionCommand) WithUploads(uploads []*models.Attachment) {
+	c.uploads = uploads
+}
+
+// ids of uploaded attachments, they are linked to persisted user message and
+// sent with it
+func (c *CreateCompletionCommand) WithAttachments(ids []int64) {
+	c.attachments = ids
+}
+
+// chat was created for this completion, it is removed when completion fails,
+// so no chat is left without answer
+func (c *CreateCompletionCommand) DiscardChatOnError(discard bool) {
+	c.discardChat = discard
+}
+
 func (c *CreateCompletionCommand) Execute(ctx context.Context) error {
-	input, err := c.core.prepareMessage(ctx, c.message, c.template)
+	err := c.execute(ctx)
+	if err == nil || !c.discardChat {
+		return err
+	}
+	// chat is removed even if request was cancelled
+	if discardErr := c.core.db.DiscardChat(context.WithoutCancel(ctx), c.chatID); discardErr != nil {
+		return fmt.Errorf("%w\nfailed to remove chat: %s\n", err, discardErr)
+	}
+	return err
+}
+
+func (c *CreateCompletionCommand) execute(ctx context.Context) error {
+	input, err := c.core.prepareMessage(ctx, c.message, c.template, c.variables)
 	if err != nil {
 		return err
 	}
+	attachments, err := c.core.pendingAttachments(ctx, c.attachments, c.uploads)
+	if err != nil {
+		return err
+	}
+	chat, err := c.core.db.GetChatByID(ctx, c.chatID)
+	if err != nil {
+		return err
+	}
+	if c.persona != "" {
+		if chat, err = c.core.attachPersona(ctx, chat, c.persona); err != nil {
+			return err
+		}
+	}
+	parameters := c.core.resolveParameters(chat, c.parameters)
+	if chat.Model == "" {
+		// chats created before parameters were stored adopt first used ones
+		model, chatParams := chatParameters(parameters)
+		if _, err := c.core.db.UpdateChatParameters(ctx, chat.ID, model, chatParams); err != nil {
+			return err
+		}
+	}
 	prev, err := c.core.db.GetChatMessages(ctx, c.chatID)
 	if err != nil {
 		return err
 	}
+	if err := c.core.loadAttachments(ctx, c.chatID, prev, true); err != nil {
+		return err
+	}
+	persona, err := c.core.personaMessages(ctx, chat)
+	if err != nil {
+		return err
+	}
+	history, err := c.core.reduceHistory(
+		ctx,
+		chat,
+		c.history,
+		parameters,
+		c.completion,
+		append(
+			append(persona, prev...),
+			&models.Message{Content: input, Role: UserRole, Attachments: attachments},
+		),
+	)
+	if err != nil {
+		return err
+	}
+	res, err := c.completion(
+		ctx,
+		history,
+		parameters,
+		&c.core.config.Providers,
+		c.onDelta,
+	)
+	if err != nil {
+		return err
+	}
+	// user message is stored only with its answer, failed request leaves
+	// nothing behind
+	var question *models.Message
 	if c.shouldPersistUserMessage {
-		_, err = c.core.db.CreateMessage(
-			ctx,
-			c.chatID,
-			c.role,
-			input,
-		)
-		if err != nil {
-			return err
+		question = &models.Message{
+			ChatID:      c.chatID,
+			Role:        c.role,
+			Content:     input,
+			Attachments: attachments,
 		}
 	}
-	history := []*ai_clients.Message{}
-	for _, p := range prev {
-		history = append(history, messageToAIMessage(p))
+	message, err := c.core.persistCompletion(ctx, c.chatID, question, parameters.Model, res)
+	c.Result = message
+	return err
+}
+
+type RegenerateMessageCommand struct {
+	core       *Core
+	messageID  int64
+	parameters *ai_clients.Parameters
+	completion ai_clients.CompletionFn
+	onDelta    ai_clients.OnDelta
+	history    *models.History
+	Result     *models.Message
+}
+
+// creates new generation of assistant message from the same history, new
+// generation becomes selected one
+func NewRegenerateMessageCommand(
+	core *Core,
+	messageID int64,
+	parameters *ai_clients.Parameters,
+	completion ai_clients.CompletionFn,
+) *RegenerateMessageCommand {
+	return &RegenerateMessageCommand{
+		core:       core,
+		messageID:  messageID,
+		parameters: parameters,
+		completion: completion,
+	}
+}
+
+func (c *RegenerateMessageCommand) Stream(onDelta ai_clients.OnDelta) {
+	c.onDelta = onDelta
+}
+
+// overrides history strategy stored in chat for this generation only
+func (c *RegenerateMessageCommand) WithHistory(history *models.History) {
+	c.history = history
+}
+
+func (c *RegenerateMessageCommand) Execute(ctx context.Context) error {
+	message, err := c.core.db.GetMessageByID(ctx, c.messageID)
+	if err != nil {
+		return err
 	}
-	history = append(history, &ai_clients.Message{Content: input, Role: UserRole})
-	// TODO insert used value into the db and adjust queries to receive less messages
-	res, err := c.completion(history, c.parameters, &c.core.config.Providers)
+	if message.Role != AssistantRole {
+		return fmt.Errorf("only assistant messages can be regenerated\n")
+	}
+	chat, err := c.core.db.GetChatByID(ctx, message.ChatID)
+	if err != nil {
+		return err
+	}
+	parameters := c.core.resolveParameters(chat, c.parameters)
+	prev, err := c.core.db.GetMessageHistory(ctx, c.messageID)
+	if err != nil {
+		return err
+	}
+	if err := c.core.loadAttachments(ctx, chat.ID, prev, true); err != nil {
+		return err
+	}
+	persona, err := c.core.personaMessages(ctx, chat)
 	if err != nil {
 		return err
 	}
-	message, err := c.core.db.CreateMessage(
+	history, err := c.core.reduceHistory(
 		ctx,
-		c.chatID,
+		chat,
+		c.history,
+		parameters,
+		c.completion,
+		append(persona, prev...),
+	)
+	if err != nil {
+		return err
+	}
+	res, err := c.completion(
+		ctx,
+		history,
+		parameters,
+		&c.core.config.Providers,
+		c.onDelta,
+	)
+	if err != nil {
+		return err
+	}
+	ctx = completionContext(ctx, res)
+	generation, err := c.core.db.CreateMessageGeneration(
+		ctx,
+		c.messageID,
 		res.Role,
 		res.Content,
+		res.Interrupted,
+		completionUsage(parameters.Model, res),
 	)
+	if err != nil {
+		return err
+	}
+	c.Result = generation
+	return nil
+}
+
+type SelectGenerationCommand struct {
+	core      *Core
+	messageID int64
+	Result    *models.Message
+}
+
+// selected generation replaces its siblings in chat history
+func NewSelectGenerationCommand(core *Core, messageID int64) *SelectGenerationCommand {
+	return &SelectGenerationCommand{core: core, messageID: messageID}
+}
+
+func (c *SelectGenerationCommand) Execute(ctx context.Context) error {
+	message, err := c.core.db.SelectMessageGeneration(ctx, c.messageID)
 	c.Result = message
 	return err
 }
 
+type ForkChatCommand struct {
+	core      *Core
+	chatID    int64
+	messageID int64
+	Result    *CreateChatWithMessageCommandResult
+}
+
+// copies chat history up to and including given message into new chat,
+// result message is the last one in forked chat
+func NewForkChatCommand(core *Core, chatID int64, messageID int64) *ForkChatCommand {
+	return &ForkChatCommand{
+		core:      core,
+		chatID:    chatID,
+		messageID: messageID,
+	}
+}
+
+func (c *ForkChatCommand) Execute(ctx context.Context) error {
+	chat, err := c.core.db.ForkChat(ctx, c.chatID, c.messageID)
+	if err != nil {
+		return err
+	}
+	messages, err := c.core.db.GetChatMessages(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("forked chat with id %d has no messages\n", chat.ID)
+	}
+	c.Result = &CreateChatWithMessageCommandResult{
+		Chat:    chat,
+		Message: messages[len(messages)-1],
+	}
+	return nil
+}
+
+type RenameChatCommand struct {
+	core   *Core
+	chatID int64
+	name   string
+	Result *models.Chat
+}
+
+func NewRenameChatCommand(core *Core, chatID int64, name string) *RenameChatCommand {
+	return &RenameChatCommand{
+		core:   core,
+		chatID: chatID,
+		name:   name,
+	}
+}
+
+func (c *RenameChatCommand) Execute(ctx context.Context) error {
+	name := strings.TrimSpace(c.name)
+	if name == "" {
+		return fmt.Errorf("chat name cannot be empty\n")
+	}
+	chat, err := c.core.db.RenameChat(ctx, c.chatID, name)
+	c.Result = chat
+	return err
+}
+
+type DeleteChatCommand struct {
+	core   *Core
+	chatID int64
+	Result *models.Chat
+}
+
+// soft delete, chat is hidden but its messages and usage are kept
+func NewDeleteChatCommand(core *Core, chatID int64) *DeleteChatCommand {
+	return &DeleteChatCommand{
+		core:   core,
+		chatID: chatID,
+	}
+}
+
+func (c *DeleteChatCommand) Execute(ctx context.Context) error {
+	chat, err := c.core.db.DeleteChat(ctx, c.chatID)
+	c.Result = chat
+	return err
+}
+
+type UpdateChatHistoryCommand struct {
+	core    *Core
+	chatID  int64
+	history models.History
+	Result  *models.Chat
+}
+
+// stores history strategy used when conversation in chat continues
+func NewUpdateChatHistoryCommand(
+	core *Core,
+	chatID int64,
+	history models.History,
+) *UpdateChatHistoryCommand {
+	return &UpdateChatHistoryCommand{
+		core:    core,
+		chatID:  chatID,
+		history: history,
+	}
+}
+
+func (c *UpdateChatHistoryCommand) Execute(ctx context.Context) error {
+	if err := validateHistory(c.history); err != nil {
+		return err
+	}
+	chat, err := c.core.db.UpdateChatHistory(ctx, c.chatID, c.history)
+	c.Result = chat
+	return err
+}
+
+type UpsertPersonaCommand struct {
+	core    *Core
+	persona *models.Persona
+	Result  *models.Persona
+}
+
+// persona with the same name is replaced
+func NewUpsertPersonaCommand(core *Core, persona *models.Persona) *UpsertPersonaCommand {
+	return &UpsertPersonaCommand{core: core, persona: persona}
+}
+
+func (c *UpsertPersonaCommand) Execute(ctx context.Context) error {
+	persona := *c.persona
+	persona.Name = strings.TrimSpace(persona.Name)
+	persona.Prompt = strings.TrimSpace(persona.Prompt)
+	if persona.Name == "" {
+		return fmt.Errorf("persona name cannot be empty\n")
+	}
+	if persona.Prompt == "" {
+		return fmt.Errorf("persona prompt cannot be empty\n")
+	}
+	res, err := c.core.db.UpsertPersona(ctx, &persona)
+	c.Result = res
+	return err
+}
+
+type DeletePersonaCommand struct {
+	core   *Core
+	name   string
+	Result *models.Persona
+}
+
+// chats with deleted persona continue without its prompt
+func NewDeletePersonaCommand(core *Core, name string) *DeletePersonaCommand {
+	return &DeletePersonaCommand{core: core, name: name}
+}
+
+func (c *DeletePersonaCommand) Execute(ctx context.Context) error {
+	persona, err := c.core.db.DeletePersonaByName(ctx, c.name)
+	c.Result = persona
+	return err
+}
+
+type GenerateChatTitleCommand struct {
+	core       *Core
+	chatID     int64
+	completion ai_clients.CompletionFn
+	Result     *models.Chat
+}
+
+// asks title model to name chat after its first exchange
+func NewGenerateChatTitleCommand(
+	core *Core,
+	chatID int64,
+	completion ai_clients.CompletionFn,
+) *GenerateChatTitleCommand {
+	return &GenerateChatTitleCommand{
+		core:       core,
+		chatID:     chatID,
+		completion: completion,
+	}
+}
+
+const (
+	titleMaxTokens     int64 = 20
+	titleContentLength       = 1000
+	titlePrompt              = `Write a short title (at most 6 words) for the conversation below. Reply with the title only, no quotes or punctuation at the end.
+
+User: %s
+
+Assistant: %s`
+)
+
+func (c *GenerateChatTitleCommand) Execute(ctx context.Context) error {
+	model := c.core.config.TitleModel
+	if model == "" {
+		return fmt.Errorf("title model is not configured\n")
+	}
+	messages, err := c.core.db.GetChatMessages(ctx, c.chatID)
+	if err != nil {
+		return err
+	}
+	var question, answer *models.Message
+	for _, message := range messages {
+		if question == nil && message.Role == UserRole {
+			question = message
+		}
+		if question != nil && message.Role == AssistantRole {
+			answer = message
+			break
+		}
+	}
+	if answer == nil {
+		return fmt.Errorf("chat with id %d has no answers to be titled\n", c.chatID)
+	}
+	maxTokens := titleMaxTokens
+	res, err := c.completion(
+		ctx,
+		[]*ai_clients.Message{{
+			Role: UserRole,
+			Content: fmt.Sprintf(
+				titlePrompt,
+				truncate(question.Content, titleContentLength),
+				truncate(answer.Content, titleContentLength),
+			),
+		}},
+		&ai_clients.Parameters{Model: model, MaxTokens: &maxTokens},
+		&c.core.config.Providers,
+		nil,
+	)
+	if err != nil {
+		return err
+	}
+	if err := c.core.db.CreateChatUsage(
+		ctx,
+		c.chatID,
+		models.UsagePurposeTitle,
+		completionUsage(model, res),
+	); err != nil {
+		return err
+	}
+	title := cleanTitle(res.Content)
+	if title == "" {
+		return fmt.Errorf("title model returned empty title\n")
+	}
+	chat, err := c.core.db.RenameChat(ctx, c.chatID, title)
+	c.Result = chat
+	return err
+}
+
+// first line without surrounding quotes and trailing dot
+func cleanTitle(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	s = strings.Trim(s, " \t\"'`*#")
+	return strings.TrimSuffix(s, ".")
+}
+
+func truncate(s string, length int) string {
+	runes := []rune(s)
+	if len(runes) <= length {
+		return s
+	}
+	return string(runes[:length])
+}
+
+type UpdateChatParametersCommand struct {
+	core       *Core
+	chatID     int64
+	parameters *ai_clients.Parameters
+	Result     *models.Chat
+}
+
+// only provided parameters are changed, the rest stays as stored
+func NewUpdateChatParametersCommand(
+	core *Core,
+	chatID int64,
+	parameters *ai_clients.Parameters,
+) *UpdateChatParametersCommand {
+	return &UpdateChatParametersCommand{
+		core:       core,
+		chatID:     chatID,
+		parameters: parameters,
+	}
+}
+
+func (c *UpdateChatParametersCommand) Execute(ctx context.Context) error {
+	chat, err := c.core.db.GetChatByID(ctx, c.chatID)
+	if err != nil {
+		return err
+	}
+	model, parameters := chatParameters(c.core.resolveParameters(chat, c.parameters))
+	chat, err = c.core.db.UpdateChatParameters(ctx, c.chatID, model, parameters)
+	c.Result = chat
+	return err
+}
+
 type UpdateWebSettingsCommand struct {
 	core        *Core
 	WebSettings models.WebSettings
@@ -233,14 +786,173 @@ func (c *UpsertTemplateCommand) Execute(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	template, err := c.core.db.UpsertTemplate(ctx, name, c.template)
+	dependencies, err := c.core.resolveTemplateDependencies(ctx, name, "", c.template)
+	if err != nil {
+		return err
+	}
+	template, err := c.core.db.UpsertTemplate(ctx, name, c.template, dependencies)
 	c.Result = template
 	return err
 }
 
+type ExportTemplatesCommand struct {
+	core   *Core
+	dir    string
+	Result []string
+}
+
+// writes every stored template into its own file inside dir, result holds
+// written paths sorted by template name
+func NewExportTemplatesCommand(core *Core, dir string) *ExportTemplatesCommand {
+	return &ExportTemplatesCommand{core: core, dir: dir}
+}
+
+func (c *ExportTemplatesCommand) Execute(ctx context.Context) error {
+	templates, err := c.core.db.GetTemplates(ctx, -1, -1, "")
+	if err != nil {
+		return err
+	}
+	slices.SortFunc(templates, func(a, b *models.Template) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return err
+	}
+	paths := []string{}
+	for _, template := range templates {
+		path := filepath.Join(c.dir, templateFileName(template.Name))
+		if err := os.WriteFile(path, []byte(template.Content), 0o644); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+	}
+	c.Result = paths
+	return nil
+}
+
+type ImportTemplatesCommand struct {
+	core   *Core
+	dir    string
+	prune  bool
+	Result *models.TemplateImport
+}
+
+// upserts template files from dir, names derive from template definitions
+func NewImportTemplatesCommand(core *Core, dir string) *ImportTemplatesCommand {
+	return &ImportTemplatesCommand{core: core, dir: dir}
+}
+
+// deletes stored templates that have no file in dir
+func (c *ImportTemplatesCommand) WithPrune(prune bool) {
+	c.prune = prune
+}
+
+func (c *ImportTemplatesCommand) Execute(ctx context.Context) error {
+	files, err := readTemplateFiles(c.dir)
+	if err != nil {
+		return err
+	}
+	stored, err := c.core.db.GetTemplates(ctx, -1, -1, "")
+	if err != nil {
+		return err
+	}
+	storedByName := map[string]*models.Template{}
+	for _, template := range stored {
+		storedByName[template.Name] = template
+	}
+	imported := map[string]bool{}
+	for _, file := range files {
+		imported[file.Name] = true
+	}
+	// validated upfront, so broken directory does not leave import half done
+	references := map[string][]string{}
+	for _, file := range files {
+		fileReferences, err := getTemplateReferences(file.Content)
+		if err != nil {
+			return err
+		}
+		references[file.Name] = fileReferences
+		for _, reference := range fileReferences {
+			if imported[reference] {
+				continue
+			}
+			if _, ok := storedByName[reference]; !ok {
+				return fmt.Errorf(
+					"template %q uses unknown template %q\n",
+					file.Name,
+					reference,
+				)
+			}
+			if c.prune {
+				return fmt.Errorf(
+					"template %q uses %q, which has no file and would be pruned\n",
+					file.Name,
+					reference,
+				)
+			}
+		}
+	}
+	ordered, err := orderTemplatesByDependencies(files)
+	if err != nil {
+		return err
+	}
+	dependencies, err := c.core.templateDependencies(ctx)
+	if err != nil {
+		return err
+	}
+	graph := templateGraph(dependencies, "")
+	for name, fileReferences := range references {
+		graph[name] = fileReferences
+	}
+	for _, file := range ordered {
+		if cycle := findTemplateCycle(graph, file.Name, []string{file.Name}); cycle != nil {
+			return fmt.Errorf(
+				"template dependency cycle: %s\n",
+				strings.Join(cycle, " -> "),
+			)
+		}
+	}
+	result := &models.TemplateImport{
+		Created:   []*models.Template{},
+		Updated:   []*models.Template{},
+		Unchanged: []*models.Template{},
+		Deleted:   []string{},
+	}
+	changed := []*models.Template{}
+	for _, file := range ordered {
+		if previous, ok := storedByName[file.Name]; ok && previous.Content == file.Content {
+			result.Unchanged = append(result.Unchanged, previous)
+			continue
+		}
+		changed = append(changed, file)
+	}
+	if c.prune {
+		for _, template := range stored {
+			if !imported[template.Name] {
+				result.Deleted = append(result.Deleted, template.Name)
+			}
+		}
+		slices.Sort(result.Deleted)
+	}
+	upserted, err := c.core.db.ImportTemplates(ctx, changed, references, result.Deleted)
+	if err != nil {
+		return err
+	}
+	for _, template := range upserted {
+		if _, exists := storedByName[template.Name]; exists {
+			result.Updated = append(result.Updated, template)
+		} else {
+			result.Created = append(result.Created, template)
+		}
+	}
+	c.Result = result
+	return nil
+}
+
 type DeleteTemplateByName struct {
-	core *Core
-	name string
+	core  *Core
+	name  string
+	force bool
 }
 
 func NewDeleteTemplateByName(core *Core, name string) *DeleteTemplateByName {
@@ -250,7 +962,25 @@ func NewDeleteTemplateByName(core *Core, name string) *DeleteTemplateByName {
 	}
 }
 
+// deletes template even if other templates use it
+func (c *DeleteTemplateByName) WithForce(force bool) {
+	c.force = force
+}
+
 func (c DeleteTemplateByName) Execute(ctx context.Context) error {
+	if !c.force {
+		dependencies, err := c.core.templateDependencies(ctx)
+		if err != nil {
+			return err
+		}
+		if dependents := directDependents(dependencies, c.name); len(dependents) > 0 {
+			return fmt.Errorf(
+				"template %q is used by: %s, use force to delete anyway\n",
+				c.name,
+				strings.Join(dependents, ", "),
+			)
+		}
+	}
 	ok, err := c.core.db.DeleteTemplateByName(ctx, c.name)
 	if !ok {
 		return fmt.Errorf("Failed. Template %q not found.", c.name)
@@ -319,7 +1049,178 @@ func (c *EditTemplateByName) handleClone(
 }
 
 func (c *EditTemplateByName) handleEdit(ctx context.Context, newName string) error {
-	tmp, err := c.core.db.EditTemplateByName(ctx, c.name, newName, c.content)
+	dependencies, err := c.core.resolveTemplateDependencies(ctx, newName, c.name, c.content)
+	if err != nil {
+		return err
+	}
+	tmp, err := c.core.db.EditTemplateByName(ctx, c.name, newName, c.content, dependencies)
 	c.Result = tmp
 	return err
 }
+
+type RollbackTemplateCommand struct {
+	core    *Core
+	name    string
+	version int64
+	Result  *models.Template
+}
+
+// restores content of given version, rollback itself is stored as new version
+func NewRollbackTemplateCommand(
+	core *Core,
+	name string,
+	version int64,
+) *RollbackTemplateCommand {
+	return &RollbackTemplateCommand{
+		core:    core,
+		name:    name,
+		version: version,
+	}
+}
+
+func (c *RollbackTemplateCommand) Execute(ctx context.Context) error {
+	version, err := c.core.db.GetTemplateVersion(ctx, c.name, c.version)
+	if err != nil {
+		return err
+	}
+	current, err := c.core.db.GetTemplatesByNames(ctx, []string{c.name})
+	if err != nil {
+		return err
+	}
+	if len(current) == 1 && current[0].Content == version.Content {
+		return fmt.Errorf("template %q already matches version %d\n", c.name, c.version)
+	}
+	edit := NewEditTemplateByName(c.core, c.name, version.Content, false)
+	if err := edit.Execute(ctx); err != nil {
+		return err
+	}
+	c.Result = edit.Result
+	return nil
+}
+
+type ImportChatCommand struct {
+	core   *Core
+	export *models.ChatExport
+	Result *CreateChatWithMessageCommandResult
+}
+
+// recreates exported chat, see ParseChatExport for accepted input,
+// result message is the last one in imported chat
+func NewImportChatCommand(core *Core, export *models.ChatExport) *ImportChatCommand {
+	return &ImportChatCommand{core: core, export: export}
+}
+
+func (c *ImportChatCommand) Execute(ctx context.Context) error {
+	if len(c.export.Messages) == 0 {
+		return fmt.Errorf("chat import has no messages\n")
+	}
+	if err := validateHistory(c.export.History); err != nil {
+		return err
+	}
+	export := *c.export
+	if export.Model == "" {
+		export.Model = c.core.defaultModel()
+	}
+	// same as new chats, named after first message
+	if export.Name == "" {
+		export.Name = export.Messages[0].Content
+	}
+	chat, err := c.core.db.ImportChat(ctx, &export)
+	if err != nil {
+		return err
+	}
+	messages, err := c.core.db.GetChatMessages(ctx, chat.ID)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("imported chat with id %d has no messages\n", chat.ID)
+	}
+	c.Result = &CreateChatWithMessageCommandResult{
+		Chat:    chat,
+		Message: messages[len(messages)-1],
+	}
+	return nil
+}
+
+type MigrateCommand struct {
+	core   *Core
+	steps  int
+	Result string // path of backup, empty when nothing changed
+}
+
+// positive steps apply migrations, negative roll back, zero applies every
+// pending migration
+func NewMigrateCommand(core *Core, steps int) *MigrateCommand {
+	return &MigrateCommand{core: core, steps: steps}
+}
+
+func (c *MigrateCommand) Execute(ctx context.Context) error {
+	backup, err := c.core.db.Migrate(ctx, c.steps)
+	c.Result = backup
+	return err
+}
+
+type ForceMigrationVersionCommand struct {
+	core    *Core
+	version int64
+	Result  string // path of backup
+}
+
+// marks version as applied and clean, nothing is migrated
+func NewForceMigrationVersionCommand(core *Core, version int64) *ForceMigrationVersionCommand {
+	return &ForceMigrationVersionCommand{core: core, version: version}
+}
+
+func (c *ForceMigrationVersionCommand) Execute(ctx context.Context) error {
+	backup, err := c.core.db.ForceMigrationVersion(ctx, c.version)
+	c.Result = backup
+	return err
+}
+
+type BackupDatabaseCommand struct {
+	core *Core
+	path string
+}
+
+// existing file is never overwritten
+func NewBackupDatabaseCommand(core *Core, path string) *BackupDatabaseCommand {
+	return &BackupDatabaseCommand{core: core, path: path}
+}
+
+func (c *BackupDatabaseCommand) Execute(ctx context.Context) error {
+	if c.path == "" {
+		return fmt.Errorf("backup path is empty\n")
+	}
+	return c.core.db.Backup(ctx, c.path)
+}
+
+type RestoreDatabaseCommand struct {
+	core   *Core
+	path   string
+	Result string // path of backup made from replaced database
+}
+
+// backup must be clean and not newer than known migrations, older schema is
+// migrated on next start
+func NewRestoreDatabaseCommand(core *Core, path string) *RestoreDatabaseCommand {
+	return &RestoreDatabaseCommand{core: core, path: path}
+}
+
+func (c *RestoreDatabaseCommand) Execute(ctx context.Context) error {
+	backup, err := c.core.db.Restore(ctx, c.path)
+	c.Result = backup
+	return err
+}
+
+type VacuumDatabaseCommand struct {
+	core *Core
+}
+
+func NewVacuumDatabaseCommand(core *Core) *VacuumDatabaseCommand {
+	return &VacuumDatabaseCommand{core: core}
+}
+
+func (c *VacuumDatabaseCommand) Execute(ctx context.Context) error {
+	return c.core.db.Vacuum(ctx)
+}
@@ -3,12 +3,15 @@ package core_test
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
 	"github.com/k10wl/hermes/internal/test_helpers"
 	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
 )
@@ -21,7 +24,7 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 		expectedResult models.Message
 	}
 	// XXX having one core instance and one database is fucking painful
-	coreInstance, _ := test_helpers.CreateCore()
+	coreInstance, db := test_helpers.CreateCore()
 	var currentCommand *core.CreateChatAndCompletionCommand
 
 	dbTemplates := map[string]string{
@@ -33,14 +36,28 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 		"loop2":   `--{{define "loop2"}}--{{template "loop1"}}--{{end}}`,
 	}
 
-	for _, template := range dbTemplates {
+	// dependencies have to be stored before templates that use them
+	for _, name := range []string{"welcome", "wrapper", "nested2", "nested1"} {
 		if err := core.NewUpsertTemplateCommand(
 			coreInstance,
-			template,
+			dbTemplates[name],
 		).Execute(context.Background()); err != nil {
 			panic(err)
 		}
 	}
+	// cycles are rejected on write, but may be left from data stored before
+	if _, err := db.Exec(
+		"INSERT INTO templates (id, name, content) VALUES (100, 'loop1', ?), (101, 'loop2', ?)",
+		dbTemplates["loop1"],
+		dbTemplates["loop2"],
+	); err != nil {
+		panic(err)
+	}
+	if _, err := db.Exec(
+		"INSERT INTO template_dependencies (core_id, linked_id) VALUES (100, 101), (101, 100)",
+	); err != nil {
+		panic(err)
+	}
 
 	table := []testCase{
 		{
@@ -57,10 +74,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ID:      2,
-				ChatID:  1,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello world",
+				ID:                 2,
+				ChatID:             1,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello world",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -77,10 +95,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ID:      4,
-				ChatID:  2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello world!",
+				ID:                 4,
+				ChatID:             2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello world!",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -97,10 +116,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ID:      6,
-				ChatID:  3,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello world!",
+				ID:                 6,
+				ChatID:             3,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello world!",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -117,10 +137,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ID:      8,
-				ChatID:  4,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello world! - wrapper",
+				ID:                 8,
+				ChatID:             4,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello world! - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -152,10 +173,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ID:      10,
-				ChatID:  5,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello world! - wrapper",
+				ID:                 10,
+				ChatID:             5,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello world! - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -217,10 +239,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  6,
-				ID:      12,
-				Role:    core.AssistantRole,
-				Content: "> mocked: should fill welcome (hello world!)(hello world!)",
+				ChatID:             6,
+				ID:                 12,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: should fill welcome (hello world!)(hello world!)",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -237,10 +260,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  7,
-				ID:      14,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - should fill welcome (hello world!)(hello world!) - wrapper",
+				ChatID:             7,
+				ID:                 14,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - should fill welcome (hello world!)(hello world!) - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -260,10 +284,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  8,
-				ID:      16,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello",
+				ChatID:             8,
+				ID:                 16,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -283,10 +308,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  9,
-				ID:      18,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello - wrapper",
+				ChatID:             9,
+				ID:                 18,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -306,10 +332,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  10,
-				ID:      20,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello",
+				ChatID:             10,
+				ID:                 20,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -331,10 +358,11 @@ func TestCreateChatAndCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  11,
-				ID:      22,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello - wrapper",
+				ChatID:             11,
+				ID:                 22,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 	}
@@ -401,10 +429,11 @@ func TestCreateCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  1,
-				ID:      2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello world",
+				ChatID:             1,
+				ID:                 2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello world",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -430,10 +459,11 @@ func TestCreateCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  1,
-				ID:      2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello",
+				ChatID:             1,
+				ID:                 2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -472,10 +502,11 @@ func TestCreateCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  1,
-				ID:      2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello - wrapper",
+				ChatID:             1,
+				ID:                 2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -503,10 +534,11 @@ func TestCreateCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  1,
-				ID:      2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: hello",
+				ChatID:             1,
+				ID:                 2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: hello",
+				SelectedGeneration: true,
 			},
 		},
 		{
@@ -546,10 +578,11 @@ func TestCreateCompletionCommand(t *testing.T) {
 			},
 			shouldError: false,
 			expectedResult: models.Message{
-				ChatID:  1,
-				ID:      2,
-				Role:    core.AssistantRole,
-				Content: "> mocked: wrapper - hello - wrapper",
+				ChatID:             1,
+				ID:                 2,
+				Role:               core.AssistantRole,
+				Content:            "> mocked: wrapper - hello - wrapper",
+				SelectedGeneration: true,
 			},
 		},
 	}
@@ -619,6 +652,320 @@ func TestCreateCompletionCommandStoreTemplate(t *testing.T) {
 	}
 }
 
+func TestCreateCompletionCommandWithVariables(t *testing.T) {
+	type testCase struct {
+		name      string
+		template  string
+		message   string
+		variables map[string]any
+		expected  string
+		errors    bool
+	}
+	table := []testCase{
+		{
+			name:      "should read variables while dot prints message",
+			template:  "review",
+			message:   "func main() {}",
+			variables: map[string]any{"language": "go", "strict": true},
+			expected:  "review go code: func main() {} (strict)",
+		},
+		{
+			name:      "should pass variables to nested templates",
+			template:  "nested",
+			message:   "content",
+			variables: map[string]any{"language": "js", "strict": false},
+			expected:  "[review js code: content]",
+		},
+		{
+			name:      "should keep dot as message for string functions",
+			template:  "shout",
+			message:   "content",
+			variables: map[string]any{"language": "go"},
+			expected:  "CONTENT (7) in go",
+		},
+		{
+			name:     "should print missing variable as no value",
+			template: "shout",
+			message:  "content",
+			expected: "CONTENT (7) in <no value>",
+		},
+		{
+			name:      "should ignore variables without template",
+			message:   "content",
+			variables: map[string]any{"language": "go"},
+			expected:  "content",
+		},
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	// upserted in dependency order, nested template is loaded through them
+	for _, template := range []string{
+		`--{{define "review"}}review --{{var "language"}} code: --{{.}}--{{if var "strict"}} (strict)--{{end}}--{{end}}`,
+		`--{{define "nested"}}[--{{template "review" .}}]--{{end}}`,
+		`--{{define "shout"}}--{{. | upper}} (--{{len .}}) in --{{var "language"}}--{{end}}`,
+	} {
+		if err := core.NewUpsertTemplateCommand(coreInstance, template).Execute(ctx); err != nil {
+			t.Fatalf("failed to create template, err: %s\n", err)
+		}
+	}
+
+	for _, test := range table {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			test.message,
+			test.template,
+			&ai_clients.Parameters{Model: "gpt-4o"},
+			test_helpers.MockCompletion,
+		)
+		cmd.WithVariables(test.variables)
+		err := cmd.Execute(ctx)
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got nil\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %s\n", test.name, err)
+			continue
+		}
+		dbMessages, err := db_helpers.GetMessagesByChatID(db, ctx, 1)
+		if err != nil {
+			t.Fatalf("failed to get created messages, err: %s\n", err)
+		}
+		// last two are user message and completion
+		actual := dbMessages[len(dbMessages)-2].Content
+		if actual != test.expected {
+			t.Errorf(
+				"%q bad stored message\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestCreateCompletionCommandStoreUsage(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	cmd := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		func(
+			ctx context.Context,
+			messages []*ai_clients.Message,
+			parameters *ai_clients.Parameters,
+			providers *settings.Providers,
+			onDelta ai_clients.OnDelta,
+		) (*ai_clients.AIResponse, error) {
+			return &ai_clients.AIResponse{
+				Message:     ai_clients.Message{Role: core.AssistantRole, Content: "answer"},
+				TokensUsage: ai_clients.TokensUsage{Input: 1_000_000, Output: 100_000},
+				Model:       "openai/gpt-4o-2024-08-06",
+			}, nil
+		},
+	)
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to execute command, err: %s\n", err)
+	}
+	var usage models.Usage
+	if err := db.QueryRow(
+		`SELECT message_id, model, input_tokens, output_tokens, cost FROM message_usage`,
+	).Scan(
+		&usage.MessageID,
+		&usage.Model,
+		&usage.InputTokens,
+		&usage.OutputTokens,
+		&usage.Cost,
+	); err != nil {
+		t.Fatalf("failed to read stored usage, err: %s\n", err)
+	}
+	expected := models.Usage{
+		MessageID:    cmd.Result.ID,
+		Model:        "openai/gpt-4o-2024-08-06",
+		InputTokens:  1_000_000,
+		OutputTokens: 100_000,
+		Cost:         3.5,
+	}
+	if !reflect.DeepEqual(expected, usage) {
+		t.Errorf("bad stored usage\nexpected: %+v\nactual:   %+v\n", expected, usage)
+	}
+}
+
+func TestCreateCompletionCommandStoreInterrupted(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		func(
+			ctx context.Context,
+			messages []*ai_clients.Message,
+			parameters *ai_clients.Parameters,
+			providers *settings.Providers,
+			onDelta ai_clients.OnDelta,
+		) (*ai_clients.AIResponse, error) {
+			onDelta("part")
+			cancel()
+			return &ai_clients.AIResponse{
+				Message:     ai_clients.Message{Role: core.AssistantRole, Content: "part"},
+				TokensUsage: ai_clients.TokensUsage{Input: 100, Output: 5},
+				Interrupted: true,
+			}, nil
+		},
+	)
+	cmd.Stream(func(string) {})
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to execute command, err: %s\n", err)
+	}
+	if !cmd.Result.Interrupted || cmd.Result.Content != "part" {
+		t.Errorf("expected interrupted partial answer, got %+v\n", cmd.Result)
+	}
+	var interrupted bool
+	if err := db.QueryRow(
+		`SELECT interrupted FROM messages WHERE id = $1`,
+		cmd.Result.ID,
+	).Scan(&interrupted); err != nil {
+		t.Fatalf("failed to read stored message, err: %s\n", err)
+	}
+	if !interrupted {
+		t.Errorf("expected stored message to be marked as interrupted\n")
+	}
+	var input, output int64
+	if err := db.QueryRow(
+		`SELECT input_tokens, output_tokens FROM message_usage WHERE message_id = $1`,
+		cmd.Result.ID,
+	).Scan(&input, &output); err != nil {
+		t.Fatalf("failed to read stored usage, err: %s\n", err)
+	}
+	if input != 100 || output != 5 {
+		t.Errorf("expected usage reported by stream, got input %d output %d\n", input, output)
+	}
+}
+
+func TestCreateCompletionCommandFailureLeavesNothing(t *testing.T) {
+	failing := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		return nil, fmt.Errorf("provider is down\n")
+	}
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	upload := func() int64 {
+		attachment := core.NewCreateAttachmentCommand(coreInstance, "notes.txt", []byte("notes"))
+		if err := attachment.Execute(ctx); err != nil {
+			t.Fatalf("failed to create attachment, err: %s\n", err)
+		}
+		return attachment.Result.ID
+	}
+	countRows := func(query string, args ...any) int {
+		var count int
+		if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+			t.Fatalf("failed to count rows, err: %s\n", err)
+		}
+		return count
+	}
+
+	existing := upload()
+	cmd := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		failing,
+	)
+	cmd.WithAttachments([]int64{existing})
+	if err := cmd.Execute(ctx); err == nil {
+		t.Fatalf("expected failed completion to error\n")
+	}
+	if count := countRows("SELECT COUNT(*) FROM messages WHERE chat_id = 1"); count != 0 {
+		t.Errorf("expected user message to be dropped with failed completion, got %d messages\n", count)
+	}
+	if count := countRows("SELECT COUNT(*) FROM attachments WHERE message_id IS NULL"); count != 1 {
+		t.Errorf("expected attachment to stay pending, got %d pending\n", count)
+	}
+
+	cmd = core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		failing,
+	)
+	cmd.WithUploads([]*models.Attachment{{Name: "file.txt", Data: []byte("file")}})
+	if err := cmd.Execute(ctx); err == nil {
+		t.Fatalf("expected failed completion to error\n")
+	}
+	if count := countRows("SELECT COUNT(*) FROM attachments"); count != 1 {
+		t.Errorf("expected file to be stored only with message, got %d attachments\n", count)
+	}
+
+	created := core.NewCreateChatWithMessageCommand(
+		coreInstance,
+		&models.Message{Role: core.UserRole, Content: "content"},
+		"",
+	)
+	created.WithAttachments([]int64{upload()})
+	if err := created.Execute(ctx); err != nil {
+		t.Fatalf("failed to create chat, err: %s\n", err)
+	}
+	cmd = core.NewCreateCompletionCommand(
+		coreInstance,
+		created.Result.Chat.ID,
+		core.UserRole,
+		"content",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		failing,
+	)
+	cmd.ShouldPersistUserMessage(false)
+	cmd.DiscardChatOnError(true)
+	if err := cmd.Execute(ctx); err == nil {
+		t.Fatalf("expected failed completion to error\n")
+	}
+	if count := countRows("SELECT COUNT(*) FROM chats WHERE id = $1", created.Result.Chat.ID); count != 0 {
+		t.Errorf("expected new chat to be removed with failed completion\n")
+	}
+	if count := countRows("SELECT COUNT(*) FROM messages"); count != 0 {
+		t.Errorf("expected messages of removed chat to be gone, got %d\n", count)
+	}
+	if count := countRows("SELECT COUNT(*) FROM attachments WHERE message_id IS NULL"); count != 2 {
+		t.Errorf("expected attachments of removed chat to be pending again, got %d pending\n", count)
+	}
+}
+
 func TestCreateChatWithMessageCommandStoreTemplate(t *testing.T) {
 	coreInstance, db := test_helpers.CreateCore()
 	ctx := context.Background()
@@ -744,8 +1091,8 @@ func TestDeleteTemplateByName(t *testing.T) {
 	var command core.DeleteTemplateByName
 
 	templates := []string{
-		`--{{define "welcome1"}}welcome{{end}}`,
-		`--{{define "welcome2"}}welcome{{end}}`,
+		`--{{define "welcome1"}}welcome--{{end}}`,
+		`--{{define "welcome2"}}welcome--{{end}}`,
 	}
 
 	for _, template := range templates {
@@ -953,6 +1300,217 @@ func TestEditTemplateByName(t *testing.T) {
 	}
 }
 
+func TestTemplateVersions(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	// seeded directly, without version history
+	if _, err := db.Exec(
+		"INSERT INTO templates (name, content) VALUES (?, ?)",
+		"welcome",
+		`--{{define "welcome"}}v1--{{end}}`,
+	); err != nil {
+		t.Fatalf("failed to seed template, err: %s\n", err)
+	}
+	steps := []func(context.Context) error{
+		core.NewUpsertTemplateCommand(coreInstance, `--{{define "welcome"}}v2--{{end}}`).Execute,
+		// identical content does not create version
+		core.NewUpsertTemplateCommand(coreInstance, `--{{define "welcome"}}v2--{{end}}`).Execute,
+		core.NewEditTemplateByName(coreInstance, "welcome", `--{{define "welcome"}}v3--{{end}}`, false).Execute,
+		core.NewRollbackTemplateCommand(coreInstance, "welcome", 1).Execute,
+	}
+	for i, step := range steps {
+		if err := step(ctx); err != nil {
+			t.Fatalf("failed step %d, err: %s\n", i, err)
+		}
+	}
+
+	history := core.NewGetTemplateHistoryQuery(coreInstance, "welcome")
+	if err := history.Execute(ctx); err != nil {
+		t.Fatalf("failed to get history, err: %s\n", err)
+	}
+	actual := []string{}
+	for _, version := range history.Result {
+		actual = append(actual, fmt.Sprintf("%d %s", version.Version, version.Content))
+	}
+	expected := []string{
+		`4 --{{define "welcome"}}v1--{{end}}`,
+		`3 --{{define "welcome"}}v3--{{end}}`,
+		`2 --{{define "welcome"}}v2--{{end}}`,
+		`1 --{{define "welcome"}}v1--{{end}}`,
+	}
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bad history\nexpected: %q\nactual:   %q\n", expected, actual)
+	}
+
+	diff := core.NewDiffTemplateQuery(coreInstance, "welcome", 2, 3)
+	if err := diff.Execute(ctx); err != nil {
+		t.Fatalf("failed to diff, err: %s\n", err)
+	}
+	expectedDiff := `--- welcome v2
++++ welcome v3
+@@ -1 +1 @@
+---{{define "welcome"}}v2--{{end}}
++--{{define "welcome"}}v3--{{end}}
+`
+	if diff.Result != expectedDiff {
+		t.Errorf("bad diff\nexpected:\n%s\nactual:\n%s\n", expectedDiff, diff.Result)
+	}
+
+	if err := core.NewRollbackTemplateCommand(coreInstance, "welcome", 1).
+		Execute(ctx); err == nil {
+		t.Errorf("expected rollback to current content to error\n")
+	}
+	if err := core.NewRollbackTemplateCommand(coreInstance, "welcome", 10).
+		Execute(ctx); err == nil {
+		t.Errorf("expected rollback to missing version to error\n")
+	}
+	if err := core.NewGetTemplateHistoryQuery(coreInstance, "missing").
+		Execute(ctx); err == nil {
+		t.Errorf("expected history of missing template to error\n")
+	}
+}
+
+func TestTemplateDependencies(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	ctx := context.Background()
+	upsert := func(content string) error {
+		return core.NewUpsertTemplateCommand(coreInstance, content).Execute(ctx)
+	}
+	for _, content := range []string{
+		`--{{define "c"}}c--{{end}}`,
+		`--{{define "b"}}b --{{template "c" .}}--{{end}}`,
+		`--{{define "a"}}--{{if .}}--{{template "b" .}}--{{else}}--{{template "c"}}--{{end}}--{{end}}`,
+	} {
+		if err := upsert(content); err != nil {
+			t.Fatalf("failed to upsert template, err: %s\n", err)
+		}
+	}
+
+	type testCase struct {
+		name          string
+		run           func() error
+		expectedError string
+	}
+	table := []testCase{
+		{
+			name:          "should reject unknown dependency",
+			run:           func() error { return upsert(`--{{define "d"}}--{{template "missing"}}--{{end}}`) },
+			expectedError: `template "d" uses unknown template "missing"`,
+		},
+		{
+			name: "should reject cycle on edit",
+			run: func() error {
+				return core.NewEditTemplateByName(
+					coreInstance,
+					"c",
+					`--{{define "c"}}--{{template "a"}}--{{end}}`,
+					false,
+				).Execute(ctx)
+			},
+			expectedError: "template dependency cycle: c -> a -> b -> c",
+		},
+		{
+			name: "should reject rename of used template",
+			run: func() error {
+				return core.NewEditTemplateByName(
+					coreInstance,
+					"c",
+					`--{{define "renamed"}}c--{{end}}`,
+					false,
+				).Execute(ctx)
+			},
+			expectedError: `cannot rename template "c", it is used by: a, b`,
+		},
+		{
+			name:          "should refuse delete of used template",
+			run:           func() error { return core.NewDeleteTemplateByName(coreInstance, "b").Execute(ctx) },
+			expectedError: `template "b" is used by: a`,
+		},
+		{
+			name: "should allow self contained recursion",
+			run: func() error {
+				return upsert(`--{{define "e"}}--{{if .}}--{{template "e"}}--{{end}}--{{end}}`)
+			},
+		},
+	}
+	for _, test := range table {
+		err := test.run()
+		if test.expectedError == "" {
+			if err != nil {
+				t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+			t.Errorf(
+				"%q - bad error\nexpected: %q\nactual:   %v\n",
+				test.name,
+				test.expectedError,
+				err,
+			)
+		}
+	}
+
+	deps := core.NewGetTemplateDependenciesQuery(coreInstance, "b")
+	if err := deps.Execute(ctx); err != nil {
+		t.Fatalf("failed to get dependencies, err: %s\n", err)
+	}
+	expected := &models.TemplateDependencies{
+		Name:         "b",
+		Dependencies: []*models.TemplateTree{{Name: "c", Children: []*models.TemplateTree{}}},
+		Dependents:   []*models.TemplateTree{{Name: "a", Children: []*models.TemplateTree{}}},
+	}
+	if !reflect.DeepEqual(expected, deps.Result) {
+		t.Errorf("bad dependencies\nexpected: %+v\nactual:   %+v\n", expected, deps.Result)
+	}
+
+	force := core.NewDeleteTemplateByName(coreInstance, "c")
+	force.WithForce(true)
+	if err := force.Execute(ctx); err != nil {
+		t.Fatalf("failed to force delete, err: %s\n", err)
+	}
+	if err := deps.Execute(ctx); err != nil {
+		t.Fatalf("failed to get dependencies, err: %s\n", err)
+	}
+	if len(deps.Result.Dependencies) != 1 || !deps.Result.Dependencies[0].Deleted {
+		t.Errorf("expected force deleted dependency to be marked, got %+v\n", deps.Result.Dependencies)
+	}
+}
+
+func TestTemplateDependenciesWithoutStoredEdges(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	// templates stored before dependencies were tracked have no edges
+	for _, template := range []*models.Template{
+		{Name: "c", Content: `--{{define "c"}}c--{{end}}`},
+		{Name: "b", Content: `--{{define "b"}}b --{{template "c" .}}--{{end}}`},
+		{Name: "a", Content: `--{{define "a"}}--{{template "b" .}}--{{end}}`},
+	} {
+		if err := db_helpers.CreateTemplate(db, ctx, template); err != nil {
+			t.Fatalf("failed to create template, err: %s\n", err)
+		}
+	}
+
+	err := core.NewDeleteTemplateByName(coreInstance, "b").Execute(ctx)
+	if expected := `template "b" is used by: a`; err == nil ||
+		!strings.Contains(err.Error(), expected) {
+		t.Errorf("bad delete error\nexpected: %q\nactual:   %v\n", expected, err)
+	}
+
+	deps := core.NewGetTemplateDependenciesQuery(coreInstance, "b")
+	if err := deps.Execute(ctx); err != nil {
+		t.Fatalf("failed to get dependencies, err: %s\n", err)
+	}
+	expected := &models.TemplateDependencies{
+		Name:         "b",
+		Dependencies: []*models.TemplateTree{{Name: "c", Children: []*models.TemplateTree{}}},
+		Dependents:   []*models.TemplateTree{{Name: "a", Children: []*models.TemplateTree{}}},
+	}
+	if !reflect.DeepEqual(expected, deps.Result) {
+		t.Errorf("bad dependencies\nexpected: %+v\nactual:   %+v\n", expected, deps.Result)
+	}
+}
+
 func TestCreateChat(t *testing.T) {
 	c, db := test_helpers.CreateCore()
 	cmd := core.NewCreateChatWithMessageCommand(c, &models.Message{
@@ -978,6 +1536,69 @@ func TestCreateChat(t *testing.T) {
 	}
 }
 
+func TestCreateChatStoresParameters(t *testing.T) {
+	type testCase struct {
+		name       string
+		parameters *ai_clients.Parameters
+		expected   models.Chat
+	}
+
+	temperature := 0.3
+	maxTokens := int64(100)
+
+	table := []testCase{
+		{
+			name:       "should store default model when parameters are omitted",
+			parameters: nil,
+			expected:   models.Chat{ID: 1, Name: "hello", Model: core.DefaultModel},
+		},
+		{
+			name: "should store provided parameters",
+			parameters: &ai_clients.Parameters{
+				Model:       "anthropic/claude-haiku",
+				Temperature: &temperature,
+				MaxTokens:   &maxTokens,
+			},
+			expected: models.Chat{
+				ID:    1,
+				Name:  "hello",
+				Model: "anthropic/claude-haiku",
+				CompletionParameters: models.CompletionParameters{
+					Temperature: &temperature,
+					MaxTokens:   &maxTokens,
+				},
+			},
+		},
+	}
+
+	for _, test := range table {
+		c, db := test_helpers.CreateCore()
+		cmd := core.NewCreateChatWithMessageCommand(c, &models.Message{
+			Role:    core.UserRole,
+			Content: "hello",
+		}, "")
+		cmd.WithParameters(test.parameters)
+		if err := cmd.Execute(context.Background()); err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		dbChat, err := db_helpers.GetChatByID(db, context.Background(), 1)
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		dbChat.TimestampsToNilForTest__()
+		if !reflect.DeepEqual(test.expected, *dbChat) {
+			t.Errorf(
+				"%q - bad stored chat\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				*dbChat,
+			)
+		}
+	}
+}
+
 func TestCreateChatNames(t *testing.T) {
 	tooLong := "toolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolongtoolong"
 
@@ -1090,3 +1711,562 @@ func TestCreateChatNames(t *testing.T) {
 		}
 	}
 }
+
+func TestRegenerateMessageCommand(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	for _, content := range []string{"first", "second"} {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			content,
+			"",
+			&ai_clients.Parameters{Model: "openai/gpt-4o"},
+			test_helpers.MockCompletion,
+		)
+		if err := cmd.Execute(ctx); err != nil {
+			t.Fatalf("failed to create completion, err: %s\n", err)
+		}
+	}
+
+	var history []string
+	regenerated := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		history = []string{}
+		for _, message := range messages {
+			history = append(history, message.Content)
+		}
+		return &ai_clients.AIResponse{
+			Message: ai_clients.Message{Role: core.AssistantRole, Content: "regenerated"},
+		}, nil
+	}
+
+	chatContents := func() []string {
+		query := core.GetChatMessagesQuery{Core: coreInstance, ChatID: 1}
+		if err := query.Execute(ctx); err != nil {
+			t.Fatalf("failed to get chat messages, err: %s\n", err)
+		}
+		res := []string{}
+		for _, message := range query.Result {
+			res = append(res, message.Content)
+		}
+		return res
+	}
+
+	if err := core.NewRegenerateMessageCommand(
+		coreInstance,
+		1,
+		nil,
+		regenerated,
+	).Execute(ctx); err == nil {
+		t.Errorf("expected to error upon regeneration of user message\n")
+	}
+
+	cmd := core.NewRegenerateMessageCommand(coreInstance, 2, nil, regenerated)
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to regenerate message, err: %s\n", err)
+	}
+	expectedResult := models.Message{
+		ID:                 5,
+		ChatID:             1,
+		Role:               core.AssistantRole,
+		Content:            "regenerated",
+		Generation:         1,
+		SelectedGeneration: true,
+	}
+	cmd.Result.TimestampsToNilForTest__()
+	if !reflect.DeepEqual(expectedResult, *cmd.Result) {
+		t.Errorf(
+			"bad regeneration result\nexpected: %+v\nactual:   %+v\n",
+			expectedResult,
+			*cmd.Result,
+		)
+	}
+	if expected := []string{"first"}; !reflect.DeepEqual(expected, history) {
+		t.Errorf("bad regeneration history\nexpected: %q\nactual:   %q\n", expected, history)
+	}
+	expected := []string{"first", "regenerated", "second", "> mocked: first"}
+	if actual := chatContents(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bad chat after regeneration\nexpected: %q\nactual:   %q\n", expected, actual)
+	}
+
+	generations := core.NewGetMessageGenerationsQuery(coreInstance, 5)
+	if err := generations.Execute(ctx); err != nil {
+		t.Fatalf("failed to get generations, err: %s\n", err)
+	}
+	selected := []bool{}
+	for _, generation := range generations.Result {
+		selected = append(selected, generation.SelectedGeneration)
+	}
+	if expected := []bool{false, true}; !reflect.DeepEqual(expected, selected) {
+		t.Errorf("bad generations\nexpected: %v\nactual:   %v\n", expected, selected)
+	}
+
+	if err := core.NewSelectGenerationCommand(coreInstance, 2).Execute(ctx); err != nil {
+		t.Fatalf("failed to select generation, err: %s\n", err)
+	}
+	expected = []string{"first", "> mocked: first", "second", "> mocked: first"}
+	if actual := chatContents(); !reflect.DeepEqual(expected, actual) {
+		t.Errorf("bad chat after selection\nexpected: %q\nactual:   %q\n", expected, actual)
+	}
+
+	if err := core.NewSelectGenerationCommand(coreInstance, 999).Execute(ctx); err == nil {
+		t.Errorf("expected to error upon selection of non existing message\n")
+	}
+}
+
+func TestRegenerateMessageCommandInterrupted(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	if err := db_helpers.NewSeeder(db, context.Background()).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	completion := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"first",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		test_helpers.MockCompletion,
+	)
+	if err := completion.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to create completion, err: %s\n", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := core.NewRegenerateMessageCommand(
+		coreInstance,
+		completion.Result.ID,
+		nil,
+		func(
+			ctx context.Context,
+			messages []*ai_clients.Message,
+			parameters *ai_clients.Parameters,
+			providers *settings.Providers,
+			onDelta ai_clients.OnDelta,
+		) (*ai_clients.AIResponse, error) {
+			onDelta("part")
+			cancel()
+			return &ai_clients.AIResponse{
+				Message:     ai_clients.Message{Role: core.AssistantRole, Content: "part"},
+				TokensUsage: ai_clients.TokensUsage{Input: 100, Output: 5},
+				Interrupted: true,
+			}, nil
+		},
+	)
+	cmd.Stream(func(string) {})
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to regenerate message, err: %s\n", err)
+	}
+	if !cmd.Result.Interrupted || cmd.Result.SelectedGeneration {
+		t.Errorf("expected interrupted generation to be stored aside, got %+v\n", cmd.Result)
+	}
+
+	generations := core.NewGetMessageGenerationsQuery(coreInstance, completion.Result.ID)
+	if err := generations.Execute(context.Background()); err != nil {
+		t.Fatalf("failed to get generations, err: %s\n", err)
+	}
+	selected := []bool{}
+	for _, generation := range generations.Result {
+		selected = append(selected, generation.SelectedGeneration)
+	}
+	if expected := []bool{true, false}; !reflect.DeepEqual(expected, selected) {
+		t.Errorf("expected previous generation to remain selected\nexpected: %v\nactual:   %v\n", expected, selected)
+	}
+
+	var input, output int64
+	if err := db.QueryRow(
+		`SELECT input_tokens, output_tokens FROM message_usage WHERE message_id = $1`,
+		cmd.Result.ID,
+	).Scan(&input, &output); err != nil {
+		t.Fatalf("failed to read stored usage, err: %s\n", err)
+	}
+	if input != 100 || output != 5 {
+		t.Errorf("expected usage reported by stream, got input %d output %d\n", input, output)
+	}
+}
+
+func TestForkChatCommand(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	for _, content := range []string{"first", "second"} {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			content,
+			"",
+			&ai_clients.Parameters{Model: "openai/gpt-4o"},
+			test_helpers.MockCompletion,
+		)
+		if err := cmd.Execute(ctx); err != nil {
+			t.Fatalf("failed to create completion, err: %s\n", err)
+		}
+	}
+	// message 5 replaces message 2 in chat history
+	if err := core.NewRegenerateMessageCommand(
+		coreInstance,
+		2,
+		nil,
+		test_helpers.MockCompletion,
+	).Execute(ctx); err != nil {
+		t.Fatalf("failed to regenerate message, err: %s\n", err)
+	}
+
+	type testCase struct {
+		name             string
+		chatID           int64
+		messageID        int64
+		expectedContents []string
+		shouldError      bool
+	}
+
+	table := []testCase{
+		{
+			name:             "should copy selected history up to message",
+			chatID:           1,
+			messageID:        3,
+			expectedContents: []string{"first", "> mocked: first", "second"},
+		},
+		{
+			name:             "should fork from not selected generation",
+			chatID:           1,
+			messageID:        2,
+			expectedContents: []string{"first", "> mocked: first"},
+		},
+		{
+			name:        "should error when message belongs to another chat",
+			chatID:      2,
+			messageID:   3,
+			shouldError: true,
+		},
+		{
+			name:        "should error on non existing message",
+			chatID:      1,
+			messageID:   999,
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		cmd := core.NewForkChatCommand(coreInstance, test.chatID, test.messageID)
+		err := cmd.Execute(ctx)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		chat := cmd.Result.Chat
+		if chat.ParentChatID == nil || *chat.ParentChatID != test.chatID ||
+			chat.ParentMessageID == nil || *chat.ParentMessageID != test.messageID ||
+			chat.Model != "openai/gpt-4o" {
+			t.Errorf("%q - bad forked chat: %+v\n", test.name, chat)
+		}
+		query := core.GetChatMessagesQuery{Core: coreInstance, ChatID: chat.ID}
+		if err := query.Execute(ctx); err != nil {
+			t.Fatalf("%q - failed to get chat messages, err: %s\n", test.name, err)
+		}
+		contents := []string{}
+		for _, message := range query.Result {
+			contents = append(contents, message.Content)
+		}
+		if !reflect.DeepEqual(test.expectedContents, contents) {
+			t.Errorf(
+				"%q - bad forked messages\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expectedContents,
+				contents,
+			)
+		}
+		if cmd.Result.Message.ID != query.Result[len(query.Result)-1].ID {
+			t.Errorf("%q - result message is not the last one in fork\n", test.name)
+		}
+		chats := core.NewGetChatsQuery(coreInstance, 1, 0)
+		if err := chats.Execute(ctx); err != nil {
+			t.Fatalf("%q - failed to get chats, err: %s\n", test.name, err)
+		}
+		if len(chats.Result) != 1 || chats.Result[0].ID != chat.ID {
+			t.Errorf("%q - forked chat is not listed in chats\n", test.name)
+		}
+	}
+}
+
+func TestGenerateChatTitleCommand(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+
+	var parameters *ai_clients.Parameters
+	var prompt string
+	titled := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		p *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		parameters = p
+		prompt = messages[0].Content
+		return &ai_clients.AIResponse{
+			Message: ai_clients.Message{
+				Role:    core.AssistantRole,
+				Content: "\"Mass of the Sun.\"\nsecond line is ignored",
+			},
+			TokensUsage: ai_clients.TokensUsage{Input: 100, Output: 5},
+		}, nil
+	}
+
+	coreInstance.GetConfig().TitleModel = ""
+	if err := core.NewGenerateChatTitleCommand(coreInstance, 1, titled).
+		Execute(ctx); err == nil {
+		t.Errorf("expected to error when title model is not configured\n")
+	}
+
+	coreInstance.GetConfig().TitleModel = "openai/gpt-4o-mini"
+	if err := core.NewGenerateChatTitleCommand(coreInstance, 1, titled).
+		Execute(ctx); err == nil {
+		t.Errorf("expected to error when chat has no answers\n")
+	}
+
+	completion := core.NewCreateCompletionCommand(
+		coreInstance,
+		1,
+		core.UserRole,
+		"what is the mass of the Sun?",
+		"",
+		&ai_clients.Parameters{Model: "openai/gpt-4o"},
+		test_helpers.MockCompletion,
+	)
+	if err := completion.Execute(ctx); err != nil {
+		t.Fatalf("failed to create completion, err: %s\n", err)
+	}
+
+	cmd := core.NewGenerateChatTitleCommand(coreInstance, 1, titled)
+	if err := cmd.Execute(ctx); err != nil {
+		t.Fatalf("failed to generate title, err: %s\n", err)
+	}
+	if cmd.Result.Name != "Mass of the Sun" {
+		t.Errorf("bad title\nexpected: %q\nactual:   %q\n", "Mass of the Sun", cmd.Result.Name)
+	}
+	if parameters.Model != "openai/gpt-4o-mini" {
+		t.Errorf("expected title model to be used, got %q\n", parameters.Model)
+	}
+	if !strings.Contains(prompt, "what is the mass of the Sun?") ||
+		!strings.Contains(prompt, "> mocked: what is the mass of the Sun?") {
+		t.Errorf("expected prompt to contain first exchange, got %q\n", prompt)
+	}
+	chat := core.NewGetChatByIDQuery(coreInstance, 1)
+	if err := chat.Execute(ctx); err != nil {
+		t.Fatalf("failed to get chat, err: %s\n", err)
+	}
+	if chat.Result.Name != "Mass of the Sun" {
+		t.Errorf("expected title to be stored, got %q\n", chat.Result.Name)
+	}
+	usage := core.NewGetUsageReportQuery(coreInstance, models.UsageByModel)
+	if err := usage.Execute(ctx); err != nil {
+		t.Fatalf("failed to get usage report, err: %s\n", err)
+	}
+	var titleUsage *models.UsageReport
+	for _, report := range usage.Result {
+		if report.Key == "openai/gpt-4o-mini" {
+			titleUsage = report
+		}
+	}
+	if titleUsage == nil || titleUsage.InputTokens != 100 ||
+		titleUsage.OutputTokens != 5 || titleUsage.Messages != 0 {
+		t.Errorf("expected title usage without messages, got %+v\n", titleUsage)
+	}
+}
+
+func TestPersonaCommands(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	ctx := context.Background()
+	temperature := 0.2
+
+	if err := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name:   " ",
+		Prompt: "prompt",
+	}).Execute(ctx); err == nil {
+		t.Errorf("expected to error upon empty persona name\n")
+	}
+	if err := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name: "reviewer",
+	}).Execute(ctx); err == nil {
+		t.Errorf("expected to error upon empty persona prompt\n")
+	}
+
+	create := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name:   "reviewer",
+		Prompt: "be strict",
+	})
+	if err := create.Execute(ctx); err != nil {
+		t.Fatalf("failed to create persona, err: %s\n", err)
+	}
+	update := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name:                 "reviewer",
+		Prompt:               " be very strict ",
+		Model:                "openai/o1",
+		CompletionParameters: models.CompletionParameters{Temperature: &temperature},
+	})
+	if err := update.Execute(ctx); err != nil {
+		t.Fatalf("failed to update persona, err: %s\n", err)
+	}
+	if update.Result.ID != create.Result.ID {
+		t.Errorf("expected persona id to be kept upon update, got %d and %d\n", create.Result.ID, update.Result.ID)
+	}
+
+	query := core.NewGetPersonaByNameQuery(coreInstance, "reviewer")
+	if err := query.Execute(ctx); err != nil {
+		t.Fatalf("failed to get persona, err: %s\n", err)
+	}
+	query.Result.Timestamps = models.Timestamps{}
+	expected := models.Persona{
+		ID:                   create.Result.ID,
+		Name:                 "reviewer",
+		Prompt:               "be very strict",
+		Model:                "openai/o1",
+		CompletionParameters: models.CompletionParameters{Temperature: &temperature},
+	}
+	if !reflect.DeepEqual(expected, *query.Result) {
+		t.Errorf("bad persona\nexpected: %+v\nactual:   %+v\n", expected, *query.Result)
+	}
+
+	if err := core.NewDeletePersonaCommand(coreInstance, "reviewer").Execute(ctx); err != nil {
+		t.Fatalf("failed to delete persona, err: %s\n", err)
+	}
+	if err := core.NewDeletePersonaCommand(coreInstance, "reviewer").Execute(ctx); err == nil {
+		t.Errorf("expected to error upon deletion of missing persona\n")
+	}
+	list := core.NewGetPersonasQuery(coreInstance)
+	if err := list.Execute(ctx); err != nil {
+		t.Fatalf("failed to list personas, err: %s\n", err)
+	}
+	if len(list.Result) != 0 {
+		t.Errorf("expected deleted persona to be hidden, got %+v\n", list.Result)
+	}
+}
+
+func TestChatWithPersona(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name:   "reviewer",
+		Prompt: "be strict",
+		Model:  "anthropic/claude-3-7-sonnet",
+	}).Execute(ctx); err != nil {
+		t.Fatalf("failed to create persona, err: %s\n", err)
+	}
+	if err := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+		Name:   "poet",
+		Prompt: "rhyme",
+	}).Execute(ctx); err != nil {
+		t.Fatalf("failed to create persona, err: %s\n", err)
+	}
+
+	var sent []string
+	var model string
+	completion := func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		sent = []string{}
+		for _, message := range messages {
+			sent = append(sent, message.Role+": "+message.Content)
+		}
+		model = parameters.Model
+		return &ai_clients.AIResponse{
+			Message: ai_clients.Message{Role: core.AssistantRole, Content: "ok"},
+		}, nil
+	}
+	complete := func(content string, persona string, parameters *ai_clients.Parameters) {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			content,
+			"",
+			parameters,
+			completion,
+		)
+		cmd.WithPersona(persona)
+		if err := cmd.Execute(ctx); err != nil {
+			t.Fatalf("failed to complete %q, err: %s\n", content, err)
+		}
+	}
+
+	create := core.NewCreateChatWithMessageCommand(
+		coreInstance,
+		&models.Message{Role: core.UserRole, Content: "first"},
+		"",
+	)
+	create.WithPersona("missing")
+	if err := create.Execute(ctx); err == nil {
+		t.Errorf("expected to error upon missing persona\n")
+	}
+	create.WithPersona("reviewer")
+	if err := create.Execute(ctx); err != nil {
+		t.Fatalf("failed to create chat, err: %s\n", err)
+	}
+	if create.Result.Chat.Model != "anthropic/claude-3-7-sonnet" || create.Result.Chat.PersonaID == nil {
+		t.Errorf("expected chat to adopt persona, got %+v\n", create.Result.Chat)
+	}
+
+	complete("second", "", nil)
+	expected := []string{"system: be strict", "user: first", "user: second"}
+	if !reflect.DeepEqual(expected, sent) {
+		t.Errorf("bad messages sent with persona\nexpected: %q\nactual:   %q\n", expected, sent)
+	}
+	if model != "anthropic/claude-3-7-sonnet" {
+		t.Errorf("expected persona model to be used, got %q\n", model)
+	}
+
+	complete("third", "", &ai_clients.Parameters{Model: "openai/gpt-4o"})
+	if model != "openai/gpt-4o" {
+		t.Errorf("expected explicit model to take precedence, got %q\n", model)
+	}
+
+	complete("fourth", "poet", nil)
+	if sent[0] != "system: rhyme" {
+		t.Errorf("expected attached persona prompt, got %q\n", sent)
+	}
+	if model != "anthropic/claude-3-7-sonnet" {
+		t.Errorf("expected persona without model to keep chat model, got %q\n", model)
+	}
+
+	if err := core.NewDeletePersonaCommand(coreInstance, "poet").Execute(ctx); err != nil {
+		t.Fatalf("failed to delete persona, err: %s\n", err)
+	}
+	complete("fifth", "", nil)
+	if sent[0] == "system: rhyme" {
+		t.Errorf("expected deleted persona not to be applied, got %q\n", sent)
+	}
+	chat := core.NewGetChatByIDQuery(coreInstance, 1)
+	if err := chat.Execute(ctx); err != nil {
+		t.Fatalf("failed to get chat, err: %s\n", err)
+	}
+	if chat.Result.PersonaID != nil {
+		t.Errorf("expected deleted persona to be detached from chat, got %d\n", *chat.Result.PersonaID)
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"context"
+	"sync"
+
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/db"
 	"github.com/k10wl/hermes/internal/models"
@@ -13,18 +16,36 @@ const (
 	SystemRole    = "system"
 )
 
+// used when neither request nor chat specify model
+const DefaultModel = "openai/gpt-4o-mini"
+
 type Core struct {
-	db     db.Client
-	config *settings.Config
+	db         db.Client
+	config     *settings.Config
+	background *sync.WaitGroup
 }
 
 func NewCore(db db.Client, config *settings.Config) *Core {
 	return &Core{
-		db:     db,
-		config: config,
+		db:         db,
+		config:     config,
+		background: &sync.WaitGroup{},
 	}
 }
 
+// runs task without blocking caller, process should WaitBackground before exit
+func (c Core) Background(task func()) {
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		task()
+	}()
+}
+
+func (c Core) WaitBackground() {
+	c.background.Wait()
+}
+
 func (c Core) GetConfig() *settings.Config {
 	return c.config
 }
@@ -35,7 +56,106 @@ func (c Core) GetDB() db.Client {
 
 func messageToAIMessage(m *models.Message) *ai_clients.Message {
 	return &ai_clients.Message{
-		Content: m.Content,
-		Role:    m.Role,
+		Content:     m.Content,
+		Role:        m.Role,
+		Attachments: attachmentsToAIAttachments(m.Attachments),
+	}
+}
+
+// stores assistant message together with spent tokens and cost, question is
+// user message that is stored along with it, nil when it is stored already
+func (c Core) persistCompletion(
+	ctx context.Context,
+	chatID int64,
+	question *models.Message,
+	requestedModel string,
+	res *ai_clients.AIResponse,
+) (*models.Message, error) {
+	ctx = completionContext(ctx, res)
+	usage := completionUsage(requestedModel, res)
+	if question == nil {
+		return c.db.CreateMessageWithUsage(
+			ctx,
+			chatID,
+			res.Role,
+			res.Content,
+			res.Interrupted,
+			usage,
+		)
+	}
+	_, message, err := c.db.CreateMessageAndCompletion(
+		ctx,
+		question,
+		&models.Message{
+			ChatID:      chatID,
+			Role:        res.Role,
+			Content:     res.Content,
+			Interrupted: res.Interrupted,
+		},
+		usage,
+	)
+	return message, err
+}
+
+// interrupted completion is stored after its context was cancelled
+func completionContext(ctx context.Context, res *ai_clients.AIResponse) context.Context {
+	if res.Interrupted {
+		return context.WithoutCancel(ctx)
+	}
+	return ctx
+}
+
+func completionUsage(requestedModel string, res *ai_clients.AIResponse) *models.Usage {
+	model := res.Model
+	if model == "" {
+		model = requestedModel
+	}
+	return &models.Usage{
+		Model:        model,
+		InputTokens:  res.TokensUsage.Input,
+		OutputTokens: res.TokensUsage.Output,
+		Cost:         ai_clients.Cost(model, res.TokensUsage),
+	}
+}
+
+// model configured in profile, falls back to DefaultModel
+func (c Core) defaultModel() string {
+	if c.config.DefaultModel != "" {
+		return c.config.DefaultModel
+	}
+	return DefaultModel
+}
+
+// explicitly provided parameters take precedence over stored in chat
+func (c Core) resolveParameters(
+	chat *models.Chat,
+	override *ai_clients.Parameters,
+) *ai_clients.Parameters {
+	res := &ai_clients.Parameters{
+		Model:       chat.Model,
+		MaxTokens:   chat.MaxTokens,
+		Temperature: chat.Temperature,
+	}
+	if override != nil {
+		if override.Model != "" {
+			res.Model = override.Model
+		}
+		if override.MaxTokens != nil {
+			res.MaxTokens = override.MaxTokens
+		}
+		if override.Temperature != nil {
+			res.Temperature = override.Temperature
+		}
+	}
+	if res.Model == "" {
+		res.Model = c.defaultModel()
+	}
+	return res
+}
+
+func chatParameters(parameters *ai_clients.Parameters) (string, models.CompletionParameters) {
+	return parameters.Model, models.CompletionParameters{
+		MaxTokens:   parameters.MaxTokens,
+		Temperature: parameters.Temperature,
 	}
 }
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+const diffContextLines = 3
+
+type diffLine struct {
+	op   byte // ' ', '-' or '+'
+	text string
+}
+
+// unified diff of two texts, empty when texts are equal
+func unifiedDiff(fromLabel string, from string, toLabel string, to string) string {
+	lines := diffLines(splitLines(from), splitLines(to))
+	hunks := diffHunks(lines)
+	if len(hunks) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromLabel, toLabel)
+	for _, hunk := range hunks {
+		b.WriteString(hunk)
+	}
+	return b.String()
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// longest common subsequence walk, deletions go before insertions
+func diffLines(from []string, to []string) []diffLine {
+	lcs := make([][]int, len(from)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(to)+1)
+	}
+	for i := len(from) - 1; i >= 0; i-- {
+		for j := len(to) - 1; j >= 0; j-- {
+			if from[i] == to[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	res := []diffLine{}
+	i, j := 0, 0
+	for i < len(from) && j < len(to) {
+		switch {
+		case from[i] == to[j]:
+			res = append(res, diffLine{' ', from[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			res = append(res, diffLine{'-', from[i]})
+			i++
+		default:
+			res = append(res, diffLine{'+', to[j]})
+			j++
+		}
+	}
+	for ; i < len(from); i++ {
+		res = append(res, diffLine{'-', from[i]})
+	}
+	for ; j < len(to); j++ {
+		res = append(res, diffLine{'+', to[j]})
+	}
+	return res
+}
+
+// groups changes with surrounding context, close changes share hunk
+func diffHunks(lines []diffLine) []string {
+	hunks := []string{}
+	// line numbers before index, for both sides
+	fromLine := make([]int, len(lines)+1)
+	toLine := make([]int, len(lines)+1)
+	for i, line := range lines {
+		fromLine[i+1], toLine[i+1] = fromLine[i], toLine[i]
+		if line.op != '+' {
+			fromLine[i+1]++
+		}
+		if line.op != '-' {
+			toLine[i+1]++
+		}
+	}
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			i++
+			continue
+		}
+		start := max(i-diffContextLines, 0)
+		end := i
+		for k := i; k < len(lines) && k <= end+2*diffContextLines; k++ {
+			if lines[k].op != ' ' {
+				end = k
+			}
+		}
+		end = min(end+diffContextLines+1, len(lines))
+		var b strings.Builder
+		fmt.Fprintf(
+			&b,
+			"@@ -%s +%s @@\n",
+			hunkRange(fromLine[start], fromLine[end]-fromLine[start]),
+			hunkRange(toLine[start], toLine[end]-toLine[start]),
+		)
+		for _, line := range lines[start:end] {
+			fmt.Fprintf(&b, "%c%s\n", line.op, line.text)
+		}
+		hunks = append(hunks, b.String())
+		i = end
+	}
+	return hunks
+}
+
+func hunkRange(before int, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, count)
+}
@@ -0,0 +1,90 @@
+package core
+
+import "testing"
+
+func TestUnifiedDiff(t *testing.T) {
+	type testCase struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}
+	table := []testCase{
+		{
+			name:     "should be empty for equal texts",
+			from:     "a\nb\n",
+			to:       "a\nb\n",
+			expected: "",
+		},
+		{
+			name: "should replace changed line",
+			from: "a\nb\nc",
+			to:   "a\nB\nc",
+			expected: `--- from
++++ to
+@@ -1,3 +1,3 @@
+ a
+-b
++B
+ c
+`,
+		},
+		{
+			name: "should add lines to empty text",
+			from: "",
+			to:   "a\nb",
+			expected: `--- from
++++ to
+@@ -0,0 +1,2 @@
++a
++b
+`,
+		},
+		{
+			name: "should split distant changes into hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve",
+			expected: `--- from
++++ to
+@@ -1,4 +1,4 @@
+-1
++one
+ 2
+ 3
+ 4
+@@ -9,4 +9,4 @@
+ 9
+ 10
+ 11
+-12
++twelve
+`,
+		},
+		{
+			name: "should merge close changes into one hunk",
+			from: "1\n2\n3\n4\n5",
+			to:   "1\n3\n4\n5\n6",
+			expected: `--- from
++++ to
+@@ -1,5 +1,5 @@
+ 1
+-2
+ 3
+ 4
+ 5
++6
+`,
+		},
+	}
+	for _, test := range table {
+		actual := unifiedDiff("from", test.from, "to", test.to)
+		if actual != test.expected {
+			t.Errorf(
+				"%q - bad diff\nexpected:\n%s\nactual:\n%s\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/models"
+)
+
+const (
+	HistoryAll     = "all"
+	HistoryLast    = "last"
+	HistoryTokens  = "tokens"
+	HistorySummary = "summary"
+)
+
+const (
+	defaultHistoryMessages int64 = 20
+	// kept free for answer when budget is derived from context window
+	defaultOutputReserve int64 = 4096
+	summaryMaxTokens     int64 = 1024
+	summaryPrompt              = `Summarize the conversation below so it can replace it as context for the rest of the conversation. Keep facts, decisions, names, code identifiers and open questions. Reply with the summary only.
+%s
+%s`
+)
+
+type historyRequest struct {
+	core       *Core
+	chat       *models.Chat
+	limit      *int64
+	parameters *ai_clients.Parameters
+	completion ai_clients.CompletionFn
+}
+
+// reduces messages, last message is the one being answered and must be kept
+type historyStrategy func(
+	ctx context.Context,
+	req *historyRequest,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error)
+
+var historyStrategies = map[string]historyStrategy{
+	HistoryAll:     allHistory,
+	HistoryLast:    lastMessagesHistory,
+	HistoryTokens:  tokensBudgetHistory,
+	HistorySummary: summaryHistory,
+}
+
+func HistoryStrategies() []string {
+	names := []string{}
+	for name := range historyStrategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func validateHistory(history models.History) error {
+	if history.Strategy == "" {
+		return nil
+	}
+	if _, ok := historyStrategies[history.Strategy]; !ok {
+		return fmt.Errorf(
+			"unknown history strategy %q, expected one of: %s\n",
+			history.Strategy,
+			strings.Join(HistoryStrategies(), ", "),
+		)
+	}
+	if history.Limit != nil && *history.Limit < 1 {
+		return fmt.Errorf("history limit must be positive, got %d\n", *history.Limit)
+	}
+	return nil
+}
+
+// explicitly provided values take precedence over stored in chat
+func resolveHistory(chat *models.Chat, override *models.History) models.History {
+	res := chat.History
+	if override == nil {
+		return res
+	}
+	if override.Strategy != "" {
+		res.Strategy = override.Strategy
+		res.Limit = nil
+	}
+	if override.Limit != nil {
+		res.Limit = override.Limit
+	}
+	return res
+}
+
+// system messages are never dropped, strategy reduces the rest
+func (c Core) reduceHistory(
+	ctx context.Context,
+	chat *models.Chat,
+	override *models.History,
+	parameters *ai_clients.Parameters,
+	completion ai_clients.CompletionFn,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error) {
+	history := resolveHistory(chat, override)
+	if err := validateHistory(history); err != nil {
+		return nil, err
+	}
+	strategy, ok := historyStrategies[history.Strategy]
+	if !ok {
+		strategy = allHistory
+	}
+	pinned := []*ai_clients.Message{}
+	rest := []*models.Message{}
+	for _, message := range messages {
+		if message.Role == SystemRole {
+			pinned = append(pinned, messageToAIMessage(message))
+			continue
+		}
+		rest = append(rest, message)
+	}
+	limit := history.Limit
+	if limit != nil && (history.Strategy == HistoryTokens || history.Strategy == HistorySummary) {
+		budget := *limit - estimateTokens(pinned)
+		limit = &budget
+	}
+	reduced, err := strategy(ctx, &historyRequest{
+		core:       &c,
+		chat:       chat,
+		limit:      limit,
+		parameters: parameters,
+		completion: completion,
+	}, rest)
+	if err != nil {
+		return nil, err
+	}
+	return append(pinned, reduced...), nil
+}
+
+func allHistory(
+	_ context.Context,
+	_ *historyRequest,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error) {
+	return toAIMessages(messages), nil
+}
+
+func lastMessagesHistory(
+	_ context.Context,
+	req *historyRequest,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error) {
+	limit := defaultHistoryMessages
+	if req.limit != nil {
+		limit = *req.limit
+	}
+	if int64(len(messages)) > limit {
+		messages = messages[int64(len(messages))-limit:]
+	}
+	return toAIMessages(messages), nil
+}
+
+func tokensBudgetHistory(
+	_ context.Context,
+	req *historyRequest,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error) {
+	budget, err := req.budget()
+	if err != nil {
+		return nil, err
+	}
+	return toAIMessages(messages[fitTokens(messages, budget):]), nil
+}
+
+// messages that do not fit into budget are folded into summary, which is
+// stored and reused until the rest of history outgrows budget again
+func summaryHistory(
+	ctx context.Context,
+	req *historyRequest,
+	messages []*models.Message,
+) ([]*ai_clients.Message, error) {
+	budget, err := req.budget()
+	if err != nil {
+		return nil, err
+	}
+	summaries, err := req.core.db.GetChatSummaries(ctx, req.chat.ID)
+	if err != nil {
+		return nil, err
+	}
+	summary := ""
+	for _, s := range summaries {
+		// summaries past regenerated message or of unselected generations are skipped
+		if i := indexOfMessage(messages, s.UntilMessageID); i != -1 {
+			summary = s.Content
+			messages = messages[i+1:]
+			break
+		}
+	}
+	if estimateTokens(toAIMessages(messages))+ai_clients.EstimateTokens(summary) <= budget {
+		return withSummary(summary, messages), nil
+	}
+	split := fitTokens(messages, budget/2)
+	if split == 0 {
+		return withSummary(summary, messages), nil
+	}
+	folded := messages[:split]
+	for len(folded) > 0 {
+		chunk := fitTokensPrefix(folded, budget/2)
+		summary, err = req.summarize(ctx, summary, folded[:chunk])
+		if err != nil {
+			return nil, err
+		}
+		folded = folded[chunk:]
+	}
+	if _, err := req.core.db.CreateChatSummary(
+		ctx,
+		req.chat.ID,
+		messages[split-1].ID,
+		summary,
+	); err != nil {
+		return nil, err
+	}
+	return withSummary(summary, messages[split:]), nil
+}
+
+func (req *historyRequest) budget() (int64, error) {
+	if req.limit != nil {
+		return *req.limit, nil
+	}
+	window, ok := ai_clients.ContextWindow(req.parameters.Model)
+	if !ok {
+		return 0, fmt.Errorf(
+			"context window of %q is unknown, provide history limit\n",
+			req.parameters.Model,
+		)
+	}
+	reserve := defaultOutputReserve
+	if req.parameters.MaxTokens != nil {
+		reserve = *req.parameters.MaxTokens
+	}
+	return window - reserve, nil
+}
+
+func (req *historyRequest) summarize(
+	ctx context.Context,
+	summary string,
+	messages []*models.Message,
+) (string, error) {
+	previous := ""
+	if summary != "" {
+		previous = fmt.Sprintf("Summary of earlier conversation:\n%s\n", summary)
+	}
+	transcript := []string{}
+	for _, message := range messages {
+		transcript = append(transcript, fmt.Sprintf("%s: %s", message.Role, message.Content))
+	}
+	maxTokens := summaryMaxTokens
+	res, err := req.completion(
+		ctx,
+		[]*ai_clients.Message{{
+			Role:    UserRole,
+			Content: fmt.Sprintf(summaryPrompt, previous, strings.Join(transcript, "\n\n")),
+		}},
+		&ai_clients.Parameters{Model: req.parameters.Model, MaxTokens: &maxTokens},
+		&req.core.config.Providers,
+		nil,
+	)
+	if err != nil {
+		return "", err
+	}
+	if err := req.core.db.CreateChatUsage(
+		ctx,
+		req.chat.ID,
+		models.UsagePurposeSummary,
+		completionUsage(req.parameters.Model, res),
+	); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(res.Content), nil
+}
+
+// index of first message that fits into budget when counting from the end,
+// last message is always kept
+func fitTokens(messages []*models.Message, budget int64) int {
+	used := int64(0)
+	for i := len(messages) - 1; i >= 0; i-- {
+		used += ai_clients.EstimateTokens(messages[i].Content)
+		if used > budget && i != len(messages)-1 {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// count of leading messages that fit into budget, at least one
+func fitTokensPrefix(messages []*models.Message, budget int64) int {
+	used := int64(0)
+	for i, message := range messages {
+		used += ai_clients.EstimateTokens(message.Content)
+		if used > budget && i != 0 {
+			return i
+		}
+	}
+	return len(messages)
+}
+
+func withSummary(summary string, messages []*models.Message) []*ai_clients.Message {
+	if summary == "" {
+		return toAIMessages(messages)
+	}
+	return append(
+		[]*ai_clients.Message{{
+			Role:    SystemRole,
+			Content: "Summary of earlier conversation:\n" + summary,
+		}},
+		toAIMessages(messages)...,
+	)
+}
+
+func indexOfMessage(messages []*models.Message, id int64) int {
+	for i, message := range messages {
+		if message.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func estimateTokens(messages []*ai_clients.Message) int64 {
+	tokens := int64(0)
+	for _, message := range messages {
+		tokens += ai_clients.EstimateTokens(message.Content)
+	}
+	return tokens
+}
+
+func toAIMessages(messages []*models.Message) []*ai_clients.Message {
+	res := []*ai_clients.Message{}
+	for _, message := range messages {
+		res = append(res, messageToAIMessage(message))
+	}
+	return res
+}
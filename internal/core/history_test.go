@@ -0,0 +1,254 @@
+package core_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+type historyRecorder struct {
+	sent       []string
+	summarized int
+}
+
+// summarization requests are answered with "S" and spend 10 input tokens,
+// completions are answered with "ok"
+func (r *historyRecorder) completion(
+	ctx context.Context,
+	messages []*ai_clients.Message,
+	parameters *ai_clients.Parameters,
+	providers *settings.Providers,
+	onDelta ai_clients.OnDelta,
+) (*ai_clients.AIResponse, error) {
+	if strings.HasPrefix(messages[0].Content, "Summarize") {
+		r.summarized++
+		return &ai_clients.AIResponse{
+			Message:     ai_clients.Message{Role: core.AssistantRole, Content: "S"},
+			TokensUsage: ai_clients.TokensUsage{Input: 10},
+		}, nil
+	}
+	r.sent = []string{}
+	for _, message := range messages {
+		r.sent = append(r.sent, message.Content)
+	}
+	return &ai_clients.AIResponse{
+		Message: ai_clients.Message{Role: core.AssistantRole, Content: "ok"},
+	}, nil
+}
+
+// chat 1 with system message and two exchanges, every message but system
+// one is estimated as 4 tokens, system one as 6
+func prepareHistoryChat(t *testing.T) *core.Core {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.NewSeeder(db, ctx).SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats, err: %s\n", err)
+	}
+	for _, message := range []models.Message{
+		{Role: core.SystemRole, Content: "be brief"},
+		{Role: core.UserRole, Content: "u1"},
+		{Role: core.AssistantRole, Content: "a1"},
+		{Role: core.UserRole, Content: "u2"},
+		{Role: core.AssistantRole, Content: "a2"},
+	} {
+		if _, err := coreInstance.GetDB().CreateMessage(
+			ctx,
+			1,
+			message.Role,
+			message.Content,
+		); err != nil {
+			t.Fatalf("failed to create message, err: %s\n", err)
+		}
+	}
+	return coreInstance
+}
+
+func TestHistoryStrategies(t *testing.T) {
+	type testCase struct {
+		name               string
+		model              string
+		stored             *models.History
+		override           *models.History
+		expectedSent       []string
+		expectedSummarized int
+		shouldError        bool
+	}
+
+	limit := func(n int64) *int64 { return &n }
+
+	table := []testCase{
+		{
+			name:         "should send whole history by default",
+			expectedSent: []string{"be brief", "u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:         "should keep last messages and system one",
+			override:     &models.History{Strategy: core.HistoryLast, Limit: limit(2)},
+			expectedSent: []string{"be brief", "a2", "u3"},
+		},
+		{
+			name:         "should use strategy stored in chat",
+			stored:       &models.History{Strategy: core.HistoryLast, Limit: limit(3)},
+			expectedSent: []string{"be brief", "u2", "a2", "u3"},
+		},
+		{
+			name:         "should override only limit of stored strategy",
+			stored:       &models.History{Strategy: core.HistoryLast, Limit: limit(3)},
+			override:     &models.History{Limit: limit(1)},
+			expectedSent: []string{"be brief", "u3"},
+		},
+		{
+			name:         "should fit messages into tokens budget",
+			override:     &models.History{Strategy: core.HistoryTokens, Limit: limit(18)},
+			expectedSent: []string{"be brief", "u2", "a2", "u3"},
+		},
+		{
+			name:         "should derive tokens budget from model context window",
+			model:        "openai/gpt-4o",
+			override:     &models.History{Strategy: core.HistoryTokens},
+			expectedSent: []string{"be brief", "u1", "a1", "u2", "a2", "u3"},
+		},
+		{
+			name:        "should error when context window is unknown",
+			model:       "local/llama3.1",
+			override:    &models.History{Strategy: core.HistoryTokens},
+			shouldError: true,
+		},
+		{
+			name:        "should error on unknown strategy",
+			override:    &models.History{Strategy: "everything"},
+			shouldError: true,
+		},
+		{
+			name:               "should fold old messages into summary",
+			override:           &models.History{Strategy: core.HistorySummary, Limit: limit(18)},
+			expectedSent:       []string{"be brief", "Summary of earlier conversation:\nS", "u3"},
+			expectedSummarized: 4,
+		},
+		{
+			name:               "should not summarize history that fits budget",
+			override:           &models.History{Strategy: core.HistorySummary, Limit: limit(100)},
+			expectedSent:       []string{"be brief", "u1", "a1", "u2", "a2", "u3"},
+			expectedSummarized: 0,
+		},
+	}
+
+	for _, test := range table {
+		ctx := context.Background()
+		coreInstance := prepareHistoryChat(t)
+		if test.stored != nil {
+			if err := core.NewUpdateChatHistoryCommand(coreInstance, 1, *test.stored).
+				Execute(ctx); err != nil {
+				t.Fatalf("%q - failed to store history, err: %s\n", test.name, err)
+			}
+		}
+		model := test.model
+		if model == "" {
+			model = "openai/gpt-4o"
+		}
+		recorder := &historyRecorder{}
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			"u3",
+			"",
+			&ai_clients.Parameters{Model: model},
+			recorder.completion,
+		)
+		cmd.WithHistory(test.override)
+		err := cmd.Execute(ctx)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expectedSent, recorder.sent) {
+			t.Errorf(
+				"%q - bad history\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expectedSent,
+				recorder.sent,
+			)
+		}
+		if test.expectedSummarized != recorder.summarized {
+			t.Errorf(
+				"%q - bad summarization calls\nexpected: %d\nactual:   %d\n",
+				test.name,
+				test.expectedSummarized,
+				recorder.summarized,
+			)
+		}
+	}
+}
+
+func TestSummaryIsReused(t *testing.T) {
+	ctx := context.Background()
+	coreInstance := prepareHistoryChat(t)
+	recorder := &historyRecorder{}
+	limit := int64(18)
+	if err := core.NewUpdateChatHistoryCommand(
+		coreInstance,
+		1,
+		models.History{Strategy: core.HistorySummary, Limit: &limit},
+	).Execute(ctx); err != nil {
+		t.Fatalf("failed to store history, err: %s\n", err)
+	}
+	complete := func(content string, history *models.History) {
+		cmd := core.NewCreateCompletionCommand(
+			coreInstance,
+			1,
+			core.UserRole,
+			content,
+			"",
+			nil,
+			recorder.completion,
+		)
+		cmd.WithHistory(history)
+		if err := cmd.Execute(ctx); err != nil {
+			t.Fatalf("failed to create completion, err: %s\n", err)
+		}
+	}
+
+	complete("u3", nil)
+	summaries, err := coreInstance.GetDB().GetChatSummaries(ctx, 1)
+	if err != nil {
+		t.Fatalf("failed to get summaries, err: %s\n", err)
+	}
+	if len(summaries) != 1 || summaries[0].Content != "S" || summaries[0].UntilMessageID != 5 {
+		t.Fatalf("expected summary until message 5 to be stored, got %+v\n", summaries)
+	}
+	usage := core.NewGetUsageReportQuery(coreInstance, models.UsageByChat)
+	if err := usage.Execute(ctx); err != nil {
+		t.Fatalf("failed to get usage report, err: %s\n", err)
+	}
+	if len(usage.Result) != 1 ||
+		usage.Result[0].InputTokens != 10*int64(recorder.summarized) ||
+		usage.Result[0].Messages != 1 {
+		t.Errorf("expected summary usage to be recorded, got %+v\n", usage.Result)
+	}
+
+	recorder.summarized = 0
+	bigger := int64(100)
+	complete("u4", &models.History{Limit: &bigger})
+	expected := []string{"be brief", "Summary of earlier conversation:\nS", "u3", "ok", "u4"}
+	if !reflect.DeepEqual(expected, recorder.sent) {
+		t.Errorf("bad history\nexpected: %q\nactual:   %q\n", expected, recorder.sent)
+	}
+	if recorder.summarized != 0 {
+		t.Errorf("expected stored summary to be reused, got %d summarizations\n", recorder.summarized)
+	}
+}
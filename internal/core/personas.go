@@ -0,0 +1,63 @@
+package core
+
+import (
+	"context"
+
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/models"
+)
+
+// persona defaults as chat, so explicit parameters still take precedence
+func personaChat(persona *models.Persona) *models.Chat {
+	if persona == nil {
+		return &models.Chat{}
+	}
+	return &models.Chat{
+		Model:                persona.Model,
+		CompletionParameters: persona.CompletionParameters,
+	}
+}
+
+// attaches persona to chat, its model and parameters replace stored ones
+func (c Core) attachPersona(
+	ctx context.Context,
+	chat *models.Chat,
+	name string,
+) (*models.Chat, error) {
+	persona, err := c.db.GetPersonaByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if chat.PersonaID == nil || *chat.PersonaID != persona.ID {
+		if chat, err = c.db.SetChatPersona(ctx, chat.ID, &persona.ID); err != nil {
+			return nil, err
+		}
+	}
+	parameters := c.resolveParameters(chat, &ai_clients.Parameters{
+		Model:       persona.Model,
+		MaxTokens:   persona.MaxTokens,
+		Temperature: persona.Temperature,
+	})
+	model, chatParams := chatParameters(parameters)
+	return c.db.UpdateChatParameters(ctx, chat.ID, model, chatParams)
+}
+
+// system message with persona prompt, empty when chat has no persona.
+// Deleted persona is detached from its chats
+func (c Core) personaMessages(
+	ctx context.Context,
+	chat *models.Chat,
+) ([]*models.Message, error) {
+	if chat.PersonaID == nil {
+		return []*models.Message{}, nil
+	}
+	persona, err := c.db.GetPersonaByID(ctx, *chat.PersonaID)
+	if err != nil {
+		return nil, err
+	}
+	return []*models.Message{{
+		ChatID:  chat.ID,
+		Role:    SystemRole,
+		Content: persona.Prompt,
+	}}, nil
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/k10wl/hermes/internal/models"
 )
@@ -35,6 +36,25 @@ func (q *GetChatsQuery) Execute(ctx context.Context) error {
 	return nil
 }
 
+type GetChatByIDQuery struct {
+	core   *Core
+	id     int64
+	Result *models.Chat
+}
+
+func NewGetChatByIDQuery(c *Core, id int64) *GetChatByIDQuery {
+	return &GetChatByIDQuery{
+		core: c,
+		id:   id,
+	}
+}
+
+func (q *GetChatByIDQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetChatByID(ctx, q.id)
+	q.Result = res
+	return err
+}
+
 type GetChatMessagesQuery struct {
 	Core   *Core
 	ChatID int64
@@ -46,6 +66,9 @@ func (q *GetChatMessagesQuery) Execute(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := q.Core.loadAttachments(ctx, q.ChatID, messages, false); err != nil {
+		return err
+	}
 	q.Result = messages
 	return nil
 }
@@ -134,3 +157,309 @@ func (q *GetTemplateByIDQuery) Execute(ctx context.Context) error {
 	q.Result = res
 	return err
 }
+
+type GetUsageReportQuery struct {
+	core    *Core
+	groupBy string
+	Result  []*models.UsageReport
+}
+
+// groupBy is one of models.UsageByDay, models.UsageByModel, models.UsageByChat
+func NewGetUsageReportQuery(c *Core, groupBy string) *GetUsageReportQuery {
+	return &GetUsageReportQuery{
+		core:    c,
+		groupBy: groupBy,
+	}
+}
+
+func (q *GetUsageReportQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetUsageReport(ctx, q.groupBy)
+	q.Result = res
+	return err
+}
+
+type GetMessageGenerationsQuery struct {
+	core      *Core
+	messageID int64
+	Result    []*models.Message
+}
+
+// all generations of message, including itself, ordered by generation
+func NewGetMessageGenerationsQuery(c *Core, messageID int64) *GetMessageGenerationsQuery {
+	return &GetMessageGenerationsQuery{
+		core:      c,
+		messageID: messageID,
+	}
+}
+
+func (q *GetMessageGenerationsQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetMessageGenerations(ctx, q.messageID)
+	q.Result = res
+	return err
+}
+
+type SearchMessagesQuery struct {
+	core      *Core
+	terms     string
+	limit     int64
+	highlight models.Highlight
+	Result    []*models.SearchResult
+}
+
+// matched terms are wrapped into "**" unless other highlight is set,
+// limit -1 returns all matches
+func NewSearchMessagesQuery(c *Core, terms string, limit int64) *SearchMessagesQuery {
+	return &SearchMessagesQuery{
+		core:      c,
+		terms:     terms,
+		limit:     limit,
+		highlight: models.Highlight{Open: "**", Close: "**"},
+	}
+}
+
+func (q *SearchMessagesQuery) Highlight(open string, close string) {
+	q.highlight = models.Highlight{Open: open, Close: close}
+}
+
+func (q *SearchMessagesQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.SearchMessages(ctx, q.terms, q.limit, q.highlight)
+	q.Result = res
+	return err
+}
+
+type GetPersonasQuery struct {
+	core   *Core
+	Result []*models.Persona
+}
+
+func NewGetPersonasQuery(c *Core) *GetPersonasQuery {
+	return &GetPersonasQuery{core: c}
+}
+
+func (q *GetPersonasQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetPersonas(ctx)
+	q.Result = res
+	return err
+}
+
+type GetPersonaByNameQuery struct {
+	core   *Core
+	name   string
+	Result *models.Persona
+}
+
+func NewGetPersonaByNameQuery(c *Core, name string) *GetPersonaByNameQuery {
+	return &GetPersonaByNameQuery{core: c, name: name}
+}
+
+func (q *GetPersonaByNameQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetPersonaByName(ctx, q.name)
+	q.Result = res
+	return err
+}
+
+type GetTemplateHistoryQuery struct {
+	core   *Core
+	name   string
+	Result []*models.TemplateVersion
+}
+
+// newest version first
+func NewGetTemplateHistoryQuery(c *Core, name string) *GetTemplateHistoryQuery {
+	return &GetTemplateHistoryQuery{core: c, name: name}
+}
+
+func (q *GetTemplateHistoryQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetTemplateVersions(ctx, q.name)
+	if err != nil {
+		return err
+	}
+	if len(res) == 0 {
+		return fmt.Errorf("template %q not found\n", q.name)
+	}
+	q.Result = res
+	return nil
+}
+
+type DiffTemplateQuery struct {
+	core   *Core
+	name   string
+	from   int64
+	to     int64
+	Result string
+}
+
+// unified diff between two versions of template, empty when equal
+func NewDiffTemplateQuery(c *Core, name string, from int64, to int64) *DiffTemplateQuery {
+	return &DiffTemplateQuery{core: c, name: name, from: from, to: to}
+}
+
+func (q *DiffTemplateQuery) Execute(ctx context.Context) error {
+	from, err := q.core.db.GetTemplateVersion(ctx, q.name, q.from)
+	if err != nil {
+		return err
+	}
+	to, err := q.core.db.GetTemplateVersion(ctx, q.name, q.to)
+	if err != nil {
+		return err
+	}
+	q.Result = unifiedDiff(
+		fmt.Sprintf("%s v%d", from.Name, from.Version),
+		from.Content,
+		fmt.Sprintf("%s v%d", to.Name, to.Version),
+		to.Content,
+	)
+	return nil
+}
+
+type GetTemplateDependenciesQuery struct {
+	core   *Core
+	name   string
+	Result *models.TemplateDependencies
+}
+
+func NewGetTemplateDependenciesQuery(c *Core, name string) *GetTemplateDependenciesQuery {
+	return &GetTemplateDependenciesQuery{core: c, name: name}
+}
+
+func (q *GetTemplateDependenciesQuery) Execute(ctx context.Context) error {
+	stored, err := q.core.db.GetTemplatesByNames(ctx, []string{q.name})
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		return fmt.Errorf("template %q not found\n", q.name)
+	}
+	dependencies, err := q.core.templateDependencies(ctx)
+	if err != nil {
+		return err
+	}
+	uses := map[string][]string{}
+	usedBy := map[string][]string{}
+	deleted := map[string]bool{}
+	for _, dependency := range dependencies {
+		uses[dependency.CoreName] = append(uses[dependency.CoreName], dependency.LinkedName)
+		usedBy[dependency.LinkedName] = append(usedBy[dependency.LinkedName], dependency.CoreName)
+		deleted[dependency.LinkedName] = dependency.LinkedDeleted
+	}
+	q.Result = &models.TemplateDependencies{
+		Name:         q.name,
+		Dependencies: templateTree(uses, deleted, q.name, []string{q.name}),
+		Dependents:   templateTree(usedBy, map[string]bool{}, q.name, []string{q.name}),
+	}
+	return nil
+}
+
+type RenderTemplateQuery struct {
+	core      *Core
+	name      string
+	content   string
+	variables map[string]any
+	Result    string
+}
+
+// expands template with content the same way completion does, without
+// calling provider
+func NewRenderTemplateQuery(c *Core, name string, content string) *RenderTemplateQuery {
+	return &RenderTemplateQuery{core: c, name: name, content: content}
+}
+
+func (q *RenderTemplateQuery) WithVariables(variables map[string]any) {
+	q.variables = variables
+}
+
+func (q *RenderTemplateQuery) Execute(ctx context.Context) error {
+	stored, err := q.core.db.GetTemplatesByNames(ctx, []string{q.name})
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		return fmt.Errorf("template %q not found\n", q.name)
+	}
+	res, err := q.core.prepareMessage(ctx, q.content, q.name, q.variables)
+	if err != nil {
+		return err
+	}
+	q.Result = res
+	return nil
+}
+
+type LintTemplatesQuery struct {
+	core   *Core
+	Result []*models.TemplateIssue
+}
+
+// problems of stored templates, empty result means every template is valid
+func NewLintTemplatesQuery(c *Core) *LintTemplatesQuery {
+	return &LintTemplatesQuery{core: c}
+}
+
+func (q *LintTemplatesQuery) Execute(ctx context.Context) error {
+	templates, err := q.core.db.GetTemplates(ctx, -1, -1, "")
+	if err != nil {
+		return err
+	}
+	q.Result = lintTemplates(templates)
+	return nil
+}
+
+type ExportChatQuery struct {
+	core   *Core
+	chatID int64
+	Result *models.ChatExport
+}
+
+// selected generations of chat messages with their usage and attachment names
+func NewExportChatQuery(c *Core, chatID int64) *ExportChatQuery {
+	return &ExportChatQuery{core: c, chatID: chatID}
+}
+
+func (q *ExportChatQuery) Execute(ctx context.Context) error {
+	chat, err := q.core.db.GetChatByID(ctx, q.chatID)
+	if err != nil {
+		return err
+	}
+	messages, err := q.core.db.GetChatMessages(ctx, q.chatID)
+	if err != nil {
+		return err
+	}
+	if err := q.core.loadAttachments(ctx, q.chatID, messages, false); err != nil {
+		return err
+	}
+	usage, err := q.core.db.GetChatUsage(ctx, q.chatID)
+	if err != nil {
+		return err
+	}
+	q.Result = newChatExport(chat, messages, usage)
+	return nil
+}
+
+type GetMigrationStatusQuery struct {
+	core   *Core
+	Result *models.MigrationStatus
+}
+
+func NewGetMigrationStatusQuery(c *Core) *GetMigrationStatusQuery {
+	return &GetMigrationStatusQuery{core: c}
+}
+
+func (q *GetMigrationStatusQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetMigrationStatus(ctx)
+	q.Result = res
+	return err
+}
+
+type GetDatabaseStatsQuery struct {
+	core   *Core
+	Result *models.DatabaseStats
+}
+
+func NewGetDatabaseStatsQuery(c *Core) *GetDatabaseStatsQuery {
+	return &GetDatabaseStatsQuery{core: c}
+}
+
+func (q *GetDatabaseStatsQuery) Execute(ctx context.Context) error {
+	res, err := q.core.db.GetDatabaseStats(ctx)
+	q.Result = res
+	return err
+}
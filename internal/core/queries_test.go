@@ -4,6 +4,7 @@ import (
 	"context"
 	"reflect"
 	"slices"
+	"strings"
 	"testing"
 
 	"github.com/k10wl/hermes/internal/core"
@@ -84,6 +85,59 @@ func TestGetTemplateByNameQuery(t *testing.T) {
 	}
 }
 
+func TestRenderDefaultTemplate(t *testing.T) {
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateTemplate(db, ctx, &models.Template{
+		Name:    models.DefaultTemplate.Name,
+		Content: models.DefaultTemplate.Content,
+	}); err != nil {
+		t.Fatalf("failed to store default template, err: %s\n", err)
+	}
+
+	type testCase struct {
+		name        string
+		variables   map[string]any
+		contains    []string
+		notContains []string
+	}
+	table := []testCase{
+		{
+			name:      "should run every example with variables",
+			variables: map[string]any{"isEnabled": true, "jsonKey": "json value"},
+			contains: []string{
+				">>> message - Prints entire template input",
+				"message - Will print temlate input only if it is not empty",
+				"json value - prints out variable",
+			},
+		},
+		{
+			name:        "should skip condition without variables",
+			contains:    []string{">>> message - Prints entire template input"},
+			notContains: []string{"prints out variable"},
+		},
+	}
+
+	for _, test := range table {
+		query := core.NewRenderTemplateQuery(coreInstance, models.DefaultTemplate.Name, "message")
+		query.WithVariables(test.variables)
+		if err := query.Execute(ctx); err != nil {
+			t.Errorf("%q - failed to render default template, err: %s\n", test.name, err)
+			continue
+		}
+		for _, expected := range test.contains {
+			if !strings.Contains(query.Result, expected) {
+				t.Errorf("%q - expected %q in:\n%s\n", test.name, expected, query.Result)
+			}
+		}
+		for _, unexpected := range test.notContains {
+			if strings.Contains(query.Result, unexpected) {
+				t.Errorf("%q - unexpected %q in:\n%s\n", test.name, unexpected, query.Result)
+			}
+		}
+	}
+}
+
 func TestGetChatsQuery(t *testing.T) {
 	type closeDB func() error
 	type testCase struct {
@@ -413,3 +467,97 @@ func TestTemplateByIDQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestSearchMessagesQuery(t *testing.T) {
+	type testCase struct {
+		name           string
+		terms          string
+		expectedResult []*models.SearchResult
+		shouldError    bool
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateChats(db, ctx, []*models.Chat{
+		{Name: "docker setup"},
+		{Name: "deleted"},
+	}); err != nil {
+		t.Fatalf("failed to create chats, err: %s\n", err)
+	}
+	if err := db_helpers.CreateMessages(db, ctx, []*models.Message{
+		{ChatID: 1, Role: core.UserRole, Content: "how to fix segmentation fault?"},
+		{ChatID: 1, Role: core.AssistantRole, Content: "Segmentation fault happens upon bad memory access"},
+		{ChatID: 2, Role: core.UserRole, Content: "segmentation fault in deleted chat"},
+	}); err != nil {
+		t.Fatalf("failed to create messages, err: %s\n", err)
+	}
+	if err := core.NewDeleteChatCommand(coreInstance, 2).Execute(ctx); err != nil {
+		t.Fatalf("failed to delete chat, err: %s\n", err)
+	}
+	if err := core.NewRenameChatCommand(coreInstance, 1, "container setup").Execute(ctx); err != nil {
+		t.Fatalf("failed to rename chat, err: %s\n", err)
+	}
+
+	table := []testCase{
+		{
+			name:  "should find messages containing all terms",
+			terms: "memory segmentation",
+			expectedResult: []*models.SearchResult{
+				{
+					MessageID: 2,
+					ChatID:    1,
+					ChatName:  "container setup",
+					Role:      core.AssistantRole,
+					Snippet:   "**Segmentation** fault happens upon bad **memory** access",
+				},
+			},
+		},
+		{
+			name:  "should search by renamed chat name",
+			terms: "container",
+			expectedResult: []*models.SearchResult{
+				{MessageID: 1, ChatID: 1, ChatName: "container setup", Role: core.UserRole, Snippet: "**container** setup"},
+				{MessageID: 2, ChatID: 1, ChatName: "container setup", Role: core.AssistantRole, Snippet: "**container** setup"},
+			},
+		},
+		{
+			name:           "should not treat input as query syntax",
+			terms:          `fault" OR "docker`,
+			expectedResult: []*models.SearchResult{},
+		},
+		{
+			name:        "should error upon empty terms",
+			terms:       "  ",
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		query := core.NewSearchMessagesQuery(coreInstance, test.terms, -1)
+		err := query.Execute(ctx)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		for _, result := range query.Result {
+			result.CreatedAt = nil
+		}
+		slices.SortFunc(query.Result, func(a, b *models.SearchResult) int {
+			return int(a.MessageID - b.MessageID)
+		})
+		if !reflect.DeepEqual(test.expectedResult, query.Result) {
+			t.Errorf(
+				"%q - bad result\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expectedResult,
+				query.Result,
+			)
+		}
+	}
+}
@@ -39,16 +39,25 @@ func (tb *templateBuilder) process(
 		inputTemplates = append(inputTemplates, tb.requiredTemplate)
 	}
 	inputTemplates = tb.removeStored(inputTemplates)
-	if len(inputTemplates) == 0 {
-		return
-	}
-	query := NewGetTemplatesByNamesQuery(tb.core, inputTemplates)
-	if err := query.Execute(ctx); err != nil {
-		return
-	}
-	for _, template := range query.Result {
-		tb.storedTemplates[template.Name] = template
-		tb.process(ctx, template.Content)
+	// nested templates come from stored dependencies, fetched templates are
+	// parsed as well to catch edges that were never stored
+	for len(inputTemplates) > 0 {
+		templates, err := tb.core.db.GetTemplatesWithDependencies(ctx, inputTemplates)
+		if err != nil {
+			return
+		}
+		for _, template := range templates {
+			tb.storedTemplates[template.Name] = template
+		}
+		missing := []string{}
+		for _, template := range templates {
+			references, err := getTemplateReferences(template.Content)
+			if err != nil {
+				continue
+			}
+			missing = append(missing, references...)
+		}
+		inputTemplates = tb.removeStored(missing)
 	}
 }
 
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/k10wl/hermes/internal/sqlite3"
 )
 
 func TestBuildTemplateString(t *testing.T) {
@@ -16,7 +18,7 @@ func TestBuildTemplateString(t *testing.T) {
 		shouldError bool
 	}
 
-	coreInstance, _ := __createCoreAndDB()
+	coreInstance, db := __createCoreAndDB()
 
 	dbTemplates := map[string]string{
 		"hello-world": `--{{define "hello-world"}}Hello world!--{{end}}`,
@@ -26,12 +28,43 @@ func TestBuildTemplateString(t *testing.T) {
 		"nested-l3":   `--{{define "nested-l3"}}nested l3--{{end}}`,
 		"loop1":       `--{{define "loop1"}}--{{template "loop2"}}--{{end}}`,
 		"loop2":       `--{{define "loop2"}}--{{template "loop1"}}--{{end}}`,
+		"unlinked-l1": `--{{define "unlinked-l1"}}--{{template "unlinked-l2"}}--{{end}}`,
+		"unlinked-l2": `--{{define "unlinked-l2"}}unlinked l2--{{end}}`,
 	}
-	for _, template := range dbTemplates {
-		if err := NewUpsertTemplateCommand(coreInstance, template).Execute(context.Background()); err != nil {
+	// dependencies have to be stored before templates that use them
+	for _, name := range []string{
+		"hello-world",
+		"bye-world",
+		"nested-l3",
+		"nested-l2",
+		"nested-l1",
+	} {
+		if err := NewUpsertTemplateCommand(coreInstance, dbTemplates[name]).Execute(context.Background()); err != nil {
 			panic(err)
 		}
 	}
+	// cycles are rejected on write, but may be left from data stored before
+	sqlDB := db.(*sqlite3.SQLite3).DB
+	if _, err := sqlDB.Exec(
+		"INSERT INTO templates (id, name, content) VALUES (100, 'loop1', ?), (101, 'loop2', ?)",
+		dbTemplates["loop1"],
+		dbTemplates["loop2"],
+	); err != nil {
+		panic(err)
+	}
+	if _, err := sqlDB.Exec(
+		"INSERT INTO template_dependencies (core_id, linked_id) VALUES (100, 101), (101, 100)",
+	); err != nil {
+		panic(err)
+	}
+	// edges may be missing for templates stored before they were tracked
+	if _, err := sqlDB.Exec(
+		"INSERT INTO templates (id, name, content) VALUES (102, 'unlinked-l1', ?), (103, 'unlinked-l2', ?)",
+		dbTemplates["unlinked-l1"],
+		dbTemplates["unlinked-l2"],
+	); err != nil {
+		panic(err)
+	}
 
 	table := []testCase{
 		{
@@ -103,6 +136,15 @@ func TestBuildTemplateString(t *testing.T) {
 			expected:    []string{"loop1", "loop2"},
 			shouldError: false,
 		},
+		{
+			name: "should return nested templates without stored dependencies",
+			input: []string{
+				`--{{template "unlinked-l1"}}`,
+			},
+			template:    "",
+			expected:    []string{"unlinked-l1", "unlinked-l2"},
+			shouldError: false,
+		},
 		{
 			name: "should return template specified in argument",
 			input: []string{
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// `var "key"` template function, missing variable prints as <no value> like
+// missing map key. `.` stays message content, so string functions keep working
+func templateVariable(variables map[string]any) func(name string) any {
+	return func(name string) any {
+		return variables[name]
+	}
+}
+
+// parses `key=value` pairs into variables, values stay strings
+func ParseTemplateVariables(pairs []string) (map[string]any, error) {
+	variables := map[string]any{}
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("bad variable %q, expected key=value\n", pair)
+		}
+		variables[key] = value
+	}
+	return variables, nil
+}
+
+// decodes JSON object or YAML mapping into variables, value types follow
+// JSON decoding, so numbers are float64 in both formats
+func ParseTemplateData(content []byte) (map[string]any, error) {
+	trimmed := bytes.TrimSpace(content)
+	data := map[string]any{}
+	if len(trimmed) == 0 {
+		return data, nil
+	}
+	if trimmed[0] == '{' {
+		if err := json.Unmarshal(trimmed, &data); err != nil {
+			return nil, fmt.Errorf("failed to decode template data: %s\n", err)
+		}
+		return data, nil
+	}
+	var value any
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return nil, fmt.Errorf("failed to decode template data: %s\n", err)
+	}
+	if value == nil {
+		return data, nil
+	}
+	if _, ok := value.(map[string]any); !ok {
+		return nil, fmt.Errorf("template data must be an object with string keys\n")
+	}
+	normalized, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode template data: %s\n", err)
+	}
+	if err := json.Unmarshal(normalized, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode template data: %s\n", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTemplateData(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected map[string]any
+		errors   bool
+	}
+	table := []testCase{
+		{
+			name:     "should decode JSON object",
+			input:    `{"language": "go", "strict": true, "limit": 3, "tags": ["a", "b"]}`,
+			expected: map[string]any{"language": "go", "strict": true, "limit": 3.0, "tags": []any{"a", "b"}},
+		},
+		{
+			name:   "should error on bad JSON",
+			input:  `{"language": }`,
+			errors: true,
+		},
+		{
+			name:     "should treat empty content as no variables",
+			input:    "\n  \n",
+			expected: map[string]any{},
+		},
+		{
+			name: "should decode YAML scalars",
+			input: `# review settings
+---
+language: go # inline comment
+strict: true
+limit: 3
+ratio: -0.5
+missing: ~
+quoted: "a # b"
+single: 'it''s'
+url: https://example.com/#anchor
+plain: don't stop`,
+			expected: map[string]any{
+				"language": "go",
+				"strict":   true,
+				"limit":    3.0,
+				"ratio":    -0.5,
+				"missing":  nil,
+				"quoted":   "a # b",
+				"single":   "it's",
+				"url":      "https://example.com/#anchor",
+				"plain":    "don't stop",
+			},
+		},
+		{
+			name: "should decode nested YAML mappings and lists",
+			input: `user:
+  name: Jane
+  roles:
+    - admin
+    - dev
+tags:
+- a
+- b
+flow: [1, 2]
+people:
+  - name: a
+    age: 1
+  - name: b
+empty:
+last: value`,
+			expected: map[string]any{
+				"user": map[string]any{
+					"name":  "Jane",
+					"roles": []any{"admin", "dev"},
+				},
+				"tags": []any{"a", "b"},
+				"flow": []any{1.0, 2.0},
+				"people": []any{
+					map[string]any{"name": "a", "age": 1.0},
+					map[string]any{"name": "b"},
+				},
+				"empty": nil,
+				"last":  "value",
+			},
+		},
+		{
+			name: "should decode YAML block scalars",
+			input: `prompt: |
+  Review carefully.
+  Point to lines.
+folded: >
+  one
+  two
+`,
+			expected: map[string]any{
+				"prompt": "Review carefully.\nPoint to lines.\n",
+				"folded": "one two\n",
+			},
+		},
+		{
+			name:   "should error on YAML mapping with non string keys",
+			input:  "1: a\n{x: 1}: b",
+			errors: true,
+		},
+		{
+			name:   "should error on YAML that is not an object",
+			input:  "- a\n- b",
+			errors: true,
+		},
+		{
+			name:   "should error on bad YAML indentation",
+			input:  "a: 1\n   b: 2",
+			errors: true,
+		},
+		{
+			name:   "should error on YAML line without key",
+			input:  "a: 1\njust text",
+			errors: true,
+		},
+	}
+	for _, test := range table {
+		actual, err := ParseTemplateData([]byte(test.input))
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got %+v\n", test.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf(
+				"%q bad result.\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
+
+func TestParseTemplateVariables(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    []string
+		expected map[string]any
+		errors   bool
+	}
+	table := []testCase{
+		{
+			name:     "should split pairs on first equal sign",
+			input:    []string{"language=go", "query=a=b", "empty="},
+			expected: map[string]any{"language": "go", "query": "a=b", "empty": ""},
+		},
+		{
+			name:     "should let later pair win",
+			input:    []string{"a=1", "a=2"},
+			expected: map[string]any{"a": "2"},
+		},
+		{
+			name:   "should error on pair without value",
+			input:  []string{"language"},
+			errors: true,
+		},
+		{
+			name:   "should error on pair without key",
+			input:  []string{"=go"},
+			errors: true,
+		},
+	}
+	for _, test := range table {
+		actual, err := ParseTemplateVariables(test.input)
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got %+v\n", test.name, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf(
+				"%q bad result.\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+// names of templates used by content, templates defined in content itself
+// are not dependencies
+func getTemplateReferences(content string) ([]string, error) {
+	tmpl, err := template.New("").
+		Delims(leftDelim, rightDelim).
+		Funcs(parseTemplateFuncs).
+		Parse(content)
+	if err != nil {
+		return nil, err
+	}
+	defined := map[string]bool{}
+	references := []string{}
+	for _, t := range tmpl.Templates() {
+		defined[t.Name()] = true
+		if t.Tree != nil {
+			references = collectTemplateReferences(t.Tree.Root, references)
+		}
+	}
+	references = slices.DeleteFunc(references, func(name string) bool {
+		return defined[name]
+	})
+	slices.Sort(references)
+	return slices.Compact(references), nil
+}
+
+func collectTemplateReferences(node parse.Node, references []string) []string {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return references
+		}
+		for _, child := range n.Nodes {
+			references = collectTemplateReferences(child, references)
+		}
+	case *parse.TemplateNode:
+		references = append(references, n.Name)
+	case *parse.IfNode:
+		references = collectBranchReferences(&n.BranchNode, references)
+	case *parse.RangeNode:
+		references = collectBranchReferences(&n.BranchNode, references)
+	case *parse.WithNode:
+		references = collectBranchReferences(&n.BranchNode, references)
+	}
+	return references
+}
+
+func collectBranchReferences(n *parse.BranchNode, references []string) []string {
+	references = collectTemplateReferences(n.List, references)
+	return collectTemplateReferences(n.ElseList, references)
+}
+
+// stored edges, templates that were stored before edges were written have
+// none, their edges are parsed from content until they are written again
+func (c Core) templateDependencies(ctx context.Context) ([]*models.TemplateDependency, error) {
+	dependencies, err := c.db.GetTemplateDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	templates, err := c.db.GetTemplates(ctx, -1, -1, "")
+	if err != nil {
+		return nil, err
+	}
+	withEdges := map[int64]bool{}
+	for _, dependency := range dependencies {
+		withEdges[dependency.CoreID] = true
+	}
+	byName := map[string]*models.Template{}
+	for _, t := range templates {
+		byName[t.Name] = t
+	}
+	parsed := false
+	for _, t := range templates {
+		if withEdges[t.ID] {
+			continue
+		}
+		// broken content has no edges to protect, lint reports it
+		references, err := getTemplateReferences(t.Content)
+		if err != nil {
+			continue
+		}
+		for _, reference := range references {
+			linked, ok := byName[reference]
+			if !ok || linked.ID == t.ID {
+				continue
+			}
+			parsed = true
+			dependencies = append(dependencies, &models.TemplateDependency{
+				CoreID:     t.ID,
+				CoreName:   t.Name,
+				LinkedID:   linked.ID,
+				LinkedName: linked.Name,
+			})
+		}
+	}
+	if parsed {
+		slices.SortFunc(dependencies, func(a, b *models.TemplateDependency) int {
+			if n := strings.Compare(a.CoreName, b.CoreName); n != 0 {
+				return n
+			}
+			return strings.Compare(a.LinkedName, b.LinkedName)
+		})
+	}
+	return dependencies, nil
+}
+
+// edges by template name, edges of skipped template are left out
+func templateGraph(
+	dependencies []*models.TemplateDependency,
+	skip string,
+) map[string][]string {
+	graph := map[string][]string{}
+	for _, dependency := range dependencies {
+		if dependency.CoreName == skip {
+			continue
+		}
+		graph[dependency.CoreName] = append(graph[dependency.CoreName], dependency.LinkedName)
+	}
+	return graph
+}
+
+// validates templates used by content that is about to be stored as name,
+// previous name is set when template is edited
+func (c Core) resolveTemplateDependencies(
+	ctx context.Context,
+	name string,
+	previousName string,
+	content string,
+) ([]string, error) {
+	references, err := getTemplateReferences(content)
+	if err != nil {
+		return nil, err
+	}
+	dependencies, err := c.templateDependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if previousName != "" && previousName != name {
+		if dependents := directDependents(dependencies, previousName); len(dependents) > 0 {
+			return nil, fmt.Errorf(
+				"cannot rename template %q, it is used by: %s\n",
+				previousName,
+				strings.Join(dependents, ", "),
+			)
+		}
+	}
+	if len(references) == 0 {
+		return references, nil
+	}
+	stored, err := c.db.GetTemplatesByNames(ctx, references)
+	if err != nil {
+		return nil, err
+	}
+	for _, reference := range references {
+		if !slices.ContainsFunc(stored, func(t *models.Template) bool {
+			return t.Name == reference
+		}) {
+			return nil, fmt.Errorf(
+				"template %q uses unknown template %q, create it first\n",
+				name,
+				reference,
+			)
+		}
+	}
+	graph := templateGraph(dependencies, previousName)
+	delete(graph, name)
+	graph[name] = references
+	if cycle := findTemplateCycle(graph, name, []string{name}); cycle != nil {
+		return nil, fmt.Errorf(
+			"template dependency cycle: %s\n",
+			strings.Join(cycle, " -> "),
+		)
+	}
+	return references, nil
+}
+
+// path that leads back to the first template in path, nil if there is none
+func findTemplateCycle(graph map[string][]string, current string, path []string) []string {
+	for _, next := range graph[current] {
+		if next == path[0] {
+			return append(path, next)
+		}
+		if slices.Contains(path, next) {
+			continue
+		}
+		if cycle := findTemplateCycle(graph, next, append(path, next)); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+func directDependents(dependencies []*models.TemplateDependency, name string) []string {
+	dependents := []string{}
+	for _, dependency := range dependencies {
+		if dependency.LinkedName == name && dependency.CoreName != name {
+			dependents = append(dependents, dependency.CoreName)
+		}
+	}
+	return dependents
+}
+
+// tree of templates reachable through edges, path guards against cycles
+// left by data written before dependencies were enforced
+func templateTree(
+	edges map[string][]string,
+	deleted map[string]bool,
+	name string,
+	path []string,
+) []*models.TemplateTree {
+	children := []*models.TemplateTree{}
+	for _, next := range edges[name] {
+		node := &models.TemplateTree{Name: next, Deleted: deleted[next]}
+		if !slices.Contains(path, next) {
+			node.Children = templateTree(edges, deleted, next, append(path, next))
+		}
+		children = append(children, node)
+	}
+	return children
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+const templateFileExtension = ".tmpl"
+
+// names may contain path separators, escaped name keeps file inside dir
+func templateFileName(name string) string {
+	return url.PathEscape(name) + templateFileExtension
+}
+
+// template files found in dir and its subdirectories, sorted by path
+func readTemplateFiles(dir string) ([]*models.Template, error) {
+	templates := []*models.Template{}
+	files := map[string]string{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != templateFileExtension {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if _, err := getTemplateNames(string(content)); err != nil {
+			return fmt.Errorf("%s: %s\n", path, err)
+		}
+		// same rule as upsert, helper templates may be defined after the first one
+		name, err := extractTemplateDefinitionName(string(content))
+		if err != nil {
+			return fmt.Errorf("%s: %s\n", path, err)
+		}
+		if previous, ok := files[name]; ok {
+			return fmt.Errorf("template %q is defined in %s and %s\n", name, previous, path)
+		}
+		files[name] = path
+		templates = append(templates, &models.Template{Name: name, Content: string(content)})
+		return nil
+	})
+	return templates, err
+}
+
+// templates ordered so that every template goes after templates it uses,
+// references outside of given set are expected to be stored already
+func orderTemplatesByDependencies(templates []*models.Template) ([]*models.Template, error) {
+	pending := map[string][]string{}
+	byName := map[string]*models.Template{}
+	for _, template := range templates {
+		references, err := getTemplateReferences(template.Content)
+		if err != nil {
+			return nil, err
+		}
+		pending[template.Name] = references
+		byName[template.Name] = template
+	}
+	ordered := []*models.Template{}
+	for len(pending) > 0 {
+		ready := []string{}
+		for name, references := range pending {
+			if !slices.ContainsFunc(references, func(reference string) bool {
+				_, waiting := pending[reference]
+				return waiting && reference != name
+			}) {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			graph := map[string][]string{}
+			for name, references := range pending {
+				graph[name] = references
+			}
+			names := []string{}
+			for name := range pending {
+				names = append(names, name)
+			}
+			slices.Sort(names)
+			cycle := names
+			for _, name := range names {
+				if found := findTemplateCycle(graph, name, []string{name}); found != nil {
+					cycle = found
+					break
+				}
+			}
+			return nil, fmt.Errorf(
+				"template dependency cycle: %s\n",
+				strings.Join(cycle, " -> "),
+			)
+		}
+		slices.Sort(ready)
+		for _, name := range ready {
+			ordered = append(ordered, byName[name])
+			delete(pending, name)
+		}
+	}
+	return ordered, nil
+}
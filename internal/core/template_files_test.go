@@ -0,0 +1,62 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+func TestOrderTemplatesByDependencies(t *testing.T) {
+	type testCase struct {
+		name      string
+		templates []*models.Template
+		expected  []string
+		errors    bool
+	}
+	table := []testCase{
+		{
+			name: "should put used templates first",
+			templates: []*models.Template{
+				{Name: "a", Content: `--{{define "a"}}--{{template "b"}}--{{template "c"}}--{{end}}`},
+				{Name: "b", Content: `--{{define "b"}}--{{template "c"}}--{{end}}`},
+				{Name: "c", Content: `--{{define "c"}}c--{{end}}`},
+				{Name: "d", Content: `--{{define "d"}}--{{template "stored"}}--{{end}}`},
+			},
+			expected: []string{"c", "d", "b", "a"},
+		},
+		{
+			name: "should error on cycle",
+			templates: []*models.Template{
+				{Name: "a", Content: `--{{define "a"}}--{{template "b"}}--{{end}}`},
+				{Name: "b", Content: `--{{define "b"}}--{{template "a"}}--{{end}}`},
+			},
+			errors: true,
+		},
+	}
+	for _, test := range table {
+		ordered, err := orderTemplatesByDependencies(test.templates)
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got nil\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %s\n", test.name, err)
+			continue
+		}
+		actual := []string{}
+		for _, template := range ordered {
+			actual = append(actual, template.Name)
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf(
+				"%q bad order\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/k10wl/hermes/internal/settings"
+)
+
+// largest file readFile includes into prompt
+const templateReadFileLimit = 1 << 20
+
+// functions available in templates, only readFile and env reach outside of
+// template and both are limited by config
+func templateFuncs(config settings.TemplateFunctions, variables map[string]any) template.FuncMap {
+	return template.FuncMap{
+		// variables from --data, --var or websocket payload
+		"var": templateVariable(variables),
+		// dates
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+		// strings
+		"upper":      strings.ToUpper,
+		"lower":      strings.ToLower,
+		"title":      title,
+		"trim":       strings.TrimSpace,
+		"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+		"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+		"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+		"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+		"split":      func(sep string, s string) []string { return strings.Split(s, sep) },
+		"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+		"indent":     indent,
+		// json
+		"toJSON":   toJSON,
+		"fromJSON": fromJSON,
+		// outside world
+		"readFile": func(path string) (string, error) { return readFile(config.FileDirs, path) },
+		"env":      func(name string) (string, error) { return env(config.Env, name) },
+	}
+}
+
+// names are the same for every config, parsing does not need limits
+var parseTemplateFuncs = templateFuncs(settings.TemplateFunctions{}, nil)
+
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// prefixes every line with given amount of spaces
+func indent(spaces int, s string) string {
+	pad := strings.Repeat(" ", spaces)
+	return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func fromJSON(s string) (any, error) {
+	var v any
+	err := json.Unmarshal([]byte(s), &v)
+	return v, err
+}
+
+// reads file only if it resolves inside one of allowed directories,
+// relative paths are looked up in each allowed directory
+func readFile(dirs []string, path string) (string, error) {
+	if len(dirs) == 0 {
+		return "", fmt.Errorf(
+			"readFile is disabled, allow directories with %s\n",
+			settings.HermesTemplateFileDirsName,
+		)
+	}
+	for _, dir := range dirs {
+		resolvedDir, err := resolvePath(dir)
+		if err != nil {
+			continue
+		}
+		candidate := path
+		if !filepath.IsAbs(candidate) {
+			candidate = filepath.Join(resolvedDir, candidate)
+		}
+		resolved, err := resolvePath(candidate)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(resolvedDir, resolved)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return readLimited(resolved, path)
+	}
+	return "", fmt.Errorf("readFile is not allowed to read %q\n", path)
+}
+
+// reads at most templateReadFileLimit bytes, so growing files can not bypass it
+func readLimited(resolved string, path string) (string, error) {
+	f, err := os.Open(resolved)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, templateReadFileLimit+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > templateReadFileLimit {
+		return "", fmt.Errorf("file %q exceeds %d bytes\n", path, templateReadFileLimit)
+	}
+	return string(data), nil
+}
+
+// absolute path with symlinks followed, so links can not escape directory
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
+
+func env(allowed []string, name string) (string, error) {
+	if !slices.Contains(allowed, name) {
+		return "", fmt.Errorf(
+			"env %q is not allowed, add it to %s\n",
+			name,
+			settings.HermesTemplateEnvName,
+		)
+	}
+	return os.Getenv(name), nil
+}
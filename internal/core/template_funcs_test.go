@@ -0,0 +1,187 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/k10wl/hermes/internal/settings"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	allowed := t.TempDir()
+	forbidden := t.TempDir()
+	if err := os.WriteFile(filepath.Join(allowed, "notes.txt"), []byte("notes"), 0o644); err != nil {
+		t.Fatalf("failed to write file - %s\n", err)
+	}
+	if err := os.WriteFile(
+		filepath.Join(allowed, "large.txt"),
+		[]byte(strings.Repeat("a", templateReadFileLimit+1)),
+		0o644,
+	); err != nil {
+		t.Fatalf("failed to write file - %s\n", err)
+	}
+	if err := os.WriteFile(filepath.Join(forbidden, "secret.txt"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("failed to write file - %s\n", err)
+	}
+	if err := os.Symlink(
+		filepath.Join(forbidden, "secret.txt"),
+		filepath.Join(allowed, "link.txt"),
+	); err != nil {
+		t.Fatalf("failed to create symlink - %s\n", err)
+	}
+	t.Setenv("HERMES_TEST_ALLOWED", "allowed value")
+	t.Setenv("HERMES_TEST_FORBIDDEN", "forbidden value")
+	config := settings.TemplateFunctions{
+		FileDirs: []string{allowed},
+		Env:      []string{"HERMES_TEST_ALLOWED"},
+	}
+
+	type testCase struct {
+		name     string
+		template string
+		input    any
+		expected string
+		errors   bool
+	}
+	table := []testCase{
+		{
+			name:     "should format current date",
+			template: `--{{now | date "2006"}}`,
+			expected: time.Now().Format("2006"),
+		},
+		{
+			name:     "should change case and trim",
+			template: `--{{upper .}}|--{{lower .}}|--{{title (trim .)}}`,
+			input:    "  hello World ",
+			expected: "  HELLO WORLD |  hello world |Hello World",
+		},
+		{
+			name:     "should manipulate strings in pipelines",
+			template: `--{{. | trimPrefix "a-" | trimSuffix "-z" | replace "-" " "}}`,
+			input:    "a-b-c-z",
+			expected: "b c",
+		},
+		{
+			name:     "should check substrings",
+			template: `--{{contains "b" .}} --{{hasPrefix "a" .}} --{{hasSuffix "a" .}}`,
+			input:    "abc",
+			expected: "true true false",
+		},
+		{
+			name:     "should split and join",
+			template: `--{{split "," . | join " + "}}`,
+			input:    "a,b,c",
+			expected: "a + b + c",
+		},
+		{
+			name:     "should indent every line",
+			template: `--{{indent 2 .}}`,
+			input:    "a\nb",
+			expected: "  a\n  b",
+		},
+		{
+			name:     "should encode and decode json",
+			template: `--{{toJSON .}} --{{(fromJSON "{\"a\": [1, 2]}").a}}`,
+			input:    map[string]any{"key": "value"},
+			expected: `{"key":"value"} [1 2]`,
+		},
+		{
+			name:     "should read file from allowed directory",
+			template: `--{{readFile .}}`,
+			input:    filepath.Join(allowed, "notes.txt"),
+			expected: "notes",
+		},
+		{
+			name:     "should resolve relative path against allowed directory",
+			template: `--{{readFile .}}`,
+			input:    "notes.txt",
+			expected: "notes",
+		},
+		{
+			name:     "should not escape allowed directory with relative path outside",
+			template: `--{{readFile .}}`,
+			input:    filepath.Join("..", filepath.Base(forbidden), "secret.txt"),
+			errors:   true,
+		},
+		{
+			name:     "should not read file larger than limit",
+			template: `--{{readFile .}}`,
+			input:    "large.txt",
+			errors:   true,
+		},
+		{
+			name:     "should not read file outside of allowed directories",
+			template: `--{{readFile .}}`,
+			input:    filepath.Join(forbidden, "secret.txt"),
+			errors:   true,
+		},
+		{
+			name:     "should not escape allowed directory with relative path",
+			template: `--{{readFile .}}`,
+			input:    filepath.Join(allowed, "..", filepath.Base(forbidden), "secret.txt"),
+			errors:   true,
+		},
+		{
+			name:     "should not escape allowed directory with symlink",
+			template: `--{{readFile .}}`,
+			input:    filepath.Join(allowed, "link.txt"),
+			errors:   true,
+		},
+		{
+			name:     "should read allowed env",
+			template: `--{{env "HERMES_TEST_ALLOWED"}}`,
+			expected: "allowed value",
+		},
+		{
+			name:     "should not read env outside of allowlist",
+			template: `--{{env "HERMES_TEST_FORBIDDEN"}}`,
+			errors:   true,
+		},
+	}
+	for _, test := range table {
+		content := withDelims(`define "test"`) + test.template + withDelims("end")
+		if _, err := getTemplateNames(content); err != nil {
+			t.Errorf("%q failed to parse template without limits - %s\n", test.name, err)
+			continue
+		}
+		buf := &strings.Builder{}
+		err := prepareTemplates(content, templateFuncs(config, nil)).
+			ExecuteTemplate(buf, "test", test.input)
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got %q\n", test.name, buf.String())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %s\n", test.name, err)
+			continue
+		}
+		if buf.String() != test.expected {
+			t.Errorf(
+				"%q bad result\nexpected: %q\nactual:   %q\n",
+				test.name,
+				test.expected,
+				buf.String(),
+			)
+		}
+	}
+}
+
+func TestTemplateFuncsDisabledByDefault(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file, []byte("notes"), 0o644); err != nil {
+		t.Fatalf("failed to write file - %s\n", err)
+	}
+	t.Setenv("HERMES_TEST_ALLOWED", "allowed value")
+	funcs := templateFuncs(settings.TemplateFunctions{}, nil)
+	if _, err := funcs["readFile"].(func(string) (string, error))(file); err == nil {
+		t.Errorf("expected readFile to be disabled without allowed directories\n")
+	}
+	if _, err := funcs["env"].(func(string) (string, error))("HERMES_TEST_ALLOWED"); err == nil {
+		t.Errorf("expected env to be disabled without allowlist\n")
+	}
+}
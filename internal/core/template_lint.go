@@ -0,0 +1,53 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+// reports syntax errors, templates that do not define their own name and
+// references to templates that are not stored, sorted by template name
+func lintTemplates(templates []*models.Template) []*models.TemplateIssue {
+	stored := map[string]bool{}
+	for _, template := range templates {
+		stored[template.Name] = true
+	}
+	issues := []*models.TemplateIssue{}
+	for _, template := range templates {
+		issues = append(issues, lintTemplate(template, stored)...)
+	}
+	slices.SortStableFunc(issues, func(a, b *models.TemplateIssue) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return issues
+}
+
+func lintTemplate(template *models.Template, stored map[string]bool) []*models.TemplateIssue {
+	issue := func(format string, args ...any) *models.TemplateIssue {
+		return &models.TemplateIssue{
+			Name:    template.Name,
+			Message: fmt.Sprintf(format, args...),
+		}
+	}
+	names, err := getTemplateNames(template.Content)
+	if err != nil {
+		return []*models.TemplateIssue{issue("syntax error: %s", err)}
+	}
+	issues := []*models.TemplateIssue{}
+	if !slices.Contains(names, template.Name) {
+		issues = append(issues, issue("missing definition of %q", template.Name))
+	}
+	references, err := getTemplateReferences(template.Content)
+	if err != nil {
+		return append(issues, issue("syntax error: %s", err))
+	}
+	for _, reference := range references {
+		if !stored[reference] {
+			issues = append(issues, issue("unresolved reference to %q", reference))
+		}
+	}
+	return issues
+}
@@ -21,6 +21,7 @@ func (c Core) prepareMessage(
 	ctx context.Context,
 	input string,
 	templateName string,
+	variables map[string]any,
 ) (string, error) {
 	trimmed := trim(input)
 	templateBuilder := newTemplateBuilder(&c)
@@ -30,7 +31,10 @@ func (c Core) prepareMessage(
 	if err != nil {
 		return trimmed, err
 	}
-	t := prepareTemplates(templateString)
+	t := prepareTemplates(
+		templateString,
+		templateFuncs(c.config.TemplateFunctions, variables),
+	)
 	refinedInput := prepareInput(templateName, trimmed)
 	buf := &strings.Builder{}
 	err = executor(t, buf, refinedInput)
@@ -42,7 +46,7 @@ const definitionError = "failed to get template name"
 func getTemplateNames(content string) ([]string, error) {
 	names := []string{}
 	tmpl := template.New("")
-	tmpl = tmpl.Delims(leftDelim, rightDelim)
+	tmpl = tmpl.Delims(leftDelim, rightDelim).Funcs(parseTemplateFuncs)
 	tmpl, err := tmpl.Parse(content)
 	if err != nil {
 		return names, err
@@ -154,9 +158,10 @@ func withTemplateDefinition(name string, content string) string {
 
 func prepareTemplates(
 	templates string,
+	funcs template.FuncMap,
 ) *template.Template {
 	tmpl := template.New(rootTemplateName)
-	tmpl = tmpl.Delims(leftDelim, rightDelim)
+	tmpl = tmpl.Delims(leftDelim, rightDelim).Funcs(funcs)
 	tmpl = template.Must(tmpl.Parse(withTemplateDefinition(rootTemplateName, templates)))
 	return tmpl
 }
@@ -178,7 +183,9 @@ func executor(t *template.Template, writer io.Writer, str string) error {
 		}
 		return executor(updated, writer, buf.String())
 	}
-	return t.ExecuteTemplate(writer, rootTemplateName, str)
+	// root is redefined by in-place blocks, rendered input is written as is
+	_, err := io.WriteString(writer, str)
+	return err
 }
 
 func withDelims(content string) string {
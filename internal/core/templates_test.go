@@ -124,6 +124,62 @@ func TestExtractUsedTemplates(t *testing.T) {
 
 }
 
+func TestGetTemplateReferences(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected []string
+		errors   bool
+	}
+	table := []testCase{
+		{
+			name:     "should get sorted unique references",
+			input:    `--{{define "a"}}--{{template "c"}}--{{template "b" .}}--{{template "c" .x}}--{{end}}`,
+			expected: []string{"b", "c"},
+		},
+		{
+			name: "should get references nested in actions",
+			input: `--{{define "a"}}
+--{{if .}}--{{template "b"}}--{{else}}--{{template "c"}}--{{end}}
+--{{range .}}--{{template "d"}}--{{end}}
+--{{with .}}--{{template "e"}}--{{end}}
+--{{end}}`,
+			expected: []string{"b", "c", "d", "e"},
+		},
+		{
+			name:     "should skip templates defined in content",
+			input:    `--{{define "a"}}--{{template "helper"}}--{{template "a"}}--{{end}}--{{define "helper"}}h--{{end}}`,
+			expected: []string{},
+		},
+		{
+			name:   "should error on invalid content",
+			input:  `--{{define "a"}}`,
+			errors: true,
+		},
+	}
+	for _, test := range table {
+		actual, err := getTemplateReferences(test.input)
+		if test.errors {
+			if err == nil {
+				t.Errorf("%q expected error, but got nil\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q unexpected error: %v\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf(
+				"%q bad result.\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				actual,
+			)
+		}
+	}
+}
+
 type MockAIClient struct{}
 
 func (mockClient MockAIClient) ChatCompletion(
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"time"
 
 	"github.com/k10wl/hermes/internal/models"
 )
@@ -16,11 +17,66 @@ type Client interface {
 		role string,
 		content string,
 	) (*models.Message, error)
+	CreateMessageWithUsage(
+		ctx context.Context,
+		chatID int64,
+		role string,
+		content string,
+		interrupted bool,
+		usage *models.Usage,
+	) (*models.Message, error)
+	// stores message with its attachments and completion with its usage in
+	// one transaction, so failed completion leaves no message behind
+	CreateMessageAndCompletion(
+		ctx context.Context,
+		message *models.Message,
+		completion *models.Message,
+		usage *models.Usage,
+	) (*models.Message, *models.Message, error)
+	// stores attachments in same transaction, all or none are linked
 	CreateChatAndMessage(
 		ctx context.Context,
 		role string,
 		content string,
+		model string,
+		parameters models.CompletionParameters,
+		personaID *int64,
+		attachments []*models.Attachment,
 	) (*models.Chat, *models.Message, error)
+	GetChatByID(ctx context.Context, id int64) (*models.Chat, error)
+	// removes chat with its messages for good, their attachments become
+	// pending again
+	DiscardChat(ctx context.Context, id int64) error
+	RenameChat(ctx context.Context, id int64, name string) (*models.Chat, error)
+	// sets deleted_at, deleted chats are hidden from chat queries
+	DeleteChat(ctx context.Context, id int64) (*models.Chat, error)
+	UpdateChatParameters(
+		ctx context.Context,
+		id int64,
+		model string,
+		parameters models.CompletionParameters,
+	) (*models.Chat, error)
+	UpdateChatHistory(
+		ctx context.Context,
+		id int64,
+		history models.History,
+	) (*models.Chat, error)
+	CreateChatSummary(
+		ctx context.Context,
+		chatID int64,
+		untilMessageID int64,
+		content string,
+	) (*models.ChatSummary, error)
+	// newest first
+	GetChatSummaries(ctx context.Context, chatID int64) ([]*models.ChatSummary, error)
+	// nil persona detaches it from chat
+	SetChatPersona(ctx context.Context, chatID int64, personaID *int64) (*models.Chat, error)
+	// persona with the same name is replaced, id is kept
+	UpsertPersona(ctx context.Context, persona *models.Persona) (*models.Persona, error)
+	GetPersonas(ctx context.Context) ([]*models.Persona, error)
+	GetPersonaByName(ctx context.Context, name string) (*models.Persona, error)
+	GetPersonaByID(ctx context.Context, id int64) (*models.Persona, error)
+	DeletePersonaByName(ctx context.Context, name string) (*models.Persona, error)
 	GetChats(
 		ctx context.Context,
 		limit int64,
@@ -30,17 +86,94 @@ type Client interface {
 		ctx context.Context,
 		chatID int64,
 	) ([]*models.Message, error)
+	// usage of selected messages in chat
+	GetChatUsage(ctx context.Context, chatID int64) ([]*models.Usage, error)
+	// creates chat with messages in one transaction, timestamps are preserved
+	ImportChat(ctx context.Context, export *models.ChatExport) (*models.Chat, error)
+
+	// copies chat messages up to given message into new chat
+	ForkChat(ctx context.Context, chatID int64, messageID int64) (*models.Chat, error)
+	GetMessageByID(ctx context.Context, id int64) (*models.Message, error)
+	// selected messages that precede given message and its generations
+	GetMessageHistory(ctx context.Context, id int64) ([]*models.Message, error)
+	GetMessageGenerations(ctx context.Context, id int64) ([]*models.Message, error)
+	CreateMessageGeneration(
+		ctx context.Context,
+		id int64,
+		role string,
+		content string,
+		interrupted bool,
+		usage *models.Usage,
+	) (*models.Message, error)
+	SelectMessageGeneration(ctx context.Context, id int64) (*models.Message, error)
+
+	// attachment without message waits until it is sent
+	CreateAttachment(
+		ctx context.Context,
+		attachment *models.Attachment,
+	) (*models.Attachment, error)
+	// removes uploads that were not sent with message for given time
+	DeleteStaleAttachments(ctx context.Context, lifetime time.Duration) error
+	// attachments of every message in chat, without data
+	GetChatAttachments(ctx context.Context, chatID int64) ([]*models.Attachment, error)
+	GetAttachmentByID(ctx context.Context, id int64) (*models.Attachment, error)
+
+	// full text search, limit -1 returns all matches
+	SearchMessages(
+		ctx context.Context,
+		terms string,
+		limit int64,
+		highlight models.Highlight,
+	) ([]*models.SearchResult, error)
+
+	// usage of request that did not produce message, like title or summary
+	CreateChatUsage(
+		ctx context.Context,
+		chatID int64,
+		purpose string,
+		usage *models.Usage,
+	) error
+	GetUsageReport(
+		ctx context.Context,
+		groupBy string,
+	) ([]*models.UsageReport, error)
 
 	GetWebSettings(context.Context) (*models.WebSettings, error)
 	UpdateWebSettings(ctx context.Context, dark_mode bool) error
 
 	GetLatestChat(context.Context) (*models.Chat, error)
 
+	// dependencies are names of templates used by upserted one
 	UpsertTemplate(
 		ctx context.Context,
 		name string,
 		template string,
+		dependencies []string,
 	) (*models.Template, error)
+	// upserts templates in given order and deletes named ones in one
+	// transaction, dependencies are keyed by template name
+	ImportTemplates(
+		ctx context.Context,
+		templates []*models.Template,
+		dependencies map[string][]string,
+		deleted []string,
+	) ([]*models.Template, error)
+	GetTemplateDependencies(ctx context.Context) ([]*models.TemplateDependency, error)
+	// named templates and every template they use, recursively
+	GetTemplatesWithDependencies(
+		ctx context.Context,
+		names []string,
+	) ([]*models.Template, error)
+	// newest version first
+	GetTemplateVersions(
+		ctx context.Context,
+		name string,
+	) ([]*models.TemplateVersion, error)
+	GetTemplateVersion(
+		ctx context.Context,
+		name string,
+		version int64,
+	) (*models.TemplateVersion, error)
 	GetTemplatesByNames(
 		ctx context.Context,
 		names []string,
@@ -64,8 +197,23 @@ type Client interface {
 		name string,
 		newName string,
 		content string,
+		dependencies []string,
 	) (*models.Template, error)
 
+	GetMigrationStatus(context.Context) (*models.MigrationStatus, error)
+	// positive steps apply migrations, negative roll back, zero applies all
+	// pending, returns path of backup made before change
+	Migrate(ctx context.Context, steps int) (string, error)
+	// marks version as clean without running migrations, returns backup path
+	ForceMigrationVersion(ctx context.Context, version int64) (string, error)
+	// consistent copy of database, safe while server is running
+	Backup(ctx context.Context, path string) error
+	// replaces database with validated backup, returns path of backup made
+	// from replaced content
+	Restore(ctx context.Context, path string) (string, error)
+	GetDatabaseStats(context.Context) (*models.DatabaseStats, error)
+	Vacuum(context.Context) error
+
 	CreateActiveSession(*models.ActiveSession) error
 	RemoveActiveSession(*models.ActiveSession) error
 	GetActiveSessionByDatabaseDNS(string) (*models.ActiveSession, error)
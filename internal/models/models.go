@@ -2,18 +2,30 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 )
 
+// wrapped by lookups of missing records, match it with errors.Is
+var ErrNotFound = errors.New("does not exist")
+
 type Timestamps struct {
 	CreatedAt *time.Time    `json:"created_at"`
 	UpdatedAt *time.Time    `json:"updated_at"`
 	DeletedAt *sql.NullTime `json:"deleted_at"`
 }
 
+// nil values fallback to provider defaults
 type CompletionParameters struct {
-	MaxTokens   int64 `json:"max_tokens"`
-	Temperature int64 `json:"temperature"`
+	MaxTokens   *int64   `json:"max_tokens"`
+	Temperature *float64 `json:"temperature"`
+}
+
+// how history is reduced before completion, empty strategy sends whole history
+type History struct {
+	Strategy string `json:"history_strategy"`
+	// messages count or tokens budget, depends on strategy
+	Limit *int64 `json:"history_limit"`
 }
 
 type Chat struct {
@@ -21,6 +33,11 @@ type Chat struct {
 	Model string `json:"model"`
 	Name  string `json:"name"`
 	CompletionParameters
+	History
+	PersonaID *int64 `json:"persona_id"`
+	// set only for forked chats
+	ParentChatID    *int64 `json:"parent_chat_id"`
+	ParentMessageID *int64 `json:"parent_message_id"`
 	Timestamps
 }
 
@@ -31,9 +48,24 @@ type Message struct {
 	Content            string `json:"content"`
 	Generation         int64  `json:"generation"`
 	SelectedGeneration bool   `json:"selected_generation"`
+	// streamed answer cancelled before it was finished
+	Interrupted bool `json:"interrupted"`
+	// filled only by queries that need them
+	Attachments []*Attachment `json:"attachments,omitempty"`
 	Timestamps
 }
 
+// file sent together with user message, data is served separately
+type Attachment struct {
+	ID        int64      `json:"id"`
+	MessageID *int64     `json:"message_id"` // nil until message is sent
+	Name      string     `json:"name"`
+	MediaType string     `json:"media_type"`
+	Size      int64      `json:"size"`
+	Data      []byte     `json:"-"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
 type Role struct {
 	ID   int64  `json:"id"`
 	Name string `json:"name"`
@@ -51,6 +83,174 @@ type Template struct {
 	Timestamps
 }
 
+// revision of template content, created on every upsert or edit
+type TemplateVersion struct {
+	ID         int64      `json:"id"`
+	TemplateID int64      `json:"template_id"`
+	Version    int64      `json:"version"`
+	Name       string     `json:"name"`
+	Content    string     `json:"content"`
+	CreatedAt  *time.Time `json:"created_at"`
+}
+
+// core template uses linked one through `template "name"` action
+type TemplateDependency struct {
+	CoreID        int64  `json:"core_id"`
+	CoreName      string `json:"core_name"`
+	LinkedID      int64  `json:"linked_id"`
+	LinkedName    string `json:"linked_name"`
+	LinkedDeleted bool   `json:"linked_deleted"`
+}
+
+type TemplateTree struct {
+	Name     string          `json:"name"`
+	Deleted  bool            `json:"deleted"`
+	Children []*TemplateTree `json:"children"`
+}
+
+// templates used by named one and templates that use it, both recursively
+type TemplateDependencies struct {
+	Name         string          `json:"name"`
+	Dependencies []*TemplateTree `json:"dependencies"`
+	Dependents   []*TemplateTree `json:"dependents"`
+}
+
+// outcome of syncing directory of template files into database
+type TemplateImport struct {
+	Created   []*Template `json:"created"`
+	Updated   []*Template `json:"updated"`
+	Unchanged []*Template `json:"unchanged"`
+	Deleted   []string    `json:"deleted"`
+}
+
+// problem found while linting stored template
+type TemplateIssue struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+type Migration struct {
+	Version int64  `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+// schema state of database, version 0 means no migration was applied
+type MigrationStatus struct {
+	Version    int64        `json:"version"`
+	Dirty      bool         `json:"dirty"`
+	Latest     int64        `json:"latest"`
+	Migrations []*Migration `json:"migrations"`
+}
+
+// size is in bytes, free size is reclaimed by vacuum
+type DatabaseStats struct {
+	Path     string        `json:"path"` // empty for in-memory database
+	Size     int64         `json:"size"`
+	FreeSize int64         `json:"free_size"`
+	Version  int64         `json:"version"`
+	Dirty    bool          `json:"dirty"`
+	Tables   []*TableStats `json:"tables"`
+}
+
+type TableStats struct {
+	Name string `json:"name"`
+	Rows int64  `json:"rows"`
+}
+
+type Usage struct {
+	MessageID    int64   `json:"message_id"`
+	Model        string  `json:"model"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// portable chat, written by export and read back by import
+type ChatExport struct {
+	Name  string `json:"name"`
+	Model string `json:"model"`
+	CompletionParameters
+	History
+	CreatedAt *time.Time       `json:"created_at"`
+	Messages  []*ExportMessage `json:"messages"`
+}
+
+type ExportMessage struct {
+	Role      string       `json:"role"`
+	Content   string       `json:"content"`
+	CreatedAt *time.Time   `json:"created_at,omitempty"`
+	Usage     *ExportUsage `json:"usage,omitempty"` // only for completions
+	// attachment data is not exported, import drops attachments
+	Attachments []*ExportAttachment `json:"attachments,omitempty"`
+}
+
+type ExportAttachment struct {
+	Name      string `json:"name"`
+	MediaType string `json:"media_type"`
+}
+
+type ExportUsage struct {
+	Model        string  `json:"model"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+const (
+	UsageByDay   = "day"
+	UsageByModel = "model"
+	UsageByChat  = "chat"
+)
+
+// purposes of chat usage, spent outside of messages
+const (
+	UsagePurposeTitle   = "title"
+	UsagePurposeSummary = "summary"
+)
+
+type UsageReport struct {
+	Key          string  `json:"key"`  // day, model or chat id, based on grouping
+	Name         string  `json:"name"` // chat name, when grouped by chat
+	Messages     int64   `json:"messages"`
+	InputTokens  int64   `json:"input_tokens"`
+	OutputTokens int64   `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// wraps matched terms in search snippets
+type Highlight struct {
+	Open  string
+	Close string
+}
+
+// reusable system prompt, model and parameters are defaults for chats with persona
+type Persona struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Prompt string `json:"prompt"`
+	Model  string `json:"model"`
+	CompletionParameters
+	Timestamps
+}
+
+type ChatSummary struct {
+	ID             int64      `json:"id"`
+	ChatID         int64      `json:"chat_id"`
+	UntilMessageID int64      `json:"until_message_id"`
+	Content        string     `json:"content"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
+
+type SearchResult struct {
+	MessageID int64      `json:"message_id"`
+	ChatID    int64      `json:"chat_id"`
+	ChatName  string     `json:"chat_name"`
+	Role      string     `json:"role"`
+	Snippet   string     `json:"snippet"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
 type ActiveSession struct {
 	ID          int64  `json:"id"`
 	Address     string `json:"address"`
@@ -79,8 +279,8 @@ Quick info about template capabilities:
       --{{.}} - Will print temlate input only if it is not empty
   --{{end}}
 
->>> --{{if .isEnabled}}
-      --{{.jsonKey}} - prints out specific json key
+>>> --{{if var "isEnabled"}}
+      --{{var "jsonKey"}} - prints out variable from --data file or --var flag
       This block runs if the condition is true.
   --{{end}}
 
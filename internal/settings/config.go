@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"sync"
+	"time"
 )
 
 const Version = "5.5.2"
@@ -34,17 +35,63 @@ type Settings struct {
 	Stdoout         io.Writer
 	Stderr          io.Writer
 	MockCompletion  bool
+	// model that names new chats after first answer, titling is off when empty
+	TitleModel        string
+	TemplateFunctions TemplateFunctions
+	// profile values, empty ones keep built-in defaults
+	DefaultModel    string
+	DefaultTemplate string
+	Server          Server
+	// name of profile selected with --profile, HERMES_PROFILE or config file
+	Profile string
+}
+
+type Server struct {
+	Host string
+	Port string
+}
+
+// limits of template functions that reach outside of template, empty lists
+// disable them
+type TemplateFunctions struct {
+	FileDirs []string // readFile reads only files inside these directories
+	Env      []string // env reads only these variables
 }
 
 type Providers struct {
 	OpenAIKey    string
 	AnthropicKey string
+	GoogleKey    string
+	Local        LocalProvider
+	Requests     Requests
+}
+
+// limits of requests to providers, zero values disable them
+type Requests struct {
+	// waiting for response, streamed response waits that long for every event
+	Timeout    time.Duration
+	MaxRetries int // of rate limited and failed requests
 }
 
-func GetConfig(stdin io.Reader, stdout io.Writer, stderr io.Writer) (*Config, error) {
+// self-hosted server that speaks OpenAI chat completions format
+type LocalProvider struct {
+	BaseURL    string
+	AuthHeader string
+	APIKey     string
+	Models     []string // empty list allows any model
+}
+
+// profile is value of --profile flag, empty one is resolved by
+// SelectProfile
+func GetConfig(
+	profile string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) (*Config, error) {
 	var err error
 	once.Do(func() {
-		config, err = loadConfig(stdin, stdout, stderr)
+		config, err = loadConfig(profile, stdin, stdout, stderr)
 	})
 	return config, err
 }
@@ -56,10 +103,31 @@ func GetInstance() (*Config, error) {
 	return config, nil
 }
 
-func loadConfig(stdin io.Reader, stdout io.Writer, stderr io.Writer) (*Config, error) {
+// env takes precedence over profile from config file
+func loadConfig(
+	profile string,
+	stdin io.Reader,
+	stdout io.Writer,
+	stderr io.Writer,
+) (*Config, error) {
 	var c Config
 	c.AppName = appName
-	loadEnv(&c)
+	if err := loadEnv(&c); err != nil {
+		return &c, err
+	}
+	if err := prepareConfigDir(&c); err != nil {
+		return &c, err
+	}
+	file := &ConfigFile{Profiles: map[string]map[string]string{}}
+	if c.ConfigDir != "" {
+		read, err := ReadConfigFile(ConfigFilePath(c.ConfigDir))
+		if err != nil {
+			return &c, err
+		}
+		file = read
+	}
+	c.Profile = SelectProfile(profile, file)
+	applyProfile(&c, file.Profiles[c.Profile])
 	if err := prepareDNS(&c); err != nil {
 		return &c, err
 	}
@@ -72,19 +140,29 @@ func loadConfig(stdin io.Reader, stdout io.Writer, stderr io.Writer) (*Config, e
 	return &c, nil
 }
 
+// config dir stays empty when system has none, e.g. HOME is not set, then
+// only env and defaults are used
+func prepareConfigDir(c *Config) error {
+	sharedConfigDir, err := os.UserConfigDir()
+	if err != nil {
+		return nil
+	}
+	c.ConfigDir = path.Join(sharedConfigDir, c.AppName)
+	return ensureExists(c.ConfigDir)
+}
+
 func prepareDNS(c *Config) error {
 	if c.DatabaseDSN == ":memory:" || c.DatabaseDSN != "" {
 		return nil
 	}
-	sharedConfigDir, err := os.UserConfigDir()
-	if err != nil {
-		return err
+	if c.ConfigDir == "" {
+		return fmt.Errorf(
+			"config directory is unavailable, set database location with %s\n",
+			HermesDBDNSName,
+		)
 	}
-	hermesConfigDir := path.Join(sharedConfigDir, c.AppName)
-	c.ConfigDir = hermesConfigDir
-	c.DatabaseDSN = path.Join(hermesConfigDir, DefaultDatabaseName)
-	err = ensureExists(hermesConfigDir)
-	return err
+	c.DatabaseDSN = path.Join(c.ConfigDir, DefaultDatabaseName)
+	return nil
 }
 
 func ensureExists(path string) error {
@@ -0,0 +1,27 @@
+package settings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigWithoutConfigDir(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv(HermesProfileName, "")
+
+	t.Setenv(HermesDBDNSName, ":memory:")
+	c, err := loadConfig("", nil, nil, nil)
+	if err != nil {
+		t.Fatalf("expected env and defaults to be enough, got error: %s\n", err)
+	}
+	if c.ConfigDir != "" || c.DatabaseDSN != ":memory:" || c.Profile != DefaultProfile {
+		t.Errorf("bad config without config dir: %+v\n", c.Settings)
+	}
+
+	t.Setenv(HermesDBDNSName, "")
+	if _, err := loadConfig("", nil, nil, nil); err == nil ||
+		!strings.Contains(err.Error(), HermesDBDNSName) {
+		t.Errorf("expected missing database location to be reported, got %v\n", err)
+	}
+}
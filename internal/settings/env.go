@@ -1,20 +1,84 @@
 package settings
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
-	HermesOpenAIApiKeyName    = "HERMES_OPENAI_API_KEY"
-	HermesAnthropicApiKeyName = "HERMES_ANTHROPIC_API_KEY"
+	HermesOpenAIApiKeyName     = "HERMES_OPENAI_API_KEY"
+	HermesAnthropicApiKeyName  = "HERMES_ANTHROPIC_API_KEY"
+	HermesGoogleApiKeyName     = "HERMES_GOOGLE_API_KEY"
+	HermesLocalBaseURLName     = "HERMES_LOCAL_BASE_URL"
+	HermesLocalAuthHeaderName  = "HERMES_LOCAL_AUTH_HEADER"
+	HermesLocalApiKeyName      = "HERMES_LOCAL_API_KEY"
+	HermesLocalModelsName      = "HERMES_LOCAL_MODELS"
+	HermesTitleModelName       = "HERMES_TITLE_MODEL"
+	HermesTemplateFileDirsName = "HERMES_TEMPLATE_FILE_DIRS"
+	HermesTemplateEnvName      = "HERMES_TEMPLATE_ENV"
+	HermesDBDNSName            = "HERMES_DB_DNS"
+	HermesProfileName          = "HERMES_PROFILE"
+	HermesRequestTimeoutName   = "HERMES_REQUEST_TIMEOUT"
+	HermesMaxRetriesName       = "HERMES_MAX_RETRIES"
 )
 
-func loadEnv(c *Config) {
+const (
+	DefaultRequestTimeout = 2 * time.Minute
+	DefaultMaxRetries     = 3
+)
+
+func loadEnv(c *Config) error {
 	c.OpenAIKey = os.Getenv(HermesOpenAIApiKeyName)
 	c.AnthropicKey = os.Getenv(HermesAnthropicApiKeyName)
-	c.DatabaseDSN = os.Getenv("HERMES_DB_DNS")
+	c.GoogleKey = os.Getenv(HermesGoogleApiKeyName)
+	c.Local = LocalProvider{
+		BaseURL:    os.Getenv(HermesLocalBaseURLName),
+		AuthHeader: os.Getenv(HermesLocalAuthHeaderName),
+		APIKey:     os.Getenv(HermesLocalApiKeyName),
+		Models:     splitList(os.Getenv(HermesLocalModelsName)),
+	}
+	c.TitleModel = os.Getenv(HermesTitleModelName)
+	c.TemplateFunctions = TemplateFunctions{
+		FileDirs: splitList(os.Getenv(HermesTemplateFileDirsName)),
+		Env:      splitList(os.Getenv(HermesTemplateEnvName)),
+	}
+	c.DatabaseDSN = os.Getenv(HermesDBDNSName)
 	mockCompletion := os.Getenv("HERMES_MOCK_COMPLETION")
 	if mockCompletion != "" {
 		c.MockCompletion = true
 	}
+	return loadRequests(c)
+}
+
+func loadRequests(c *Config) error {
+	c.Requests = Requests{Timeout: DefaultRequestTimeout, MaxRetries: DefaultMaxRetries}
+	if value := os.Getenv(HermesRequestTimeoutName); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("%s must be duration like 90s or 5m, got %q\n", HermesRequestTimeoutName, value)
+		}
+		c.Requests.Timeout = timeout
+	}
+	if value := os.Getenv(HermesMaxRetriesName); value != "" {
+		retries, err := strconv.Atoi(value)
+		if err != nil || retries < 0 {
+			return fmt.Errorf("%s must be non negative number, got %q\n", HermesMaxRetriesName, value)
+		}
+		c.Requests.MaxRetries = retries
+	}
+	return nil
+}
+
+// splits comma separated list, blank entries are dropped
+func splitList(value string) []string {
+	res := []string{}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			res = append(res, entry)
+		}
+	}
+	return res
 }
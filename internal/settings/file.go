@@ -0,0 +1,242 @@
+package settings
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+const ConfigFileName = "config.toml"
+
+// profile used when none is selected
+const DefaultProfile = "default"
+
+// key that selects active profile, it lives outside of profiles
+const ProfileKey = "profile"
+
+// keys allowed inside profile, in order they are written
+var ProfileKeys = []string{
+	"openai_api_key",
+	"anthropic_api_key",
+	"google_api_key",
+	"local_base_url",
+	"local_auth_header",
+	"local_api_key",
+	"local_models",
+	"title_model",
+	"default_model",
+	"default_template",
+	"database_dsn",
+	"host",
+	"port",
+}
+
+// values are masked by `hermes config list`
+var SecretKeys = []string{
+	"openai_api_key",
+	"anthropic_api_key",
+	"google_api_key",
+	"local_api_key",
+}
+
+// config.toml with top level `profile` key and `[profiles.<name>]` tables
+// of string values. Files with comments are not rewritten, comments would
+// be lost
+type ConfigFile struct {
+	Profile  string
+	Profiles map[string]map[string]string
+	// file has comments, it is read only for `hermes config set`
+	commented bool
+}
+
+// layout of config.toml as decoded by toml library
+type configFileContent struct {
+	Profile  string                       `toml:"profile,omitempty"`
+	Profiles map[string]map[string]string `toml:"profiles,omitempty"`
+}
+
+func ConfigFilePath(configDir string) string {
+	return path.Join(configDir, ConfigFileName)
+}
+
+// missing file is empty config
+func ReadConfigFile(filePath string) (*ConfigFile, error) {
+	file := &ConfigFile{Profiles: map[string]map[string]string{}}
+	content, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return file, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := file.parse(string(content)); err != nil {
+		return nil, fmt.Errorf("%s: %s", filePath, err)
+	}
+	return file, nil
+}
+
+func (f *ConfigFile) parse(content string) error {
+	decoded := configFileContent{}
+	meta, err := toml.Decode(content, &decoded)
+	if err != nil {
+		return fmt.Errorf("%s\n", err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return fmt.Errorf(
+			"unknown key %q, only %q and [profiles.<name>] tables are allowed\n",
+			undecoded[0].String(),
+			ProfileKey,
+		)
+	}
+	for name, profile := range decoded.Profiles {
+		if name == "" {
+			return fmt.Errorf("profile name is empty\n")
+		}
+		if meta.Type("profiles", name) != "Hash" {
+			return fmt.Errorf("%q must be [profiles.%s] table\n", name, name)
+		}
+		for key := range profile {
+			if !slices.Contains(ProfileKeys, key) {
+				return fmt.Errorf("unknown key %q in profile %q\n", key, name)
+			}
+		}
+		f.Profiles[name] = profile
+	}
+	f.Profile = decoded.Profile
+	f.commented = hasComment(content)
+	return nil
+}
+
+// reports `#` that is not inside of string, toml library drops comments
+// without telling about them
+func hasComment(content string) bool {
+	for i := 0; i < len(content); i++ {
+		switch content[i] {
+		case '#':
+			return true
+		case '"', '\'':
+			quote := content[i : i+1]
+			if strings.HasPrefix(content[i:], strings.Repeat(quote, 3)) {
+				quote = strings.Repeat(quote, 3)
+			}
+			i += len(quote)
+			for i < len(content) && !strings.HasPrefix(content[i:], quote) {
+				// literal strings have no escapes
+				if content[i] == '\\' && quote[0] == '"' {
+					i++
+				}
+				i++
+			}
+			i += len(quote) - 1
+		}
+	}
+	return false
+}
+
+func (f *ConfigFile) String() string {
+	b := &strings.Builder{}
+	encoder := toml.NewEncoder(b)
+	encoder.Indent = ""
+	// encoding of string maps does not fail
+	encoder.Encode(configFileContent{Profile: f.Profile, Profiles: f.Profiles})
+	return b.String()
+}
+
+func (f *ConfigFile) Save(filePath string) error {
+	if f.commented {
+		return fmt.Errorf(
+			"%s has comments that would be lost, edit it manually\n",
+			filePath,
+		)
+	}
+	// file holds api keys
+	return os.WriteFile(filePath, []byte(f.String()), 0o600)
+}
+
+func (f *ConfigFile) ProfileNames() []string {
+	names := []string{}
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// `profile` reads active profile, other keys are read from given profile
+func (f *ConfigFile) Get(profile string, key string) (string, error) {
+	if key == ProfileKey {
+		return f.Profile, nil
+	}
+	if !slices.Contains(ProfileKeys, key) {
+		return "", unknownKeyError(key)
+	}
+	return f.Profiles[profile][key], nil
+}
+
+// empty value removes key, profile is created when missing
+func (f *ConfigFile) Set(profile string, key string, value string) error {
+	if key == ProfileKey {
+		f.Profile = value
+		return nil
+	}
+	if !slices.Contains(ProfileKeys, key) {
+		return unknownKeyError(key)
+	}
+	if _, ok := f.Profiles[profile]; !ok {
+		f.Profiles[profile] = map[string]string{}
+	}
+	if value == "" {
+		delete(f.Profiles[profile], key)
+		return nil
+	}
+	f.Profiles[profile][key] = value
+	return nil
+}
+
+func unknownKeyError(key string) error {
+	return fmt.Errorf(
+		"unknown config key %q, expected %s or one of: %s\n",
+		key,
+		ProfileKey,
+		strings.Join(ProfileKeys, ", "),
+	)
+}
+
+// profile selected by flag, then by env, then by config file
+func SelectProfile(flag string, file *ConfigFile) string {
+	for _, name := range []string{flag, os.Getenv(HermesProfileName), file.Profile} {
+		if name != "" {
+			return name
+		}
+	}
+	return DefaultProfile
+}
+
+// profile values fill settings that were not set by env
+func applyProfile(c *Config, profile map[string]string) {
+	for key, target := range map[string]*string{
+		"openai_api_key":    &c.OpenAIKey,
+		"anthropic_api_key": &c.AnthropicKey,
+		"google_api_key":    &c.GoogleKey,
+		"local_base_url":    &c.Local.BaseURL,
+		"local_auth_header": &c.Local.AuthHeader,
+		"local_api_key":     &c.Local.APIKey,
+		"title_model":       &c.TitleModel,
+		"default_model":     &c.DefaultModel,
+		"default_template":  &c.DefaultTemplate,
+		"database_dsn":      &c.DatabaseDSN,
+		"host":              &c.Server.Host,
+		"port":              &c.Server.Port,
+	} {
+		if *target == "" {
+			*target = profile[key]
+		}
+	}
+	if len(c.Local.Models) == 0 {
+		c.Local.Models = splitList(profile["local_models"])
+	}
+}
@@ -0,0 +1,195 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigFileParse(t *testing.T) {
+	type testCase struct {
+		name        string
+		content     string
+		expected    *ConfigFile
+		shouldError bool
+	}
+
+	table := []testCase{
+		{
+			name: "should read profiles",
+			content: `# selected when nothing else is set
+profile = "work"
+
+[profiles.default]
+default_model = "openai/gpt-4o" # trailing comment
+port = '8080'
+
+[profiles."work laptop"]
+openai_api_key = "sk-#not-a-comment"
+`,
+			expected: &ConfigFile{
+				Profile: "work",
+				Profiles: map[string]map[string]string{
+					"default":     {"default_model": "openai/gpt-4o", "port": "8080"},
+					"work laptop": {"openai_api_key": "sk-#not-a-comment"},
+				},
+			},
+		},
+		{
+			name:     "should read empty file",
+			content:  "",
+			expected: &ConfigFile{Profiles: map[string]map[string]string{}},
+		},
+		{
+			name:        "should reject unknown key",
+			content:     "[profiles.default]\nmodel = \"x\"\n",
+			shouldError: true,
+		},
+		{
+			name:        "should reject profile key outside of table",
+			content:     "default_model = \"x\"\n",
+			shouldError: true,
+		},
+		{
+			name:        "should reject unknown table",
+			content:     "[server]\n",
+			shouldError: true,
+		},
+		{
+			name:        "should reject unknown key inside profiles table",
+			content:     "[profiles]\ndefault_model = \"x\"\n",
+			shouldError: true,
+		},
+		{
+			name: "should read multiline and escaped strings",
+			content: `[profiles.default]
+local_auth_header = """
+X-Token"""
+local_api_key = "a\"b"
+`,
+			expected: &ConfigFile{
+				Profiles: map[string]map[string]string{
+					"default": {"local_auth_header": "X-Token", "local_api_key": `a"b`},
+				},
+			},
+		},
+		{
+			name:        "should reject unquoted value",
+			content:     "[profiles.default]\nport = 8080\n",
+			shouldError: true,
+		},
+	}
+
+	for _, test := range table {
+		file := &ConfigFile{Profiles: map[string]map[string]string{}}
+		err := file.parse(test.content)
+		if test.shouldError {
+			if err == nil {
+				t.Errorf("%q - expected to error but did not\n", test.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q - unexpected error: %s\n", test.name, err)
+			continue
+		}
+		file.commented = false
+		if !reflect.DeepEqual(test.expected, file) {
+			t.Errorf("%q - bad result\nexpected: %+v\nactual:   %+v\n", test.name, test.expected, file)
+		}
+		reparsed := &ConfigFile{Profiles: map[string]map[string]string{}}
+		if err := reparsed.parse(file.String()); err != nil || !reflect.DeepEqual(file, reparsed) {
+			t.Errorf("%q - written file does not read back: %v\n%s\n", test.name, err, file.String())
+		}
+	}
+}
+
+func TestConfigFileSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	file, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("missing file should be empty config, got error: %s\n", err)
+	}
+	if err := file.Set("work", "default_model", "openai/gpt-4o"); err != nil {
+		t.Fatalf("failed to set value: %s\n", err)
+	}
+	if err := file.Set("work", "model", "openai/gpt-4o"); err == nil {
+		t.Errorf("expected unknown key to error\n")
+	}
+	if err := file.Save(path); err != nil {
+		t.Fatalf("failed to save: %s\n", err)
+	}
+	saved, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("failed to read saved file: %s\n", err)
+	}
+	if value, _ := saved.Get("work", "default_model"); value != "openai/gpt-4o" {
+		t.Errorf("bad saved value: %q\n", value)
+	}
+	if err := saved.Set("work", "default_model", ""); err != nil {
+		t.Fatalf("failed to remove value: %s\n", err)
+	}
+	if _, ok := saved.Profiles["work"]["default_model"]; ok {
+		t.Errorf("expected empty value to remove key\n")
+	}
+}
+
+func TestConfigFileWithCommentsIsNotRewritten(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	content := "[profiles.default]\n# used at work\ndefault_model = \"openai/gpt-4o\"\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write file: %s\n", err)
+	}
+	file, err := ReadConfigFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %s\n", err)
+	}
+	if err := file.Set("default", "port", "9000"); err != nil {
+		t.Fatalf("failed to set value: %s\n", err)
+	}
+	if err := file.Save(path); err == nil {
+		t.Errorf("expected file with comments to be left alone\n")
+	}
+	saved, err := os.ReadFile(path)
+	if err != nil || string(saved) != content {
+		t.Errorf("file with comments was changed: %q, error: %v\n", saved, err)
+	}
+}
+
+func TestProfilePrecedence(t *testing.T) {
+	file := &ConfigFile{Profile: "file"}
+
+	t.Setenv(HermesProfileName, "")
+	if profile := SelectProfile("", &ConfigFile{}); profile != DefaultProfile {
+		t.Errorf("expected default profile, got %q\n", profile)
+	}
+	if profile := SelectProfile("", file); profile != "file" {
+		t.Errorf("expected profile from file, got %q\n", profile)
+	}
+	t.Setenv(HermesProfileName, "env")
+	if profile := SelectProfile("", file); profile != "env" {
+		t.Errorf("expected profile from env, got %q\n", profile)
+	}
+	if profile := SelectProfile("flag", file); profile != "flag" {
+		t.Errorf("expected profile from flag, got %q\n", profile)
+	}
+
+	c := &Config{}
+	c.OpenAIKey = "from env"
+	applyProfile(c, map[string]string{
+		"openai_api_key": "from profile",
+		"default_model":  "openai/gpt-4o",
+		"local_models":   "llama3, qwen",
+		"port":           "9000",
+	})
+	if c.OpenAIKey != "from env" {
+		t.Errorf("profile should not override env, got %q\n", c.OpenAIKey)
+	}
+	if c.DefaultModel != "openai/gpt-4o" || c.Server.Port != "9000" {
+		t.Errorf("profile values were not applied: %+v\n", c.Settings)
+	}
+	if !reflect.DeepEqual(c.Local.Models, []string{"llama3", "qwen"}) {
+		t.Errorf("bad local models: %v\n", c.Local.Models)
+	}
+}
@@ -0,0 +1,173 @@
+package sqlite3
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/ncruces/go-sqlite3/driver"
+)
+
+// path of main database file, empty for in-memory database
+func databaseFile(db *sql.DB) (string, error) {
+	var seq int
+	var name, file string
+	if err := db.QueryRow("PRAGMA database_list").Scan(&seq, &name, &file); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// copies database next to its file with reason and timestamp suffix,
+// in-memory database has no file and returns empty path
+func backupDatabase(db *sql.DB, reason string) (string, error) {
+	file, err := databaseFile(db)
+	if err != nil || file == "" {
+		return "", err
+	}
+	path := fmt.Sprintf("%s.%s-%s.bak", file, reason, time.Now().Format("20060102-150405.000"))
+	return path, backupDatabaseTo(db, path)
+}
+
+// consistent snapshot, safe while other connections write
+func backupDatabaseTo(db *sql.DB, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("backup %q already exists\n", path)
+	}
+	_, err := db.Exec("VACUUM INTO ?", path)
+	return err
+}
+
+// schema version of backup, it must be clean hermes database that this
+// binary knows how to migrate
+func checkBackup(path string, latest int64) (int64, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	// escaped uri, so "?" or "#" in path are not read as parameters, path
+	// is absolute, otherwise its first segment would become uri host
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return 0, err
+	}
+	uri := url.URL{Scheme: "file", Path: abs, RawQuery: "mode=ro"}
+	backup, err := sql.Open("sqlite3", uri.String())
+	if err != nil {
+		return 0, err
+	}
+	defer backup.Close()
+	var check string
+	if err := backup.QueryRow("PRAGMA quick_check").Scan(&check); err != nil {
+		return 0, fmt.Errorf("%q is not sqlite database: %s\n", path, err)
+	}
+	if check != "ok" {
+		return 0, fmt.Errorf("%q is corrupted: %s\n", path, check)
+	}
+	var version int64
+	var dirty bool
+	if err := backup.QueryRow(
+		fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", DefaultMigrationsTable),
+	).Scan(&version, &dirty); err != nil {
+		return 0, fmt.Errorf("%q is not hermes database, schema version is missing\n", path)
+	}
+	if dirty {
+		return version, fmt.Errorf("%q is dirty at migration %d\n", path, version)
+	}
+	if version > latest {
+		return version, fmt.Errorf(
+			"%q has schema version %d, this hermes knows migrations up to %d\n",
+			path,
+			version,
+			latest,
+		)
+	}
+	return version, nil
+}
+
+// replaces database content with backup using online backup API, current
+// content is backed up first. Returns path of that backup
+func restoreDatabase(ctx context.Context, db *sql.DB, path string) (string, error) {
+	migrations, err := listMigrations()
+	if err != nil {
+		return "", err
+	}
+	latest := int64(0)
+	for _, migration := range migrations {
+		latest = max(latest, migration.Version)
+	}
+	if _, err := checkBackup(path, latest); err != nil {
+		return "", err
+	}
+	previous, err := backupDatabase(db, "restore")
+	if err != nil {
+		return "", fmt.Errorf("failed to back up database before restore: %w", err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return previous, err
+	}
+	defer conn.Close()
+	return previous, conn.Raw(func(driverConn any) error {
+		return driverConn.(driver.Conn).Raw().Restore("main", path)
+	})
+}
+
+const tableStatsQuery = `
+SELECT name FROM pragma_table_list
+WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite_%' AND name != $1
+ORDER BY name;
+`
+
+func getDatabaseStats(db *sql.DB) (*models.DatabaseStats, error) {
+	stats := &models.DatabaseStats{Tables: []*models.TableStats{}}
+	file, err := databaseFile(db)
+	if err != nil {
+		return nil, err
+	}
+	stats.Path = file
+	var pageSize, pageCount, freePages int64
+	for pragma, receiver := range map[string]*int64{
+		"page_size":      &pageSize,
+		"page_count":     &pageCount,
+		"freelist_count": &freePages,
+	} {
+		if err := db.QueryRow("PRAGMA " + pragma).Scan(receiver); err != nil {
+			return nil, err
+		}
+	}
+	stats.Size = pageSize * pageCount
+	stats.FreeSize = pageSize * freePages
+	if err := db.QueryRow(
+		fmt.Sprintf("SELECT version, dirty FROM %s LIMIT 1", DefaultMigrationsTable),
+	).Scan(&stats.Version, &stats.Dirty); err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	rows, err := db.Query(tableStatsQuery, DefaultMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		table := &models.TableStats{}
+		if err := rows.Scan(&table.Name); err != nil {
+			return nil, err
+		}
+		stats.Tables = append(stats.Tables, table)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, table := range stats.Tables {
+		if err := db.QueryRow(
+			fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, table.Name),
+		).Scan(&table.Rows); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
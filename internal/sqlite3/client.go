@@ -26,6 +26,19 @@ func NewSQLite3(dsn string) (*SQLite3, error) {
 	return &SQLite3{DB: db}, err
 }
 
+// opens database without running migrations, used to recover database
+// that can not be migrated automatically
+func OpenSQLite3(dsn string) (*SQLite3, error) {
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePragma(db); err != nil {
+		return nil, err
+	}
+	return &SQLite3{DB: db}, nil
+}
+
 func (s *SQLite3) Close() error {
 	return s.DB.Close()
 }
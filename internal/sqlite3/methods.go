@@ -2,6 +2,8 @@ package sqlite3
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/k10wl/hermes/internal/models"
 )
@@ -12,35 +14,355 @@ func (s *SQLite3) CreateMessage(
 	role string,
 	content string,
 ) (*models.Message, error) {
-	return createMessage(s.DB.QueryRowContext, ctx, chatId, role, content)
+	return createMessage(s.DB.QueryRowContext, ctx, chatId, role, content, false)
+}
+
+func (s *SQLite3) CreateMessageWithUsage(
+	ctx context.Context,
+	chatID int64,
+	role string,
+	content string,
+	interrupted bool,
+	usage *models.Usage,
+) (*models.Message, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	message, err := createMessage(tx.QueryRowContext, ctx, chatID, role, content, interrupted)
+	if err != nil {
+		return nil, err
+	}
+	usage.MessageID = message.ID
+	if err := createMessageUsage(tx.ExecContext, ctx, usage); err != nil {
+		return nil, err
+	}
+	return message, tx.Commit()
+}
+
+func (s *SQLite3) CreateMessageAndCompletion(
+	ctx context.Context,
+	message *models.Message,
+	completion *models.Message,
+	usage *models.Usage,
+) (*models.Message, *models.Message, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	created, err := createMessage(
+		tx.QueryRowContext,
+		ctx,
+		message.ChatID,
+		message.Role,
+		message.Content,
+		false,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := storeAttachments(
+		tx.QueryRowContext,
+		tx.ExecContext,
+		ctx,
+		created.ID,
+		message.Attachments,
+	); err != nil {
+		return nil, nil, err
+	}
+	answer, err := createMessage(
+		tx.QueryRowContext,
+		ctx,
+		completion.ChatID,
+		completion.Role,
+		completion.Content,
+		completion.Interrupted,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	usage.MessageID = answer.ID
+	if err := createMessageUsage(tx.ExecContext, ctx, usage); err != nil {
+		return nil, nil, err
+	}
+	return created, answer, tx.Commit()
+}
+
+func (s *SQLite3) ForkChat(
+	ctx context.Context,
+	chatID int64,
+	messageID int64,
+) (*models.Chat, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chat, err := forkChat(tx.QueryRowContext, tx.ExecContext, ctx, chatID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	return chat, tx.Commit()
+}
+
+func (s *SQLite3) GetMessageByID(ctx context.Context, id int64) (*models.Message, error) {
+	return getMessageByID(s.DB.QueryRowContext, ctx, id)
+}
+
+func (s *SQLite3) GetMessageHistory(ctx context.Context, id int64) ([]*models.Message, error) {
+	return getMessageHistory(s.DB.QueryContext, ctx, id)
+}
+
+func (s *SQLite3) GetMessageGenerations(
+	ctx context.Context,
+	id int64,
+) ([]*models.Message, error) {
+	return getMessageGenerations(s.DB.QueryContext, ctx, id)
+}
+
+func (s *SQLite3) CreateMessageGeneration(
+	ctx context.Context,
+	id int64,
+	role string,
+	content string,
+	interrupted bool,
+	usage *models.Usage,
+) (*models.Message, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	message, err := createMessageGeneration(
+		tx.QueryRowContext,
+		tx.ExecContext,
+		ctx,
+		id,
+		role,
+		content,
+		interrupted,
+	)
+	if err != nil {
+		return nil, err
+	}
+	usage.MessageID = message.ID
+	if err := createMessageUsage(tx.ExecContext, ctx, usage); err != nil {
+		return nil, err
+	}
+	return message, tx.Commit()
+}
+
+func (s *SQLite3) CreateAttachment(
+	ctx context.Context,
+	attachment *models.Attachment,
+) (*models.Attachment, error) {
+	return createAttachment(s.DB.QueryRowContext, ctx, attachment)
+}
+
+func (s *SQLite3) DeleteStaleAttachments(ctx context.Context, lifetime time.Duration) error {
+	return deleteStaleAttachments(s.DB.ExecContext, ctx, lifetime)
+}
+
+func (s *SQLite3) GetChatAttachments(
+	ctx context.Context,
+	chatID int64,
+) ([]*models.Attachment, error) {
+	return getChatAttachments(s.DB.QueryContext, ctx, chatID)
+}
+
+func (s *SQLite3) GetAttachmentByID(ctx context.Context, id int64) (*models.Attachment, error) {
+	return getAttachmentByID(s.DB.QueryRowContext, ctx, id)
+}
+
+func (s *SQLite3) SelectMessageGeneration(
+	ctx context.Context,
+	id int64,
+) (*models.Message, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	message, err := selectMessageGeneration(tx.QueryRowContext, tx.ExecContext, ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return message, tx.Commit()
+}
+
+func (s *SQLite3) SearchMessages(
+	ctx context.Context,
+	terms string,
+	limit int64,
+	highlight models.Highlight,
+) ([]*models.SearchResult, error) {
+	return searchMessages(s.DB.QueryContext, ctx, terms, limit, highlight)
+}
+
+func (s *SQLite3) CreateChatUsage(
+	ctx context.Context,
+	chatID int64,
+	purpose string,
+	usage *models.Usage,
+) error {
+	return createChatUsage(s.DB.ExecContext, ctx, chatID, purpose, usage)
+}
+
+func (s *SQLite3) GetUsageReport(
+	ctx context.Context,
+	groupBy string,
+) ([]*models.UsageReport, error) {
+	return getUsageReport(s.DB.QueryContext, ctx, groupBy)
 }
 
 func (s *SQLite3) CreateChat(ctx context.Context, name string) (*models.Chat, error) {
-	return createChat(s.DB.QueryRowContext, ctx, name)
+	return createChat(s.DB.QueryRowContext, ctx, name, "", models.CompletionParameters{}, nil)
 }
 
 func (s *SQLite3) CreateChatAndMessage(
 	ctx context.Context,
 	role string,
 	content string,
+	model string,
+	parameters models.CompletionParameters,
+	personaID *int64,
+	attachments []*models.Attachment,
 ) (*models.Chat, *models.Message, error) {
 	tx, err := s.DB.Begin()
 	if err != nil {
 		return nil, nil, err
 	}
 	defer tx.Rollback()
-	chat, err := createChat(tx.QueryRowContext, ctx, content)
+	chat, err := createChat(tx.QueryRowContext, ctx, content, model, parameters, personaID)
 	if err != nil {
 		return nil, nil, err
 	}
-	message, err := createMessage(tx.QueryRowContext, ctx, chat.ID, role, content)
+	message, err := createMessage(tx.QueryRowContext, ctx, chat.ID, role, content, false)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := storeAttachments(
+		tx.QueryRowContext,
+		tx.ExecContext,
+		ctx,
+		message.ID,
+		attachments,
+	); err != nil {
+		return nil, nil, err
+	}
 	err = tx.Commit()
 	return chat, message, err
 }
 
+func (s *SQLite3) GetChatByID(ctx context.Context, id int64) (*models.Chat, error) {
+	return getChatByID(s.DB.QueryRowContext, ctx, id)
+}
+
+func (s *SQLite3) RenameChat(
+	ctx context.Context,
+	id int64,
+	name string,
+) (*models.Chat, error) {
+	return renameChat(s.DB.QueryRowContext, ctx, id, name)
+}
+
+func (s *SQLite3) DeleteChat(ctx context.Context, id int64) (*models.Chat, error) {
+	return deleteChat(s.DB.QueryRowContext, ctx, id)
+}
+
+func (s *SQLite3) DiscardChat(ctx context.Context, id int64) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := discardChat(tx.ExecContext, ctx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLite3) UpdateChatParameters(
+	ctx context.Context,
+	id int64,
+	model string,
+	parameters models.CompletionParameters,
+) (*models.Chat, error) {
+	return updateChatParameters(s.DB.QueryRowContext, ctx, id, model, parameters)
+}
+
+func (s *SQLite3) UpdateChatHistory(
+	ctx context.Context,
+	id int64,
+	history models.History,
+) (*models.Chat, error) {
+	return updateChatHistory(s.DB.QueryRowContext, ctx, id, history)
+}
+
+func (s *SQLite3) CreateChatSummary(
+	ctx context.Context,
+	chatID int64,
+	untilMessageID int64,
+	content string,
+) (*models.ChatSummary, error) {
+	return createChatSummary(s.DB.QueryRowContext, ctx, chatID, untilMessageID, content)
+}
+
+func (s *SQLite3) GetChatSummaries(
+	ctx context.Context,
+	chatID int64,
+) ([]*models.ChatSummary, error) {
+	return getChatSummaries(s.DB.QueryContext, ctx, chatID)
+}
+
+func (s *SQLite3) SetChatPersona(
+	ctx context.Context,
+	chatID int64,
+	personaID *int64,
+) (*models.Chat, error) {
+	return setChatPersona(s.DB.QueryRowContext, ctx, chatID, personaID)
+}
+
+func (s *SQLite3) UpsertPersona(
+	ctx context.Context,
+	persona *models.Persona,
+) (*models.Persona, error) {
+	return upsertPersona(s.DB.QueryRowContext, ctx, persona)
+}
+
+func (s *SQLite3) GetPersonas(ctx context.Context) ([]*models.Persona, error) {
+	return getPersonas(s.DB.QueryContext, ctx)
+}
+
+func (s *SQLite3) GetPersonaByName(ctx context.Context, name string) (*models.Persona, error) {
+	return getPersonaByName(s.DB.QueryRowContext, ctx, name)
+}
+
+func (s *SQLite3) GetPersonaByID(ctx context.Context, id int64) (*models.Persona, error) {
+	return getPersonaByID(s.DB.QueryRowContext, ctx, id)
+}
+
+func (s *SQLite3) DeletePersonaByName(
+	ctx context.Context,
+	name string,
+) (*models.Persona, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	persona, err := deletePersonaByName(tx.QueryRowContext, ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := detachPersona(tx.ExecContext, ctx, persona.ID); err != nil {
+		return nil, err
+	}
+	return persona, tx.Commit()
+}
+
 func (s *SQLite3) GetChats(
 	ctx context.Context,
 	limit int64,
@@ -56,6 +378,29 @@ func (s *SQLite3) GetChatMessages(
 	return getChatMessages(s.DB.QueryContext, ctx, chatID)
 }
 
+func (s *SQLite3) GetChatUsage(
+	ctx context.Context,
+	chatID int64,
+) ([]*models.Usage, error) {
+	return getChatUsage(s.DB.QueryContext, ctx, chatID)
+}
+
+func (s *SQLite3) ImportChat(
+	ctx context.Context,
+	export *models.ChatExport,
+) (*models.Chat, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chat, err := importChat(tx.QueryRowContext, tx.ExecContext, ctx, export)
+	if err != nil {
+		return nil, err
+	}
+	return chat, tx.Commit()
+}
+
 func (s *SQLite3) GetWebSettings(ctx context.Context) (*models.WebSettings, error) {
 	return getWebSettings(s.DB.QueryRowContext, ctx)
 }
@@ -77,8 +422,111 @@ func (s SQLite3) UpsertTemplate(
 	ctx context.Context,
 	name string,
 	template string,
+	dependencies []string,
 ) (*models.Template, error) {
-	return upsertTemplate(s.DB.QueryRowContext, ctx, name, template)
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := snapshotTemplates(tx, ctx, name); err != nil {
+		return nil, err
+	}
+	res, err := upsertTemplate(tx.QueryRowContext, ctx, name, template)
+	if err != nil {
+		return nil, err
+	}
+	if err := createTemplateVersion(tx.ExecContext, ctx, res.ID); err != nil {
+		return nil, err
+	}
+	if err := setTemplateDependencies(tx.ExecContext, ctx, res.ID, dependencies); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
+}
+
+func (s SQLite3) ImportTemplates(
+	ctx context.Context,
+	templates []*models.Template,
+	dependencies map[string][]string,
+	deleted []string,
+) ([]*models.Template, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	upserted := []*models.Template{}
+	for _, template := range templates {
+		if err := snapshotTemplates(tx, ctx, template.Name); err != nil {
+			return nil, err
+		}
+		res, err := upsertTemplate(tx.QueryRowContext, ctx, template.Name, template.Content)
+		if err != nil {
+			return nil, err
+		}
+		if err := createTemplateVersion(tx.ExecContext, ctx, res.ID); err != nil {
+			return nil, err
+		}
+		if err := setTemplateDependencies(
+			tx.ExecContext,
+			ctx,
+			res.ID,
+			dependencies[template.Name],
+		); err != nil {
+			return nil, err
+		}
+		upserted = append(upserted, res)
+	}
+	for _, name := range deleted {
+		if _, err := deleteTemplateByName(tx.ExecContext, ctx, name); err != nil {
+			return nil, err
+		}
+	}
+	return upserted, tx.Commit()
+}
+
+// templates written before versioning have no history, their content is
+// stored before being overwritten
+func snapshotTemplates(tx *sql.Tx, ctx context.Context, names ...string) error {
+	templates, err := getTemplatesByNames(tx.QueryContext, ctx, names)
+	if err != nil {
+		return err
+	}
+	for _, template := range templates {
+		if err := createTemplateVersion(tx.ExecContext, ctx, template.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s SQLite3) GetTemplateDependencies(
+	ctx context.Context,
+) ([]*models.TemplateDependency, error) {
+	return getTemplateDependencies(s.DB.QueryContext, ctx)
+}
+
+func (s SQLite3) GetTemplatesWithDependencies(
+	ctx context.Context,
+	names []string,
+) ([]*models.Template, error) {
+	return getTemplatesWithDependencies(s.DB.QueryContext, ctx, names)
+}
+
+func (s SQLite3) GetTemplateVersions(
+	ctx context.Context,
+	name string,
+) ([]*models.TemplateVersion, error) {
+	return getTemplateVersions(s.DB.QueryContext, ctx, name)
+}
+
+func (s SQLite3) GetTemplateVersion(
+	ctx context.Context,
+	name string,
+	version int64,
+) (*models.TemplateVersion, error) {
+	return getTemplateVersion(s.DB.QueryRowContext, ctx, name, version)
 }
 
 func (s SQLite3) GetTemplatesByNames(
@@ -100,8 +548,27 @@ func (s SQLite3) EditTemplateByName(
 	name string,
 	newName string,
 	content string,
+	dependencies []string,
 ) (*models.Template, error) {
-	return editTemplateByName(s.DB.QueryRowContext, ctx, name, newName, content)
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if err := snapshotTemplates(tx, ctx, name); err != nil {
+		return nil, err
+	}
+	res, err := editTemplateByName(tx.QueryRowContext, ctx, name, newName, content)
+	if err != nil {
+		return nil, err
+	}
+	if err := createTemplateVersion(tx.ExecContext, ctx, res.ID); err != nil {
+		return nil, err
+	}
+	if err := setTemplateDependencies(tx.ExecContext, ctx, res.ID, dependencies); err != nil {
+		return nil, err
+	}
+	return res, tx.Commit()
 }
 
 func (s SQLite3) CreateActiveSession(activeSession *models.ActiveSession) error {
@@ -131,3 +598,32 @@ func (s SQLite3) GetTemplateByID(
 ) (*models.Template, error) {
 	return getTemplateByID(s.DB.QueryRowContext, ctx, id)
 }
+
+func (s *SQLite3) GetMigrationStatus(ctx context.Context) (*models.MigrationStatus, error) {
+	return getMigrationStatus(s.DB)
+}
+
+func (s *SQLite3) Migrate(ctx context.Context, steps int) (string, error) {
+	return migrateDatabase(s.DB, steps)
+}
+
+func (s *SQLite3) ForceMigrationVersion(ctx context.Context, version int64) (string, error) {
+	return forceMigrationVersion(s.DB, version)
+}
+
+func (s *SQLite3) Backup(ctx context.Context, path string) error {
+	return backupDatabaseTo(s.DB, path)
+}
+
+func (s *SQLite3) Restore(ctx context.Context, path string) (string, error) {
+	return restoreDatabase(ctx, s.DB, path)
+}
+
+func (s *SQLite3) GetDatabaseStats(ctx context.Context) (*models.DatabaseStats, error) {
+	return getDatabaseStats(s.DB)
+}
+
+func (s *SQLite3) Vacuum(ctx context.Context) error {
+	_, err := s.DB.ExecContext(ctx, "VACUUM")
+	return err
+}
@@ -4,70 +4,173 @@ import (
 	"database/sql"
 	"embed"
 	"fmt"
-	"log"
-	"os"
+	"io/fs"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/golang-migrate/migrate/v4"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/k10wl/hermes/internal/models"
 )
 
 //go:embed migrations/*.sql
 var migrationFiles embed.FS
 
-func runMigrations(db *sql.DB) error {
+func newMigrate(db *sql.DB) (*migrate.Migrate, error) {
 	driver, err := WithInstance(db, &Config{})
 	if err != nil {
-		return fmt.Errorf("failed to create driver: %w", err)
+		return nil, fmt.Errorf("failed to create driver: %w", err)
 	}
 	d, err := iofs.New(migrationFiles, "migrations")
 	if err != nil {
-		return fmt.Errorf("failed to create iofs source: %w", err)
+		return nil, fmt.Errorf("failed to create iofs source: %w", err)
 	}
 	m, err := migrate.NewWithInstance("iofs", d, "sqlite3", driver)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
-	}
-	if dirty, err := isDirty(m); err != nil {
-		return fmt.Errorf("failed to check if database is dirty: %w", err)
-	} else if dirty {
-		log.Println("Database is dirty, resetting...")
-		if err := rerunMigrations(m); err != nil {
-			return fmt.Errorf("failed to rerun migrations: %w", err)
-		}
-	} else {
-		err = m.Up()
-		if err != nil && err != migrate.ErrNoChange {
-			return fmt.Errorf("migration failed: %w", err)
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+	return m, nil
+}
+
+// applies pending migrations, existing database is backed up first. Dirty
+// database is never touched, it has to be recovered with `hermes db migrate`
+func runMigrations(db *sql.DB) error {
+	status, err := getMigrationStatus(db)
+	if err != nil {
+		return fmt.Errorf("failed to read migration status: %w", err)
+	}
+	if status.Dirty {
+		return dirtyError(status.Version)
+	}
+	if status.Version >= status.Latest {
+		return nil
+	}
+	if status.Version > 0 {
+		if _, err := backupDatabase(db, "migration"); err != nil {
+			return fmt.Errorf("failed to back up database before migration: %w", err)
 		}
 	}
+	m, err := newMigrate(db)
+	if err != nil {
+		return err
+	}
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("migration failed: %w", err)
+	}
 	return nil
 }
 
-func isDirty(m *migrate.Migrate) (bool, error) {
-	_, dirty, err := m.Version()
+func dirtyError(version int64) error {
+	return fmt.Errorf(
+		"%w, migration %d did not finish. Nothing was changed, inspect schema with `hermes db migrate status` and mark version it matches with `hermes db migrate force <version>`",
+		ErrDatabaseDirty,
+		version,
+	)
+}
+
+// migrations embedded into binary, ordered by version
+func listMigrations() ([]*models.Migration, error) {
+	files, err := fs.Glob(migrationFiles, "migrations/*.up.sql")
 	if err != nil {
-		if err == migrate.ErrNilVersion {
-			return false, nil
+		return nil, err
+	}
+	migrations := []*models.Migration{}
+	for _, file := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(file, "migrations/"), ".up.sql")
+		prefix, name, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad migration file name %q\n", file)
 		}
-		return false, err
+		migrations = append(migrations, &models.Migration{Version: version, Name: name})
 	}
-	return dirty, nil
+	return migrations, nil
 }
 
-func resetDatabase(dbPath string) error {
-	if err := os.Remove(dbPath); err != nil {
-		return err
+func getMigrationStatus(db *sql.DB) (*models.MigrationStatus, error) {
+	m, err := newMigrate(db)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.MigrationStatus{}
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, err
+	}
+	if err == nil {
+		status.Version = int64(version)
+		status.Dirty = dirty
+	}
+	status.Migrations, err = listMigrations()
+	if err != nil {
+		return nil, err
 	}
-	_, err := os.Create(dbPath)
-	return err
+	for _, migration := range status.Migrations {
+		migration.Applied = migration.Version <= status.Version
+		status.Latest = max(status.Latest, migration.Version)
+	}
+	return status, nil
 }
 
-func rerunMigrations(m *migrate.Migrate) error {
-	if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-		return err
+// positive steps apply migrations, negative roll back, zero applies every
+// pending one. Returns path of backup made before change
+func migrateDatabase(db *sql.DB, steps int) (string, error) {
+	status, err := getMigrationStatus(db)
+	if err != nil {
+		return "", err
 	}
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return err
+	if status.Dirty {
+		return "", dirtyError(status.Version)
 	}
-	return nil
+	if (steps >= 0 && status.Version >= status.Latest) || (steps < 0 && status.Version == 0) {
+		return "", nil
+	}
+	backup := ""
+	if status.Version > 0 {
+		backup, err = backupDatabase(db, "migration")
+		if err != nil {
+			return "", fmt.Errorf("failed to back up database before migration: %w", err)
+		}
+	}
+	m, err := newMigrate(db)
+	if err != nil {
+		return backup, err
+	}
+	if steps == 0 {
+		err = m.Up()
+	} else {
+		err = m.Steps(steps)
+	}
+	if err != nil && err != migrate.ErrNoChange {
+		return backup, fmt.Errorf("migration failed: %w", err)
+	}
+	return backup, nil
+}
+
+// marks version as applied and clean without running migrations, version 0
+// marks database as empty
+func forceMigrationVersion(db *sql.DB, version int64) (string, error) {
+	migrations, err := listMigrations()
+	if err != nil {
+		return "", err
+	}
+	if version < 0 || (version > 0 && !slices.ContainsFunc(migrations, func(migration *models.Migration) bool {
+		return migration.Version == version
+	})) {
+		return "", fmt.Errorf("migration %d does not exist\n", version)
+	}
+	backup, err := backupDatabase(db, "force")
+	if err != nil {
+		return "", fmt.Errorf("failed to back up database before force: %w", err)
+	}
+	m, err := newMigrate(db)
+	if err != nil {
+		return backup, err
+	}
+	forced := int(version)
+	if version == 0 {
+		forced = -1
+	}
+	return backup, m.Force(forced)
 }
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS message_usage_created_at;
+DROP TABLE IF EXISTS message_usage;
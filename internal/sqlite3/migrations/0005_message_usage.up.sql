@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS message_usage;
+
+-- Tokens and cost spent on assistant message
+CREATE TABLE message_usage (
+    message_id INTEGER PRIMARY KEY, -- Foreign key to messages table
+    model TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0, -- USD
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (message_id) REFERENCES messages(id)
+);
+
+CREATE INDEX message_usage_created_at ON message_usage(created_at);
@@ -0,0 +1,3 @@
+ALTER TABLE chats DROP COLUMN temperature;
+ALTER TABLE chats DROP COLUMN max_tokens;
+ALTER TABLE chats DROP COLUMN model;
@@ -0,0 +1,4 @@
+-- Completion parameters reused upon chat continuation
+ALTER TABLE chats ADD COLUMN model TEXT NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN max_tokens INTEGER;
+ALTER TABLE chats ADD COLUMN temperature REAL;
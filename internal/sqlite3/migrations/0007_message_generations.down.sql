@@ -0,0 +1,8 @@
+DELETE FROM message_usage WHERE message_id IN (
+    SELECT id FROM messages WHERE generation_of IS NOT NULL
+);
+DELETE FROM messages WHERE generation_of IS NOT NULL;
+DROP INDEX IF EXISTS messages_generation_of;
+ALTER TABLE messages DROP COLUMN selected_generation;
+ALTER TABLE messages DROP COLUMN generation_of;
+ALTER TABLE messages DROP COLUMN generation;
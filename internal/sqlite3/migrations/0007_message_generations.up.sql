@@ -0,0 +1,6 @@
+-- Alternative answers, generations of one message share root (generation_of)
+ALTER TABLE messages ADD COLUMN generation INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE messages ADD COLUMN generation_of INTEGER REFERENCES messages(id);
+ALTER TABLE messages ADD COLUMN selected_generation BOOLEAN NOT NULL DEFAULT true;
+
+CREATE INDEX messages_generation_of ON messages(generation_of);
@@ -0,0 +1,2 @@
+ALTER TABLE chats DROP COLUMN parent_message_id;
+ALTER TABLE chats DROP COLUMN parent_chat_id;
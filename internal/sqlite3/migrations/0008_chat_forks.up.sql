@@ -0,0 +1,3 @@
+-- Chat copied from another chat up to (and including) parent message
+ALTER TABLE chats ADD COLUMN parent_chat_id INTEGER REFERENCES chats(id);
+ALTER TABLE chats ADD COLUMN parent_message_id INTEGER REFERENCES messages(id);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS chats_search_rename;
+DROP TRIGGER IF EXISTS messages_search_delete;
+DROP TRIGGER IF EXISTS messages_search_update;
+DROP TRIGGER IF EXISTS messages_search_insert;
+DROP TABLE IF EXISTS messages_search;
@@ -0,0 +1,34 @@
+-- Full text index over message contents and names of their chats, rowid is
+-- message id
+CREATE VIRTUAL TABLE messages_search USING fts5(
+    content,
+    chat_name,
+    chat_id UNINDEXED,
+    tokenize = 'unicode61 remove_diacritics 2'
+);
+
+INSERT INTO messages_search (rowid, content, chat_name, chat_id)
+SELECT m.id, m.content, c.name, c.id
+FROM messages AS m
+JOIN chats AS c ON c.id = m.chat_id;
+
+CREATE TRIGGER messages_search_insert AFTER INSERT ON messages
+BEGIN
+    INSERT INTO messages_search (rowid, content, chat_name, chat_id)
+    SELECT NEW.id, NEW.content, name, id FROM chats WHERE id = NEW.chat_id;
+END;
+
+CREATE TRIGGER messages_search_update AFTER UPDATE OF content ON messages
+BEGIN
+    UPDATE messages_search SET content = NEW.content WHERE rowid = NEW.id;
+END;
+
+CREATE TRIGGER messages_search_delete AFTER DELETE ON messages
+BEGIN
+    DELETE FROM messages_search WHERE rowid = OLD.id;
+END;
+
+CREATE TRIGGER chats_search_rename AFTER UPDATE OF name ON chats
+BEGIN
+    UPDATE messages_search SET chat_name = NEW.name WHERE chat_id = NEW.id;
+END;
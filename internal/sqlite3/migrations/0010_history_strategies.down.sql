@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS chat_summaries_chat_id;
+DROP TABLE IF EXISTS chat_summaries;
+ALTER TABLE chats DROP COLUMN history_limit;
+ALTER TABLE chats DROP COLUMN history_strategy;
@@ -0,0 +1,16 @@
+-- Strategy that reduces history sent for completion, empty sends whole history
+ALTER TABLE chats ADD COLUMN history_strategy TEXT NOT NULL DEFAULT '';
+ALTER TABLE chats ADD COLUMN history_limit INTEGER;
+
+-- Rolling summary of chat messages up to (and including) until message
+CREATE TABLE chat_summaries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    until_message_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chat_id) REFERENCES chats(id),
+    FOREIGN KEY (until_message_id) REFERENCES messages(id)
+);
+
+CREATE INDEX chat_summaries_chat_id ON chat_summaries(chat_id);
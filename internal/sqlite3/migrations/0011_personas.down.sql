@@ -0,0 +1,2 @@
+ALTER TABLE chats DROP COLUMN persona_id;
+DROP TABLE IF EXISTS personas;
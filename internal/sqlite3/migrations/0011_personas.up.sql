@@ -0,0 +1,14 @@
+-- Reusable system prompt, model and parameters are defaults for chats with persona
+CREATE TABLE personas (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT UNIQUE NOT NULL,
+    prompt TEXT NOT NULL,
+    model TEXT NOT NULL DEFAULT '',
+    max_tokens INTEGER,
+    temperature REAL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+ALTER TABLE chats ADD COLUMN persona_id INTEGER REFERENCES personas(id);
@@ -0,0 +1,14 @@
+-- Every stored revision of template, latest version equals templates.content
+CREATE TABLE template_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    template_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (template_id) REFERENCES templates(id),
+    UNIQUE (template_id, version)
+);
+
+INSERT INTO template_versions (template_id, version, name, content, created_at)
+SELECT id, 1, name, content, updated_at FROM templates;
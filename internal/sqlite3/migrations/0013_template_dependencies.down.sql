@@ -0,0 +1,2 @@
+DELETE FROM template_dependencies;
+DROP INDEX IF EXISTS template_dependencies_edge;
@@ -0,0 +1,5 @@
+-- Edge from template (core) to template it uses (linked). Edges are written
+-- from parsed content, content of templates stored before is parsed when
+-- edges are read, until they are written again
+CREATE UNIQUE INDEX template_dependencies_edge
+ON template_dependencies(core_id, linked_id);
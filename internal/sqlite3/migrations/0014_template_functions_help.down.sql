@@ -0,0 +1,35 @@
+UPDATE templates
+SET content = REPLACE(
+    content,
+    '## Template functions' || CHAR(10) ||
+    'Templates can call following functions, arguments go before piped value, e.g. `. | trimPrefix "a" | upper`' || CHAR(10) ||
+    '- dates: `now`, `date "2006-01-02" now` (Go time layout);' || CHAR(10) ||
+    '- strings: `upper`, `lower`, `title`, `trim`, `trimPrefix "a"`, `trimSuffix "z"`, `replace "old" "new"`, `contains "x"`, `hasPrefix "x"`, `hasSuffix "x"`, `split ","`, `join ", "`, `indent 4`;' || CHAR(10) ||
+    '- json: `toJSON`, `fromJSON`;' || CHAR(10) ||
+    '- `readFile "notes.md"` reads files only inside directories listed in HERMES_TEMPLATE_FILE_DIRS (comma separated), disabled when empty;' || CHAR(10) ||
+    '- `env "USER"` reads only variables listed in HERMES_TEMPLATE_ENV (comma separated), disabled when empty;' || CHAR(10) ||
+    CHAR(10) ||
+    '--------------------------------------------------------------------------------' || CHAR(10) ||
+    CHAR(10) ||
+    '## Development',
+    '--------------------------------------------------------------------------------' || CHAR(10) ||
+    CHAR(10) ||
+    '## Development'
+)
+WHERE name = 'hermes-help';
+
+INSERT INTO template_versions (template_id, version, name, content)
+SELECT
+    t.id,
+    COALESCE((SELECT MAX(version) FROM template_versions WHERE template_id = t.id), 0) + 1,
+    t.name,
+    t.content
+FROM templates AS t
+WHERE
+    t.name = 'hermes-help' AND
+    t.content IS NOT (
+        SELECT content FROM template_versions
+        WHERE template_id = t.id
+        ORDER BY version DESC
+        LIMIT 1
+    );
@@ -0,0 +1,2 @@
+-- Streamed answer that was cancelled before completion finished
+ALTER TABLE messages ADD COLUMN interrupted BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS attachments_message_id;
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS chat_usage_chat_id;
+DROP TABLE IF EXISTS chat_usage;
@@ -0,0 +1,15 @@
+-- Tokens and cost spent on requests that do not produce message, e.g. titles
+-- and history summaries
+CREATE TABLE chat_usage (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    chat_id INTEGER NOT NULL,
+    purpose TEXT NOT NULL,
+    model TEXT NOT NULL,
+    input_tokens INTEGER NOT NULL DEFAULT 0,
+    output_tokens INTEGER NOT NULL DEFAULT 0,
+    cost REAL NOT NULL DEFAULT 0, -- USD
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (chat_id) REFERENCES chats(id)
+);
+
+CREATE INDEX chat_usage_chat_id ON chat_usage(chat_id);
@@ -0,0 +1,2 @@
+-- Detached personas can not be restored, nothing to undo
+SELECT 1;
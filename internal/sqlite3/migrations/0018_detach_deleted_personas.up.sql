@@ -0,0 +1,4 @@
+-- Chats keep no reference to deleted personas, deletion detaches them now
+UPDATE chats
+SET persona_id = NULL
+WHERE persona_id IN (SELECT id FROM personas WHERE deleted_at IS NOT NULL);
@@ -0,0 +1,23 @@
+UPDATE templates
+SET content = REPLACE(
+    content,
+    '- `var "key"` reads variable from --data, --var or websocket payload, `.` stays message;' || CHAR(10),
+    ''
+)
+WHERE name = 'hermes-help';
+
+INSERT INTO template_versions (template_id, version, name, content)
+SELECT
+    t.id,
+    COALESCE((SELECT MAX(version) FROM template_versions WHERE template_id = t.id), 0) + 1,
+    t.name,
+    t.content
+FROM templates AS t
+WHERE
+    t.name = 'hermes-help' AND
+    t.content IS NOT (
+        SELECT content FROM template_versions
+        WHERE template_id = t.id
+        ORDER BY version DESC
+        LIMIT 1
+    );
@@ -14,11 +14,13 @@ type queryRow func(context.Context, string, ...interface{}) *sql.Row
 type queryRows func(context.Context, string, ...interface{}) (*sql.Rows, error)
 type execute func(context.Context, string, ...interface{}) (sql.Result, error)
 
-const createMessageQuery = `
-INSERT INTO messages (chat_id, role_id, content)
-VALUES ($1,$2,$3)
-RETURNING id, chat_id, content, created_at, updated_at, deleted_at;
-`
+const insertedMessageColumns = `id, chat_id, content, generation, selected_generation, interrupted, created_at, updated_at, deleted_at`
+
+var createMessageQuery = fmt.Sprintf(`
+INSERT INTO messages (chat_id, role_id, content, interrupted)
+VALUES ($1,$2,$3,$4)
+RETURNING %s;
+`, insertedMessageColumns)
 
 const getRoleIDByName = `
 SELECT id FROM roles WHERE name = $1;
@@ -30,6 +32,7 @@ func createMessage(
 	chatId int64,
 	role string,
 	content string,
+	interrupted bool,
 ) (*models.Message, error) {
 	row := executor(ctx, getRoleIDByName, role)
 	var roleID int64
@@ -43,43 +46,707 @@ func createMessage(
 		chatId,
 		roleID,
 		content,
+		interrupted,
 	)
 	var message models.Message
 	message.Role = role
-	err = row.Scan(
-		&message.ID,
-		&message.ChatID,
-		&message.Content,
-		&message.CreatedAt,
-		&message.UpdatedAt,
-		&message.DeletedAt,
+	err = scanInsertedMessage(row.Scan, &message)
+	return &message, err
+}
+
+func scanInsertedMessage(scan func(dest ...any) error, receiver *models.Message) error {
+	return scan(
+		&receiver.ID,
+		&receiver.ChatID,
+		&receiver.Content,
+		&receiver.Generation,
+		&receiver.SelectedGeneration,
+		&receiver.Interrupted,
+		&receiver.CreatedAt,
+		&receiver.UpdatedAt,
+		&receiver.DeletedAt,
+	)
+}
+
+// generations of the same message share slot, slot is identified by id of
+// the first generation
+const messageSlot = `COALESCE(m.generation_of, m.id)`
+
+var getMessagesQueryBase = `
+SELECT
+    m.id,
+    m.chat_id,
+    roles.name,
+    m.content,
+    m.generation,
+    m.selected_generation,
+    m.interrupted,
+    m.created_at,
+    m.updated_at,
+    m.deleted_at
+FROM messages AS m
+JOIN roles ON m.role_id = roles.id`
+
+func scanMessage(scan func(dest ...any) error, receiver *models.Message) error {
+	return scan(
+		&receiver.ID,
+		&receiver.ChatID,
+		&receiver.Role,
+		&receiver.Content,
+		&receiver.Generation,
+		&receiver.SelectedGeneration,
+		&receiver.Interrupted,
+		&receiver.CreatedAt,
+		&receiver.UpdatedAt,
+		&receiver.DeletedAt,
 	)
+}
+
+func scanMessages(rows *sql.Rows, err error) ([]*models.Message, error) {
+	messages := []*models.Message{}
+	if err != nil {
+		return messages, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var message models.Message
+		if err := scanMessage(rows.Scan, &message); err != nil {
+			return messages, err
+		}
+		messages = append(messages, &message)
+	}
+	return messages, rows.Err()
+}
+
+var getMessageByIDQuery = fmt.Sprintf(`%s
+WHERE m.id = $1;
+`, getMessagesQueryBase)
+
+func getMessageByID(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+) (*models.Message, error) {
+	var message models.Message
+	err := scanMessage(executor(ctx, getMessageByIDQuery, id).Scan, &message)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("message with id %d does not exist\n", id)
+	}
+	return &message, err
+}
+
+var getMessageHistoryQuery = fmt.Sprintf(`%s
+WHERE
+    m.chat_id = (SELECT chat_id FROM messages WHERE id = $1) AND
+    m.selected_generation AND
+    %s < (SELECT COALESCE(generation_of, id) FROM messages WHERE id = $1)
+ORDER BY %s;
+`, getMessagesQueryBase, messageSlot, messageSlot)
+
+// selected generations that precede slot of given message
+func getMessageHistory(
+	executor queryRows,
+	ctx context.Context,
+	id int64,
+) ([]*models.Message, error) {
+	return scanMessages(executor(ctx, getMessageHistoryQuery, id))
+}
+
+var getMessageGenerationsQuery = fmt.Sprintf(`%s
+WHERE %s = (SELECT COALESCE(generation_of, id) FROM messages WHERE id = $1)
+ORDER BY m.generation;
+`, getMessagesQueryBase, messageSlot)
+
+func getMessageGenerations(
+	executor queryRows,
+	ctx context.Context,
+	id int64,
+) ([]*models.Message, error) {
+	return scanMessages(executor(ctx, getMessageGenerationsQuery, id))
+}
+
+const getMessageSlotQuery = `
+SELECT
+    chat_id,
+    COALESCE(generation_of, id),
+    (
+        SELECT MAX(generation) FROM messages
+        WHERE COALESCE(generation_of, id) = COALESCE(target.generation_of, target.id)
+    )
+FROM messages AS target
+WHERE id = $1;
+`
+
+const unselectSlotQuery = `
+UPDATE messages
+SET selected_generation = false
+WHERE COALESCE(generation_of, id) = $1;
+`
+
+var createMessageGenerationQuery = fmt.Sprintf(`
+INSERT INTO messages (
+    chat_id, role_id, content, generation, generation_of, interrupted, selected_generation
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING %s;
+`, insertedMessageColumns)
+
+// new generation becomes selected one, interrupted generation is stored
+// aside and previous one remains selected
+func createMessageGeneration(
+	query queryRow,
+	exec execute,
+	ctx context.Context,
+	id int64,
+	role string,
+	content string,
+	interrupted bool,
+) (*models.Message, error) {
+	var chatID, slot, lastGeneration int64
+	if err := query(ctx, getMessageSlotQuery, id).Scan(
+		&chatID,
+		&slot,
+		&lastGeneration,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("message with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	var roleID int64
+	if err := query(ctx, getRoleIDByName, role).Scan(&roleID); err != nil {
+		return nil, err
+	}
+	if !interrupted {
+		if _, err := exec(ctx, unselectSlotQuery, slot); err != nil {
+			return nil, err
+		}
+	}
+	message := models.Message{Role: role}
+	err := scanInsertedMessage(query(
+		ctx,
+		createMessageGenerationQuery,
+		chatID,
+		roleID,
+		content,
+		lastGeneration+1,
+		slot,
+		interrupted,
+		!interrupted,
+	).Scan, &message)
 	return &message, err
 }
 
-const createChatQuery = `
-INSERT INTO chats (name)
-VALUES ($1)
-RETURNING id, name, created_at, updated_at, deleted_at;
+const selectGenerationQuery = `
+UPDATE messages
+SET selected_generation = true
+WHERE id = $1;
+`
+
+func selectMessageGeneration(
+	query queryRow,
+	exec execute,
+	ctx context.Context,
+	id int64,
+) (*models.Message, error) {
+	var chatID, slot, lastGeneration int64
+	if err := query(ctx, getMessageSlotQuery, id).Scan(
+		&chatID,
+		&slot,
+		&lastGeneration,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("message with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	if _, err := exec(ctx, unselectSlotQuery, slot); err != nil {
+		return nil, err
+	}
+	if _, err := exec(ctx, selectGenerationQuery, id); err != nil {
+		return nil, err
+	}
+	return getMessageByID(query, ctx, id)
+}
+
+const createMessageUsageQuery = `
+INSERT INTO message_usage (message_id, model, input_tokens, output_tokens, cost)
+VALUES ($1, $2, $3, $4, $5);
 `
 
+func createMessageUsage(
+	executor execute,
+	ctx context.Context,
+	usage *models.Usage,
+) error {
+	_, err := executor(
+		ctx,
+		createMessageUsageQuery,
+		usage.MessageID,
+		usage.Model,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.Cost,
+	)
+	return err
+}
+
+const createChatUsageQuery = `
+INSERT INTO chat_usage (chat_id, purpose, model, input_tokens, output_tokens, cost)
+VALUES ($1, $2, $3, $4, $5, $6);
+`
+
+func createChatUsage(
+	executor execute,
+	ctx context.Context,
+	chatID int64,
+	purpose string,
+	usage *models.Usage,
+) error {
+	_, err := executor(
+		ctx,
+		createChatUsageQuery,
+		chatID,
+		purpose,
+		usage.Model,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.Cost,
+	)
+	return err
+}
+
+// chat usage rows have no message and are not counted as messages
+const getUsageReportQueryBase = `
+SELECT
+    %s AS key,
+    %s AS name,
+    COUNT(u.message_id),
+    SUM(u.input_tokens),
+    SUM(u.output_tokens),
+    SUM(u.cost)
+FROM (
+    SELECT mu.message_id, m.chat_id, mu.model, mu.input_tokens, mu.output_tokens, mu.cost, mu.created_at
+    FROM message_usage AS mu
+    JOIN messages AS m ON m.id = mu.message_id
+    UNION ALL
+    SELECT NULL, chat_id, model, input_tokens, output_tokens, cost, created_at
+    FROM chat_usage
+) AS u
+JOIN chats AS c ON c.id = u.chat_id
+GROUP BY %s
+ORDER BY %s;
+`
+
+var getUsageReportQueries = map[string]string{
+	models.UsageByDay: fmt.Sprintf(
+		getUsageReportQueryBase,
+		"date(u.created_at)", "''", "key", "key DESC",
+	),
+	models.UsageByModel: fmt.Sprintf(
+		getUsageReportQueryBase,
+		"u.model", "''", "key", "SUM(u.cost) DESC",
+	),
+	models.UsageByChat: fmt.Sprintf(
+		getUsageReportQueryBase,
+		"CAST(c.id AS TEXT)", "c.name", "c.id", "c.id DESC",
+	),
+}
+
+func getUsageReport(
+	executor queryRows,
+	ctx context.Context,
+	groupBy string,
+) ([]*models.UsageReport, error) {
+	query, ok := getUsageReportQueries[groupBy]
+	if !ok {
+		return nil, fmt.Errorf(
+			"cannot group usage by %q - use %s, %s or %s\n",
+			groupBy,
+			models.UsageByDay,
+			models.UsageByModel,
+			models.UsageByChat,
+		)
+	}
+	rows, err := executor(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	reports := []*models.UsageReport{}
+	for rows.Next() {
+		var report models.UsageReport
+		if err := rows.Scan(
+			&report.Key,
+			&report.Name,
+			&report.Messages,
+			&report.InputTokens,
+			&report.OutputTokens,
+			&report.Cost,
+		); err != nil {
+			return reports, err
+		}
+		reports = append(reports, &report)
+	}
+	return reports, rows.Err()
+}
+
+const chatColumns = `id, name, model, max_tokens, temperature, history_strategy, history_limit, persona_id, parent_chat_id, parent_message_id, created_at, updated_at, deleted_at`
+
+func scanChat(scan func(dest ...any) error, receiver *models.Chat) error {
+	return scan(
+		&receiver.ID,
+		&receiver.Name,
+		&receiver.Model,
+		&receiver.MaxTokens,
+		&receiver.Temperature,
+		&receiver.Strategy,
+		&receiver.Limit,
+		&receiver.PersonaID,
+		&receiver.ParentChatID,
+		&receiver.ParentMessageID,
+		&receiver.CreatedAt,
+		&receiver.UpdatedAt,
+		&receiver.DeletedAt,
+	)
+}
+
+var createChatQuery = fmt.Sprintf(`
+INSERT INTO chats (name, model, max_tokens, temperature, persona_id)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING %s;
+`, chatColumns)
+
 func createChat(
 	executor queryRow,
 	ctx context.Context,
 	name string,
+	model string,
+	parameters models.CompletionParameters,
+	personaID *int64,
 ) (*models.Chat, error) {
-	row := executor(ctx, createChatQuery, ellipsis(name, 80, 3, "."))
-	var chat models.Chat
-	err := row.Scan(
-		&chat.ID,
-		&chat.Name,
-		&chat.CreatedAt,
-		&chat.UpdatedAt,
-		&chat.DeletedAt,
+	row := executor(
+		ctx,
+		createChatQuery,
+		ellipsis(name, 80, 3, "."),
+		model,
+		parameters.MaxTokens,
+		parameters.Temperature,
+		personaID,
 	)
+	var chat models.Chat
+	err := scanChat(row.Scan, &chat)
 	return &chat, err
 }
 
+var getChatByIDQuery = fmt.Sprintf(`
+SELECT %s FROM chats
+WHERE id = $1 AND deleted_at IS NULL;
+`, chatColumns)
+
+func getChatByID(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+) (*models.Chat, error) {
+	row := executor(ctx, getChatByIDQuery, id)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d %w\n", id, models.ErrNotFound)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// attachments are released, so they can be sent again
+var discardChatQueries = []string{
+	`UPDATE attachments SET message_id = NULL
+	WHERE message_id IN (SELECT id FROM messages WHERE chat_id = $1);`,
+	`DELETE FROM message_usage
+	WHERE message_id IN (SELECT id FROM messages WHERE chat_id = $1);`,
+	`DELETE FROM chat_usage WHERE chat_id = $1;`,
+	`DELETE FROM chat_summaries WHERE chat_id = $1;`,
+	`DELETE FROM messages WHERE chat_id = $1;`,
+	`DELETE FROM chats WHERE id = $1;`,
+}
+
+func discardChat(executor execute, ctx context.Context, id int64) error {
+	for _, query := range discardChatQueries {
+		if _, err := executor(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var updateChatParametersQuery = fmt.Sprintf(`
+UPDATE chats
+SET
+    model = $1,
+    max_tokens = $2,
+    temperature = $3,
+    updated_at = $4
+WHERE id = $5
+RETURNING %s;
+`, chatColumns)
+
+func updateChatParameters(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+	model string,
+	parameters models.CompletionParameters,
+) (*models.Chat, error) {
+	row := executor(
+		ctx,
+		updateChatParametersQuery,
+		model,
+		parameters.MaxTokens,
+		parameters.Temperature,
+		time.Now(),
+		id,
+	)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+var updateChatHistoryQuery = fmt.Sprintf(`
+UPDATE chats
+SET
+    history_strategy = $1,
+    history_limit = $2,
+    updated_at = $3
+WHERE id = $4 AND deleted_at IS NULL
+RETURNING %s;
+`, chatColumns)
+
+func updateChatHistory(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+	history models.History,
+) (*models.Chat, error) {
+	row := executor(
+		ctx,
+		updateChatHistoryQuery,
+		history.Strategy,
+		history.Limit,
+		time.Now(),
+		id,
+	)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+const chatSummaryColumns = `id, chat_id, until_message_id, content, created_at`
+
+func scanChatSummary(scan func(dest ...any) error, receiver *models.ChatSummary) error {
+	return scan(
+		&receiver.ID,
+		&receiver.ChatID,
+		&receiver.UntilMessageID,
+		&receiver.Content,
+		&receiver.CreatedAt,
+	)
+}
+
+var createChatSummaryQuery = fmt.Sprintf(`
+INSERT INTO chat_summaries (chat_id, until_message_id, content)
+VALUES ($1, $2, $3)
+RETURNING %s;
+`, chatSummaryColumns)
+
+func createChatSummary(
+	executor queryRow,
+	ctx context.Context,
+	chatID int64,
+	untilMessageID int64,
+	content string,
+) (*models.ChatSummary, error) {
+	var summary models.ChatSummary
+	err := scanChatSummary(
+		executor(ctx, createChatSummaryQuery, chatID, untilMessageID, content).Scan,
+		&summary,
+	)
+	return &summary, err
+}
+
+var getChatSummariesQuery = fmt.Sprintf(`
+SELECT %s FROM chat_summaries
+WHERE chat_id = $1
+ORDER BY id DESC;
+`, chatSummaryColumns)
+
+// newest first
+func getChatSummaries(
+	executor queryRows,
+	ctx context.Context,
+	chatID int64,
+) ([]*models.ChatSummary, error) {
+	rows, err := executor(ctx, getChatSummariesQuery, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	summaries := []*models.ChatSummary{}
+	for rows.Next() {
+		var summary models.ChatSummary
+		if err := scanChatSummary(rows.Scan, &summary); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, &summary)
+	}
+	return summaries, rows.Err()
+}
+
+var createForkQuery = fmt.Sprintf(`
+INSERT INTO chats (name, model, max_tokens, temperature, history_strategy, history_limit, persona_id, parent_chat_id, parent_message_id)
+SELECT name, model, max_tokens, temperature, history_strategy, history_limit, persona_id, id, $1
+FROM chats
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING %s;
+`, chatColumns)
+
+// selected generations that precede fork message, and fork message itself
+var forkedMessagesCondition = fmt.Sprintf(`
+    m.chat_id = $2 AND
+    m.deleted_at IS NULL AND
+    (
+        m.id = $3 OR
+        (
+            m.selected_generation AND
+            %s < (SELECT COALESCE(generation_of, id) FROM messages WHERE id = $3)
+        )
+    )`, messageSlot)
+
+var copyForkMessagesQuery = fmt.Sprintf(`
+INSERT INTO messages (chat_id, role_id, content, interrupted, created_at, updated_at)
+SELECT $1, m.role_id, m.content, m.interrupted, m.created_at, m.updated_at
+FROM messages AS m
+WHERE %s
+ORDER BY %s;
+`, forkedMessagesCondition, messageSlot)
+
+// copies are matched with originals by position, both are inserted in the
+// same order. Parameters are numbered by first appearance, so copy goes first
+var copyForkAttachmentsQuery = fmt.Sprintf(`
+WITH
+    copy AS (
+        SELECT id, ROW_NUMBER() OVER (ORDER BY id) AS position
+        FROM messages
+        WHERE chat_id = $1
+    ),
+    original AS (
+        SELECT m.id, ROW_NUMBER() OVER (ORDER BY %s) AS position
+        FROM messages AS m
+        WHERE %s
+    )
+INSERT INTO attachments (message_id, name, media_type, size, data, created_at)
+SELECT copy.id, a.name, a.media_type, a.size, a.data, a.created_at
+FROM attachments AS a
+JOIN original ON original.id = a.message_id
+JOIN copy ON copy.position = original.position
+ORDER BY a.id;
+`, messageSlot, forkedMessagesCondition)
+
+func forkChat(
+	query queryRow,
+	exec execute,
+	ctx context.Context,
+	chatID int64,
+	messageID int64,
+) (*models.Chat, error) {
+	message, err := getMessageByID(query, ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.ChatID != chatID {
+		return nil, fmt.Errorf(
+			"message with id %d does not belong to chat with id %d\n",
+			messageID,
+			chatID,
+		)
+	}
+	var chat models.Chat
+	if err := scanChat(
+		query(ctx, createForkQuery, messageID, chatID).Scan,
+		&chat,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", chatID)
+		}
+		return nil, err
+	}
+	if _, err := exec(ctx, copyForkMessagesQuery, chat.ID, chatID, messageID); err != nil {
+		return nil, err
+	}
+	if _, err := exec(ctx, copyForkAttachmentsQuery, chat.ID, chatID, messageID); err != nil {
+		return nil, err
+	}
+	return &chat, nil
+}
+
+var renameChatQuery = fmt.Sprintf(`
+UPDATE chats
+SET name = $1, updated_at = $2
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING %s;
+`, chatColumns)
+
+func renameChat(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+	name string,
+) (*models.Chat, error) {
+	row := executor(ctx, renameChatQuery, ellipsis(name, 80, 3, "."), time.Now(), id)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+// soft delete, chat and its messages remain in database
+var deleteChatQuery = fmt.Sprintf(`
+UPDATE chats
+SET deleted_at = $1
+WHERE id = $2 AND deleted_at IS NULL
+RETURNING %s;
+`, chatColumns)
+
+func deleteChat(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+) (*models.Chat, error) {
+	row := executor(ctx, deleteChatQuery, time.Now(), id)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
 func ellipsis(text string, max int, times int, replacement string) string {
 	if len(text) <= max {
 		return text
@@ -92,22 +759,21 @@ func ellipsis(text string, max int, times int, replacement string) string {
 	return fmt.Sprintf("%s%s", cut, strings.Repeat(replacement, times))
 }
 
-const getChatsQueryWithWhere = `
-SELECT 
-    id, name, created_at, updated_at, deleted_at
+var getChatsQueryWithWhere = fmt.Sprintf(`
+SELECT %s
 FROM chats
-WHERE id < ?
+WHERE id < ? AND deleted_at IS NULL
 ORDER BY id DESC
 LIMIT ?;
-`
+`, chatColumns)
 
-const getChatsQuery = `
-SELECT 
-    id, name, created_at, updated_at, deleted_at
+var getChatsQuery = fmt.Sprintf(`
+SELECT %s
 FROM chats
+WHERE deleted_at IS NULL
 ORDER BY id DESC
 LIMIT ?;
-`
+`, chatColumns)
 
 func getChats(
 	executor queryRows,
@@ -128,13 +794,7 @@ func getChats(
 	}
 	for rows.Next() {
 		var chat models.Chat
-		err = rows.Scan(
-			&chat.ID,
-			&chat.Name,
-			&chat.CreatedAt,
-			&chat.UpdatedAt,
-			&chat.DeletedAt,
-		)
+		err = scanChat(rows.Scan, &chat)
 		chats = append(chats, &chat)
 		if err != nil {
 			break
@@ -143,40 +803,121 @@ func getChats(
 	return chats, err
 }
 
-const getChatMessagesQuery = `
-SELECT m.id, chat_id, roles.name, content, m.created_at, m.updated_at, m.deleted_at 
-FROM messages AS m
-JOIN roles ON role_id = roles.id
-WHERE chat_id = $1;
-`
+// only selected generations are part of chat
+var getChatMessagesQuery = fmt.Sprintf(`%s
+WHERE m.chat_id = $1 AND m.selected_generation
+ORDER BY %s;
+`, getMessagesQueryBase, messageSlot)
 
 func getChatMessages(
 	executor queryRows,
 	ctx context.Context,
 	chatId int64,
 ) ([]*models.Message, error) {
-	rows, err := executor(ctx, getChatMessagesQuery, chatId)
-	messages := []*models.Message{}
+	return scanMessages(executor(ctx, getChatMessagesQuery, chatId))
+}
+
+const getChatUsageQuery = `
+SELECT u.message_id, u.model, u.input_tokens, u.output_tokens, u.cost
+FROM message_usage AS u
+JOIN messages AS m ON m.id = u.message_id
+WHERE m.chat_id = $1 AND m.selected_generation;
+`
+
+func getChatUsage(
+	executor queryRows,
+	ctx context.Context,
+	chatID int64,
+) ([]*models.Usage, error) {
+	rows, err := executor(ctx, getChatUsageQuery, chatID)
 	if err != nil {
-		return messages, err
+		return nil, err
 	}
+	defer rows.Close()
+	usage := []*models.Usage{}
 	for rows.Next() {
-		var message models.Message
-		err = rows.Scan(
-			&message.ID,
-			&message.ChatID,
-			&message.Role,
-			&message.Content,
-			&message.CreatedAt,
-			&message.UpdatedAt,
-			&message.DeletedAt,
-		)
-		messages = append(messages, &message)
-		if err != nil {
-			break
+		var u models.Usage
+		if err := rows.Scan(
+			&u.MessageID,
+			&u.Model,
+			&u.InputTokens,
+			&u.OutputTokens,
+			&u.Cost,
+		); err != nil {
+			return nil, err
+		}
+		usage = append(usage, &u)
+	}
+	return usage, rows.Err()
+}
+
+var importChatQuery = fmt.Sprintf(`
+INSERT INTO chats (name, model, max_tokens, temperature, history_strategy, history_limit, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, CURRENT_TIMESTAMP))
+RETURNING %s;
+`, chatColumns)
+
+const importMessageQuery = `
+INSERT INTO messages (chat_id, role_id, content, created_at)
+VALUES ($1, (SELECT id FROM roles WHERE name = $2), $3, COALESCE($4, CURRENT_TIMESTAMP))
+RETURNING id;
+`
+
+const importMessageUsageQuery = `
+INSERT INTO message_usage (message_id, model, input_tokens, output_tokens, cost, created_at)
+VALUES ($1, $2, $3, $4, $5, COALESCE($6, CURRENT_TIMESTAMP));
+`
+
+// creates chat with messages and their usage, original timestamps are kept
+func importChat(
+	query queryRow,
+	exec execute,
+	ctx context.Context,
+	export *models.ChatExport,
+) (*models.Chat, error) {
+	var chat models.Chat
+	if err := scanChat(query(
+		ctx,
+		importChatQuery,
+		ellipsis(export.Name, 80, 3, "."),
+		export.Model,
+		export.MaxTokens,
+		export.Temperature,
+		export.Strategy,
+		export.Limit,
+		export.CreatedAt,
+	).Scan, &chat); err != nil {
+		return nil, err
+	}
+	for _, message := range export.Messages {
+		var id int64
+		if err := query(
+			ctx,
+			importMessageQuery,
+			chat.ID,
+			message.Role,
+			message.Content,
+			message.CreatedAt,
+		).Scan(&id); err != nil {
+			return nil, err
+		}
+		if message.Usage == nil {
+			continue
+		}
+		if _, err := exec(
+			ctx,
+			importMessageUsageQuery,
+			id,
+			message.Usage.Model,
+			message.Usage.InputTokens,
+			message.Usage.OutputTokens,
+			message.Usage.Cost,
+			message.CreatedAt,
+		); err != nil {
+			return nil, err
 		}
 	}
-	return messages, err
+	return &chat, nil
 }
 
 const getWebSettingsQuery = `
@@ -203,28 +944,201 @@ func updateWebSettings(executor queryRow, ctx context.Context, darkMode bool) er
 	return row.Err()
 }
 
-const getLatestChatQuery = `
-SELECT id, name, created_at, updated_at, deleted_at FROM chats
+var getLatestChatQuery = fmt.Sprintf(`
+SELECT %s FROM chats
+WHERE deleted_at IS NULL
 ORDER BY id DESC
 LIMIT 1;
-`
+`, chatColumns)
 
 func getLatestChat(executor queryRow, ctx context.Context) (*models.Chat, error) {
 	row := executor(ctx, getLatestChatQuery)
 	var chat models.Chat
-	err := row.Scan(
-		&chat.ID,
-		&chat.Name,
-		&chat.CreatedAt,
-		&chat.UpdatedAt,
-		&chat.DeletedAt,
-	)
+	err := scanChat(row.Scan, &chat)
 	return &chat, err
 }
 
+var setChatPersonaQuery = fmt.Sprintf(`
+UPDATE chats
+SET
+    persona_id = $1,
+    updated_at = $2
+WHERE id = $3 AND deleted_at IS NULL
+RETURNING %s;
+`, chatColumns)
+
+// nil persona detaches it from chat
+func setChatPersona(
+	executor queryRow,
+	ctx context.Context,
+	chatID int64,
+	personaID *int64,
+) (*models.Chat, error) {
+	row := executor(ctx, setChatPersonaQuery, personaID, time.Now(), chatID)
+	var chat models.Chat
+	if err := scanChat(row.Scan, &chat); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("chat with id %d does not exist\n", chatID)
+		}
+		return nil, err
+	}
+	return &chat, nil
+}
+
+const personaColumns = `id, name, prompt, model, max_tokens, temperature, created_at, updated_at, deleted_at`
+
+func scanPersona(scan func(dest ...any) error, receiver *models.Persona) error {
+	return scan(
+		&receiver.ID,
+		&receiver.Name,
+		&receiver.Prompt,
+		&receiver.Model,
+		&receiver.MaxTokens,
+		&receiver.Temperature,
+		&receiver.CreatedAt,
+		&receiver.UpdatedAt,
+		&receiver.DeletedAt,
+	)
+}
+
+// id is kept upon update, chats reference persona by it
+var upsertPersonaQuery = fmt.Sprintf(`
+INSERT INTO personas (name, prompt, model, max_tokens, temperature)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (name) DO UPDATE SET
+    prompt = excluded.prompt,
+    model = excluded.model,
+    max_tokens = excluded.max_tokens,
+    temperature = excluded.temperature,
+    updated_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL
+RETURNING %s;
+`, personaColumns)
+
+func upsertPersona(
+	executor queryRow,
+	ctx context.Context,
+	persona *models.Persona,
+) (*models.Persona, error) {
+	row := executor(
+		ctx,
+		upsertPersonaQuery,
+		persona.Name,
+		persona.Prompt,
+		persona.Model,
+		persona.MaxTokens,
+		persona.Temperature,
+	)
+	var res models.Persona
+	err := scanPersona(row.Scan, &res)
+	return &res, err
+}
+
+var getPersonasQuery = fmt.Sprintf(`
+SELECT %s FROM personas
+WHERE deleted_at IS NULL
+ORDER BY name;
+`, personaColumns)
+
+func getPersonas(executor queryRows, ctx context.Context) ([]*models.Persona, error) {
+	rows, err := executor(ctx, getPersonasQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	personas := []*models.Persona{}
+	for rows.Next() {
+		var persona models.Persona
+		if err := scanPersona(rows.Scan, &persona); err != nil {
+			return nil, err
+		}
+		personas = append(personas, &persona)
+	}
+	return personas, rows.Err()
+}
+
+var getPersonaByNameQuery = fmt.Sprintf(`
+SELECT %s FROM personas
+WHERE name = $1 AND deleted_at IS NULL;
+`, personaColumns)
+
+func getPersonaByName(
+	executor queryRow,
+	ctx context.Context,
+	name string,
+) (*models.Persona, error) {
+	var persona models.Persona
+	if err := scanPersona(executor(ctx, getPersonaByNameQuery, name).Scan, &persona); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("persona %q does not exist\n", name)
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+var getPersonaByIDQuery = fmt.Sprintf(`
+SELECT %s FROM personas
+WHERE id = $1 AND deleted_at IS NULL;
+`, personaColumns)
+
+func getPersonaByID(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+) (*models.Persona, error) {
+	var persona models.Persona
+	if err := scanPersona(executor(ctx, getPersonaByIDQuery, id).Scan, &persona); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("persona with id %d does not exist\n", id)
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+var deletePersonaByNameQuery = fmt.Sprintf(`
+UPDATE personas
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE name = $1 AND deleted_at IS NULL
+RETURNING %s;
+`, personaColumns)
+
+func deletePersonaByName(
+	executor queryRow,
+	ctx context.Context,
+	name string,
+) (*models.Persona, error) {
+	var persona models.Persona
+	if err := scanPersona(executor(ctx, deletePersonaByNameQuery, name).Scan, &persona); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("persona %q does not exist\n", name)
+		}
+		return nil, err
+	}
+	return &persona, nil
+}
+
+const detachPersonaQuery = `
+UPDATE chats
+SET persona_id = NULL
+WHERE persona_id = $1;
+`
+
+// chats of deleted persona continue without it
+func detachPersona(executor execute, ctx context.Context, personaID int64) error {
+	_, err := executor(ctx, detachPersonaQuery, personaID)
+	return err
+}
+
+// keeps template id so versions stay attached to it
 const upsertTemplateQuery = `
-INSERT OR REPLACE INTO templates (name, content)
+INSERT INTO templates (name, content)
 VALUES ($1, $2)
+ON CONFLICT(name) DO UPDATE SET
+    content = excluded.content,
+    updated_at = CURRENT_TIMESTAMP,
+    deleted_at = NULL
 RETURNING id, name, content, created_at, updated_at, deleted_at;
 `
 
@@ -418,6 +1332,230 @@ func editTemplateByName(
 	return tmp, nil
 }
 
+// stores current template content as next version, unless latest version
+// already holds it
+const createTemplateVersionQuery = `
+INSERT INTO template_versions (template_id, version, name, content)
+SELECT
+    templates.id,
+    COALESCE(latest.version, 0) + 1,
+    templates.name,
+    templates.content
+FROM templates
+LEFT JOIN template_versions AS latest ON
+    latest.template_id = templates.id AND
+    latest.version = (
+        SELECT MAX(version) FROM template_versions WHERE template_id = templates.id
+    )
+WHERE
+    templates.id = $1 AND
+    (latest.id IS NULL OR latest.name != templates.name OR latest.content != templates.content);
+`
+
+func createTemplateVersion(
+	executor execute,
+	ctx context.Context,
+	templateID int64,
+) error {
+	_, err := executor(ctx, createTemplateVersionQuery, templateID)
+	return err
+}
+
+const templateVersionColumns = `
+    template_versions.id,
+    template_versions.template_id,
+    template_versions.version,
+    template_versions.name,
+    template_versions.content,
+    template_versions.created_at`
+
+func scanTemplateVersion(scan func(dest ...any) error, receiver *models.TemplateVersion) error {
+	return scan(
+		&receiver.ID,
+		&receiver.TemplateID,
+		&receiver.Version,
+		&receiver.Name,
+		&receiver.Content,
+		&receiver.CreatedAt,
+	)
+}
+
+var getTemplateVersionsQuery = fmt.Sprintf(`
+SELECT %s
+FROM template_versions
+JOIN templates ON templates.id = template_versions.template_id
+WHERE templates.name = $1 AND templates.deleted_at IS NULL
+ORDER BY template_versions.version DESC;
+`, templateVersionColumns)
+
+// newest version first
+func getTemplateVersions(
+	executor queryRows,
+	ctx context.Context,
+	name string,
+) ([]*models.TemplateVersion, error) {
+	rows, err := executor(ctx, getTemplateVersionsQuery, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	versions := []*models.TemplateVersion{}
+	for rows.Next() {
+		var version models.TemplateVersion
+		if err := scanTemplateVersion(rows.Scan, &version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, &version)
+	}
+	return versions, rows.Err()
+}
+
+var getTemplateVersionQuery = fmt.Sprintf(`
+SELECT %s
+FROM template_versions
+JOIN templates ON templates.id = template_versions.template_id
+WHERE
+    templates.name = $1 AND
+    templates.deleted_at IS NULL AND
+    template_versions.version = $2;
+`, templateVersionColumns)
+
+func getTemplateVersion(
+	executor queryRow,
+	ctx context.Context,
+	name string,
+	version int64,
+) (*models.TemplateVersion, error) {
+	var res models.TemplateVersion
+	if err := scanTemplateVersion(
+		executor(ctx, getTemplateVersionQuery, name, version).Scan,
+		&res,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("template %q has no version %d\n", name, version)
+		}
+		return nil, err
+	}
+	return &res, nil
+}
+
+const deleteTemplateDependenciesQuery = `
+DELETE FROM template_dependencies WHERE core_id = $1;
+`
+
+const createTemplateDependencyQuery = `
+INSERT OR IGNORE INTO template_dependencies (core_id, linked_id)
+SELECT $1, id FROM templates WHERE name = $2 AND deleted_at IS NULL;
+`
+
+// replaces templates used by core template
+func setTemplateDependencies(
+	executor execute,
+	ctx context.Context,
+	coreID int64,
+	dependencies []string,
+) error {
+	if _, err := executor(ctx, deleteTemplateDependenciesQuery, coreID); err != nil {
+		return err
+	}
+	for _, name := range dependencies {
+		if _, err := executor(ctx, createTemplateDependencyQuery, coreID, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const getTemplateDependenciesQuery = `
+SELECT
+    core.id,
+    core.name,
+    linked.id,
+    linked.name,
+    linked.deleted_at IS NOT NULL
+FROM template_dependencies
+JOIN templates AS core ON core.id = template_dependencies.core_id
+JOIN templates AS linked ON linked.id = template_dependencies.linked_id
+WHERE core.deleted_at IS NULL
+ORDER BY core.name, linked.name;
+`
+
+// edges of templates that are not deleted, dependency itself may be deleted
+func getTemplateDependencies(
+	executor queryRows,
+	ctx context.Context,
+) ([]*models.TemplateDependency, error) {
+	rows, err := executor(ctx, getTemplateDependenciesQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	dependencies := []*models.TemplateDependency{}
+	for rows.Next() {
+		var dependency models.TemplateDependency
+		if err := rows.Scan(
+			&dependency.CoreID,
+			&dependency.CoreName,
+			&dependency.LinkedID,
+			&dependency.LinkedName,
+			&dependency.LinkedDeleted,
+		); err != nil {
+			return nil, err
+		}
+		dependencies = append(dependencies, &dependency)
+	}
+	return dependencies, rows.Err()
+}
+
+func getTemplatesWithDependenciesQuery(names []interface{}) string {
+	return `
+WITH RECURSIVE required(id) AS (
+    SELECT id FROM templates
+    WHERE name IN (?` + strings.Repeat(",?", len(names)-1) + `) AND deleted_at IS NULL
+    UNION
+    SELECT template_dependencies.linked_id
+    FROM template_dependencies
+    JOIN required ON required.id = template_dependencies.core_id
+)
+SELECT
+    templates.id,
+    templates.name,
+    templates.content,
+    templates.created_at,
+    templates.updated_at,
+    templates.deleted_at
+FROM templates
+JOIN required ON required.id = templates.id
+WHERE templates.deleted_at IS NULL;`
+}
+
+// named templates and every template they use, recursively
+func getTemplatesWithDependencies(
+	executor queryRows,
+	ctx context.Context,
+	names []string,
+) ([]*models.Template, error) {
+	namesInterface := convertToAnySlice(names)
+	rows, err := executor(
+		ctx,
+		getTemplatesWithDependenciesQuery(namesInterface),
+		namesInterface...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	templates := []*models.Template{}
+	for rows.Next() {
+		var templateDoc models.Template
+		if err := scanTemplate(rows.Scan, &templateDoc); err != nil {
+			return nil, err
+		}
+		templates = append(templates, &templateDoc)
+	}
+	return templates, rows.Err()
+}
+
 const createActiveSessionQuery = `
 INSERT INTO active_sessions (address, database_dns)
 VALUES ($1, $2);
@@ -496,3 +1634,246 @@ func getActiveSession(
 	}
 	return &activeSession, nil
 }
+
+// only selected generations of not deleted chats, best matches first
+const searchMessagesQuery = `
+SELECT
+    m.id,
+    m.chat_id,
+    c.name,
+    roles.name,
+    snippet(messages_search, -1, $1, $2, '...', 16),
+    m.created_at
+FROM messages_search AS s
+JOIN messages AS m ON m.id = s.rowid
+JOIN chats AS c ON c.id = m.chat_id
+JOIN roles ON roles.id = m.role_id
+WHERE
+    messages_search MATCH $3 AND
+    m.selected_generation AND
+    m.deleted_at IS NULL AND
+    c.deleted_at IS NULL
+ORDER BY rank
+LIMIT $4;
+`
+
+func searchMessages(
+	executor queryRows,
+	ctx context.Context,
+	terms string,
+	limit int64,
+	highlight models.Highlight,
+) ([]*models.SearchResult, error) {
+	results := []*models.SearchResult{}
+	match := matchExpression(terms)
+	if match == "" {
+		return results, fmt.Errorf("search terms cannot be empty\n")
+	}
+	rows, err := executor(
+		ctx,
+		searchMessagesQuery,
+		highlight.Open,
+		highlight.Close,
+		match,
+		limit,
+	)
+	if err != nil {
+		return results, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var result models.SearchResult
+		if err := rows.Scan(
+			&result.MessageID,
+			&result.ChatID,
+			&result.ChatName,
+			&result.Role,
+			&result.Snippet,
+			&result.CreatedAt,
+		); err != nil {
+			return results, err
+		}
+		results = append(results, &result)
+	}
+	return results, rows.Err()
+}
+
+// every term is quoted, so user input is never parsed as fts5 syntax, and
+// results must contain all of terms
+func matchExpression(terms string) string {
+	quoted := []string{}
+	for _, term := range strings.Fields(terms) {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+const attachmentColumns = `id, message_id, name, media_type, size, created_at`
+
+func scanAttachment(scan func(dest ...any) error, receiver *models.Attachment) error {
+	return scan(
+		&receiver.ID,
+		&receiver.MessageID,
+		&receiver.Name,
+		&receiver.MediaType,
+		&receiver.Size,
+		&receiver.CreatedAt,
+	)
+}
+
+var createAttachmentQuery = fmt.Sprintf(`
+INSERT INTO attachments (message_id, name, media_type, size, data)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING %s;
+`, attachmentColumns)
+
+func createAttachment(
+	executor queryRow,
+	ctx context.Context,
+	attachment *models.Attachment,
+) (*models.Attachment, error) {
+	var created models.Attachment
+	err := scanAttachment(
+		executor(
+			ctx,
+			createAttachmentQuery,
+			attachment.MessageID,
+			attachment.Name,
+			attachment.MediaType,
+			len(attachment.Data),
+			attachment.Data,
+		).Scan,
+		&created,
+	)
+	return &created, err
+}
+
+const linkAttachmentQuery = `
+UPDATE attachments
+SET message_id = $1
+WHERE id = $2 AND message_id IS NULL;
+`
+
+// attachment belongs to single message, linked ones can not be moved
+func linkAttachments(
+	exec execute,
+	ctx context.Context,
+	messageID int64,
+	ids []int64,
+) error {
+	for _, id := range ids {
+		res, err := exec(ctx, linkAttachmentQuery, messageID, id)
+		if err != nil {
+			return err
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf(
+				"attachment with id %d does not exist or was already sent\n",
+				id,
+			)
+		}
+	}
+	return nil
+}
+
+// uploads without id are created, others are linked
+func storeAttachments(
+	query queryRow,
+	exec execute,
+	ctx context.Context,
+	messageID int64,
+	attachments []*models.Attachment,
+) error {
+	ids := []int64{}
+	for _, attachment := range attachments {
+		if attachment.ID != 0 {
+			ids = append(ids, attachment.ID)
+			continue
+		}
+		attachment.MessageID = &messageID
+		created, err := createAttachment(query, ctx, attachment)
+		if err != nil {
+			return err
+		}
+		created.Data = attachment.Data
+		*attachment = *created
+	}
+	if err := linkAttachments(exec, ctx, messageID, ids); err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		attachment.MessageID = &messageID
+	}
+	return nil
+}
+
+const deleteStaleAttachmentsQuery = `
+DELETE FROM attachments
+WHERE message_id IS NULL AND created_at < datetime('now', $1);
+`
+
+func deleteStaleAttachments(exec execute, ctx context.Context, lifetime time.Duration) error {
+	_, err := exec(
+		ctx,
+		deleteStaleAttachmentsQuery,
+		fmt.Sprintf("-%d seconds", int64(lifetime.Seconds())),
+	)
+	return err
+}
+
+var getChatAttachmentsQuery = fmt.Sprintf(`
+SELECT %s FROM attachments
+WHERE message_id IN (SELECT id FROM messages WHERE chat_id = $1)
+ORDER BY id;
+`, attachmentColumns)
+
+// attachments of every message in chat, without data
+func getChatAttachments(
+	executor queryRows,
+	ctx context.Context,
+	chatID int64,
+) ([]*models.Attachment, error) {
+	rows, err := executor(ctx, getChatAttachmentsQuery, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	attachments := []*models.Attachment{}
+	for rows.Next() {
+		var attachment models.Attachment
+		if err := scanAttachment(rows.Scan, &attachment); err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, &attachment)
+	}
+	return attachments, rows.Err()
+}
+
+var getAttachmentByIDQuery = fmt.Sprintf(`
+SELECT %s, data FROM attachments
+WHERE id = $1;
+`, attachmentColumns)
+
+func getAttachmentByID(
+	executor queryRow,
+	ctx context.Context,
+	id int64,
+) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := executor(ctx, getAttachmentByIDQuery, id).Scan(
+		&attachment.ID,
+		&attachment.MessageID,
+		&attachment.Name,
+		&attachment.MediaType,
+		&attachment.Size,
+		&attachment.CreatedAt,
+		&attachment.Data,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("attachment with id %d does not exist\n", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
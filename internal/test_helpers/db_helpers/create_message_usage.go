@@ -0,0 +1,32 @@
+package db_helpers
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/k10wl/hermes/internal/models"
+)
+
+func CreateMessageUsage(db *sql.DB, ctx context.Context, usage []*models.Usage) error {
+	tx, err := db.BeginTx(ctx, nil)
+	vals := []any{}
+	if err != nil {
+		return err
+	}
+	sqlStr := "INSERT INTO message_usage (message_id, model, input_tokens, output_tokens, cost) VALUES "
+	for _, v := range usage {
+		sqlStr += "(?, ?, ?, ?, ?), "
+		vals = append(vals, v.MessageID, v.Model, v.InputTokens, v.OutputTokens, v.Cost)
+	}
+	sqlStr = strings.TrimSuffix(sqlStr, ", ")
+	stmt, err := tx.Prepare(sqlStr)
+	if err != nil {
+		return err
+	}
+	_, err = stmt.ExecContext(ctx, vals...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
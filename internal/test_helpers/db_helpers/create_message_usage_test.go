@@ -0,0 +1,62 @@
+package db_helpers_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestCreateMessageUsage(t *testing.T) {
+	db := prepare(t)
+	defer db.Close()
+	ctx := context.Background()
+	seeder := db_helpers.NewSeeder(db, ctx)
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatalf("failed to seed chats - %s\n", err)
+	}
+	if err := seeder.SeedMessagesN(2, 1); err != nil {
+		t.Fatalf("failed to seed messages - %s\n", err)
+	}
+
+	subject := []*models.Usage{
+		{MessageID: 1, Model: "openai/gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.5},
+		{MessageID: 2, Model: "local/llama", InputTokens: 20, OutputTokens: 15},
+	}
+	if err := db_helpers.CreateMessageUsage(db, ctx, subject); err != nil {
+		t.Fatalf("error upon usage creation - %s\n", err)
+	}
+
+	rows, err := db.Query(`SELECT message_id, model, input_tokens, output_tokens, cost
+FROM message_usage ORDER BY message_id`)
+	if err != nil {
+		t.Fatalf("error upon db query - %s\n", err)
+	}
+	actual := []*models.Usage{}
+	for rows.Next() {
+		var usage models.Usage
+		if err := rows.Scan(
+			&usage.MessageID,
+			&usage.Model,
+			&usage.InputTokens,
+			&usage.OutputTokens,
+			&usage.Cost,
+		); err != nil {
+			t.Fatalf("scanning error - %s\n", err)
+		}
+		actual = append(actual, &usage)
+	}
+	if !reflect.DeepEqual(
+		test_helpers.UnpointerSlice(subject),
+		test_helpers.UnpointerSlice(actual),
+	) {
+		t.Errorf(
+			"bad usage\nexpected: %+v\nactual:   %+v\n",
+			test_helpers.UnpointerSlice(subject),
+			test_helpers.UnpointerSlice(actual),
+		)
+	}
+}
@@ -13,6 +13,14 @@ func GetChatByID(db *sql.DB, ctx context.Context, id int64) (*models.Chat, error
 SELECT 
     id,
     name,
+    model,
+    max_tokens,
+    temperature,
+    history_strategy,
+    history_limit,
+    persona_id,
+    parent_chat_id,
+    parent_message_id,
     created_at,
     updated_at,
     deleted_at
@@ -24,6 +32,14 @@ WHERE id = ?`, id)
 	err := row.Scan(
 		&chat.ID,
 		&chat.Name,
+		&chat.Model,
+		&chat.MaxTokens,
+		&chat.Temperature,
+		&chat.Strategy,
+		&chat.Limit,
+		&chat.PersonaID,
+		&chat.ParentChatID,
+		&chat.ParentMessageID,
 		&chat.CreatedAt,
 		&chat.UpdatedAt,
 		&chat.DeletedAt,
@@ -19,6 +19,8 @@ SELECT
     m.chat_id,
     r.name,
     m.content,
+    m.generation,
+    m.selected_generation,
     m.created_at,
     m.updated_at,
     m.deleted_at
@@ -37,6 +39,8 @@ JOIN
 			&msg.ChatID,
 			&msg.Role,
 			&msg.Content,
+			&msg.Generation,
+			&msg.SelectedGeneration,
 			&msg.CreatedAt,
 			&msg.UpdatedAt,
 			&msg.DeletedAt,
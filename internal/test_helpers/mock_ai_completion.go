@@ -1,6 +1,7 @@
 package test_helpers
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/k10wl/hermes/internal/ai_clients"
@@ -9,12 +10,17 @@ import (
 )
 
 func MockCompletion(
+	ctx context.Context,
 	messages []*ai_clients.Message,
 	params *ai_clients.Parameters,
 	settings *settings.Providers,
+	onDelta ai_clients.OnDelta,
 ) (*ai_clients.AIResponse, error) {
 	messages[0].Role = core.AssistantRole
 	messages[0].Content = fmt.Sprintf("> mocked: %s", messages[0].Content)
+	if onDelta != nil {
+		onDelta(messages[0].Content)
+	}
 	return &ai_clients.AIResponse{
 		Message: *messages[0],
 	}, nil
@@ -13,6 +13,9 @@ export const config = {
     pathnames: {
       healthCheck: apiPathnameV1("health-check"),
       webSocket: apiPathnameV1("ws"),
+      search: apiPathnameV1("search"),
+      personas: apiPathnameV1("personas"),
+      attachments: apiPathnameV1("attachments"),
     },
   },
   chats: {
@@ -51,6 +51,11 @@ export class Chats extends HTMLElement {
       ServerEvents.on("chat-created", (data) => {
         list.current.prepandNodes(rendrer.createElement(data.payload.chat));
       }),
+      ServerEvents.on("chat-updated", (data) => {
+        list.current
+          .querySelector(`#chat-${data.payload.chat.id}`)
+          ?.replaceWith(rendrer.createElement(data.payload.chat));
+      }),
       ShortcutManager.keydown("<M-ArrowUp>", this.navigateInDir("prev")),
       ShortcutManager.keydown("<M-ArrowDown>", this.navigateInDir("next")),
     );
@@ -1,15 +1,28 @@
+import { config } from "/assets/scripts/config.mjs";
 import { html } from "/assets/scripts/lib/libdim.mjs";
 
 import { AssertInstance, AssertNumber, AssertString } from "../assert.mjs";
-import { CreateCompletionMessageEvent } from "../events/client-events-list.mjs";
+import {
+  CancelCompletionEvent,
+  CreateCompletionMessageEvent,
+} from "../events/client-events-list.mjs";
 import { ServerEvents } from "../events/server-events.mjs";
 import {
   ChatCreatedEvent,
   ServerErrorEvent,
 } from "../events/server-events-list.mjs";
 import { LocationControll } from "../location-control.mjs";
+import { AlertDialog } from "./dialog.mjs";
 
 export class MessageForm extends HTMLElement {
+  /** id of completion request that is waiting for answer */
+  #pending = "";
+  /**
+   * uploaded files, sent with next message
+   * @type {{id: number, name: string}[]}
+   */
+  #attachments = [];
+
   constructor() {
     super();
     this.shadow = this.attachShadow({ mode: "open" });
@@ -22,45 +35,169 @@ export class MessageForm extends HTMLElement {
       this.shadow.querySelector("form"),
       HTMLFormElement,
     );
+    const submit = AssertInstance.once(
+      this.shadow.querySelector("#submit-message"),
+      HTMLButtonElement,
+    );
+    this.#loadPersonas();
+
+    const fileInput = AssertInstance.once(
+      this.shadow.querySelector("#attachment-input"),
+      HTMLInputElement,
+    );
+    AssertInstance.once(
+      this.shadow.querySelector("#attach"),
+      HTMLButtonElement,
+    ).addEventListener("click", () => fileInput.click());
+    fileInput.addEventListener("change", async () => {
+      for (const file of Array.from(fileInput.files ?? [])) {
+        await this.#upload(file);
+      }
+      fileInput.value = "";
+    });
 
     form.addEventListener("submit", (e) => {
       e.preventDefault();
+      if (this.#pending) {
+        ServerEvents.send(
+          new CancelCompletionEvent({ request_id: this.#pending }),
+        );
+        return;
+      }
       /** @type {string | number | undefined} */
       let chat_id = LocationControll.pathname.split("/").at(-1);
       chat_id = AssertNumber.check(chat_id ? +chat_id : -1);
-      const content = AssertString.check(new FormData(form).get("content"));
+      const data = new FormData(form);
+      const content = AssertString.check(data.get("content"));
       if (content.trim() === "") {
         return;
       }
+      const persona = data.get("persona");
       const message = new CreateCompletionMessageEvent({
         chat_id,
         content: content,
         parameters: {
-          model: "openai/gpt-4o-mini",
+          model: undefined,
           max_tokens: undefined,
           temperature: undefined,
         },
+        stream: true,
+        persona: persona ? AssertString.check(persona) : undefined,
+        attachments: this.#attachments.length
+          ? this.#attachments.map((attachment) => attachment.id)
+          : undefined,
       });
       ServerEvents.send(message);
+      this.#setPending(form, submit, message.id);
       const off = ServerEvents.on(
         ["chat-created", "message-created", "server-error"],
         (event) => {
           if (event.id !== message.id) {
             return;
           }
-          off();
           if (event instanceof ChatCreatedEvent) {
             LocationControll.navigate(`/chats/${event.payload.chat.id}`);
+            form.reset();
+            this.#setAttachments([]);
+            // answer arrives later with same id
+            return;
           }
+          off();
+          this.#setPending(form, submit, "");
           if (event instanceof ServerErrorEvent) {
             return;
           }
           form.reset();
+          this.#setAttachments([]);
         },
       );
     });
   }
 
+  /**
+   * submit button stops pending completion
+   * @param {HTMLFormElement} form
+   * @param {HTMLButtonElement} submit
+   * @param {string} id
+   */
+  #setPending(form, submit, id) {
+    this.#pending = id;
+    form.toggleAttribute("data-pending", id !== "");
+    submit.textContent = id ? "■" : "↑";
+    submit.title = id ? "Stop answer" : "";
+  }
+
+  /** @param {File} file */
+  async #upload(file) {
+    const data = new FormData();
+    data.append("file", file);
+    try {
+      const res = await fetch(config.server.pathnames.attachments, {
+        method: "POST",
+        body: data,
+      });
+      if (!res.ok) {
+        throw new Error(await res.text());
+      }
+      /** @type {{id: number, name: string}} */
+      const attachment = await res.json();
+      this.#setAttachments([...this.#attachments, attachment]);
+    } catch (error) {
+      AlertDialog.instance.alert({
+        title: "Attachment Not Supported",
+        description: `${file.name}: ${error instanceof Error ? error.message : error}`,
+      });
+    }
+  }
+
+  /** @param {{id: number, name: string}[]} attachments */
+  #setAttachments(attachments) {
+    this.#attachments = attachments;
+    const list = AssertInstance.once(
+      this.shadow.querySelector("#attachments"),
+      HTMLElement,
+    );
+    list.replaceChildren(
+      ...attachments.map((attachment) => {
+        const remove = document.createElement("button");
+        remove.type = "button";
+        remove.title = "Remove attachment";
+        remove.textContent = `📎 ${attachment.name} ×`;
+        remove.addEventListener("click", () => {
+          this.#setAttachments(
+            this.#attachments.filter((other) => other !== attachment),
+          );
+        });
+        return remove;
+      }),
+    );
+    list.hidden = attachments.length === 0;
+  }
+
+  async #loadPersonas() {
+    const select = AssertInstance.once(
+      this.shadow.querySelector("select"),
+      HTMLSelectElement,
+    );
+    try {
+      const res = await fetch(config.server.pathnames.personas);
+      /** @type {{name: string}[]} */
+      const personas = await res.json();
+      if (personas.length === 0) {
+        return;
+      }
+      for (const persona of personas) {
+        const option = document.createElement("option");
+        option.value = persona.name;
+        option.textContent = persona.name;
+        select.append(option);
+      }
+      select.hidden = false;
+    } catch {
+      // personas are optional, form works without them
+    }
+  }
+
   #render() {
     this.shadow.append(html`
       <style>
@@ -70,6 +207,20 @@ export class MessageForm extends HTMLElement {
           --radius: 1rem;
         }
 
+        #attachments {
+          display: flex;
+          flex-wrap: wrap;
+          justify-content: flex-end;
+          gap: 0.25rem;
+          margin-bottom: 0.5rem;
+          button {
+            width: auto;
+            padding: 0 0.75rem;
+            font-size: 0.75rem;
+            background: var(--bg);
+          }
+        }
+
         form {
           display: flex;
           justify-content: center;
@@ -90,12 +241,22 @@ export class MessageForm extends HTMLElement {
           border: none;
         }
 
-        form:has(textarea:invalid) button[type="submit"] {
+        form:has(textarea:invalid):not([data-pending]) button[type="submit"] {
           background: var(--bg);
           color: rgb(from var(--text) r g b / 0.25);
           cursor: auto;
         }
 
+        select {
+          flex-shrink: 0;
+          height: 2rem;
+          background: var(--bg);
+          color: var(--text);
+          border: none;
+          border-radius: 1rem;
+          padding: 0 0.5rem;
+        }
+
         button {
           --_size: 2rem;
           transition: all var(--color-transition-duration);
@@ -112,7 +273,11 @@ export class MessageForm extends HTMLElement {
         }
       </style>
 
+      <div id="attachments" hidden></div>
       <form is="hermes-form">
+        <select name="persona" title="Persona" hidden>
+          <option value="">no persona</option>
+        </select>
         <textarea
           id="message-content-input"
           is="hermes-textarea-autoresize"
@@ -123,6 +288,8 @@ export class MessageForm extends HTMLElement {
           autofocus
           required
         ></textarea>
+        <input id="attachment-input" type="file" multiple hidden />
+        <button id="attach" type="button" title="Attach files">+</button>
         <button id="submit-message" type="submit">↑</button>
       </form>
     `);
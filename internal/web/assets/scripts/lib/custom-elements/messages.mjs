@@ -1,6 +1,10 @@
+import { config } from "/assets/scripts/config.mjs";
 import { AssertInstance } from "/assets/scripts/lib/assert.mjs";
 import { escapeMarkup } from "/assets/scripts/lib/escape-markup.mjs";
-import { RequestReadChatEvent } from "/assets/scripts/lib/events/client-events-list.mjs";
+import {
+  RegenerateMessageEvent,
+  RequestReadChatEvent,
+} from "/assets/scripts/lib/events/client-events-list.mjs";
 import { ServerEvents } from "/assets/scripts/lib/events/server-events.mjs";
 import { Bind, html, Signal } from "/assets/scripts/lib/libdim.mjs";
 import { LocationControll } from "/assets/scripts/lib/location-control.mjs";
@@ -346,10 +350,27 @@ class ChatMessage extends HTMLElement {
           opacity: 1;
           transition-delay: 0ms;
         }
+        :host(:not([data-role="assistant"])) #regenerate {
+          display: none;
+        }
+        ::slotted([slot="attachments"]) {
+          display: flex;
+          flex-wrap: wrap;
+          gap: 0.25rem;
+          font-size: 0.75em;
+        }
+        :host([data-interrupted="true"]) #content::after {
+          content: "answer was stopped";
+          font-size: 0.75em;
+          color: rgb(from var(--_text) r g b / 0.5);
+        }
       </style>
 
       <div id="wrapper">
-        <div id="content"><slot bind="${this.#slot}"></slot></div>
+        <div id="content">
+          <slot name="attachments"></slot
+          ><slot bind="${this.#slot}"></slot>
+        </div>
         <div part="actions" id="actions">
           <h-button
             id="copy"
@@ -364,6 +385,20 @@ class ChatMessage extends HTMLElement {
             }}"
             >${COPY_SYMBOL}</h-button
           >
+          <h-button
+            id="regenerate"
+            title="Regenerate answer"
+            onclick="${() => {
+              ServerEvents.send(
+                new RegenerateMessageEvent({
+                  message_id: Number(this.dataset.id),
+                  parameters: {},
+                  stream: true,
+                }),
+              );
+            }}"
+            >↻</h-button
+          >
         </div>
       </div>
     `);
@@ -454,6 +489,9 @@ export class Messages extends HTMLElement {
 
   messages = new Bind((el) => AssertInstance.once(el, HTMLElement));
 
+  /** @type {HTMLElement | null} streamed answer, dropped once message is created */
+  #pending = null;
+
   constructor() {
     super();
     this.shadow = this.attachShadow({ mode: "open" });
@@ -467,6 +505,13 @@ export class Messages extends HTMLElement {
         h-chat-message[data-role="assistant"]:last-child::part(actions) {
           opacity: 1;
         }
+
+        #pending {
+          padding: 0.5rem 0;
+          white-space: pre-wrap;
+          word-break: break-word;
+          color: var(--_text);
+        }
       </style>
 
       <section bind="${this.messages}"></section>
@@ -483,10 +528,25 @@ export class Messages extends HTMLElement {
     this.#cleanupOnDisconnect.push(
       LocationControll.attach(routeObserver),
 
+      ServerEvents.on("message-delta", (data) => {
+        if (data.payload.chat_id !== LocationControll.chatId) {
+          return;
+        }
+        if (!this.#pending) {
+          this.#pending = document.createElement("div");
+          this.#pending.id = "pending";
+          messagesContainer.append(this.#pending);
+        }
+        this.#pending.append(document.createTextNode(data.payload.delta));
+      }),
+
       ServerEvents.on("message-created", async (data) => {
         if (data.payload.chat_id !== LocationControll.chatId) {
           return;
         }
+        if (data.payload.message.role === "assistant") {
+          this.#dropPending();
+        }
         const newMessage = AssertInstance.once(
           this.#messageToHtml(data.payload.message).firstElementChild,
           HTMLElement,
@@ -504,7 +564,23 @@ export class Messages extends HTMLElement {
         }
       }),
 
+      ServerEvents.on("server-error", () => {
+        this.#dropPending();
+      }),
+
+      ServerEvents.on(
+        ["generation-created", "generation-selected", "message-discarded"],
+        (data) => {
+          if (data.payload.chat_id !== LocationControll.chatId) {
+            return;
+          }
+          this.#dropPending();
+          ServerEvents.send(new RequestReadChatEvent(data.payload.chat_id));
+        },
+      ),
+
       ServerEvents.on("read-chat", (data) => {
+        this.#pending = null;
         messagesContainer.replaceChildren(
           ...data.payload.messages.map((message) =>
             this.#messageToHtml(message),
@@ -520,15 +596,44 @@ export class Messages extends HTMLElement {
     }
   }
 
+  #dropPending() {
+    this.#pending?.remove();
+    this.#pending = null;
+  }
+
   /** @param {Message} message */
   #messageToHtml(message) {
-    const { role, content } = Message.validator.check(message);
+    const { id, role, content, interrupted, attachments } =
+      Message.validator.check(message);
     return html`
-      <h-chat-message data-role="${role}"
-        >${document.createTextNode(content)}</h-chat-message
+      <h-chat-message
+        data-id="${id}"
+        data-role="${role}"
+        data-interrupted="${Boolean(interrupted)}"
+        >${document.createTextNode(content)}${this.#attachmentsToHtml(
+          attachments ?? [],
+        )}</h-chat-message
       >
     `;
   }
+
+  /** @param {import("/assets/scripts/models.mjs").Attachment[]} attachments */
+  #attachmentsToHtml(attachments) {
+    if (attachments.length === 0) {
+      return [];
+    }
+    const links = document.createElement("div");
+    links.slot = "attachments";
+    for (const attachment of attachments) {
+      const link = document.createElement("a");
+      link.href = `${config.server.pathnames.attachments}/${attachment.id}`;
+      link.target = "_blank";
+      link.textContent = `📎 ${attachment.name}`;
+      link.title = `${attachment.media_type}, ${attachment.size} bytes`;
+      links.append(link);
+    }
+    return [links];
+  }
 }
 
 class RouteObserver {
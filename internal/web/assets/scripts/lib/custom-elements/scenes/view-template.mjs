@@ -1,11 +1,14 @@
 import { Bind, html } from "/assets/scripts/lib/libdim.mjs";
-import { Template } from "/assets/scripts/models.mjs";
+import { Template, TemplateVersion } from "/assets/scripts/models.mjs";
 
 import { AssertInstance, AssertString } from "../../assert.mjs";
 import {
   DeleteTemplateEvent,
   RequestEditTemplateEvent,
   RequestReadTemplateEvent,
+  RequestTemplateDiffEvent,
+  RequestTemplateHistoryEvent,
+  RollbackTemplateEvent,
 } from "../../events/client-events-list.mjs";
 import { ServerEvents } from "../../events/server-events.mjs";
 import { ServerErrorEvent } from "../../events/server-events-list.mjs";
@@ -235,6 +238,120 @@ class NameCollisionDialog extends HTMLElement {
 
 customElements.define("h-name-collision-dialog", NameCollisionDialog);
 
+class TemplateHistoryDialog extends HTMLElement {
+  dialog = new Bind((el) => AssertInstance.once(el, HermesDialog));
+  #versions = new Bind((el) => AssertInstance.once(el, HTMLElement));
+  #diff = new Bind((el) => AssertInstance.once(el, HTMLPreElement));
+
+  constructor() {
+    super();
+    this.attachShadow({ mode: "closed" }).append(html`
+      <style>
+        * {
+          color: var(--text-0);
+          box-sizing: border-box;
+        }
+
+        ul {
+          list-style: none;
+          padding: 0;
+          margin: 0;
+          max-height: 30vh;
+          overflow: auto;
+        }
+
+        li {
+          display: flex;
+          align-items: center;
+          gap: 0.5rem;
+          padding: 0.25rem 0;
+          span {
+            flex: 1;
+          }
+        }
+
+        pre {
+          max-height: 40vh;
+          overflow: auto;
+          margin: 0;
+          padding: 0.5rem;
+          border-radius: 0.5rem;
+          background-color: var(--bg-2);
+          white-space: pre-wrap;
+          &:empty {
+            display: none;
+          }
+        }
+      </style>
+
+      <h-dialog bind="${this.dialog}">
+        <h-dialog-card>
+          <h-dialog-title>Template history</h-dialog-title>
+          <h-dialog-block>
+            <ul bind="${this.#versions}"></ul>
+          </h-dialog-block>
+          <h-dialog-block>
+            <pre bind="${this.#diff}"></pre>
+          </h-dialog-block>
+          <h-dialog-block>
+            <h-button onclick="${() => this.close()}"
+              >Close <h-key>Esc</h-key></h-button
+            >
+          </h-dialog-block>
+        </h-dialog-card>
+      </h-dialog>
+    `);
+  }
+
+  /** @param {"diff" | "rollback"} eventName @param {TemplateVersion} version */
+  #dispatch = (eventName, version) => {
+    this.dispatchEvent(new CustomEvent(eventName, { detail: version }));
+  };
+
+  /** @param {TemplateVersion[]} versions newest first */
+  showModal(versions) {
+    this.#diff.current.textContent = "";
+    this.#versions.current.replaceChildren(
+      ...versions.map(
+        (version, i) => html`
+          <li>
+            <span>v${version.version} - ${version.name}</span>
+            ${i === 0
+              ? html`<span>current</span>`
+              : html`
+                  <h-button onclick="${() => this.#dispatch("diff", version)}"
+                    >Diff</h-button
+                  >
+                  <h-button
+                    variant="error"
+                    onclick="${() => this.#dispatch("rollback", version)}"
+                    >Rollback</h-button
+                  >
+                `}
+          </li>
+        `,
+      ),
+    );
+    if (this.dialog.current.element.open) {
+      return;
+    }
+    this.dialog.current.element.showModal();
+  }
+
+  /** @param {string} diff */
+  showDiff(diff) {
+    this.#diff.current.textContent = diff || "Versions are identical";
+  }
+
+  close() {
+    if (!this.dialog.current.element.open) {
+      return;
+    }
+    this.dialog.current.element.close();
+  }
+}
+customElements.define("h-template-history-dialog", TemplateHistoryDialog);
+
 export class HermesViewTemplateScene extends HTMLElement {
   /** @type {(() => void)[]} */
   #cleanup = [];
@@ -249,6 +366,11 @@ export class HermesViewTemplateScene extends HTMLElement {
   templateUpdatedDialog = new Bind((el) =>
     AssertInstance.once(el, TemplateUpdatedDialog),
   );
+  historyDialog = new Bind((el) =>
+    AssertInstance.once(el, TemplateHistoryDialog),
+  );
+  /** @type {TemplateVersion[]} */
+  #versions = [];
   form = new Bind((el) => AssertInstance.once(el, HTMLFormElement));
   #textarea = new Bind((el) => AssertInstance.once(el, HTMLTextAreaElement));
 
@@ -268,6 +390,7 @@ export class HermesViewTemplateScene extends HTMLElement {
           this.form.current.requestSubmit(),
         ),
       ),
+      ActionStore.add(new Action("template: history", this.history)),
       ServerEvents.on(["template-changed"], (event) => {
         if (
           !this.#textarea ||
@@ -344,23 +467,132 @@ ${event.payload.template.content.trim()}</textarea
     if (!ok) {
       return;
     }
+    this.#sendDelete(false);
+  };
+
+  /** @param {boolean} force */
+  #sendDelete = (force) => {
     const deleteEvent = new DeleteTemplateEvent({
       name: AssertString.check(this.#template?.name),
+      force,
     });
-    ServerEvents.on(["server-error", "template-deleted"], (event) => {
-      if (event.id !== deleteEvent.id) {
+    const off = ServerEvents.on(
+      ["server-error", "template-deleted"],
+      async (event) => {
+        if (event.id !== deleteEvent.id) {
+          return;
+        }
+        off();
+        if (event instanceof ServerErrorEvent) {
+          // templates used by other templates are deleted only when forced
+          if (!force && event.payload.includes("is used by")) {
+            const ok = await ConfirmDialog.instance.confirm({
+              title: "Template is in use, delete anyway?",
+              description: event.payload,
+            });
+            if (ok) {
+              this.#sendDelete(true);
+            }
+            return;
+          }
+          AlertDialog.instance.alert({
+            title: "Failed to delete template",
+            description: `Delete errored: ${event.payload}`,
+          });
+          return;
+        }
+        LocationControll.navigate("/templates/");
+      },
+    );
+    ServerEvents.send(deleteEvent);
+  };
+
+  history = () => {
+    if (!LocationControll.templateId || !this.#template) {
+      return;
+    }
+    const historyEvent = new RequestTemplateHistoryEvent({
+      name: this.#template.name,
+    });
+    const off = ServerEvents.on(
+      ["template-history", "server-error"],
+      (event) => {
+        if (event.id !== historyEvent.id) {
+          return;
+        }
+        off();
+        if (event instanceof ServerErrorEvent) {
+          AlertDialog.instance.alert({
+            title: "Failed to read history",
+            description: `History errored: ${event.payload}`,
+          });
+          return;
+        }
+        this.#versions = event.payload.versions;
+        this.historyDialog.current.showModal(this.#versions);
+      },
+    );
+    this.#cleanup.push(off);
+    ServerEvents.send(historyEvent);
+  };
+
+  /** @param {TemplateVersion} version */
+  #diff = (version) => {
+    const diffEvent = new RequestTemplateDiffEvent({
+      name: AssertString.check(this.#template?.name),
+      from: version.version,
+      to: this.#versions[0]?.version ?? version.version,
+    });
+    const off = ServerEvents.on(["template-diff", "server-error"], (event) => {
+      if (event.id !== diffEvent.id) {
         return;
       }
+      off();
       if (event instanceof ServerErrorEvent) {
-        AlertDialog.instance.alert({
-          title: "Failed to delete template",
-          description: `Delete errored: ${event.payload}`,
-        });
+        this.historyDialog.current.showDiff(`Diff failed - ${event.payload}`);
         return;
       }
-      LocationControll.navigate("/templates/");
+      this.historyDialog.current.showDiff(event.payload.diff);
     });
-    ServerEvents.send(deleteEvent);
+    this.#cleanup.push(off);
+    ServerEvents.send(diffEvent);
+  };
+
+  /** @param {TemplateVersion} version */
+  #rollback = async (version) => {
+    const ok = await ConfirmDialog.instance.confirm({
+      title: "Rollback template",
+      description: `Replace content of '${this.#template?.name}' with v${version.version}?`,
+    });
+    if (!ok) {
+      return;
+    }
+    const rollbackEvent = new RollbackTemplateEvent({
+      name: AssertString.check(this.#template?.name),
+      version: version.version,
+    });
+    const off = ServerEvents.on(
+      ["template-changed", "server-error"],
+      (event) => {
+        if (event.id !== rollbackEvent.id) {
+          return;
+        }
+        off();
+        if (event instanceof ServerErrorEvent) {
+          AlertDialog.instance.alert({
+            description: `Rollback failed - ${event.payload}`,
+          });
+          return;
+        }
+        this.#template = event.payload.template;
+        this.#textarea.current.value = event.payload.template.content;
+        this.templateUpdatedDialog.current.close();
+        this.historyDialog.current.close();
+        this.#savedIndicator();
+      },
+    );
+    this.#cleanup.push(off);
+    ServerEvents.send(rollbackEvent);
   };
 
   /** @param {boolean} clone */
@@ -524,6 +756,14 @@ ${event.payload.template.content.trim()}</textarea
       }}"
       bind="${this.templateUpdatedDialog}"
     ></h-teplate-updated-dialog>
+
+    <h-template-history-dialog
+      ondiff="${(/** @type {CustomEvent<TemplateVersion>} */ event) =>
+        this.#diff(event.detail)}"
+      onrollback="${(/** @type {CustomEvent<TemplateVersion>} */ event) =>
+        this.#rollback(event.detail)}"
+      bind="${this.historyDialog}"
+    ></h-template-history-dialog>
   `;
 }
 
@@ -1,4 +1,5 @@
 import {
+  AssertArray,
   AssertBoolean,
   AssertNumber,
   AssertObject,
@@ -41,10 +42,14 @@ export class CreateCompletionMessageEvent extends ClientEvent {
     chat_id: AssertNumber,
     content: AssertString,
     parameters: new AssertObject({
-      model: AssertString,
+      model: new AssertOptional(AssertString),
       max_tokens: new AssertOptional(AssertNumber),
       temperature: new AssertOptional(AssertNumber),
     }),
+    stream: new AssertOptional(AssertBoolean),
+    persona: new AssertOptional(AssertString),
+    variables: new AssertOptional(new AssertObject({})),
+    attachments: new AssertOptional(new AssertArray(AssertNumber)),
   });
 
   /** @param {ReturnType<CreateCompletionMessageEvent['validatePayload']>} payload  */
@@ -133,6 +138,7 @@ export class DeleteTemplateEvent extends ClientEvent {
 
   static #eventValidation = new AssertObject({
     name: AssertString,
+    force: new AssertOptional(AssertBoolean),
   });
 
   /** @param {ReturnType<DeleteTemplateEvent['validatePayload']>} payload  */
@@ -148,3 +154,138 @@ export class DeleteTemplateEvent extends ClientEvent {
     return DeleteTemplateEvent.#eventValidation.check(data);
   }
 }
+
+export class RegenerateMessageEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "regenerate-message";
+
+  static #eventValidation = new AssertObject({
+    message_id: AssertNumber,
+    parameters: new AssertObject({
+      model: new AssertOptional(AssertString),
+      max_tokens: new AssertOptional(AssertNumber),
+      temperature: new AssertOptional(AssertNumber),
+    }),
+    stream: new AssertOptional(AssertBoolean),
+  });
+
+  /** @param {ReturnType<RegenerateMessageEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: RegenerateMessageEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return RegenerateMessageEvent.#eventValidation.check(data);
+  }
+}
+
+export class SelectGenerationEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "select-generation";
+
+  static #eventValidation = new AssertObject({
+    message_id: AssertNumber,
+  });
+
+  /** @param {ReturnType<SelectGenerationEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: SelectGenerationEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return SelectGenerationEvent.#eventValidation.check(data);
+  }
+}
+
+export class RequestTemplateHistoryEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "request-template-history";
+
+  static #eventValidation = new AssertObject({
+    name: AssertString,
+  });
+
+  /** @param {ReturnType<RequestTemplateHistoryEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: RequestTemplateHistoryEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return RequestTemplateHistoryEvent.#eventValidation.check(data);
+  }
+}
+
+export class RequestTemplateDiffEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "request-template-diff";
+
+  static #eventValidation = new AssertObject({
+    name: AssertString,
+    from: AssertNumber,
+    to: AssertNumber,
+  });
+
+  /** @param {ReturnType<RequestTemplateDiffEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: RequestTemplateDiffEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return RequestTemplateDiffEvent.#eventValidation.check(data);
+  }
+}
+
+export class RollbackTemplateEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "rollback-template";
+
+  static #eventValidation = new AssertObject({
+    name: AssertString,
+    version: AssertNumber,
+  });
+
+  /** @param {ReturnType<RollbackTemplateEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: RollbackTemplateEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return RollbackTemplateEvent.#eventValidation.check(data);
+  }
+}
+
+export class CancelCompletionEvent extends ClientEvent {
+  static canonicalType = /** @type {const} */ "cancel-completion";
+
+  static #eventValidation = new AssertObject({
+    request_id: AssertString,
+  });
+
+  /** @param {ReturnType<CancelCompletionEvent['validatePayload']>} payload  */
+  constructor(payload) {
+    super({
+      type: CancelCompletionEvent.canonicalType,
+    });
+    this.payload = this.validatePayload(payload);
+  }
+
+  /** @param {unknown} data */
+  validatePayload(data) {
+    return CancelCompletionEvent.#eventValidation.check(data);
+  }
+}
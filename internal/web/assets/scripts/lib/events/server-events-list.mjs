@@ -7,7 +7,12 @@ import {
   AssertOptional,
   AssertString,
 } from "/assets/scripts/lib/assert.mjs";
-import { Chat, Message, Template } from "/assets/scripts/models.mjs";
+import {
+  Chat,
+  Message,
+  Template,
+  TemplateVersion,
+} from "/assets/scripts/models.mjs";
 
 export class ServerEvent {
   static #eventValidation = new AssertObject({
@@ -99,6 +104,38 @@ export class ChatCreatedEvent extends ServerEvent {
   }
 }
 
+export class ChatUpdatedEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      chat: Chat.validator,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("chat-updated");
+
+  /** @param { ReturnType<ChatUpdatedEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = {
+      chat: new Chat(data.payload.chat.id, data.payload.chat.name),
+    };
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    return new ChatUpdatedEvent(
+      ChatUpdatedEvent.validate(JSON.parse(AssertString.check(data))),
+    );
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return ChatUpdatedEvent.#eventValidation.check(data);
+  }
+}
+
 export class ReadChatEvent extends ServerEvent {
   static #eventValidation = new AssertObject({
     id: AssertString,
@@ -141,6 +178,7 @@ export class ServerErrorEvent extends ServerEvent {
     id: AssertString,
     type: AssertString,
     payload: AssertString,
+    code: new AssertOptional(AssertString),
   });
 
   static canonicalType = /** @type {const} */ ("server-error");
@@ -149,6 +187,8 @@ export class ServerErrorEvent extends ServerEvent {
   constructor(data) {
     super(data);
     this.payload = data.payload;
+    /** kind of failed completion, undefined for other errors */
+    this.code = data.code;
   }
 
   /** @param {unknown} data */
@@ -202,6 +242,143 @@ export class MessageCreatedEvent extends ServerEvent {
   }
 }
 
+export class MessageDiscardedEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      chat_id: AssertNumber,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("message-discarded");
+
+  /** @param { ReturnType<MessageDiscardedEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    return new MessageDiscardedEvent(
+      MessageDiscardedEvent.validate(JSON.parse(AssertString.check(data))),
+    );
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return MessageDiscardedEvent.#eventValidation.check(data);
+  }
+}
+
+export class MessageDeltaEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      chat_id: AssertNumber,
+      delta: AssertString,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("message-delta");
+
+  /** @param { ReturnType<MessageDeltaEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    return new MessageDeltaEvent(
+      MessageDeltaEvent.validate(JSON.parse(AssertString.check(data))),
+    );
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return MessageDeltaEvent.#eventValidation.check(data);
+  }
+}
+
+export class GenerationCreatedEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      chat_id: AssertNumber,
+      message: Message.validator,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("generation-created");
+
+  /** @param { ReturnType<GenerationCreatedEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    const parsed = GenerationCreatedEvent.validate(
+      JSON.parse(AssertString.check(data)),
+    );
+    return new GenerationCreatedEvent({
+      ...parsed,
+      payload: {
+        ...parsed.payload,
+        message: new Message(parsed.payload.message),
+      },
+    });
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return GenerationCreatedEvent.#eventValidation.check(data);
+  }
+}
+
+export class GenerationSelectedEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      chat_id: AssertNumber,
+      message: Message.validator,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("generation-selected");
+
+  /** @param { ReturnType<GenerationSelectedEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    const parsed = GenerationSelectedEvent.validate(
+      JSON.parse(AssertString.check(data)),
+    );
+    return new GenerationSelectedEvent({
+      ...parsed,
+      payload: {
+        ...parsed.payload,
+        message: new Message(parsed.payload.message),
+      },
+    });
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return GenerationSelectedEvent.#eventValidation.check(data);
+  }
+}
+
 export class ReadTemplatesEvent extends ServerEvent {
   static #eventValidation = new AssertObject({
     id: AssertString,
@@ -357,3 +534,70 @@ export class TemplateDeletedEvent extends ServerEvent {
     return TemplateDeletedEvent.#eventValidation.check(data);
   }
 }
+
+export class TemplateHistoryEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      name: AssertString,
+      versions: new AssertArray(new AssertInstance(TemplateVersion)),
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("template-history");
+
+  /** @param { ReturnType<TemplateHistoryEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    /** @type {TemplateHistoryEvent} or at least it should be */
+    const parsed = JSON.parse(AssertString.check(data));
+    parsed.payload.versions = parsed.payload.versions.map(
+      (version) => new TemplateVersion(version),
+    );
+    return new TemplateHistoryEvent(TemplateHistoryEvent.validate(parsed));
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return TemplateHistoryEvent.#eventValidation.check(data);
+  }
+}
+
+export class TemplateDiffEvent extends ServerEvent {
+  static #eventValidation = new AssertObject({
+    id: AssertString,
+    type: AssertString,
+    payload: new AssertObject({
+      name: AssertString,
+      from: AssertNumber,
+      to: AssertNumber,
+      diff: AssertString,
+    }),
+  });
+
+  static canonicalType = /** @type {const} */ ("template-diff");
+
+  /** @param { ReturnType<TemplateDiffEvent.validate> } data */
+  constructor(data) {
+    super(data);
+    this.payload = data.payload;
+  }
+
+  /** @param {unknown} data */
+  static parse(data) {
+    /** @type {TemplateDiffEvent} or at least it should be */
+    const parsed = JSON.parse(AssertString.check(data));
+    return new TemplateDiffEvent(TemplateDiffEvent.validate(parsed));
+  }
+
+  /** @param {unknown} data */
+  static validate(data) {
+    return TemplateDiffEvent.#eventValidation.check(data);
+  }
+}
@@ -16,6 +16,8 @@ const _isolatedServiceEvents = {
 const _serverEvents = {
   [serverEventsList.ChatCreatedEvent.canonicalType]:
     serverEventsList.ChatCreatedEvent,
+  [serverEventsList.ChatUpdatedEvent.canonicalType]:
+    serverEventsList.ChatUpdatedEvent,
   [serverEventsList.ServerEvent.canonicalType]: serverEventsList.ServerEvent,
   [serverEventsList.ReadChatEvent.canonicalType]:
     serverEventsList.ReadChatEvent,
@@ -23,6 +25,14 @@ const _serverEvents = {
     serverEventsList.ServerErrorEvent,
   [serverEventsList.MessageCreatedEvent.canonicalType]:
     serverEventsList.MessageCreatedEvent,
+  [serverEventsList.MessageDiscardedEvent.canonicalType]:
+    serverEventsList.MessageDiscardedEvent,
+  [serverEventsList.MessageDeltaEvent.canonicalType]:
+    serverEventsList.MessageDeltaEvent,
+  [serverEventsList.GenerationCreatedEvent.canonicalType]:
+    serverEventsList.GenerationCreatedEvent,
+  [serverEventsList.GenerationSelectedEvent.canonicalType]:
+    serverEventsList.GenerationSelectedEvent,
   [serverEventsList.ReloadEvent.canonicalType]: serverEventsList.ReloadEvent,
   [serverEventsList.ReadTemplatesEvent.canonicalType]:
     serverEventsList.ReadTemplatesEvent,
@@ -34,6 +44,10 @@ const _serverEvents = {
     serverEventsList.TemplateCreatedEvent,
   [serverEventsList.TemplateDeletedEvent.canonicalType]:
     serverEventsList.TemplateDeletedEvent,
+  [serverEventsList.TemplateHistoryEvent.canonicalType]:
+    serverEventsList.TemplateHistoryEvent,
+  [serverEventsList.TemplateDiffEvent.canonicalType]:
+    serverEventsList.TemplateDiffEvent,
 };
 
 const _clientEvents = {
@@ -47,6 +61,16 @@ const _clientEvents = {
     clientEventsList.RequestReadTemplateEvent,
   [clientEventsList.DeleteTemplateEvent.canonicalType]:
     clientEventsList.DeleteTemplateEvent,
+  [clientEventsList.RegenerateMessageEvent.canonicalType]:
+    clientEventsList.RegenerateMessageEvent,
+  [clientEventsList.SelectGenerationEvent.canonicalType]:
+    clientEventsList.SelectGenerationEvent,
+  [clientEventsList.RequestTemplateHistoryEvent.canonicalType]:
+    clientEventsList.RequestTemplateHistoryEvent,
+  [clientEventsList.RequestTemplateDiffEvent.canonicalType]:
+    clientEventsList.RequestTemplateDiffEvent,
+  [clientEventsList.RollbackTemplateEvent.canonicalType]:
+    clientEventsList.RollbackTemplateEvent,
 };
 
 const _registeredEvents = {
@@ -6,10 +6,24 @@ import "./ui/connection-indicator.mjs";
 import { AlertDialog } from "./lib/custom-elements/dialog.mjs";
 import { ServerEvents } from "./lib/events/server-events.mjs";
 
+/** @type {Record<string, string>} */
+const completionErrorTitles = {
+  auth: "Provider Rejected API Key",
+  rate_limit: "Provider Rate Limit",
+  context_length: "Chat Does Not Fit Model",
+  server: "Provider Unavailable",
+  timeout: "Provider Timed Out",
+  unsupported_attachment: "Attachment Not Supported",
+};
+
 ServerEvents.on("server-error", (event) => {
+  if (event.code === "cancelled") {
+    // user stopped completion, nothing went wrong
+    return;
+  }
   console.error("server error:", event.payload);
   AlertDialog.instance.alert({
-    title: "Server Error",
+    title: completionErrorTitles[event.code ?? ""] ?? "Server Error",
     description: event.payload,
   });
 });
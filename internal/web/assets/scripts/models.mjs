@@ -1,6 +1,9 @@
 import {
+  AssertArray,
+  AssertBoolean,
   AssertNumber,
   AssertObject,
+  AssertOptional,
   AssertString,
 } from "/assets/scripts/lib/assert.mjs";
 
@@ -20,12 +23,31 @@ export class Chat {
   }
 }
 
+export class Attachment {
+  static validator = new AssertObject({
+    id: AssertNumber,
+    name: AssertString,
+    media_type: AssertString,
+    size: AssertNumber,
+  });
+
+  /** @param {ReturnType<Attachment.validator['check']>} attachment  */
+  constructor(attachment) {
+    this.id = attachment.id;
+    this.name = attachment.name;
+    this.media_type = attachment.media_type;
+    this.size = attachment.size;
+  }
+}
+
 export class Message {
   static validator = new AssertObject({
     id: AssertNumber,
     chat_id: AssertNumber,
     content: AssertString,
     role: AssertString,
+    interrupted: new AssertOptional(AssertBoolean),
+    attachments: new AssertOptional(new AssertArray(Attachment.validator)),
   });
 
   /** @param {{
@@ -33,12 +55,16 @@ export class Message {
    *   chat_id: number
    *   role: "user" | "assistant" | "system" | string
    *   content: string
+   *   interrupted?: boolean
+   *   attachments?: Attachment[]
    * }} message */
   constructor(message) {
     this.id = message.id;
     this.chat_id = message.chat_id;
     this.role = message.role;
     this.content = message.content;
+    this.interrupted = message.interrupted;
+    this.attachments = message.attachments;
   }
 }
 
@@ -56,3 +82,20 @@ export class Template {
     this.content = template.content;
   }
 }
+
+export class TemplateVersion {
+  static validator = new AssertObject({
+    template_id: AssertNumber,
+    version: AssertNumber,
+    name: AssertString,
+    content: AssertString,
+  });
+
+  /** @param {ReturnType<TemplateVersion.validator['check']>} version  */
+  constructor(version) {
+    this.template_id = version.template_id;
+    this.version = version.version;
+    this.name = version.name;
+    this.content = version.content;
+  }
+}
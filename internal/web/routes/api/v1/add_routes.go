@@ -9,6 +9,12 @@ import (
 
 func AddRoutes(mux *http.ServeMux, core *core.Core, hub *Hub) {
 	mux.Handle("/api/v1/chats", handleChats(core))
+	mux.Handle("GET /api/v1/chats/{id}/export", handleChatExport(core))
+	mux.Handle("POST /api/v1/attachments", handleCreateAttachment(core))
+	mux.Handle("GET /api/v1/attachments/{id}", handleAttachment(core))
+	mux.Handle("/api/v1/usage", handleUsage(core))
+	mux.Handle("/api/v1/search", handleSearch(core))
+	mux.Handle("/api/v1/personas", handlePersonas(core))
 	mux.Handle("/api/v1/health-check", handleCheckHeath())
 	mux.Handle("/api/v1/relay", handleRelay(hub.broadcast))
 	mux.Handle("/api/v1/ws", handleServeWebSockets(core, hub, ai_clients.Complete))
@@ -0,0 +1,132 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func uploadAttachment(url string, name string, data []byte) (*http.Response, error) {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, err := form.CreateFormFile("file", name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+	return http.Post(url, form.FormDataContentType(), body)
+}
+
+func TestHandleAttachments(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	mux := http.NewServeMux()
+	mux.Handle("POST /api/v1/attachments", handleCreateAttachment(coreInstance))
+	mux.Handle("GET /api/v1/attachments/{id}", handleAttachment(coreInstance))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	res, err := uploadAttachment(srv.URL+"/api/v1/attachments", "notes.txt", []byte("notes"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("bad upload status\nexpected: %d\nactual:   %d\n", http.StatusCreated, res.StatusCode)
+	}
+	var attachment models.Attachment
+	if err := json.NewDecoder(res.Body).Decode(&attachment); err != nil {
+		t.Fatal(err)
+	}
+	if attachment.ID != 1 ||
+		attachment.Name != "notes.txt" ||
+		attachment.MediaType != "text/plain" ||
+		attachment.Size != 5 ||
+		attachment.MessageID != nil {
+		t.Errorf("bad uploaded attachment: %+v\n", attachment)
+	}
+
+	res, err = uploadAttachment(srv.URL+"/api/v1/attachments", "archive.zip", []byte("PK\x03\x04"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected unsupported file to be rejected, got %d\n", res.StatusCode)
+	}
+
+	type testCase struct {
+		name                string
+		path                string
+		expectedStatus      int
+		expectedType        string
+		expectedDisposition string
+		expectedBody        string
+	}
+
+	table := []testCase{
+		{
+			name:                "should serve attachment data",
+			path:                "/api/v1/attachments/1",
+			expectedStatus:      http.StatusOK,
+			expectedType:        "text/plain",
+			expectedDisposition: `inline; filename=notes.txt`,
+			expectedBody:        "notes",
+		},
+		{
+			name:           "should reject bad id",
+			path:           "/api/v1/attachments/one",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "should not find missing attachment",
+			path:           "/api/v1/attachments/2",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range table {
+		res, err := http.Get(fmt.Sprintf("%s%s", srv.URL, test.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != test.expectedStatus {
+			t.Errorf(
+				"%q - bad status\nexpected: %d\nactual:   %d\n",
+				test.name,
+				test.expectedStatus,
+				res.StatusCode,
+			)
+			continue
+		}
+		if test.expectedStatus != http.StatusOK {
+			continue
+		}
+		if actual := res.Header.Get("Content-Type"); actual != test.expectedType {
+			t.Errorf("%q - bad content type: %q\n", test.name, actual)
+		}
+		if actual := res.Header.Get("Content-Disposition"); actual != test.expectedDisposition {
+			t.Errorf("%q - bad content disposition: %q\n", test.name, actual)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != test.expectedBody {
+			t.Errorf("%q - bad body: %q\n", test.name, string(body))
+		}
+	}
+}
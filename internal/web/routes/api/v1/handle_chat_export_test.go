@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestHandleChatExport(t *testing.T) {
+	type testCase struct {
+		name                string
+		path                string
+		expectedStatus      int
+		expectedType        string
+		expectedDisposition string
+		expectedBody        string
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.SeedMessagesN(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /api/v1/chats/{id}/export", handleChatExport(coreInstance))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	table := []testCase{
+		{
+			name:                "should download json by default",
+			path:                "/api/v1/chats/1/export",
+			expectedStatus:      http.StatusOK,
+			expectedType:        "application/json",
+			expectedDisposition: `attachment; filename="chat-1.json"`,
+			expectedBody:        `"content": "generated"`,
+		},
+		{
+			name:                "should download markdown",
+			path:                "/api/v1/chats/1/export?format=md",
+			expectedStatus:      http.StatusOK,
+			expectedType:        "text/markdown; charset=utf-8",
+			expectedDisposition: `attachment; filename="chat-1.md"`,
+			expectedBody:        "# 1\n",
+		},
+		{
+			name:           "should reject unknown format",
+			path:           "/api/v1/chats/1/export?format=html",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "should reject bad id",
+			path:           "/api/v1/chats/one/export",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "should not find missing chat",
+			path:           "/api/v1/chats/2/export",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range table {
+		res, err := http.Get(fmt.Sprintf("%s%s", srv.URL, test.path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != test.expectedStatus {
+			t.Errorf(
+				"%q - bad status\nexpected: %d\nactual:   %d\n",
+				test.name,
+				test.expectedStatus,
+				res.StatusCode,
+			)
+			continue
+		}
+		if test.expectedStatus != http.StatusOK {
+			continue
+		}
+		if actual := res.Header.Get("Content-Type"); actual != test.expectedType {
+			t.Errorf("%q - bad content type: %q\n", test.name, actual)
+		}
+		if actual := res.Header.Get("Content-Disposition"); actual != test.expectedDisposition {
+			t.Errorf("%q - bad content disposition: %q\n", test.name, actual)
+		}
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), test.expectedBody) {
+			t.Errorf(
+				"%q - bad body\nexpected to contain: %q\nactual: %q\n",
+				test.name,
+				test.expectedBody,
+				string(body),
+			)
+		}
+	}
+
+	// storage failures are not reported as missing chat
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get(fmt.Sprintf("%s/api/v1/chats/1/export", srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusInternalServerError {
+		t.Errorf(
+			"bad status on storage failure\nexpected: %d\nactual:   %d\n",
+			http.StatusInternalServerError,
+			res.StatusCode,
+		)
+	}
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+)
+
+func TestHandlePersonas(t *testing.T) {
+	coreInstance, _ := test_helpers.CreateCore()
+	for _, name := range []string{"reviewer", "poet"} {
+		if err := core.NewUpsertPersonaCommand(coreInstance, &models.Persona{
+			Name:   name,
+			Prompt: "prompt of " + name,
+		}).Execute(context.Background()); err != nil {
+			t.Fatalf("failed to create persona: %s\n", err)
+		}
+	}
+
+	srv := httptest.NewServer(handlePersonas(coreInstance))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("failed to request personas: %s\n", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("bad status, expected %d, got %d\n", http.StatusOK, res.StatusCode)
+	}
+	personas := []models.Persona{}
+	if err := json.NewDecoder(res.Body).Decode(&personas); err != nil {
+		t.Fatalf("failed to decode personas: %s\n", err)
+	}
+	if len(personas) != 2 || personas[0].Name != "poet" || personas[1].Prompt != "prompt of reviewer" {
+		t.Errorf("bad personas, expected poet and reviewer ordered by name, got %+v\n", personas)
+	}
+}
@@ -0,0 +1,104 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestHandleSearch(t *testing.T) {
+	type testCase struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedSnippets []string
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	ctx := context.Background()
+	if err := db_helpers.CreateChats(db, ctx, []*models.Chat{{Name: "markup"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db_helpers.CreateMessages(db, ctx, []*models.Message{
+		{ChatID: 1, Role: "user", Content: "what does <script> tag do?"},
+		{ChatID: 1, Role: "assistant", Content: "script tag runs code"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(handleSearch(coreInstance))
+	defer srv.Close()
+
+	table := []testCase{
+		{
+			name:           "should escape content and mark matches",
+			query:          "?q=script&limit=1",
+			expectedStatus: http.StatusOK,
+			expectedSnippets: []string{
+				"<mark>script</mark> tag runs code",
+			},
+		},
+		{
+			name:           "should escape markup around matches",
+			query:          "?q=does+script",
+			expectedStatus: http.StatusOK,
+			expectedSnippets: []string{
+				"what <mark>does</mark> &lt;<mark>script</mark>&gt; tag do?",
+			},
+		},
+		{
+			name:           "should reject empty search",
+			query:          "?q=",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range table {
+		res, err := http.Get(srv.URL + test.query)
+		if err != nil {
+			t.Fatalf("%q - failed to make request: %s\n", test.name, err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != test.expectedStatus {
+			t.Errorf(
+				"%q - bad status, expected %d, got %d\n",
+				test.name,
+				test.expectedStatus,
+				res.StatusCode,
+			)
+			continue
+		}
+		if test.expectedStatus != http.StatusOK {
+			continue
+		}
+		var results []*models.SearchResult
+		if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+			t.Errorf("%q - failed to decode response: %s\n", test.name, err)
+			continue
+		}
+		snippets := []string{}
+		for _, result := range results {
+			snippets = append(snippets, result.Snippet)
+		}
+		if len(snippets) != len(test.expectedSnippets) {
+			t.Errorf("%q - bad results: %q\n", test.name, snippets)
+			continue
+		}
+		for i := range snippets {
+			if snippets[i] != test.expectedSnippets[i] {
+				t.Errorf(
+					"%q - bad snippet\nexpected: %q\nactual:   %q\n",
+					test.name,
+					test.expectedSnippets[i],
+					snippets[i],
+				)
+			}
+		}
+	}
+}
@@ -3,9 +3,17 @@ package v1
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/settings"
+	"github.com/k10wl/hermes/internal/test_helpers"
 	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
 	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
 )
@@ -274,10 +282,9 @@ func TestCreateMessageInNonExistingChat(t *testing.T) {
 		t.Fatalf("could not write message to WebSocket server: %v", err)
 	}
 
-	_, response1, err := client.ReadMessage()
-	if err != nil {
-		t.Fatalf("could not read message from WebSocket server: %v", err)
-	}
+	received := readMessagesByType(t, client, 2)
+	response1 := received["message-created"]
+	response2 := received["server-error"]
 
 	res1 := messages.ServerMessageCreated{}
 	err = json.Unmarshal(response1, &res1)
@@ -294,10 +301,6 @@ func TestCreateMessageInNonExistingChat(t *testing.T) {
 		t.Errorf("Failed to return shared id\n")
 	}
 
-	_, response2, err := client.ReadMessage()
-	if err != nil {
-		t.Fatalf("could not read message from WebSocket server: %v", err)
-	}
 	res2 := messages.ServerError{}
 	err = json.Unmarshal(response2, &res2)
 	if err != nil {
@@ -310,3 +313,648 @@ func TestCreateMessageInNonExistingChat(t *testing.T) {
 		t.Errorf("Failed to return shared id\n")
 	}
 }
+
+func TestCreateMessageInExistingChatWithStream(t *testing.T) {
+	client, db, teardown := setupWebSocketTest(t)
+	defer teardown()
+
+	seeder := db_helpers.NewSeeder(db, context.TODO())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "template": "",
+    "stream": true,
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+
+	if _, _, err := client.ReadMessage(); err != nil {
+		t.Fatalf("could not read user message from WebSocket server: %v", err)
+	}
+
+	_, response, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	delta := messages.ServerMessageDelta{}
+	if err := json.Unmarshal(response, &delta); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if delta.Type != "message-delta" {
+		t.Errorf("Did not respond with 'message-delta', got %q\n", delta.Type)
+	}
+	if delta.ID != sharedID {
+		t.Errorf("Failed to return shared id\n")
+	}
+	if delta.Payload.ChatID != 1 {
+		t.Errorf("Failed to return same chat\n")
+	}
+	if delta.Payload.Delta != "> mocked: create message" {
+		t.Errorf("Failed to stream delta, got %q\n", delta.Payload.Delta)
+	}
+
+	_, response, err = client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	res := messages.ServerMessageCreated{}
+	if err := json.Unmarshal(response, &res); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if res.Type != "message-created" {
+		t.Errorf("Did not respond with 'message created'\n")
+	}
+	if res.Payload.Message.Content != delta.Payload.Delta {
+		t.Errorf(
+			"Persisted message does not match stream\nexpected: %q\nactual:   %q\n",
+			delta.Payload.Delta,
+			res.Payload.Message.Content,
+		)
+	}
+}
+
+func TestRegenerateAndSelectGeneration(t *testing.T) {
+	client, db, teardown := setupWebSocketTest(t)
+	defer teardown()
+
+	seeder := db_helpers.NewSeeder(db, context.TODO())
+	if err := seeder.SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.SeedMessagesN(2, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "regenerate-message",
+  "payload": {
+    "message_id": 2,
+    "parameters": {}
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	_, response, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	created := messages.ServerGenerationCreated{}
+	if err := json.Unmarshal(response, &created); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if created.Type != "generation-created" {
+		t.Errorf("Did not respond with 'generation-created', got %q\n", created.Type)
+	}
+	if created.ID != sharedID {
+		t.Errorf("Failed to return shared id\n")
+	}
+	if created.Payload.ChatID != 1 ||
+		created.Payload.Message.ID != 3 ||
+		created.Payload.Message.Generation != 1 ||
+		created.Payload.Message.Content != "> mocked: generated" {
+		t.Errorf("Bad generation - %s\n", response)
+	}
+
+	err = client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "select-generation",
+  "payload": {
+    "message_id": 2
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	_, response, err = client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	selected := messages.ServerGenerationSelected{}
+	if err := json.Unmarshal(response, &selected); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if selected.Type != "generation-selected" {
+		t.Errorf("Did not respond with 'generation-selected', got %q\n", selected.Type)
+	}
+	if selected.Payload.Message.ID != 2 || !selected.Payload.Message.SelectedGeneration {
+		t.Errorf("Bad selected generation - %s\n", response)
+	}
+}
+
+func TestCancelCompletion(t *testing.T) {
+	client, db, teardown := setupWebSocketTestWithCompletion(t, func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		onDelta("part")
+		<-ctx.Done()
+		return &ai_clients.AIResponse{
+			Message:     ai_clients.Message{Role: core.AssistantRole, Content: "part"},
+			Interrupted: true,
+		}, nil
+	})
+	defer teardown()
+
+	if err := db_helpers.NewSeeder(db, context.TODO()).SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "stream": true,
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	readMessagesByType(t, client, 2) // user message and delta
+
+	err = client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "a8a3a0b5-0c8f-4ac5-9c2e-4b6f2d0f3f11",
+  "type": "cancel-completion",
+  "payload": {
+    "request_id": "717dc403-63ab-48e6-94e8-21b3110da18c"
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	_, response, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	res := messages.ServerMessageCreated{}
+	if err := json.Unmarshal(response, &res); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if res.Type != "message-created" || res.ID != sharedID {
+		t.Fatalf("Expected message created for cancelled request, got %s\n", response)
+	}
+	if !res.Payload.Message.Interrupted || res.Payload.Message.Content != "part" {
+		t.Errorf("Expected partial answer to be saved as interrupted, got %+v\n", res.Payload.Message)
+	}
+
+	err = client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "a8a3a0b5-0c8f-4ac5-9c2e-4b6f2d0f3f11",
+  "type": "cancel-completion",
+  "payload": {
+    "request_id": "717dc403-63ab-48e6-94e8-21b3110da18c"
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	_, response, err = client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	serverError := messages.ServerError{}
+	if err := json.Unmarshal(response, &serverError); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if serverError.Type != "server-error" {
+		t.Errorf("Expected error when cancelling finished completion, got %s\n", response)
+	}
+}
+
+func TestFailedCompletionDiscardsMessage(t *testing.T) {
+	client, db, teardown := setupWebSocketTestWithCompletion(t, func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		return nil, fmt.Errorf("provider is down\n")
+	})
+	defer teardown()
+	if err := db_helpers.NewSeeder(db, context.TODO()).SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	received := readMessagesByType(t, client, 3)
+	for _, expected := range []string{"message-created", "server-error", "message-discarded"} {
+		if _, ok := received[expected]; !ok {
+			t.Errorf("Expected %q to be sent, got %v\n", expected, received)
+		}
+	}
+	discarded := messages.ServerMessageDiscarded{}
+	if err := json.Unmarshal(received["message-discarded"], &discarded); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", received["message-discarded"])
+	}
+	if discarded.ID != sharedID || discarded.Payload.ChatID != 1 {
+		t.Errorf("Expected discarded message of chat 1, got %+v\n", discarded)
+	}
+}
+
+func TestFailedTitleKeepsCompletion(t *testing.T) {
+	c, db := test_helpers.CreateCore()
+	c.GetConfig().TitleModel = "openai/title-model"
+	client, db, teardown := setupWebSocketTestWithCore(t, c, db, func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		if strings.HasSuffix(parameters.Model, "title-model") {
+			return nil, fmt.Errorf("provider is down\n")
+		}
+		return test_helpers.MockCompletion(ctx, messages, parameters, providers, onDelta)
+	})
+	defer teardown()
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": -1,
+    "content": "create message",
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	received := readMessagesByType(t, client, 3)
+	for _, expected := range []string{"chat-created", "message-created", "server-error"} {
+		if _, ok := received[expected]; !ok {
+			t.Errorf("Expected %q to be sent, got %v\n", expected, received)
+		}
+	}
+	serverError := messages.ServerError{}
+	if err := json.Unmarshal(received["server-error"], &serverError); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", received["server-error"])
+	}
+	if serverError.ID == sharedID {
+		t.Errorf("Expected title failure not to reuse completion id\n")
+	}
+	if !strings.HasPrefix(serverError.Payload, "failed to title chat 1: ") {
+		t.Errorf("Expected title failure, got %q\n", serverError.Payload)
+	}
+}
+
+func TestShutdownInterruptsCompletion(t *testing.T) {
+	type testCase struct {
+		name    string
+		request string
+		// messages sent before provider answers
+		pending int
+		// generation_of is null for regular messages
+		generation bool
+	}
+	table := []testCase{
+		{
+			name: "should save streamed part of completion",
+			request: `
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "stream": true,
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`,
+			pending: 2, // user message and delta
+		},
+		{
+			name: "should save streamed part of regeneration",
+			request: `
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "regenerate-message",
+  "payload": {
+    "message_id": 2,
+    "stream": true
+  }
+}
+`,
+			pending:    1,
+			generation: true,
+		},
+	}
+
+	for _, test := range table {
+		c, db := test_helpers.CreateCore()
+		shutdown, cancel := context.WithCancel(context.Background())
+		c.GetConfig().ShutdownContext = shutdown
+		if err := db_helpers.NewSeeder(db, context.TODO()).SeedChatsN(1); err != nil {
+			t.Fatal(err)
+		}
+		seed := core.NewCreateCompletionCommand(
+			c,
+			1,
+			core.UserRole,
+			"first",
+			"",
+			&ai_clients.Parameters{Model: "openai/gpt-4o-mini"},
+			test_helpers.MockCompletion,
+		)
+		if err := seed.Execute(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		client, db, teardown := setupWebSocketTestWithCore(t, c, db, func(
+			ctx context.Context,
+			messages []*ai_clients.Message,
+			parameters *ai_clients.Parameters,
+			providers *settings.Providers,
+			onDelta ai_clients.OnDelta,
+		) (*ai_clients.AIResponse, error) {
+			onDelta("part")
+			<-ctx.Done()
+			return &ai_clients.AIResponse{
+				Message:     ai_clients.Message{Role: core.AssistantRole, Content: "part"},
+				Interrupted: true,
+			}, nil
+		})
+
+		if err := client.WriteMessage(websocket.TextMessage, []byte(test.request)); err != nil {
+			t.Fatalf("%q - could not write message to WebSocket server: %v", test.name, err)
+		}
+		readMessagesByType(t, client, test.pending)
+		cancel()
+		if !messages.WaitCompletions(time.Second) {
+			t.Errorf("%q - expected completion to finish after shutdown\n", test.name)
+		}
+
+		var interrupted, generation bool
+		if err := db.QueryRow(
+			`SELECT interrupted, generation_of IS NOT NULL FROM messages WHERE content = 'part'`,
+		).Scan(&interrupted, &generation); err != nil {
+			t.Errorf("%q - expected streamed part to be saved, err: %s\n", test.name, err)
+		} else if !interrupted || generation != test.generation {
+			t.Errorf(
+				"%q - bad saved part, interrupted: %t, generation: %t\n",
+				test.name,
+				interrupted,
+				generation,
+			)
+		}
+		teardown()
+	}
+}
+
+func TestCreateMessageWithAttachments(t *testing.T) {
+	var sent []*ai_clients.Attachment
+	client, db, teardown := setupWebSocketTestWithCompletion(t, func(
+		ctx context.Context,
+		messages []*ai_clients.Message,
+		parameters *ai_clients.Parameters,
+		providers *settings.Providers,
+		onDelta ai_clients.OnDelta,
+	) (*ai_clients.AIResponse, error) {
+		sent = messages[len(messages)-1].Attachments
+		return &ai_clients.AIResponse{
+			Message: ai_clients.Message{Role: core.AssistantRole, Content: "seen"},
+		}, nil
+	})
+	defer teardown()
+
+	if err := db_helpers.NewSeeder(db, context.TODO()).SeedChatsN(1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(
+		"INSERT INTO attachments (name, media_type, size, data) VALUES ('notes.txt', 'text/plain', 5, 'notes')",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	err := client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "attachments": [1],
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+
+	for _, expected := range []string{"create message", "seen"} {
+		_, response, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not read message from WebSocket server: %v", err)
+		}
+		res := messages.ServerMessageCreated{}
+		if err := json.Unmarshal(response, &res); err != nil {
+			t.Fatalf("Failed to decode server response message - %s\n", response)
+		}
+		if res.Type != "message-created" || res.Payload.Message.Content != expected {
+			t.Fatalf("Expected %q to be created, got %s\n", expected, response)
+		}
+		if expected == "create message" &&
+			(len(res.Payload.Message.Attachments) != 1 ||
+				res.Payload.Message.Attachments[0].Name != "notes.txt") {
+			t.Errorf("Expected user message to carry attachment, got %s\n", response)
+		}
+	}
+	if len(sent) != 1 || string(sent[0].Data) != "notes" {
+		t.Errorf("Expected attachment to be sent to completion, got %+v\n", sent)
+	}
+
+	var messageID int64
+	if err := db.QueryRow("SELECT message_id FROM attachments WHERE id = 1").Scan(&messageID); err != nil {
+		t.Fatalf("Attachment was not linked to message: %s\n", err)
+	}
+	if messageID != 1 {
+		t.Errorf("Expected attachment to be linked to user message 1, got %d\n", messageID)
+	}
+
+	err = client.WriteMessage(
+		websocket.TextMessage,
+		[]byte(`
+{
+  "id": "3f1c2a8e-5b7d-4e9f-a1c3-6d8e0f2b4a6c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "send again",
+    "attachments": [1],
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`),
+	)
+	if err != nil {
+		t.Fatalf("could not write message to WebSocket server: %v", err)
+	}
+	_, response, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("could not read message from WebSocket server: %v", err)
+	}
+	res := messages.ServerError{}
+	if err := json.Unmarshal(response, &res); err != nil {
+		t.Fatalf("Failed to decode server response message - %s\n", response)
+	}
+	if res.Type != "server-error" {
+		t.Errorf("Expected sent attachment to be refused, got %s\n", response)
+	}
+	client.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, response, err := client.ReadMessage(); err == nil {
+		t.Errorf("Expected refused message not to be shown, got %s\n", response)
+	}
+}
+
+func TestCreateMessageWithDefaultTemplate(t *testing.T) {
+	type testCase struct {
+		name              string
+		noDefaultTemplate bool
+		expected          string
+	}
+	table := []testCase{
+		{
+			name:     "should apply default template when none is given",
+			expected: "[create message]",
+		},
+		{
+			name:              "should skip default template on demand",
+			noDefaultTemplate: true,
+			expected:          "create message",
+		},
+	}
+	for _, test := range table {
+		c, db := test_helpers.CreateCore()
+		c.GetConfig().DefaultTemplate = "default"
+		client, db, teardown := setupWebSocketTestWithCore(t, c, db, test_helpers.MockCompletion)
+		if err := db_helpers.NewSeeder(db, context.TODO()).SeedChatsN(1); err != nil {
+			t.Fatal(err)
+		}
+		if err := db_helpers.CreateTemplate(db, context.TODO(), &models.Template{
+			Name:    "default",
+			Content: `--{{define "default"}}[--{{.}}]--{{end}}`,
+		}); err != nil {
+			t.Fatal(err)
+		}
+		err := client.WriteMessage(
+			websocket.TextMessage,
+			[]byte(fmt.Sprintf(`
+{
+  "id": "717dc403-63ab-48e6-94e8-21b3110da18c",
+  "type": "create-completion",
+  "payload": {
+    "chat_id": 1,
+    "content": "create message",
+    "no_default_template": %t,
+    "parameters": {
+      "model": "gpt-4o-mini"
+    }
+  }
+}
+`, test.noDefaultTemplate)),
+		)
+		if err != nil {
+			t.Fatalf("could not write message to WebSocket server: %v", err)
+		}
+		// user message and completion
+		for range 2 {
+			if _, _, err := client.ReadMessage(); err != nil {
+				t.Fatalf("could not read message from WebSocket server: %v", err)
+			}
+		}
+		stored, err := db_helpers.GetMessagesByChatID(db, context.Background(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(stored) != 2 || stored[0].Content != test.expected {
+			t.Errorf("%q - bad stored messages %+v\n", test.name, stored)
+		}
+		teardown()
+	}
+}
@@ -430,3 +430,135 @@ func TestDeleteTemplate(t *testing.T) {
 		t.Fatalf("Failed to delete template - %+v\n", dbTemplate)
 	}
 }
+
+func TestTemplateVersions(t *testing.T) {
+	client, db, teardown := setupWebSocketTest(t)
+	defer teardown()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if _, err := seeder.SeedTemplatesN(1); err != nil {
+		t.Fatalf("seeding templates failed - %q\n", err)
+	}
+	send := func(messageType string, payload string) []byte {
+		if err := client.WriteMessage(
+			websocket.TextMessage,
+			[]byte(fmt.Sprintf(
+				`{"id": %q, "type": %q, "payload": %s}`,
+				sharedID,
+				messageType,
+				payload,
+			)),
+		); err != nil {
+			t.Fatalf("Failed to send %q message from client, error: %v\n", messageType, err)
+		}
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read %q response - %q\n", messageType, err)
+		}
+		return data
+	}
+
+	editContent := `--{{define "1"}}edited--{{end}}`
+	send("request-edit-template", fmt.Sprintf(`{"name": "1", "content": %q}`, editContent))
+
+	history := new(messages.ServerTemplateHistory)
+	if err := json.Unmarshal(
+		send("request-template-history", `{"name": "1"}`),
+		history,
+	); err != nil {
+		t.Fatalf("failed to unmarshal history - %s\n", err)
+	}
+	if history.Type != "template-history" ||
+		len(history.Payload.Versions) != 2 ||
+		history.Payload.Versions[0].Version != 2 ||
+		history.Payload.Versions[0].Content != editContent {
+		t.Fatalf("bad template history - %+v\n", history)
+	}
+
+	diff := new(messages.ServerTemplateDiff)
+	if err := json.Unmarshal(
+		send("request-template-diff", `{"name": "1", "from": 1, "to": 2}`),
+		diff,
+	); err != nil {
+		t.Fatalf("failed to unmarshal diff - %s\n", err)
+	}
+	expectedDiff := `--- 1 v1
++++ 1 v2
+@@ -1 +1 @@
+---{{define "1"}}1--{{end}}
++--{{define "1"}}edited--{{end}}
+`
+	if diff.Type != "template-diff" || diff.Payload.Diff != expectedDiff {
+		t.Fatalf("bad template diff\nexpected:\n%s\nactual:\n%s\n", expectedDiff, diff.Payload.Diff)
+	}
+
+	changed := new(messages.ServerTemplateChanged)
+	if err := json.Unmarshal(
+		send("rollback-template", `{"name": "1", "version": 1}`),
+		changed,
+	); err != nil {
+		t.Fatalf("failed to unmarshal rollback - %s\n", err)
+	}
+	if changed.Type != "template-changed" ||
+		changed.Payload.Template.Content != `--{{define "1"}}1--{{end}}` {
+		t.Fatalf("bad rollback result - %+v\n", changed)
+	}
+
+	serverError := new(messages.ServerError)
+	if err := json.Unmarshal(
+		send("rollback-template", `{"name": "1", "version": 9}`),
+		serverError,
+	); err != nil {
+		t.Fatalf("failed to unmarshal error - %s\n", err)
+	}
+	if serverError.Type != "server-error" {
+		t.Fatalf("expected rollback to missing version to error - %+v\n", serverError)
+	}
+}
+
+func TestDeleteUsedTemplate(t *testing.T) {
+	client, db, teardown := setupWebSocketTest(t)
+	defer teardown()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	send := func(messageType string, payload string) []byte {
+		if err := client.WriteMessage(
+			websocket.TextMessage,
+			[]byte(fmt.Sprintf(
+				`{"id": %q, "type": %q, "payload": %s}`,
+				sharedID,
+				messageType,
+				payload,
+			)),
+		); err != nil {
+			t.Fatalf("Failed to send %q message from client, error: %v\n", messageType, err)
+		}
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("Failed to read %q response - %q\n", messageType, err)
+		}
+		return data
+	}
+
+	if _, err := seeder.SeedTemplatesN(1); err != nil {
+		t.Fatalf("seeding templates failed - %q\n", err)
+	}
+	send("request-edit-template", `{"name": "1", "content": "--{{define \"user\"}}--{{template \"1\"}}--{{end}}", "clone": true}`)
+
+	serverError := new(messages.ServerError)
+	if err := json.Unmarshal(send("delete-template", `{"name": "1"}`), serverError); err != nil {
+		t.Fatalf("failed to unmarshal error - %s\n", err)
+	}
+	if serverError.Type != "server-error" {
+		t.Fatalf("expected delete of used template to error - %+v\n", serverError)
+	}
+
+	templateDeleted := new(messages.ServerTemplateDeleted)
+	if err := json.Unmarshal(
+		send("delete-template", `{"name": "1", "force": true}`),
+		templateDeleted,
+	); err != nil {
+		t.Fatalf("failed to unmarshal deletion - %s\n", err)
+	}
+	if templateDeleted.Type != "template-deleted" || templateDeleted.Payload.Name != "1" {
+		t.Fatalf("expected forced delete to succeed - %+v\n", templateDeleted)
+	}
+}
@@ -2,24 +2,44 @@ package v1
 
 import (
 	"database/sql"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/gorilla/websocket"
+	"github.com/k10wl/hermes/internal/ai_clients"
+	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/test_helpers"
 )
 
 const sharedID = "717dc403-63ab-48e6-94e8-21b3110da18c"
 
 func setupWebSocketTest(t *testing.T) (*websocket.Conn, *sql.DB, func()) {
+	return setupWebSocketTestWithCompletion(t, test_helpers.MockCompletion)
+}
+
+func setupWebSocketTestWithCompletion(
+	t *testing.T,
+	completionFn ai_clients.CompletionFn,
+) (*websocket.Conn, *sql.DB, func()) {
 	c, db := test_helpers.CreateCore()
+	return setupWebSocketTestWithCore(t, c, db, completionFn)
+}
+
+// core is configured by test before connection is made
+func setupWebSocketTestWithCore(
+	t *testing.T,
+	c *core.Core,
+	db *sql.DB,
+	completionFn ai_clients.CompletionFn,
+) (*websocket.Conn, *sql.DB, func()) {
 	hub := NewHub()
 	go hub.Run()
 	server := httptest.NewServer(
 		http.HandlerFunc(
-			handleServeWebSockets(c, hub, test_helpers.MockCompletion),
+			handleServeWebSockets(c, hub, completionFn),
 		),
 	)
 	client, cleanupClient, err := test_helpers.CreateWebsocketConnection(
@@ -35,3 +55,27 @@ func setupWebSocketTest(t *testing.T) (*websocket.Conn, *sql.DB, func()) {
 		db.Close()
 	}
 }
+
+// reads n messages and groups them by type, broadcasted and single client
+// messages travel through different channels and may arrive in any order
+func readMessagesByType(
+	t *testing.T,
+	client *websocket.Conn,
+	n int,
+) map[string][]byte {
+	res := map[string][]byte{}
+	for range n {
+		_, data, err := client.ReadMessage()
+		if err != nil {
+			t.Fatalf("could not read message from WebSocket server: %v", err)
+		}
+		var typed struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &typed); err != nil {
+			t.Fatalf("failed to decode message type - %s\n", data)
+		}
+		res[typed.Type] = data
+	}
+	return res
+}
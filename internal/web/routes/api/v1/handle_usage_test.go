@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/k10wl/hermes/internal/models"
+	"github.com/k10wl/hermes/internal/test_helpers"
+	"github.com/k10wl/hermes/internal/test_helpers/db_helpers"
+)
+
+func TestHandleUsage(t *testing.T) {
+	type testCase struct {
+		name           string
+		query          string
+		expectedStatus int
+		expected       []models.UsageReport
+	}
+
+	coreInstance, db := test_helpers.CreateCore()
+	seeder := db_helpers.NewSeeder(db, context.Background())
+	if err := seeder.SeedChatsN(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.SeedMessagesN(1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := seeder.SeedMessagesN(1, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db_helpers.CreateMessageUsage(db, context.Background(), []*models.Usage{
+		{MessageID: 1, Model: "openai/gpt-4o", InputTokens: 10, OutputTokens: 5, Cost: 0.25},
+		{MessageID: 2, Model: "openai/gpt-4o", InputTokens: 20, OutputTokens: 10, Cost: 0.5},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(handleUsage(coreInstance))
+	defer srv.Close()
+
+	table := []testCase{
+		{
+			name:           "should aggregate by model",
+			query:          "?by=model",
+			expectedStatus: http.StatusOK,
+			expected: []models.UsageReport{
+				{Key: "openai/gpt-4o", Messages: 2, InputTokens: 30, OutputTokens: 15, Cost: 0.75},
+			},
+		},
+		{
+			name:           "should aggregate by chat",
+			query:          "?by=chat",
+			expectedStatus: http.StatusOK,
+			expected: []models.UsageReport{
+				{Key: "2", Name: "2", Messages: 1, InputTokens: 20, OutputTokens: 10, Cost: 0.5},
+				{Key: "1", Name: "1", Messages: 1, InputTokens: 10, OutputTokens: 5, Cost: 0.25},
+			},
+		},
+		{
+			name:           "should reject unknown grouping",
+			query:          "?by=week",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range table {
+		res, err := http.Get(fmt.Sprintf("%s/api/v1/usage%s", srv.URL, test.query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		if res.StatusCode != test.expectedStatus {
+			t.Errorf(
+				"%q - bad status\nexpected: %d\nactual:   %d\n",
+				test.name,
+				test.expectedStatus,
+				res.StatusCode,
+			)
+			continue
+		}
+		if test.expectedStatus != http.StatusOK {
+			continue
+		}
+		actual := []*models.UsageReport{}
+		if err := json.NewDecoder(res.Body).Decode(&actual); err != nil {
+			t.Errorf("%q - failed to decode response - %s\n", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(test.expected, test_helpers.UnpointerSlice(actual)) {
+			t.Errorf(
+				"%q - bad output\nexpected: %+v\nactual:   %+v\n",
+				test.name,
+				test.expected,
+				test_helpers.UnpointerSlice(actual),
+			)
+		}
+	}
+}
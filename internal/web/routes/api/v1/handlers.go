@@ -1,13 +1,19 @@
 package v1
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/k10wl/hermes/internal/core"
+	"github.com/k10wl/hermes/internal/models"
 	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
 )
 
@@ -47,6 +53,200 @@ func handleChats(c *core.Core) http.HandlerFunc {
 	}
 }
 
+var chatExportContentTypes = map[string]string{
+	core.ChatExportMarkdown: "text/markdown; charset=utf-8",
+	core.ChatExportJSON:     "application/json",
+	core.ChatExportJSONL:    "application/x-ndjson",
+}
+
+func handleChatExport(c *core.Core) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "bad chat id %q\n", r.PathValue("id"))
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = core.ChatExportJSON
+		}
+		contentType, ok := chatExportContentTypes[format]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(
+				w,
+				"unknown export format %q - use %s\n",
+				format,
+				strings.Join(core.ChatExportFormats, ", "),
+			)
+			return
+		}
+		query := core.NewExportChatQuery(c, chatID)
+		if err := query.Execute(r.Context()); err != nil {
+			if errors.Is(err, models.ErrNotFound) {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		buf := &bytes.Buffer{}
+		if err := core.WriteChatExport(buf, query.Result, format); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set(
+			"Content-Disposition",
+			fmt.Sprintf(`attachment; filename="chat-%d.%s"`, chatID, format),
+		)
+		w.WriteHeader(http.StatusOK)
+		w.Write(buf.Bytes())
+	}
+}
+
+// multipart "file" field, returns attachment that is linked on message send
+func handleCreateAttachment(c *core.Core) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// room for multipart headers on top of file itself
+		r.Body = http.MaxBytesReader(w, r.Body, core.MaxAttachmentSize+1<<20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "failed to read file: %s\n", err)
+			return
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "failed to read file: %s\n", err)
+			return
+		}
+		cmd := core.NewCreateAttachmentCommand(c, header.Filename, data)
+		if err := cmd.Execute(r.Context()); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		bytes, err := json.Marshal(cmd.Result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(bytes)
+	}
+}
+
+func handleAttachment(c *core.Core) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "bad attachment id %q\n", r.PathValue("id"))
+			return
+		}
+		query := core.NewGetAttachmentQuery(c, id)
+		if err := query.Execute(r.Context()); err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", query.Result.MediaType)
+		w.Header().Set(
+			"Content-Disposition",
+			mime.FormatMediaType("inline", map[string]string{"filename": query.Result.Name}),
+		)
+		// uploaded text must not run as page
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("Content-Security-Policy", "sandbox")
+		w.WriteHeader(http.StatusOK)
+		w.Write(query.Result.Data)
+	}
+}
+
+func handleUsage(c *core.Core) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		by := r.URL.Query().Get("by")
+		if by == "" {
+			by = models.UsageByDay
+		}
+		query := core.NewGetUsageReportQuery(c, by)
+		if err := query.Execute(r.Context()); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		bytes, err := json.Marshal(query.Result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+}
+
+func handlePersonas(c *core.Core) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := core.NewGetPersonasQuery(c)
+		if err := query.Execute(r.Context()); err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		bytes, err := json.Marshal(query.Result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+}
+
+// snippets are html escaped, matches are wrapped into <mark>
+func handleSearch(c *core.Core) http.HandlerFunc {
+	const open, close = "\x02", "\x03"
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		limit, err := strconv.Atoi(params.Get("limit"))
+		if err != nil {
+			limit = 20
+		}
+		query := core.NewSearchMessagesQuery(c, params.Get("q"), int64(limit))
+		query.Highlight(open, close)
+		if err := query.Execute(r.Context()); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		marker := strings.NewReplacer(open, "<mark>", close, "</mark>")
+		for _, result := range query.Result {
+			result.Snippet = marker.Replace(html.EscapeString(result.Snippet))
+		}
+		bytes, err := json.Marshal(query.Result)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprintf(w, "%s\n", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(bytes)
+	}
+}
+
 func handleCheckHeath() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
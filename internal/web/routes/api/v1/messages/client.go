@@ -1,10 +1,10 @@
 package messages
 
 import (
-	"context"
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/models"
@@ -55,6 +55,18 @@ func ReadMessage(data []byte) (ClientEmittedMessage, error) {
 		msg = &ClientEditTemplate{}
 	case "delete-template":
 		msg = &ClientDeleteTemplate{}
+	case "request-template-history":
+		msg = &ClientTemplateHistory{}
+	case "request-template-diff":
+		msg = &ClientTemplateDiff{}
+	case "rollback-template":
+		msg = &ClientRollbackTemplate{}
+	case "regenerate-message":
+		msg = &ClientRegenerateMessage{}
+	case "select-generation":
+		msg = &ClientSelectGeneration{}
+	case "cancel-completion":
+		msg = &ClientCancelCompletion{}
 	}
 	if msg == nil {
 		return nil, fmt.Errorf("received unknown message type\n")
@@ -84,7 +96,7 @@ func (message *ClientRequestReadChat) Process(
 		Core:   c,
 		ChatID: message.Payload,
 	}
-	err := cmd.Execute(context.TODO())
+	err := cmd.Execute(c.GetConfig().ShutdownContext)
 	if err != nil {
 		return BroadcastServerEmittedMessage(
 			comms.Single(),
@@ -122,6 +134,18 @@ type CreateCompletionMessagePayload struct {
 	Content    string                `json:"content"    validate:"required"`
 	Template   string                `json:"template"`
 	Parameters ai_clients.Parameters `json:"parameters" validate:"required"`
+	Stream     bool                  `json:"stream"`
+	// empty template falls back to default_template of config profile
+	// unless this is set
+	NoDefaultTemplate bool `json:"no_default_template"`
+	// overrides history strategy stored in chat
+	History *models.History `json:"history"`
+	// name of persona attached to chat
+	Persona string `json:"persona"`
+	// template variables, available as `var "key"` while `.` remains content
+	Variables map[string]any `json:"variables"`
+	// ids of uploaded attachments, sent together with content
+	Attachments []int64 `json:"attachments"`
 }
 
 type ClientCreateCompletion struct {
@@ -161,7 +185,10 @@ func (message *ClientCreateCompletion) processNewChat(
 		Role:    "user",
 		Content: message.Payload.Content,
 	}, "")
-	if err := cmd.Execute(context.TODO()); err != nil {
+	cmd.WithParameters(&message.Payload.Parameters)
+	cmd.WithPersona(message.Payload.Persona)
+	cmd.WithAttachments(message.Payload.Attachments)
+	if err := cmd.Execute(c.GetConfig().ShutdownContext); err != nil {
 		return err
 	}
 	if err := BroadcastServerEmittedMessage(
@@ -170,13 +197,46 @@ func (message *ClientCreateCompletion) processNewChat(
 	); err != nil {
 		return err
 	}
-	return message.createCompletion(
+	completed, err := message.createCompletion(
 		comms,
 		c,
 		completionFn,
 		cmd.Result.Chat.ID,
 		false,
 	)
+	if err != nil || !completed {
+		return err
+	}
+	if c.GetConfig().TitleModel != "" {
+		c.Background(func() {
+			message.titleChat(comms, c, completionFn, cmd.Result.Chat.ID)
+		})
+	}
+	return nil
+}
+
+func (message *ClientCreateCompletion) titleChat(
+	comms CommunicationChannel,
+	c *core.Core,
+	completionFn ai_clients.CompletionFn,
+	chatID int64,
+) {
+	cmd := core.NewGenerateChatTitleCommand(c, chatID, completionFn)
+	if err := cmd.Execute(c.GetConfig().ShutdownContext); err != nil {
+		// completion already succeeded, so failure is not tied to its id
+		BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerError(
+				uuid.New().String(),
+				fmt.Sprintf("failed to title chat %d: %s", chatID, err),
+			),
+		)
+		return
+	}
+	BroadcastServerEmittedMessage(
+		comms.All(),
+		NewServerChatUpdated(message.ID, cmd.Result),
+	)
 }
 
 func (message *ClientCreateCompletion) processExistingChat(
@@ -184,27 +244,46 @@ func (message *ClientCreateCompletion) processExistingChat(
 	c *core.Core,
 	completionFn ai_clients.CompletionFn,
 ) error {
+	// message is shown to everyone only after its attachments are known to be
+	// sendable
+	attachments := core.NewGetPendingAttachmentsQuery(c, message.Payload.Attachments)
+	if err := attachments.Execute(c.GetConfig().ShutdownContext); err != nil {
+		return BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerCompletionError(message.ID, err),
+		)
+	}
 	if err := BroadcastServerEmittedMessage(
 		comms.All(),
 		NewServerMessageCreated(
 			message.ID,
 			message.Payload.ChatID,
 			&models.Message{
-				ID:      time.Now().UnixMilli(),
-				Content: message.Payload.Content,
-				Role:    "user",
+				ID:                 time.Now().UnixMilli(),
+				Content:            message.Payload.Content,
+				Role:               "user",
+				SelectedGeneration: true,
+				Attachments:        attachments.Result,
 			},
 		),
 	); err != nil {
 		return err
 	}
-	return message.createCompletion(
+	completed, err := message.createCompletion(
 		comms,
 		c,
 		completionFn,
 		message.Payload.ChatID,
 		true,
 	)
+	if err != nil || completed {
+		return err
+	}
+	// user message is stored only together with its answer
+	return BroadcastServerEmittedMessage(
+		comms.All(),
+		NewServerMessageDiscarded(message.ID, message.Payload.ChatID),
+	)
 }
 
 func (message *ClientCreateCompletion) createCompletion(
@@ -213,18 +292,133 @@ func (message *ClientCreateCompletion) createCompletion(
 	completionFn ai_clients.CompletionFn,
 	chatID int64,
 	skipPersistingUserMessage bool,
-) error {
+) (bool, error) {
 	cmd := core.NewCreateCompletionCommand(
 		c,
 		chatID,
 		"user",
 		message.Payload.Content,
-		message.Payload.Template,
+		message.template(c),
 		&message.Payload.Parameters,
 		completionFn,
 	)
 	cmd.ShouldPersistUserMessage(skipPersistingUserMessage)
-	if err := cmd.Execute(context.TODO()); err != nil {
+	cmd.WithHistory(message.Payload.History)
+	cmd.WithVariables(message.Payload.Variables)
+	if skipPersistingUserMessage {
+		// new chats receive persona and attachments upon creation
+		cmd.WithPersona(message.Payload.Persona)
+		cmd.WithAttachments(message.Payload.Attachments)
+	} else {
+		cmd.DiscardChatOnError(true)
+	}
+	if message.Payload.Stream {
+		cmd.Stream(func(delta string) {
+			BroadcastServerEmittedMessage(
+				comms.All(),
+				NewServerMessageDelta(message.ID, chatID, delta),
+			)
+		})
+	}
+	ctx, done := runningCompletions.start(c.GetConfig().ShutdownContext, message.ID)
+	err := cmd.Execute(ctx)
+	// finished completion can not be cancelled once its answer is sent
+	done()
+	if err != nil {
+		return false, BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerCompletionError(message.ID, err),
+		)
+	}
+	return true, BroadcastServerEmittedMessage(
+		comms.All(),
+		NewServerMessageCreated(message.ID, chatID, cmd.Result),
+	)
+}
+
+func (message *ClientCreateCompletion) template(c *core.Core) string {
+	if message.Payload.Template != "" || message.Payload.NoDefaultTemplate {
+		return message.Payload.Template
+	}
+	return c.GetConfig().DefaultTemplate
+}
+
+type RegenerateMessagePayload struct {
+	MessageID  int64                 `json:"message_id" validate:"required"`
+	Parameters ai_clients.Parameters `json:"parameters"`
+	Stream     bool                  `json:"stream"`
+	History    *models.History       `json:"history"`
+}
+
+type ClientRegenerateMessage struct {
+	ID      string                   `json:"id,required"      validate:"required,uuid4"`
+	Type    string                   `json:"type,required"`
+	Payload RegenerateMessagePayload `json:"payload,required"`
+}
+
+func (message *ClientRegenerateMessage) GetID() string { return message.ID }
+
+func (message *ClientRegenerateMessage) Process(
+	comms CommunicationChannel,
+	c *core.Core,
+	completionFn ai_clients.CompletionFn,
+) error {
+	cmd := core.NewRegenerateMessageCommand(
+		c,
+		message.Payload.MessageID,
+		&message.Payload.Parameters,
+		completionFn,
+	)
+	cmd.WithHistory(message.Payload.History)
+	if message.Payload.Stream {
+		var chatID int64
+		if original, err := c.GetDB().GetMessageByID(
+			c.GetConfig().ShutdownContext,
+			message.Payload.MessageID,
+		); err == nil {
+			chatID = original.ChatID
+		}
+		cmd.Stream(func(delta string) {
+			BroadcastServerEmittedMessage(
+				comms.All(),
+				NewServerMessageDelta(message.ID, chatID, delta),
+			)
+		})
+	}
+	ctx, done := runningCompletions.start(c.GetConfig().ShutdownContext, message.ID)
+	err := cmd.Execute(ctx)
+	done()
+	if err != nil {
+		return BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerCompletionError(message.ID, err),
+		)
+	}
+	return BroadcastServerEmittedMessage(
+		comms.All(),
+		NewServerGenerationCreated(message.ID, cmd.Result.ChatID, cmd.Result),
+	)
+}
+
+type SelectGenerationPayload struct {
+	MessageID int64 `json:"message_id" validate:"required"`
+}
+
+type ClientSelectGeneration struct {
+	ID      string                  `json:"id,required"      validate:"required,uuid4"`
+	Type    string                  `json:"type,required"`
+	Payload SelectGenerationPayload `json:"payload,required"`
+}
+
+func (message *ClientSelectGeneration) GetID() string { return message.ID }
+
+func (message *ClientSelectGeneration) Process(
+	comms CommunicationChannel,
+	c *core.Core,
+	_ ai_clients.CompletionFn,
+) error {
+	cmd := core.NewSelectGenerationCommand(c, message.Payload.MessageID)
+	if err := cmd.Execute(c.GetConfig().ShutdownContext); err != nil {
 		return BroadcastServerEmittedMessage(comms.Single(), NewServerError(
 			message.ID,
 			err.Error(),
@@ -232,6 +426,35 @@ func (message *ClientCreateCompletion) createCompletion(
 	}
 	return BroadcastServerEmittedMessage(
 		comms.All(),
-		NewServerMessageCreated(message.ID, chatID, cmd.Result),
+		NewServerGenerationSelected(message.ID, cmd.Result.ChatID, cmd.Result),
 	)
 }
+
+type CancelCompletionPayload struct {
+	// id of create-completion or regenerate-message message
+	RequestID string `json:"request_id" validate:"required,uuid4"`
+}
+
+type ClientCancelCompletion struct {
+	ID      string                  `json:"id,required"      validate:"required,uuid4"`
+	Type    string                  `json:"type,required"`
+	Payload CancelCompletionPayload `json:"payload,required"`
+}
+
+func (message *ClientCancelCompletion) GetID() string { return message.ID }
+
+// cancelled completion answers to its own request, streamed part is saved as
+// interrupted message, otherwise error with "cancelled" code is sent
+func (message *ClientCancelCompletion) Process(
+	comms CommunicationChannel,
+	_ *core.Core,
+	_ ai_clients.CompletionFn,
+) error {
+	if !runningCompletions.cancel(message.Payload.RequestID) {
+		return BroadcastServerEmittedMessage(comms.Single(), NewServerError(
+			message.ID,
+			fmt.Sprintf("completion %q is not running\n", message.Payload.RequestID),
+		))
+	}
+	return nil
+}
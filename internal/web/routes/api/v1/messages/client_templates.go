@@ -130,7 +130,8 @@ func (message *ClientEditTemplate) GetID() string {
 }
 
 type ClientDeleteTemplatePayload struct {
-	Name string `json:"name,required"    validate:"required"`
+	Name  string `json:"name,required"    validate:"required"`
+	Force bool   `json:"force"`
 }
 
 type ClientDeleteTemplate struct {
@@ -148,6 +149,7 @@ func (message *ClientDeleteTemplate) Process(
 		c,
 		message.Payload.Name,
 	)
+	cmd.WithForce(message.Payload.Force)
 	if err := cmd.Execute(context.TODO()); err != nil {
 		return BroadcastServerEmittedMessage(comms.Single(), NewServerError(
 			message.ID,
@@ -163,3 +165,111 @@ func (message *ClientDeleteTemplate) Process(
 func (message *ClientDeleteTemplate) GetID() string {
 	return message.ID
 }
+
+type ClientTemplateHistoryPayload struct {
+	Name string `json:"name,required" validate:"required"`
+}
+
+type ClientTemplateHistory struct {
+	ID      string                       `json:"id,required"      validate:"required,uuid4"`
+	Type    string                       `json:"type,required"`
+	Payload ClientTemplateHistoryPayload `json:"payload,required"`
+}
+
+func (message *ClientTemplateHistory) Process(
+	comms CommunicationChannel,
+	c *core.Core,
+	_ ai_clients.CompletionFn,
+) error {
+	query := core.NewGetTemplateHistoryQuery(c, message.Payload.Name)
+	if err := query.Execute(context.Background()); err != nil {
+		return BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerError(message.ID, err.Error()),
+		)
+	}
+	return BroadcastServerEmittedMessage(
+		comms.Single(),
+		NewServerTemplateHistory(message.ID, message.Payload.Name, query.Result),
+	)
+}
+
+func (message *ClientTemplateHistory) GetID() string { return message.ID }
+
+type ClientTemplateDiffPayload struct {
+	Name string `json:"name,required" validate:"required"`
+	From int64  `json:"from,required" validate:"required"`
+	To   int64  `json:"to,required"   validate:"required"`
+}
+
+type ClientTemplateDiff struct {
+	ID      string                    `json:"id,required"      validate:"required,uuid4"`
+	Type    string                    `json:"type,required"`
+	Payload ClientTemplateDiffPayload `json:"payload,required"`
+}
+
+func (message *ClientTemplateDiff) Process(
+	comms CommunicationChannel,
+	c *core.Core,
+	_ ai_clients.CompletionFn,
+) error {
+	query := core.NewDiffTemplateQuery(
+		c,
+		message.Payload.Name,
+		message.Payload.From,
+		message.Payload.To,
+	)
+	if err := query.Execute(context.Background()); err != nil {
+		return BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerError(message.ID, err.Error()),
+		)
+	}
+	return BroadcastServerEmittedMessage(
+		comms.Single(),
+		NewServerTemplateDiff(
+			message.ID,
+			message.Payload.Name,
+			message.Payload.From,
+			message.Payload.To,
+			query.Result,
+		),
+	)
+}
+
+func (message *ClientTemplateDiff) GetID() string { return message.ID }
+
+type ClientRollbackTemplatePayload struct {
+	Name    string `json:"name,required"    validate:"required"`
+	Version int64  `json:"version,required" validate:"required"`
+}
+
+type ClientRollbackTemplate struct {
+	ID      string                        `json:"id,required"      validate:"required,uuid4"`
+	Type    string                        `json:"type,required"`
+	Payload ClientRollbackTemplatePayload `json:"payload,required"`
+}
+
+func (message *ClientRollbackTemplate) Process(
+	comms CommunicationChannel,
+	c *core.Core,
+	_ ai_clients.CompletionFn,
+) error {
+	cmd := core.NewRollbackTemplateCommand(
+		c,
+		message.Payload.Name,
+		message.Payload.Version,
+	)
+	if err := cmd.Execute(context.Background()); err != nil {
+		return BroadcastServerEmittedMessage(
+			comms.Single(),
+			NewServerError(message.ID, err.Error()),
+		)
+	}
+	return BroadcastServerEmittedMessage(
+		comms.All(),
+		NewServerTemplateChanged(message.ID, cmd.Result),
+	)
+}
+
+func (message *ClientRollbackTemplate) GetID() string { return message.ID }
@@ -0,0 +1,65 @@
+package messages
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// completions that are still running, keyed by id of message that requested
+// them. Any connected client may cancel them
+var runningCompletions = &completions{cancels: map[string]context.CancelFunc{}}
+
+type completions struct {
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	running sync.WaitGroup
+}
+
+// done must be called once completion is over
+func (r *completions) start(
+	parent context.Context,
+	id string,
+) (ctx context.Context, done func()) {
+	ctx, cancel := context.WithCancel(parent)
+	r.running.Add(1)
+	r.mu.Lock()
+	r.cancels[id] = cancel
+	r.mu.Unlock()
+	return ctx, func() {
+		r.mu.Lock()
+		delete(r.cancels, id)
+		r.mu.Unlock()
+		cancel()
+		r.running.Done()
+	}
+}
+
+// waits until running completions are over, false when timeout passed first.
+// Completions started from shutdown context are cancelled with it and save
+// streamed part before they are over
+func WaitCompletions(timeout time.Duration) bool {
+	finished := make(chan struct{})
+	go func() {
+		runningCompletions.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// false when completion is not running, it may have already finished
+func (r *completions) cancel(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cancel, ok := r.cancels[id]
+	if ok {
+		cancel()
+		delete(r.cancels, id)
+	}
+	return ok
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/k10wl/hermes/internal/ai_clients"
 	"github.com/k10wl/hermes/internal/models"
 	"github.com/k10wl/hermes/internal/settings"
 	"github.com/k10wl/hermes/internal/validator"
@@ -48,12 +49,25 @@ type ServerError struct {
 	ID      string `json:"id,required"       validate:"required,uuid4"`
 	Type    string `json:"type,required"`
 	Payload string `json:"payload,omitempty"`
+	// kind of provider failure, see ai_clients.ErrorCode
+	Code string `json:"code,omitempty"`
 }
 
 func NewServerError(id string, info string) *ServerError {
 	return &ServerError{ID: id, Type: "server-error", Payload: info}
 }
 
+// failed completion, code tells auth, rate limit, context length, server
+// and timeout failures apart
+func NewServerCompletionError(id string, err error) *ServerError {
+	return &ServerError{
+		ID:      id,
+		Type:    "server-error",
+		Payload: err.Error(),
+		Code:    ai_clients.ErrorCode(err),
+	}
+}
+
 func (message ServerError) __serverMessageSignature() {}
 
 type ServerReadChatPayload struct {
@@ -118,6 +132,107 @@ func NewServerMessageCreated(
 
 func (message ServerMessageCreated) __serverMessageSignature() {}
 
+type ServerMessageDiscardedPayload struct {
+	ChatID int64 `json:"chat_id,required"`
+}
+
+// user message that was shown before its completion failed is not stored,
+// clients re-read chat to drop it
+type ServerMessageDiscarded struct {
+	ID      string                        `json:"id,required"      validate:"required,uuid4"`
+	Type    string                        `json:"type,required"`
+	Payload ServerMessageDiscardedPayload `json:"payload,required"`
+}
+
+func NewServerMessageDiscarded(id string, chatID int64) *ServerMessageDiscarded {
+	return &ServerMessageDiscarded{
+		ID:      id,
+		Type:    "message-discarded",
+		Payload: ServerMessageDiscardedPayload{ChatID: chatID},
+	}
+}
+
+func (message ServerMessageDiscarded) __serverMessageSignature() {}
+
+type ServerMessageDeltaPayload struct {
+	ChatID int64  `json:"chat_id,required"`
+	Delta  string `json:"delta,required"`
+}
+
+type ServerMessageDelta struct {
+	ID      string                    `json:"id,required"      validate:"required,uuid4"`
+	Type    string                    `json:"type,required"`
+	Payload ServerMessageDeltaPayload `json:"payload,required"`
+}
+
+func NewServerMessageDelta(
+	id string,
+	chatID int64,
+	delta string,
+) *ServerMessageDelta {
+	return &ServerMessageDelta{
+		ID:   id,
+		Type: "message-delta",
+		Payload: ServerMessageDeltaPayload{
+			ChatID: chatID,
+			Delta:  delta,
+		},
+	}
+}
+
+func (message ServerMessageDelta) __serverMessageSignature() {}
+
+type ServerGenerationPayload struct {
+	ChatID  int64           `json:"chat_id,required"`
+	Message *models.Message `json:"message,required"`
+}
+
+type ServerGenerationCreated struct {
+	ID      string                  `json:"id,required"      validate:"required,uuid4"`
+	Type    string                  `json:"type,required"`
+	Payload ServerGenerationPayload `json:"payload,required"`
+}
+
+func NewServerGenerationCreated(
+	id string,
+	chatID int64,
+	message *models.Message,
+) *ServerGenerationCreated {
+	return &ServerGenerationCreated{
+		ID:   id,
+		Type: "generation-created",
+		Payload: ServerGenerationPayload{
+			ChatID:  chatID,
+			Message: message,
+		},
+	}
+}
+
+func (message ServerGenerationCreated) __serverMessageSignature() {}
+
+type ServerGenerationSelected struct {
+	ID      string                  `json:"id,required"      validate:"required,uuid4"`
+	Type    string                  `json:"type,required"`
+	Payload ServerGenerationPayload `json:"payload,required"`
+}
+
+func NewServerGenerationSelected(
+	id string,
+	chatID int64,
+	message *models.Message,
+) *ServerGenerationSelected {
+	return &ServerGenerationSelected{
+		ID:   id,
+		Type: "generation-selected",
+		Payload: ServerGenerationPayload{
+			ChatID:  chatID,
+			Message: message,
+		},
+	}
+}
+
+func (message ServerGenerationSelected) __serverMessageSignature() {}
+
 type ServerChatCreatedPayload struct {
 	Chat    *models.Chat    `json:"chat,required"`
 	Message *models.Message `json:"message,required"`
@@ -146,6 +261,26 @@ func NewServerChatCreated(
 
 func (message ServerChatCreated) __serverMessageSignature() {}
 
+type ServerChatUpdatedPayload struct {
+	Chat *models.Chat `json:"chat,required"`
+}
+
+type ServerChatUpdated struct {
+	ID      string                   `json:"id,required"      validate:"required,uuid4"`
+	Type    string                   `json:"type,required"`
+	Payload ServerChatUpdatedPayload `json:"payload,required"`
+}
+
+func NewServerChatUpdated(id string, chat *models.Chat) *ServerChatUpdated {
+	return &ServerChatUpdated{
+		ID:      id,
+		Type:    "chat-updated",
+		Payload: ServerChatUpdatedPayload{Chat: chat},
+	}
+}
+
+func (message ServerChatUpdated) __serverMessageSignature() {}
+
 func Encode(serverMessage ServerEmittedMessage) ([]byte, error) {
 	err := validator.Validate.Struct(serverMessage)
 	if err != nil {
@@ -49,3 +49,63 @@ func NewServerReadTemplate(
 }
 
 func (message ServerReadTemplate) __serverMessageSignature() {}
+
+type ServerTemplateHistoryPayload struct {
+	Name     string                    `json:"name,required"`
+	Versions []*models.TemplateVersion `json:"versions,required"`
+}
+
+type ServerTemplateHistory struct {
+	ID      string                       `json:"id,required"       validate:"required,uuid4"`
+	Type    string                       `json:"type,required"`
+	Payload ServerTemplateHistoryPayload `json:"payload,omitempty"`
+}
+
+func NewServerTemplateHistory(
+	id string,
+	name string,
+	versions []*models.TemplateVersion,
+) *ServerTemplateHistory {
+	return &ServerTemplateHistory{
+		ID:   id,
+		Type: "template-history",
+		Payload: ServerTemplateHistoryPayload{
+			Name:     name,
+			Versions: versions,
+		}}
+}
+
+func (message ServerTemplateHistory) __serverMessageSignature() {}
+
+type ServerTemplateDiffPayload struct {
+	Name string `json:"name,required"`
+	From int64  `json:"from,required"`
+	To   int64  `json:"to,required"`
+	Diff string `json:"diff"`
+}
+
+type ServerTemplateDiff struct {
+	ID      string                    `json:"id,required"       validate:"required,uuid4"`
+	Type    string                    `json:"type,required"`
+	Payload ServerTemplateDiffPayload `json:"payload,omitempty"`
+}
+
+func NewServerTemplateDiff(
+	id string,
+	name string,
+	from int64,
+	to int64,
+	diff string,
+) *ServerTemplateDiff {
+	return &ServerTemplateDiff{
+		ID:   id,
+		Type: "template-diff",
+		Payload: ServerTemplateDiffPayload{
+			Name: name,
+			From: from,
+			To:   to,
+			Diff: diff,
+		}}
+}
+
+func (message ServerTemplateDiff) __serverMessageSignature() {}
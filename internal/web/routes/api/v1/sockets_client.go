@@ -19,11 +19,6 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 )
 
-var (
-	newline = []byte{'\n'}
-	space   = []byte{' '}
-)
-
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  0,
 	WriteBufferSize: 0,
@@ -137,15 +132,9 @@ func (c *Client) writePump() {
 			if err != nil {
 				return
 			}
+			// one frame per message, receivers parse each frame as single JSON
 			w.Write(message)
 
-			// Add queued chat messages to the current websocket message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write(newline)
-				w.Write(<-c.send)
-			}
-
 			if err := w.Close(); err != nil {
 				return
 			}
@@ -14,8 +14,12 @@ import (
 	"github.com/k10wl/hermes/internal/core"
 	"github.com/k10wl/hermes/internal/settings"
 	v1 "github.com/k10wl/hermes/internal/web/routes/api/v1"
+	"github.com/k10wl/hermes/internal/web/routes/api/v1/messages"
 )
 
+// cancelled completions only need to save streamed part
+const completionsShutdownTimeout = 10 * time.Second
+
 //go:embed assets
 var assetsEmbed embed.FS
 
@@ -45,7 +49,13 @@ func Serve(core *core.Core, config *settings.Config, hostname string, port strin
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	return httpServer.Shutdown(ctx)
+	err := httpServer.Shutdown(ctx)
+	// websocket connections are not tracked by http server, completions they
+	// started are waited for separately
+	if !messages.WaitCompletions(completionsShutdownTimeout) {
+		fmt.Fprintln(config.Stderr, "Running completions did not finish in time")
+	}
+	return err
 }
 
 func NewServer(core *core.Core) http.Handler {
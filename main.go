@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 
 	"github.com/k10wl/hermes/cmd"
 	"github.com/k10wl/hermes/internal/ai_clients"
@@ -13,15 +14,24 @@ import (
 )
 
 func prepare(
+	args []string,
 	stdin io.Reader,
 	stdout io.Writer,
 	stderr io.Writer,
 ) (*core.Core, error) {
-	config, err := settings.GetConfig(stdin, stdout, stderr)
+	profile, args := profileFromArgs(args)
+	config, err := settings.GetConfig(profile, stdin, stdout, stderr)
 	if err != nil {
 		return nil, err
 	}
-	sqlite, err := sqlite3.NewSQLite3(config.DatabaseDSN)
+	open := sqlite3.NewSQLite3
+	// db command manages migrations itself and config command may be used to
+	// fix database location, both have to work with database that can not
+	// be migrated
+	if len(args) > 0 && (args[0] == "db" || args[0] == "config") {
+		open = sqlite3.OpenSQLite3
+	}
+	sqlite, err := open(config.DatabaseDSN)
 	if err != nil {
 		return nil, err
 	}
@@ -29,14 +39,41 @@ func prepare(
 	return hermesCore, nil
 }
 
+// config is loaded before cobra parses flags, so global --profile flag is
+// read here. Returns remaining args
+func profileFromArgs(args []string) (string, []string) {
+	profile := ""
+	rest := []string{}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--profile="); ok {
+			profile = value
+			continue
+		}
+		if arg == "--profile" && i+1 < len(args) {
+			profile = args[i+1]
+			i++
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return profile, rest
+}
+
 func main() {
-	core, err := prepare(os.Stdin, os.Stdout, os.Stderr)
+	core, err := prepare(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 		os.Exit(1)
 	}
 	defer core.GetDB().Close()
-	if err := cmd.Execute(core, ai_clients.Complete); err != nil {
+	err = cmd.Execute(core, ai_clients.Complete)
+	core.WaitBackground()
+	if err != nil {
 		os.Exit(1)
 	}
 }